@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"api-service/gateway"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовки, которыми шлюз передает сервисам проверенную личность пользователя.
+// Значения из клиентского запроса всегда удаляются.
+const (
+	HeaderUserID        = "X-User-ID"
+	HeaderUserRoles     = "X-User-Roles"
+	HeaderEmailVerified = "X-User-Email-Verified"
+	// HeaderUserCompanies - роли в командах компаний в виде "12:owner,15:manager"
+	HeaderUserCompanies = "X-User-Companies"
+	// HeaderUserSegments - сегменты пользователя через запятую, например "employees,vip"
+	HeaderUserSegments = "X-User-Segments"
+)
+
+var identityHeaders = []string{HeaderUserID, HeaderUserRoles, HeaderEmailVerified, HeaderUserCompanies, HeaderUserSegments}
+
+var (
+	ErrInvalidToken    = errors.New("недействительный токен")
+	ErrKeysUnavailable = errors.New("не удалось получить ключи проверки токена")
+	// ErrRevocationUnavailable - user-service не ответил, отозван ли токен
+	ErrRevocationUnavailable = errors.New("не удалось проверить, отозван ли токен")
+)
+
+type Identity struct {
+	UserID        uint
+	Roles         []string
+	EmailVerified bool
+	// Companies - роль пользователя в команде по идентификатору компании
+	Companies map[uint]string
+	Segments  []string
+}
+
+type TokenVerifier interface {
+	Verify(ctx context.Context, tokenString string) (*Identity, error)
+}
+
+func identityFromClaims(claims jwt.MapClaims) (*Identity, error) {
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return nil, ErrInvalidToken
+	}
+
+	identity := &Identity{UserID: uint(userID)}
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				identity.Roles = append(identity.Roles, name)
+			}
+		}
+	}
+	if segments, ok := claims["segments"].([]interface{}); ok {
+		for _, segment := range segments {
+			if name, ok := segment.(string); ok {
+				identity.Segments = append(identity.Segments, name)
+			}
+		}
+	}
+	if companies, ok := claims["companies"].(map[string]interface{}); ok {
+		identity.Companies = make(map[uint]string, len(companies))
+		for key, value := range companies {
+			companyID, err := strconv.ParseUint(key, 10, 64)
+			role, ok := value.(string)
+			if err == nil && ok && companyID > 0 {
+				identity.Companies[uint(companyID)] = role
+			}
+		}
+	}
+	return identity, nil
+}
+
+// companiesHeader собирает значение X-User-Companies в порядке возрастания идентификаторов
+func companiesHeader(companies map[uint]string) string {
+	ids := make([]uint, 0, len(companies))
+	for id := range companies {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%d:%s", id, companies[id]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Middleware проверяет токен на защищенных маршрутах и передает сервисам
+// X-User-ID / X-User-Roles / X-User-Email-Verified / X-User-Companies / X-User-Segments.
+// Должен стоять после gateway.Resolve.
+func Middleware(verifier TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, header := range identityHeaders {
+			c.Request.Header.Del(header)
+		}
+
+		route := gateway.RouteFromContext(c)
+		if route == nil || !route.Protected {
+			c.Next()
+			return
+		}
+
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "требуется заголовок авторизации"})
+			return
+		}
+
+		tokenParts := strings.Split(authHeader, " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "неверный формат заголовка авторизации"})
+			return
+		}
+
+		identity, err := verifier.Verify(c.Request.Context(), tokenParts[1])
+		if errors.Is(err, ErrKeysUnavailable) || errors.Is(err, ErrRevocationUnavailable) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(gateway.UserIDKey, identity.UserID)
+		c.Request.Header.Set(HeaderUserID, strconv.FormatUint(uint64(identity.UserID), 10))
+		c.Request.Header.Set(HeaderUserRoles, strings.Join(identity.Roles, ","))
+		c.Request.Header.Set(HeaderEmailVerified, strconv.FormatBool(identity.EmailVerified))
+		c.Request.Header.Set(HeaderUserCompanies, companiesHeader(identity.Companies))
+		c.Request.Header.Set(HeaderUserSegments, strings.Join(identity.Segments, ","))
+		c.Next()
+	}
+}
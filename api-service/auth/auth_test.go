@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"api-service/config"
+	"api-service/gateway"
+	"api-service/pb/userpb"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Ошибка подписи токена: %v", err)
+	}
+	return tokenString
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Ошибка генерации ключа: %v", err)
+	}
+	return key
+}
+
+// jwksServer публикует переданные ключи так же, как user-service
+type jwksServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	requests int
+}
+
+func newJWKSServer(keys map[string]*rsa.PrivateKey) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		var body struct {
+			Keys []jwk `json:"keys"`
+		}
+		for kid, key := range s.keys {
+			body.Keys = append(body.Keys, jwk{
+				Kty: "RSA",
+				Alg: "RS256",
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	return s
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	oldKey, newKey, foreignKey := generateKey(t), generateKey(t), generateKey(t)
+	server := newJWKSServer(map[string]*rsa.PrivateKey{"old": oldKey})
+	defer server.Close()
+	verifier := NewJWKSVerifier(server.URL, time.Hour)
+
+	identity, err := verifier.Verify(context.Background(), signToken(t, oldKey, "old", jwt.MapClaims{
+		"user_id": 7,
+		"roles":   []string{"user", "admin"},
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}))
+	if err != nil {
+		t.Fatalf("Ожидается действительный токен, получено: %v", err)
+	}
+	if identity.UserID != 7 || len(identity.Roles) != 2 {
+		t.Errorf("Неверная личность из токена: %+v", identity)
+	}
+
+	_, err = verifier.Verify(context.Background(), signToken(t, foreignKey, "old", jwt.MapClaims{"user_id": 7}))
+	if err == nil {
+		t.Error("Токен с чужой подписью должен отклоняться")
+	}
+
+	_, err = verifier.Verify(context.Background(), signToken(t, oldKey, "old", jwt.MapClaims{
+		"user_id": 7,
+		"exp":     time.Now().Add(-time.Minute).Unix(),
+	}))
+	if err == nil {
+		t.Error("Просроченный токен должен отклоняться")
+	}
+
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 7})
+	hmacToken.Header["kid"] = "old"
+	hmacString, _ := hmacToken.SignedString([]byte("my_secret_key"))
+	if _, err := verifier.Verify(context.Background(), hmacString); err == nil {
+		t.Error("Токен с алгоритмом HS256 должен отклоняться")
+	}
+
+	// После ротации user-service публикует новый ключ, шлюз подхватывает его по неизвестному kid
+	server.mu.Lock()
+	server.keys["new"] = newKey
+	server.mu.Unlock()
+	verifier.fetchedAt = time.Now().Add(-jwksMinRefreshInterval)
+	if _, err := verifier.Verify(context.Background(), signToken(t, newKey, "new", jwt.MapClaims{"user_id": 7})); err != nil {
+		t.Errorf("Ожидается действительный токен нового ключа, получено: %v", err)
+	}
+
+	requests := server.requests
+	verifier.Verify(context.Background(), signToken(t, foreignKey, "unknown", jwt.MapClaims{"user_id": 7}))
+	verifier.Verify(context.Background(), signToken(t, foreignKey, "unknown", jwt.MapClaims{"user_id": 7}))
+	if server.requests != requests {
+		t.Errorf("Неизвестный kid не должен вызывать запрос JWKS чаще раза в %v", jwksMinRefreshInterval)
+	}
+}
+
+func TestJWKSVerifierUnavailable(t *testing.T) {
+	server := newJWKSServer(nil)
+	server.Close()
+
+	_, err := NewJWKSVerifier(server.URL, time.Hour).Verify(context.Background(), signToken(t, generateKey(t), "kid", jwt.MapClaims{"user_id": 7}))
+	if err != ErrKeysUnavailable {
+		t.Errorf("Ожидается ErrKeysUnavailable, получено: %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gw, err := gateway.New(&config.Config{
+		Services: map[string]config.Service{"user-service": {URL: "http://user-service:8081"}},
+		Routes: []config.Route{
+			{Path: "/profile", ServiceName: "user-service", Protected: true},
+			{Path: "/login", ServiceName: "user-service"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания шлюза: %v", err)
+	}
+
+	key := generateKey(t)
+	server := newJWKSServer(map[string]*rsa.PrivateKey{"kid": key})
+	defer server.Close()
+
+	var forwardedUserID, forwardedEmailVerified, forwardedCompanies, forwardedSegments string
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.NoRoute(gw.Resolve, Middleware(NewJWKSVerifier(server.URL, time.Hour)), func(c *gin.Context) {
+		forwardedUserID = c.Request.Header.Get(HeaderUserID)
+		forwardedEmailVerified = c.Request.Header.Get(HeaderEmailVerified)
+		forwardedCompanies = c.Request.Header.Get(HeaderUserCompanies)
+		forwardedSegments = c.Request.Header.Get(HeaderUserSegments)
+		c.Status(http.StatusOK)
+	})
+
+	send := func(path, token string) int {
+		forwardedUserID = ""
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set(HeaderUserID, "1")
+		req.Header.Set(HeaderEmailVerified, "true")
+		req.Header.Set(HeaderUserCompanies, "1:owner")
+		req.Header.Set(HeaderUserSegments, "vip")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send("/profile", ""); code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401 без токена, получен: %d", code)
+	}
+	if code := send("/profile", "garbage"); code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401 для недействительного токена, получен: %d", code)
+	}
+
+	token := signToken(t, key, "kid", jwt.MapClaims{
+		"user_id":        42,
+		"email_verified": false,
+		"companies":      map[string]string{"15": "manager", "12": "owner"},
+		"segments":       []string{"employees", "vip"},
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	})
+	if code := send("/profile", token); code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", code)
+	}
+	if forwardedUserID != "42" {
+		t.Errorf("Ожидается X-User-ID=42, получено: %q", forwardedUserID)
+	}
+	if forwardedEmailVerified != "false" {
+		t.Errorf("Ожидается X-User-Email-Verified=false, получено: %q", forwardedEmailVerified)
+	}
+	if forwardedCompanies != "12:owner,15:manager" {
+		t.Errorf("Ожидается X-User-Companies=12:owner,15:manager, получено: %q", forwardedCompanies)
+	}
+	if forwardedSegments != "employees,vip" {
+		t.Errorf("Ожидается X-User-Segments=employees,vip, получено: %q", forwardedSegments)
+	}
+
+	if code := send("/login", ""); code != http.StatusOK {
+		t.Errorf("Открытый маршрут не должен требовать токен, получен код: %d", code)
+	}
+	if forwardedUserID != "" {
+		t.Errorf("Клиентский X-User-ID должен удаляться, получено: %q", forwardedUserID)
+	}
+	if forwardedEmailVerified != "" {
+		t.Errorf("Клиентский X-User-Email-Verified должен удаляться, получено: %q", forwardedEmailVerified)
+	}
+	if forwardedCompanies != "" {
+		t.Errorf("Клиентский X-User-Companies должен удаляться, получено: %q", forwardedCompanies)
+	}
+	if forwardedSegments != "" {
+		t.Errorf("Клиентский X-User-Segments должен удаляться, получено: %q", forwardedSegments)
+	}
+}
+
+// stubVerifier принимает любой токен, кроме "forged"
+type stubVerifier struct{}
+
+func (stubVerifier) Verify(ctx context.Context, tokenString string) (*Identity, error) {
+	if tokenString == "forged" {
+		return nil, ErrInvalidToken
+	}
+	return &Identity{UserID: 7}, nil
+}
+
+// stubValidator отвечает как user-service: токены из revoked отозваны, при down сервис недоступен
+type stubValidator struct {
+	revoked map[string]bool
+	down    bool
+	calls   int
+}
+
+func (v *stubValidator) ValidateToken(ctx context.Context, in *userpb.ValidateTokenRequest, opts ...grpc.CallOption) (*userpb.ValidateTokenResponse, error) {
+	v.calls++
+	if v.down {
+		return nil, status.Error(codes.Unavailable, "user-service недоступен")
+	}
+	if v.revoked[in.GetToken()] {
+		return nil, status.Error(codes.Unauthenticated, "токен отозван")
+	}
+	return &userpb.ValidateTokenResponse{UserId: 7}, nil
+}
+
+func TestRevocationVerifier(t *testing.T) {
+	ctx := context.Background()
+	validator := &stubValidator{revoked: map[string]bool{"logged-out": true}}
+	verifier := NewRevocationVerifier(stubVerifier{}, validator, 10*time.Second)
+	now := time.Now()
+	verifier.now = func() time.Time { return now }
+
+	if _, err := verifier.Verify(ctx, "forged"); err != ErrInvalidToken || validator.calls != 0 {
+		t.Errorf("Токен с неверной подписью не должен доходить до user-service: %v, вызовов %d", err, validator.calls)
+	}
+	if _, err := verifier.Verify(ctx, "logged-out"); err != ErrInvalidToken {
+		t.Errorf("Ожидается отказ для отозванного токена, получено: %v", err)
+	}
+	identity, err := verifier.Verify(ctx, "active")
+	if err != nil || identity.UserID != 7 {
+		t.Fatalf("Ожидается действующий токен, получено: %+v, %v", identity, err)
+	}
+
+	// Пока ответ в кэше, user-service не спрашивается, поэтому отзыв доходит с задержкой до cacheTTL
+	validator.revoked["active"] = true
+	verifier.Verify(ctx, "active")
+	verifier.Verify(ctx, "logged-out")
+	if validator.calls != 2 {
+		t.Errorf("Ожидаются ответы из кэша, вызовов user-service: %d", validator.calls)
+	}
+	now = now.Add(10 * time.Second)
+	if _, err := verifier.Verify(ctx, "active"); err != ErrInvalidToken {
+		t.Errorf("После cacheTTL отозванный токен должен отклоняться, получено: %v", err)
+	}
+
+	validator.down = true
+	if _, err := verifier.Verify(ctx, "other"); err != ErrRevocationUnavailable {
+		t.Errorf("Ожидается ErrRevocationUnavailable, получено: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	jwksFetchTimeout = 5 * time.Second
+	// Не чаще этого интервала JWKS перезапрашивается из-за неизвестного kid
+	jwksMinRefreshInterval = 10 * time.Second
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSVerifier проверяет RS256-токены открытыми ключами из JWKS user-service.
+// Ключи кэшируются на refreshInterval; токен с неизвестным kid вызывает внеочередное обновление,
+// так что новый ключ после ротации подхватывается сразу.
+type JWKSVerifier struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewJWKSVerifier(url string, refreshInterval time.Duration) *JWKSVerifier {
+	return &JWKSVerifier{
+		url:             url,
+		client:          &http.Client{Timeout: jwksFetchTimeout},
+		refreshInterval: refreshInterval,
+	}
+}
+
+func (v *JWKSVerifier) Verify(ctx context.Context, tokenString string) (*Identity, error) {
+	var keyErr error
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, ErrInvalidToken
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.key(kid)
+		if err != nil {
+			keyErr = err
+			return nil, err
+		}
+		return key, nil
+	})
+	if keyErr == ErrKeysUnavailable {
+		return nil, ErrKeysUnavailable
+	}
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return identityFromClaims(claims)
+}
+
+func (v *JWKSVerifier) key(kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.keys[kid]
+	age := time.Since(v.fetchedAt)
+	if ok && age < v.refreshInterval {
+		return key, nil
+	}
+	if !ok && v.keys != nil && age < jwksMinRefreshInterval {
+		return nil, ErrInvalidToken
+	}
+
+	keys, err := v.fetch()
+	if err != nil {
+		log.Printf("Ошибка загрузки JWKS из %s: %v", v.url, err)
+		// Пока user-service недоступен, продолжаем проверять токены известными ключами
+		if ok {
+			return key, nil
+		}
+		return nil, ErrKeysUnavailable
+	}
+	v.keys = keys
+	v.fetchedAt = time.Now()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrInvalidToken
+}
+
+func (v *JWKSVerifier) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := v.client.Get(v.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("сервис ответил %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(body.Keys))
+	for _, key := range body.Keys {
+		if key.Kty != "RSA" || (key.Alg != "" && key.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("ключ %s: %w", key.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("ключ %s: %w", key.Kid, err)
+		}
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+var _ TokenVerifier = (*JWKSVerifier)(nil)
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"api-service/pb/userpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	revocationCheckTimeout = 2 * time.Second
+	// revocationCacheSize - после стольких записей кэш очищается от устаревших
+	revocationCacheSize = 10000
+)
+
+// TokenValidator - часть gRPC-клиента user-service, которая проверяет, не отозван ли токен
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, in *userpb.ValidateTokenRequest, opts ...grpc.CallOption) (*userpb.ValidateTokenResponse, error)
+}
+
+// RevocationVerifier дополняет проверку подписи вопросом к user-service, действует ли еще сессия токена.
+// Выход, блокировка пользователя, смена пароля и исключение из команды отзывают сессии, и такие токены
+// отклоняются раньше exp. Ответы кэшируются на cacheTTL: это наибольшая задержка, с которой отзыв
+// доходит до шлюза.
+type RevocationVerifier struct {
+	verifier  TokenVerifier
+	validator TokenValidator
+	cacheTTL  time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]revocationEntry
+}
+
+type revocationEntry struct {
+	valid     bool
+	expiresAt time.Time
+}
+
+func NewRevocationVerifier(verifier TokenVerifier, validator TokenValidator, cacheTTL time.Duration) *RevocationVerifier {
+	return &RevocationVerifier{
+		verifier:  verifier,
+		validator: validator,
+		cacheTTL:  cacheTTL,
+		now:       time.Now,
+		cache:     make(map[string]revocationEntry),
+	}
+}
+
+func (v *RevocationVerifier) Verify(ctx context.Context, tokenString string) (*Identity, error) {
+	// Подпись проверяется локально первой, чтобы поддельные токены не доходили до user-service
+	identity, err := v.verifier.Verify(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, cached := v.cached(tokenString)
+	if !cached {
+		ctx, cancel := context.WithTimeout(ctx, revocationCheckTimeout)
+		defer cancel()
+		_, err := v.validator.ValidateToken(ctx, &userpb.ValidateTokenRequest{Token: tokenString})
+		if status.Code(err) != codes.OK && status.Code(err) != codes.Unauthenticated {
+			return nil, ErrRevocationUnavailable
+		}
+		valid = err == nil
+		v.store(tokenString, valid)
+	}
+	if !valid {
+		return nil, ErrInvalidToken
+	}
+	return identity, nil
+}
+
+func (v *RevocationVerifier) cached(tokenString string) (bool, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entry, exists := v.cache[tokenString]
+	if !exists || !v.now().Before(entry.expiresAt) {
+		return false, false
+	}
+	return entry.valid, true
+}
+
+func (v *RevocationVerifier) store(tokenString string, valid bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	if len(v.cache) >= revocationCacheSize {
+		for key, entry := range v.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(v.cache, key)
+			}
+		}
+		// Все записи свежие - поток новых токенов слишком велик для кэша, начинаем заново
+		if len(v.cache) >= revocationCacheSize {
+			v.cache = make(map[string]revocationEntry)
+		}
+	}
+	v.cache[tokenString] = revocationEntry{valid: valid, expiresAt: now.Add(v.cacheTTL)}
+}
+
+var _ TokenVerifier = (*RevocationVerifier)(nil)
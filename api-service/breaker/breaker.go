@@ -0,0 +1,185 @@
+package breaker
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"api-service/config"
+)
+
+var ErrOpen = errors.New("цепь разомкнута")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type Status struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+	Threshold int        `json:"failure_threshold"`
+}
+
+// Breaker размыкает цепь после FailureThreshold неудач подряд. Через OpenTimeout
+// пропускается HalfOpenRequests пробных запросов: успех замыкает цепь, неудача снова размыкает.
+type Breaker struct {
+	name     string
+	settings config.BreakerConfig
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+func New(name string, settings config.BreakerConfig) *Breaker {
+	return &Breaker{
+		name:     name,
+		settings: settings.WithDefaults(),
+		now:      time.Now,
+	}
+}
+
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.settings.OpenTimeout() {
+			return ErrOpen
+		}
+		b.setState(StateHalfOpen)
+		b.probes = 1
+		return nil
+	case StateHalfOpen:
+		if b.probes >= b.settings.HalfOpenRequests {
+			return ErrOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state == StateHalfOpen {
+		b.setState(StateClosed)
+	}
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	switch b.state {
+	case StateHalfOpen:
+		b.open()
+	case StateClosed:
+		if b.failures >= b.settings.FailureThreshold {
+			b.open()
+		}
+	}
+}
+
+// Ignore освобождает пробный запрос, результат которого ничего не говорит о сервисе
+// (например, клиент сам отменил запрос)
+func (b *Breaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		Name:      b.name,
+		State:     b.state.String(),
+		Failures:  b.failures,
+		Threshold: b.settings.FailureThreshold,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.settings.OpenTimeout())
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.probes = 0
+	b.setState(StateOpen)
+}
+
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	log.Printf("Circuit breaker %s: %s -> %s (неудач подряд: %d)", b.name, b.state, state, b.failures)
+	b.state = state
+}
+
+type Registry struct {
+	breakers map[string]*Breaker
+}
+
+func NewRegistry(services map[string]config.Service) *Registry {
+	breakers := make(map[string]*Breaker, len(services))
+	for name, service := range services {
+		breakers[name] = New(name, service.CircuitBreaker)
+	}
+	return &Registry{breakers: breakers}
+}
+
+func (r *Registry) Get(name string) *Breaker {
+	return r.breakers[name]
+}
+
+func (r *Registry) Statuses() []Status {
+	statuses := make([]Status, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		statuses = append(statuses, b.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
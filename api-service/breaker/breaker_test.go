@@ -0,0 +1,75 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"api-service/config"
+)
+
+func TestBreakerStateTransitions(t *testing.T) {
+	b := New("user-service", config.BreakerConfig{FailureThreshold: 2, OpenTimeoutMs: 1000, HalfOpenRequests: 1})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	if b.State() != StateClosed {
+		t.Errorf("После одной неудачи цепь должна оставаться замкнутой, получено: %s", b.State())
+	}
+	b.Failure()
+	if b.State() != StateOpen {
+		t.Fatalf("После порога неудач цепь должна разомкнуться, получено: %s", b.State())
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("Разомкнутая цепь должна отклонять запросы, получено: %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Errorf("После таймаута должен пропускаться пробный запрос, получено: %v", err)
+	}
+	if b.State() != StateHalfOpen {
+		t.Errorf("Ожидается полуоткрытое состояние, получено: %s", b.State())
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("Второй пробный запрос должен отклоняться, получено: %v", err)
+	}
+
+	b.Failure()
+	if b.State() != StateOpen {
+		t.Errorf("Неудачный пробный запрос должен снова разомкнуть цепь, получено: %s", b.State())
+	}
+
+	now = now.Add(time.Second)
+	b.Allow()
+	b.Success()
+	if b.State() != StateClosed {
+		t.Errorf("Успешный пробный запрос должен замкнуть цепь, получено: %s", b.State())
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := New("user-service", config.BreakerConfig{FailureThreshold: 2})
+
+	b.Failure()
+	b.Success()
+	b.Failure()
+	if b.State() != StateClosed {
+		t.Errorf("Счет неудач должен сбрасываться после успеха, получено: %s", b.State())
+	}
+}
+
+func TestRegistryStatuses(t *testing.T) {
+	registry := NewRegistry(map[string]config.Service{
+		"user-service":       {URL: "http://user-service:8081"},
+		"promocodes-service": {URL: "http://promocodes-service:8082"},
+	})
+
+	statuses := registry.Statuses()
+	if len(statuses) != 2 || statuses[0].Name != "promocodes-service" {
+		t.Errorf("Ожидается отсортированный список из двух сервисов, получено: %+v", statuses)
+	}
+	if statuses[0].State != "closed" || statuses[0].Threshold != 5 {
+		t.Errorf("Ожидаются значения по умолчанию, получено: %+v", statuses[0])
+	}
+}
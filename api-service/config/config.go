@@ -0,0 +1,185 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config описывает таблицу маршрутизации шлюза (сущность ApiGateway в doc/gateway/ER_gateway.puml)
+type Config struct {
+	Services map[string]Service `yaml:"services"`
+	Defaults RouteConfig        `yaml:"defaults"`
+	Routes   []Route            `yaml:"routes"`
+}
+
+type Service struct {
+	URL            string        `yaml:"url"`
+	URLEnv         string        `yaml:"url_env"` // переменная окружения, переопределяющая url
+	GRPCAddr       string        `yaml:"grpc_addr"`
+	GRPCAddrEnv    string        `yaml:"grpc_addr_env"`
+	ReadinessPath  string        `yaml:"readiness_path"` // по умолчанию /readyz
+	CircuitBreaker BreakerConfig `yaml:"circuit_breaker"`
+}
+
+type BreakerConfig struct {
+	FailureThreshold int `yaml:"failure_threshold"`  // подряд неудачных запросов до размыкания
+	OpenTimeoutMs    int `yaml:"open_timeout_ms"`    // сколько цепь остается разомкнутой
+	HalfOpenRequests int `yaml:"half_open_requests"` // пробных запросов в полуоткрытом состоянии
+}
+
+func (c BreakerConfig) WithDefaults() BreakerConfig {
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = 5
+	}
+	if c.OpenTimeoutMs == 0 {
+		c.OpenTimeoutMs = 30000
+	}
+	if c.HalfOpenRequests == 0 {
+		c.HalfOpenRequests = 1
+	}
+	return c
+}
+
+func (c BreakerConfig) OpenTimeout() time.Duration {
+	return time.Duration(c.OpenTimeoutMs) * time.Millisecond
+}
+
+type Route struct {
+	Path        string      `yaml:"path"`   // префикс пути
+	Method      string      `yaml:"method"` // пустой или "*" означает любой метод
+	ServiceName string      `yaml:"service_name"`
+	Rewrite     string      `yaml:"rewrite"`     // чем заменить префикс path при проксировании
+	Protected   bool        `yaml:"protected"`   // требуется действительный токен доступа
+	GRPCMethod  string      `yaml:"grpc_method"` // если задан, запрос транскодируется в вызов gRPC
+	Config      RouteConfig `yaml:"config"`
+}
+
+// Disabled в конфигурации маршрута отключает лимит, дедлайн, повторы или паузу между ними,
+// а не берет значение из defaults, как незаданное (нулевое) поле
+const Disabled = -1
+
+// RouteConfig соответствует сущности Config; незаданные поля берутся из defaults
+type RouteConfig struct {
+	MaxRequestsPerTime int `yaml:"max_requests_per_time"` // 0 после слияния с defaults - без ограничения
+	TimeWindow         int `yaml:"time_window"`           // в секундах
+	TimeoutMs          int `yaml:"timeout_ms"`            // дедлайн запроса к сервису, 0 после слияния - без дедлайна
+	Retries            int `yaml:"retries"`               // повторы только для идемпотентных методов
+	RetryTimeoutMs     int `yaml:"retry_timeout_ms"`      // пауза перед первым повтором, далее удваивается
+}
+
+func (c RouteConfig) withDefaults(defaults RouteConfig) RouteConfig {
+	c.MaxRequestsPerTime = inherit(c.MaxRequestsPerTime, defaults.MaxRequestsPerTime)
+	if c.TimeWindow == 0 {
+		c.TimeWindow = defaults.TimeWindow
+	}
+	c.TimeoutMs = inherit(c.TimeoutMs, defaults.TimeoutMs)
+	c.Retries = inherit(c.Retries, defaults.Retries)
+	c.RetryTimeoutMs = inherit(c.RetryTimeoutMs, defaults.RetryTimeoutMs)
+	return c
+}
+
+func inherit(value, defaultValue int) int {
+	if value == 0 {
+		value = defaultValue
+	}
+	if value == Disabled {
+		return 0
+	}
+	return value
+}
+
+func (c RouteConfig) Window() time.Duration {
+	return time.Duration(c.TimeWindow) * time.Second
+}
+
+func (c RouteConfig) Timeout() time.Duration {
+	return time.Duration(c.TimeoutMs) * time.Millisecond
+}
+
+func (c RouteConfig) RetryBackoff() time.Duration {
+	return time.Duration(c.RetryTimeoutMs) * time.Millisecond
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	for name, service := range cfg.Services {
+		if service.URLEnv != "" {
+			if value := os.Getenv(service.URLEnv); value != "" {
+				service.URL = value
+			}
+		}
+		if service.GRPCAddrEnv != "" {
+			if value := os.Getenv(service.GRPCAddrEnv); value != "" {
+				service.GRPCAddr = value
+			}
+		}
+		if service.ReadinessPath == "" {
+			service.ReadinessPath = "/readyz"
+		}
+		service.CircuitBreaker = service.CircuitBreaker.WithDefaults()
+		cfg.Services[name] = service
+	}
+
+	for i := range cfg.Routes {
+		cfg.Routes[i].Method = strings.ToUpper(cfg.Routes[i].Method)
+		if cfg.Routes[i].Method == "*" {
+			cfg.Routes[i].Method = ""
+		}
+		cfg.Routes[i].Config = cfg.Routes[i].Config.withDefaults(cfg.Defaults)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) Validate() error {
+	if len(c.Routes) == 0 {
+		return errors.New("не задано ни одного маршрута")
+	}
+	for name, service := range c.Services {
+		if service.URL == "" {
+			return fmt.Errorf("у сервиса %s не задан url", name)
+		}
+		if service.CircuitBreaker.FailureThreshold < 0 || service.CircuitBreaker.OpenTimeoutMs < 0 || service.CircuitBreaker.HalfOpenRequests < 0 {
+			return fmt.Errorf("у сервиса %s заданы отрицательные параметры circuit_breaker", name)
+		}
+	}
+	for _, route := range c.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return fmt.Errorf("путь маршрута должен начинаться с /: %q", route.Path)
+		}
+		service, ok := c.Services[route.ServiceName]
+		if !ok {
+			return fmt.Errorf("маршрут %s ссылается на неизвестный сервис %q", route.Path, route.ServiceName)
+		}
+		if route.GRPCMethod != "" && service.GRPCAddr == "" {
+			return fmt.Errorf("маршрут %s использует gRPC, но у сервиса %s не задан grpc_addr", route.Path, route.ServiceName)
+		}
+		if route.Config.MaxRequestsPerTime > 0 && route.Config.TimeWindow <= 0 {
+			return fmt.Errorf("у маршрута %s задан лимит запросов без time_window", route.Path)
+		}
+		if route.Config.TimeoutMs < 0 || route.Config.Retries < 0 || route.Config.RetryTimeoutMs < 0 {
+			return fmt.Errorf("у маршрута %s заданы отрицательные таймауты или повторы", route.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+const testConfig = `
+services:
+  user-service:
+    url: http://user-service:8081
+    url_env: TEST_USER_SERVICE_URL
+routes:
+  - path: /profile
+    method: get
+    service_name: user-service
+  - path: /users
+    method: "*"
+    service_name: user-service
+    rewrite: /
+`
+
+func TestParse(t *testing.T) {
+	os.Setenv("TEST_USER_SERVICE_URL", "http://localhost:9000")
+	defer os.Unsetenv("TEST_USER_SERVICE_URL")
+
+	cfg, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("Ожидается успешный разбор конфигурации, получено: %v", err)
+	}
+
+	if cfg.Services["user-service"].URL != "http://localhost:9000" {
+		t.Errorf("Ожидается url из переменной окружения, получено: %s", cfg.Services["user-service"].URL)
+	}
+	if cfg.Routes[0].Method != "GET" {
+		t.Errorf("Ожидается метод GET, получено: %s", cfg.Routes[0].Method)
+	}
+	if cfg.Routes[1].Method != "" {
+		t.Errorf("Ожидается любой метод, получено: %s", cfg.Routes[1].Method)
+	}
+	if cfg.Services["user-service"].ReadinessPath != "/readyz" {
+		t.Errorf("Ожидается путь проверки готовности по умолчанию, получено: %s", cfg.Services["user-service"].ReadinessPath)
+	}
+}
+
+func TestParseUnknownService(t *testing.T) {
+	_, err := Parse([]byte(`
+services:
+  user-service:
+    url: http://user-service:8081
+routes:
+  - path: /promocodes
+    service_name: promocodes-service
+`))
+	if err == nil {
+		t.Error("Ожидается ошибка для неизвестного сервиса")
+	}
+}
+
+func TestParseDisabledRouteSettings(t *testing.T) {
+	cfg, err := Parse([]byte(`
+services:
+  user-service:
+    url: http://user-service:8081
+defaults:
+  max_requests_per_time: 100
+  time_window: 60
+  timeout_ms: 5000
+  retries: 2
+  retry_timeout_ms: 100
+routes:
+  - path: /profile
+    service_name: user-service
+  - path: /upload
+    service_name: user-service
+    config:
+      max_requests_per_time: -1
+      timeout_ms: -1
+      retries: -1
+`))
+	if err != nil {
+		t.Fatalf("Ожидается успешный разбор конфигурации, получено: %v", err)
+	}
+
+	inherited := cfg.Routes[0].Config
+	if inherited.MaxRequestsPerTime != 100 || inherited.TimeoutMs != 5000 || inherited.Retries != 2 {
+		t.Errorf("Незаданные поля должны браться из defaults, получено: %+v", inherited)
+	}
+	disabled := cfg.Routes[1].Config
+	if disabled.MaxRequestsPerTime != 0 || disabled.TimeoutMs != 0 || disabled.Retries != 0 || disabled.RetryTimeoutMs != 100 {
+		t.Errorf("Значение -1 должно отключать настройку, получено: %+v", disabled)
+	}
+}
@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"api-service/breaker"
+	"api-service/config"
+	"api-service/metrics"
+	"api-service/transcoder"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+)
+
+const (
+	routeKey = "gatewayRoute"
+	// UserIDKey - ключ контекста с идентификатором аутентифицированного пользователя
+	UserIDKey = "userID"
+)
+
+type Gateway struct {
+	routes   *RouteTable
+	proxies  map[string]*httputil.ReverseProxy
+	conns    map[string]*grpc.ClientConn
+	breakers *breaker.Registry
+}
+
+func New(cfg *config.Config) (*Gateway, error) {
+	breakers := breaker.NewRegistry(cfg.Services)
+	proxies := make(map[string]*httputil.ReverseProxy, len(cfg.Services))
+	for name, service := range cfg.Services {
+		target, err := url.Parse(service.URL)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный url сервиса %s: %w", name, err)
+		}
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = &retryTransport{
+			base: &breakerTransport{breaker: breakers.Get(name), base: http.DefaultTransport},
+		}
+		proxy.ErrorHandler = proxyErrorHandler(name)
+		proxies[name] = proxy
+	}
+
+	for _, route := range cfg.Routes {
+		if route.GRPCMethod == "" {
+			continue
+		}
+		if _, ok := transcoder.Lookup(route.GRPCMethod); !ok {
+			return nil, fmt.Errorf("неизвестный метод gRPC %q у маршрута %s", route.GRPCMethod, route.Path)
+		}
+	}
+
+	conns, err := dialServices(cfg.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Gateway{
+		routes:   NewRouteTable(cfg.Routes),
+		proxies:  proxies,
+		conns:    conns,
+		breakers: breakers,
+	}, nil
+}
+
+// Conn возвращает gRPC-соединение с сервисом или nil, если у сервиса не задан grpc_addr
+func (g *Gateway) Conn(serviceName string) *grpc.ClientConn {
+	return g.conns[serviceName]
+}
+
+func (g *Gateway) Breakers() *breaker.Registry {
+	return g.breakers
+}
+
+// Resolve находит маршрут запроса и сохраняет его в контексте для следующих обработчиков
+func (g *Gateway) Resolve(c *gin.Context) {
+	route, pathMatched := g.routes.Match(c.Request.Method, c.Request.URL.Path)
+	if route == nil {
+		if pathMatched {
+			c.AbortWithStatusJSON(http.StatusMethodNotAllowed, gin.H{"error": "метод не поддерживается"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "маршрут не найден"})
+		return
+	}
+
+	c.Set(routeKey, route)
+	c.Next()
+}
+
+func (g *Gateway) Proxy(c *gin.Context) {
+	route := RouteFromContext(c)
+	if route == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "маршрут не найден"})
+		return
+	}
+
+	proxy := g.proxies[route.ServiceName]
+	log.Printf("API Gateway получил запрос [%s]: %s %s -> %s", c.GetHeader("X-Request-ID"), c.Request.Method, c.Request.URL.Path, route.ServiceName)
+
+	ctx := withRoute(c.Request.Context(), route)
+	if timeout := route.Config.Timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if route.GRPCMethod != "" {
+		g.transcode(ctx, c, route)
+		return
+	}
+
+	req := c.Request.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	req.URL.Path = RewritePath(route, req.URL.Path)
+	req.URL.RawPath = ""
+	proxy.ServeHTTP(c.Writer, req)
+}
+
+func proxyErrorHandler(serviceName string) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		if errors.Is(err, breaker.ErrOpen) {
+			metrics.ProxyErrors.WithLabelValues(serviceName, "circuit_open").Inc()
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, "сервис временно недоступен")
+			return
+		}
+
+		log.Printf("Ошибка проксирования в %s: %v", serviceName, err)
+
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+			metrics.ProxyErrors.WithLabelValues(serviceName, "timeout").Inc()
+			writeError(w, http.StatusGatewayTimeout, "сервис не ответил вовремя")
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			// Клиент закрыл соединение, отвечать некому
+			return
+		}
+		metrics.ProxyErrors.WithLabelValues(serviceName, "unavailable").Inc()
+		writeError(w, http.StatusBadGateway, "сервис недоступен")
+	}
+}
+
+// writeError пишет ошибку в формате схемы Error из openapi.yml
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func RouteFromContext(c *gin.Context) *config.Route {
+	value, exists := c.Get(routeKey)
+	if !exists {
+		return nil
+	}
+	return value.(*config.Route)
+}
@@ -0,0 +1,391 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api-service/breaker"
+	"api-service/config"
+	"api-service/pb/userpb"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestRouteTableMatch(t *testing.T) {
+	table := NewRouteTable([]config.Route{
+		{Path: "/", ServiceName: "default"},
+		{Path: "/profile", Method: "GET", ServiceName: "user-service"},
+		{Path: "/promocodes", ServiceName: "promocodes-service"},
+		{Path: "/promocodes/stats", Method: "GET", ServiceName: "statistics-service"},
+	})
+
+	tests := []struct {
+		method  string
+		path    string
+		service string
+	}{
+		{"GET", "/profile", "user-service"},
+		{"GET", "/promocodes/42", "promocodes-service"},
+		{"GET", "/promocodes/stats", "statistics-service"},
+		{"POST", "/promocodes/stats", "promocodes-service"},
+		{"GET", "/promocodesx", "default"},
+	}
+
+	for _, tt := range tests {
+		route, _ := table.Match(tt.method, tt.path)
+		if route == nil || route.ServiceName != tt.service {
+			t.Errorf("%s %s: ожидается сервис %s, получено: %v", tt.method, tt.path, tt.service, route)
+		}
+	}
+}
+
+func TestRouteTableMethodNotAllowed(t *testing.T) {
+	table := NewRouteTable([]config.Route{
+		{Path: "/profile", Method: "GET", ServiceName: "user-service"},
+	})
+
+	route, pathMatched := table.Match("DELETE", "/profile")
+	if route != nil || !pathMatched {
+		t.Errorf("Ожидается совпадение пути без совпадения метода, получено: %v, %v", route, pathMatched)
+	}
+}
+
+func TestRewritePath(t *testing.T) {
+	route := &config.Route{Path: "/api/users", Rewrite: "/"}
+	if got := RewritePath(route, "/api/users/profile"); got != "/profile" {
+		t.Errorf("Ожидается /profile, получено: %s", got)
+	}
+
+	route = &config.Route{Path: "/promo", Rewrite: "/promocodes"}
+	if got := RewritePath(route, "/promo"); got != "/promocodes" {
+		t.Errorf("Ожидается /promocodes, получено: %s", got)
+	}
+	if got := RewritePath(route, "/promo/1"); got != "/promocodes/1" {
+		t.Errorf("Ожидается /promocodes/1, получено: %s", got)
+	}
+}
+
+func TestGatewayProxy(t *testing.T) {
+	var receivedPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message":"ok"}`))
+	}))
+	defer upstream.Close()
+
+	gw, err := New(&config.Config{
+		Services: map[string]config.Service{"user-service": {URL: upstream.URL}},
+		Routes: []config.Route{
+			{Path: "/users", Method: "GET", ServiceName: "user-service", Rewrite: "/"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания шлюза: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.NoRoute(gw.Resolve, gw.Proxy)
+	// ReverseProxy требует CloseNotifier, которого нет у httptest.ResponseRecorder
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/users/profile")
+	if err != nil {
+		t.Fatalf("Ошибка запроса к шлюзу: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", resp.StatusCode)
+	}
+	if receivedPath != "/profile" {
+		t.Errorf("Ожидается путь /profile у сервиса, получено: %s", receivedPath)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/users/profile", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Ожидается код 405, получен: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/unknown", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+}
+
+func newTestGateway(t *testing.T, upstreamURL string, routeConfig config.RouteConfig) *httptest.Server {
+	gw, err := New(&config.Config{
+		Services: map[string]config.Service{"user-service": {URL: upstreamURL}},
+		Routes:   []config.Route{{Path: "/", ServiceName: "user-service", Config: routeConfig}},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания шлюза: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.NoRoute(gw.Resolve, gw.Proxy)
+	return httptest.NewServer(r)
+}
+
+func TestGatewayRetriesIdempotentRequests(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer upstream.Close()
+
+	server := newTestGateway(t, upstream.URL, config.RouteConfig{Retries: 2, RetryTimeoutMs: 1})
+	defer server.Close()
+
+	req, _ := http.NewRequest("PUT", server.URL+"/profile", strings.NewReader(`{"phone":"1"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка запроса к шлюзу: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Ожидается код 200 после повторов, получен: %d", resp.StatusCode)
+	}
+	if string(body) != `{"phone":"1"}` {
+		t.Errorf("Тело запроса должно сохраняться между повторами, получено: %s", body)
+	}
+	if calls != 3 {
+		t.Errorf("Ожидается 3 обращения к сервису, получено: %d", calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	resp, err = http.Post(server.URL+"/login", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Ошибка запроса к шлюзу: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls != 1 {
+		t.Errorf("POST не должен повторяться: код %d, обращений %d", resp.StatusCode, calls)
+	}
+}
+
+// roundTripFunc позволяет подставить в retryTransport вместо сети функцию
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransportKeepsCallerRequest(t *testing.T) {
+	var bodies []string
+	transport := &retryTransport{base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		status := http.StatusServiceUnavailable
+		if len(bodies) == 2 {
+			status = http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})}
+
+	route := &config.Route{Path: "/", Config: config.RouteConfig{Retries: 2, RetryTimeoutMs: 1}}
+	req, _ := http.NewRequest("PUT", "http://user-service/profile", ioutil.NopCloser(strings.NewReader(`{"phone":"1"}`)))
+	req = req.WithContext(withRoute(req.Context(), route))
+	body := req.Body
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидается код 200 после повтора, получено: %v, %v", resp, err)
+	}
+	if len(bodies) != 2 || bodies[0] != `{"phone":"1"}` || bodies[1] != bodies[0] {
+		t.Errorf("Каждая попытка должна отправлять полное тело, получено: %q", bodies)
+	}
+	if req.Body != body || req.GetBody != nil {
+		t.Error("Запрос вызывающей стороны не должен изменяться")
+	}
+}
+
+func TestGatewayTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+
+	server := newTestGateway(t, upstream.URL, config.RouteConfig{TimeoutMs: 20})
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/profile")
+	if err != nil {
+		t.Fatalf("Ошибка запроса к шлюзу: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("Ожидается код 504, получен: %d", resp.StatusCode)
+	}
+	var response map[string]string
+	json.NewDecoder(resp.Body).Decode(&response)
+	if response["error"] == "" {
+		t.Errorf("Ожидается ошибка в формате {\"error\": ...}, получено: %v", response)
+	}
+}
+
+func TestGatewayCircuitBreaker(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	gw, err := New(&config.Config{
+		Services: map[string]config.Service{"user-service": {
+			URL:            upstream.URL,
+			CircuitBreaker: config.BreakerConfig{FailureThreshold: 2, OpenTimeoutMs: 60000},
+		}},
+		Routes: []config.Route{{Path: "/", ServiceName: "user-service"}},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания шлюза: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.NoRoute(gw.Resolve, gw.Proxy)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(server.URL + "/profile")
+		if err != nil {
+			t.Fatalf("Ошибка запроса к шлюзу: %v", err)
+		}
+		resp.Body.Close()
+		codes = append(codes, resp.StatusCode)
+	}
+
+	if codes[2] != http.StatusServiceUnavailable {
+		t.Errorf("Ожидается код 503 при разомкнутой цепи, получены: %v", codes)
+	}
+	if calls != 2 {
+		t.Errorf("При разомкнутой цепи запрос не должен доходить до сервиса, обращений: %d", calls)
+	}
+	if gw.Breakers().Get("user-service").State() != breaker.StateOpen {
+		t.Error("Ожидается разомкнутая цепь")
+	}
+}
+
+type testUserServer struct {
+	userpb.UnimplementedUserServiceServer
+}
+
+func (s *testUserServer) Register(ctx context.Context, req *userpb.RegisterRequest) (*userpb.RegisterResponse, error) {
+	return nil, status.Error(codes.InvalidArgument, "пользователь с таким логином уже существует")
+}
+
+func (s *testUserServer) Login(ctx context.Context, req *userpb.LoginRequest) (*userpb.LoginResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if req.GetLogin() == "locked" {
+		st, _ := status.New(codes.ResourceExhausted, "слишком много попыток входа, повторите позже").
+			WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(90 * time.Second)})
+		return nil, st.Err()
+	}
+	if req.GetLogin() != "testuser" || len(md.Get("x-user-id")) != 0 {
+		return nil, status.Error(codes.Unauthenticated, "неверный логин или пароль")
+	}
+	if ua := md.Get("x-forwarded-user-agent"); len(ua) != 1 || ua[0] != "test-agent" {
+		return nil, status.Error(codes.InvalidArgument, "не передан User-Agent клиента")
+	}
+	return &userpb.LoginResponse{Token: "test_token"}, nil
+}
+
+func TestGatewayTranscodesToGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Не удалось открыть порт: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	userpb.RegisterUserServiceServer(grpcServer, &testUserServer{})
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	gw, err := New(&config.Config{
+		Services: map[string]config.Service{"user-service": {URL: "http://unused", GRPCAddr: listener.Addr().String()}},
+		Routes: []config.Route{
+			{Path: "/register", Method: "POST", ServiceName: "user-service", GRPCMethod: "UserService/Register"},
+			{Path: "/login", Method: "POST", ServiceName: "user-service", GRPCMethod: "UserService/Login"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания шлюза: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.NoRoute(gw.Resolve, gw.Proxy)
+
+	send := func(path, body string) (int, map[string]string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "test-agent")
+		r.ServeHTTP(w, req)
+		var response map[string]string
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	code, response := send("/login", `{"login":"testuser","password":"password123"}`)
+	if code != http.StatusOK || response["token"] != "test_token" {
+		t.Errorf("Ожидается код 200 и токен, получено: %d %v", code, response)
+	}
+
+	code, response = send("/login", `{"login":"other","password":"password123"}`)
+	if code != http.StatusUnauthorized || response["error"] != "неверный логин или пароль" {
+		t.Errorf("Ожидается код 401 с сообщением сервиса, получено: %d %v", code, response)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"login":"locked","password":"password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "90" {
+		t.Errorf("Ожидается код 429 с Retry-After из RetryInfo, получено: %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	code, _ = send("/register", `{"login":"testuser","password":"password123","email":"test@example.com"}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400, получен: %d", code)
+	}
+
+	code, _ = send("/login", `not json`)
+	if code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного JSON, получен: %d", code)
+	}
+}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api-service/breaker"
+	"api-service/config"
+	"api-service/metrics"
+	"api-service/transcoder"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Заголовки HTTP-запроса, которые передаются сервису в метаданных gRPC
+var forwardedHeaders = []string{"Authorization", "X-User-ID", "X-User-Roles", "X-User-Email-Verified", "X-User-Companies", "X-User-Segments", "X-Request-ID"}
+
+func dialServices(services map[string]config.Service) (map[string]*grpc.ClientConn, error) {
+	conns := make(map[string]*grpc.ClientConn)
+	for name, service := range services {
+		if service.GRPCAddr == "" {
+			continue
+		}
+		// Соединение устанавливается лениво, поэтому недоступный сервис не мешает старту шлюза
+		conn, err := grpc.Dial(service.GRPCAddr,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать gRPC-клиент сервиса %s: %w", name, err)
+		}
+		conns[name] = conn
+	}
+	return conns, nil
+}
+
+func (g *Gateway) transcode(ctx context.Context, c *gin.Context, route *config.Route) {
+	method, _ := transcoder.Lookup(route.GRPCMethod)
+	req, err := method.Decode(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, header := range forwardedHeaders {
+		if value := c.GetHeader(header); value != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, header, value)
+		}
+	}
+	// Адрес и User-Agent клиента нужны сервису для списка сессий; заголовок user-agent gRPC занимает сам
+	ctx = metadata.AppendToOutgoingContext(ctx,
+		"X-Forwarded-For", c.ClientIP(),
+		"X-Forwarded-User-Agent", c.Request.UserAgent(),
+	)
+
+	conn := g.conns[route.ServiceName]
+	b := g.breakers.Get(route.ServiceName)
+	attempts := 1
+	if isIdempotent(c.Request.Method) {
+		attempts += route.Config.Retries
+	}
+
+	var resp proto.Message
+	for attempt := 0; ; attempt++ {
+		if err = b.Allow(); err != nil {
+			break
+		}
+		resp, err = method.Invoke(ctx, conn, req)
+		recordGRPCResult(b, err)
+
+		if err == nil || attempt+1 >= attempts || status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(route.Config.RetryBackoff() << uint(attempt)):
+			continue
+		}
+		break
+	}
+
+	if err != nil {
+		writeGRPCError(c, route.ServiceName, err)
+		return
+	}
+	method.Encode(c, resp)
+}
+
+func recordGRPCResult(b *breaker.Breaker, err error) {
+	switch status.Code(err) {
+	case codes.Canceled:
+		b.Ignore()
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+		b.Failure()
+	default:
+		b.Success()
+	}
+}
+
+func writeGRPCError(c *gin.Context, serviceName string, err error) {
+	if errors.Is(err, breaker.ErrOpen) {
+		metrics.ProxyErrors.WithLabelValues(serviceName, "circuit_open").Inc()
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "сервис временно недоступен"})
+		return
+	}
+
+	code := status.Code(err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		code = codes.DeadlineExceeded
+	} else if errors.Is(err, context.Canceled) {
+		code = codes.Canceled
+	}
+
+	switch code {
+	case codes.Canceled:
+		c.Abort()
+	case codes.DeadlineExceeded:
+		metrics.ProxyErrors.WithLabelValues(serviceName, "timeout").Inc()
+		log.Printf("Ошибка вызова gRPC в %s: %v", serviceName, err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "сервис не ответил вовремя"})
+	case codes.Unavailable:
+		metrics.ProxyErrors.WithLabelValues(serviceName, "unavailable").Inc()
+		log.Printf("Ошибка вызова gRPC в %s: %v", serviceName, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "сервис недоступен"})
+	default:
+		st := status.Convert(err)
+		// Сервис может подсказать, когда повторить запрос, например после неудачных попыток входа
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(info.GetRetryDelay().AsDuration().Seconds()))))
+			}
+		}
+		c.JSON(transcoder.HTTPStatus(code), gin.H{"error": st.Message()})
+	}
+}
@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"sort"
+	"strings"
+
+	"api-service/config"
+)
+
+type RouteTable struct {
+	routes []config.Route
+}
+
+func NewRouteTable(routes []config.Route) *RouteTable {
+	sorted := make([]config.Route, len(routes))
+	copy(sorted, routes)
+	// Более длинные префиксы и маршруты с явным методом проверяются первыми
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if len(sorted[i].Path) != len(sorted[j].Path) {
+			return len(sorted[i].Path) > len(sorted[j].Path)
+		}
+		return sorted[i].Method != "" && sorted[j].Method == ""
+	})
+	return &RouteTable{routes: sorted}
+}
+
+// Match возвращает маршрут для запроса. Второй результат сообщает, совпал ли путь
+// хотя бы с одним маршрутом: так можно отличить 404 от 405.
+func (t *RouteTable) Match(method, path string) (*config.Route, bool) {
+	pathMatched := false
+	for i := range t.routes {
+		route := &t.routes[i]
+		if !matchPrefix(route.Path, path) {
+			continue
+		}
+		pathMatched = true
+		if route.Method == "" || route.Method == method {
+			return route, true
+		}
+	}
+	return nil, pathMatched
+}
+
+func matchPrefix(prefix, path string) bool {
+	if prefix == "/" || prefix == path {
+		return true
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+func RewritePath(route *config.Route, path string) string {
+	if route.Rewrite == "" {
+		return path
+	}
+	rest := strings.TrimPrefix(path, route.Path)
+	rewritten := strings.TrimSuffix(route.Rewrite, "/") + "/" + strings.TrimPrefix(rest, "/")
+	if rest == "" && route.Rewrite != "/" {
+		rewritten = strings.TrimSuffix(rewritten, "/")
+	}
+	if rewritten == "" {
+		return "/"
+	}
+	return rewritten
+}
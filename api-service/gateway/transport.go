@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"api-service/breaker"
+	"api-service/config"
+)
+
+type routeContextKey struct{}
+
+func withRoute(ctx context.Context, route *config.Route) context.Context {
+	return context.WithValue(ctx, routeContextKey{}, route)
+}
+
+func routeFromRequest(req *http.Request) *config.Route {
+	route, _ := req.Context().Value(routeContextKey{}).(*config.Route)
+	return route
+}
+
+// retryTransport повторяет идемпотентные запросы при сетевых ошибках и ответах 502/503/504
+// с экспоненциальной паузой retry_timeout_ms, 2*retry_timeout_ms, ...
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	route := routeFromRequest(req)
+	if route == nil || route.Config.Retries <= 0 || !isIdempotent(req.Method) {
+		return t.base.RoundTrip(req)
+	}
+
+	// Каждая попытка отправляет свою копию запроса, а тело перечитывается через getBody:
+	// запрос вызывающей стороны не меняется
+	getBody := req.GetBody
+	if req.Body != nil && req.Body != http.NoBody && getBody == nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		getBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	backoff := route.Config.RetryBackoff()
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+		if getBody != nil {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+			attemptReq.GetBody = getBody
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if attempt >= route.Config.Retries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff << uint(attempt)):
+		}
+	}
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil || errors.Is(err, breaker.ErrOpen) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// breakerTransport учитывает результат каждой попытки в circuit breaker сервиса
+type breakerTransport struct {
+	breaker *breaker.Breaker
+	base    http.RoundTripper
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+		t.breaker.Ignore()
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		t.breaker.Failure()
+	default:
+		t.breaker.Success()
+	}
+	return resp, err
+}
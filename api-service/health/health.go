@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"api-service/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const checkTimeout = 2 * time.Second
+
+type ServiceStatus struct {
+	Status string `json:"status"`
+	Code   int    `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Checker опрашивает readiness всех сервисов из конфигурации шлюза.
+// Шлюз готов, только если готовы все сервисы.
+type Checker struct {
+	services map[string]config.Service
+	client   *http.Client
+}
+
+func NewChecker(services map[string]config.Service) *Checker {
+	return &Checker{
+		services: services,
+		client:   &http.Client{Timeout: checkTimeout},
+	}
+}
+
+func (h *Checker) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *Checker) Readiness(c *gin.Context) {
+	statuses := h.Check(c.Request.Context())
+
+	code := http.StatusOK
+	overall := "ok"
+	for _, status := range statuses {
+		if status.Status != "ok" {
+			code = http.StatusServiceUnavailable
+			overall = "unavailable"
+		}
+	}
+	c.JSON(code, gin.H{"status": overall, "services": statuses})
+}
+
+// Check опрашивает сервисы параллельно и возвращает статус каждого
+func (h *Checker) Check(ctx context.Context) map[string]ServiceStatus {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	statuses := make(map[string]ServiceStatus, len(h.services))
+	for name, service := range h.services {
+		wg.Add(1)
+		go func(name string, service config.Service) {
+			defer wg.Done()
+			status := h.checkService(ctx, service)
+			mu.Lock()
+			statuses[name] = status
+			mu.Unlock()
+		}(name, service)
+	}
+	wg.Wait()
+	return statuses
+}
+
+func (h *Checker) checkService(ctx context.Context, service config.Service) ServiceStatus {
+	url := strings.TrimSuffix(service.URL, "/") + service.ReadinessPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return ServiceStatus{Status: "unavailable", Error: err.Error()}
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return ServiceStatus{Status: "unavailable", Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ServiceStatus{
+			Status: "unavailable",
+			Code:   resp.StatusCode,
+			Error:  fmt.Sprintf("сервис ответил %d", resp.StatusCode),
+		}
+	}
+	return ServiceStatus{Status: "ok", Code: resp.StatusCode}
+}
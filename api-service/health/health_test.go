@@ -0,0 +1,60 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-service/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ready := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			t.Errorf("Ожидается запрос /readyz, получен: %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ready.Close()
+
+	starting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer starting.Close()
+
+	services := map[string]config.Service{
+		"user-service": {URL: ready.URL, ReadinessPath: "/readyz"},
+	}
+	r := gin.New()
+	r.GET("/readyz", NewChecker(services).Readiness)
+
+	get := func() (int, map[string]ServiceStatus) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		r.ServeHTTP(w, req)
+		var response struct {
+			Services map[string]ServiceStatus `json:"services"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Services
+	}
+
+	code, statuses := get()
+	if code != http.StatusOK || statuses["user-service"].Status != "ok" {
+		t.Errorf("Ожидается 200 при готовых сервисах, получено: %d %v", code, statuses)
+	}
+
+	services["promocodes-service"] = config.Service{URL: starting.URL, ReadinessPath: "/readyz"}
+	code, statuses = get()
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Ожидается 503, если хотя бы один сервис не готов, получен: %d", code)
+	}
+	if statuses["user-service"].Status != "ok" || statuses["promocodes-service"].Code != http.StatusServiceUnavailable {
+		t.Errorf("Ожидается статус по каждому сервису, получено: %v", statuses)
+	}
+}
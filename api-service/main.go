@@ -1,34 +1,98 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net/http/httputil"
-	"net/url"
+	"net/http"
 	"os"
+	"time"
+
+	"api-service/auth"
+	"api-service/config"
+	"api-service/gateway"
+	"api-service/health"
+	"api-service/metrics"
+	"api-service/pb/userpb"
+	"api-service/ratelimit"
+	"api-service/tracing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// revocationCacheTTL - наибольшая задержка, с которой шлюз начинает отклонять отозванный токен
+const revocationCacheTTL = 10 * time.Second
+
 func main() {
-	r := gin.Default()
+	shutdownTracing, err := tracing.Init(context.Background(), "api-service")
+	if err != nil {
+		log.Fatalf("Ошибка настройки трассировки: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 
-	userServiceURL := os.Getenv("USER_SERVICE_URL")
-	if userServiceURL == "" {
-		userServiceURL = "http://user-service:8081"
+	configPath := os.Getenv("GATEWAY_CONFIG")
+	if configPath == "" {
+		configPath = "config.yml"
 	}
 
-	target, err := url.Parse(userServiceURL)
+	cfg, err := config.Load(configPath)
 	if err != nil {
-		log.Fatalf("Ошибка при парсинге URL: %v", err)
+		log.Fatalf("Ошибка загрузки конфигурации шлюза: %v", err)
 	}
 
-	proxy := httputil.NewSingleHostReverseProxy(target)
+	gw, err := gateway.New(cfg)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации шлюза: %v", err)
+	}
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore())
 
-	r.Any("/*path", func(c *gin.Context) {
-		log.Printf("API Gateway получил запрос: %s %s", c.Request.Method, c.Request.URL.Path)
-		
-		proxy.ServeHTTP(c.Writer, c.Request)
+	// Шлюз проверяет токены только открытыми ключами user-service и не может их выпускать
+	jwksURL := os.Getenv("JWKS_URL")
+	if jwksURL == "" {
+		jwksURL = "http://user-service:8081/.well-known/jwks.json"
+	}
+	// Отзыв сессий проверяет только user-service, поэтому шлюз спрашивает его о каждом токене
+	userConn := gw.Conn("user-service")
+	if userConn == nil {
+		log.Fatal("Для проверки отзыва токенов у user-service должен быть задан grpc_addr")
+	}
+	verifier := auth.NewRevocationVerifier(auth.NewJWKSVerifier(jwksURL, 5*time.Minute), userpb.NewUserServiceClient(userConn), revocationCacheTTL)
+
+	r := gin.New()
+	r.Use(tracing.RequestID(), tracing.Logger(), gin.Recovery())
+	// Шлюз - точка входа, поэтому X-Forwarded-For от клиентов не доверяем
+	if err := r.SetTrustedProxies(nil); err != nil {
+		log.Fatalf("Ошибка настройки доверенных прокси: %v", err)
+	}
+
+	checker := health.NewChecker(cfg.Services)
+	r.GET("/healthz", checker.Liveness)
+	r.GET("/readyz", checker.Readiness)
+
+	routeLabels := func(c *gin.Context) (string, string, bool) {
+		route := gateway.RouteFromContext(c)
+		if route == nil {
+			return "", "", false
+		}
+		return route.Path, route.ServiceName, true
+	}
+
+	r.NoRoute(metrics.Middleware(routeLabels), gw.Resolve, tracing.Middleware(), limiter.Middleware(), auth.Middleware(verifier), limiter.UserMiddleware(), gw.Proxy)
+
+	adminPort := os.Getenv("ADMIN_PORT")
+	if adminPort == "" {
+		adminPort = "8090"
+	}
+	admin := gin.Default()
+	admin.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	admin.GET("/breakers", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gw.Breakers().Statuses())
 	})
+	go func() {
+		log.Printf("Административный API шлюза запущен на порту %s", adminPort)
+		log.Fatal(admin.Run(":" + adminPort))
+	}()
 
 	port := os.Getenv("PORT")
 	if port == "" {
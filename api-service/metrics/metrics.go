@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Метки route - шаблон маршрута из конфигурации, а не сырой путь запроса
+var (
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_requests_total",
+		Help: "Количество запросов к шлюзу по маршрутам и сервисам.",
+	}, []string{"route", "method", "upstream", "code"})
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_request_duration_seconds",
+		Help:    "Время обработки запроса шлюзом, включая обращение к сервису.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "upstream"})
+
+	ProxyErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_proxy_errors_total",
+		Help: "Ошибки обращения к сервисам: timeout, unavailable, circuit_open.",
+	}, []string{"upstream", "reason"})
+)
+
+const unmatchedRoute = "unmatched"
+
+// Middleware учитывает запрос после обработки. routeLabels возвращает шаблон маршрута и имя сервиса,
+// если маршрут был найден.
+func Middleware(routeLabels func(c *gin.Context) (route, upstream string, ok bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route, upstream, ok := routeLabels(c)
+		if !ok {
+			route, upstream = unmatchedRoute, ""
+		}
+		method := c.Request.Method
+
+		RequestsTotal.WithLabelValues(route, method, upstream, strconv.Itoa(c.Writer.Status())).Inc()
+		RequestDuration.WithLabelValues(route, method, upstream).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareUsesRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.NoRoute(Middleware(func(c *gin.Context) (string, string, bool) {
+		if c.Request.URL.Path == "/unknown" {
+			return "", "", false
+		}
+		return "/promocodes", "promocodes-service", true
+	}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	before := testutil.ToFloat64(RequestsTotal.WithLabelValues("/promocodes", "GET", "promocodes-service", "200"))
+	unmatchedBefore := testutil.ToFloat64(RequestsTotal.WithLabelValues(unmatchedRoute, "GET", "", "200"))
+
+	for _, path := range []string{"/promocodes/1", "/promocodes/2", "/unknown"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := testutil.ToFloat64(RequestsTotal.WithLabelValues("/promocodes", "GET", "promocodes-service", "200")) - before; got != 2 {
+		t.Errorf("Запросы к разным путям одного маршрута должны попадать в одну серию, получено: %v", got)
+	}
+	if got := testutil.ToFloat64(RequestsTotal.WithLabelValues(unmatchedRoute, "GET", "", "200")) - unmatchedBefore; got != 1 {
+		t.Errorf("Ожидается 1 запрос без маршрута, получено: %v", got)
+	}
+}
@@ -0,0 +1,4 @@
+// Package userpb содержит сгенерированный код контракта proto/user.proto.
+package userpb
+
+//go:generate protoc -I ../../../proto --go_out=. --go_opt=paths=source_relative,Muser.proto=api-service/pb/userpb --go-grpc_out=. --go-grpc_opt=paths=source_relative,Muser.proto=api-service/pb/userpb user.proto
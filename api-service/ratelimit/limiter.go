@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api-service/gateway"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Limiter struct {
+	store Store
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Middleware ограничивает число запросов с одного IP к маршруту согласно max_requests_per_time/time_window.
+// Должен стоять после gateway.Resolve и до auth.Middleware: запросы с неверным или отсутствующим
+// токеном тоже учитываются, иначе подбор токенов не ограничен.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.limit(c, "ip:"+c.ClientIP())
+	}
+}
+
+// UserMiddleware дополнительно ограничивает аутентифицированного пользователя по id, с каких бы IP
+// он ни приходил. Должен стоять после auth.Middleware.
+func (l *Limiter) UserMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get(gateway.UserIDKey)
+		if !exists {
+			c.Next()
+			return
+		}
+		l.limit(c, fmt.Sprintf("user:%v", userID))
+	}
+}
+
+func (l *Limiter) limit(c *gin.Context, client string) {
+	route := gateway.RouteFromContext(c)
+	if route == nil || route.Config.MaxRequestsPerTime <= 0 {
+		c.Next()
+		return
+	}
+
+	key := fmt.Sprintf("%s %s|%s", route.Method, route.Path, client)
+	result, err := l.store.Allow(c.Request.Context(), key, route.Config.MaxRequestsPerTime, route.Config.Window())
+	if err != nil {
+		// Недоступность хранилища счетчиков не должна останавливать трафик
+		log.Printf("Ошибка лимитера запросов: %v", err)
+		c.Next()
+		return
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(seconds(result.ResetAfter)))
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "слишком много запросов"})
+		return
+	}
+
+	c.Next()
+}
+
+func seconds(d time.Duration) int {
+	s := int(math.Ceil(d.Seconds()))
+	if s < 1 {
+		return 1
+	}
+	return s
+}
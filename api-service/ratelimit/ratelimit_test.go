@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api-service/config"
+	"api-service/gateway"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryStoreSlidingWindow(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		result, _ := store.Allow(context.Background(), "key", 3, time.Minute)
+		if !result.Allowed {
+			t.Fatalf("Запрос %d должен быть разрешен", i+1)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("Ожидается остаток %d, получено: %d", 2-i, result.Remaining)
+		}
+	}
+
+	result, _ := store.Allow(context.Background(), "key", 3, time.Minute)
+	if result.Allowed {
+		t.Error("Четвертый запрос в окне должен быть отклонен")
+	}
+	if result.RetryAfter <= 0 {
+		t.Error("Ожидается положительный RetryAfter")
+	}
+
+	// Через пол-окна после начала следующего окна предыдущее учитывается с весом 0.5
+	now = now.Add(90 * time.Second)
+	result, _ = store.Allow(context.Background(), "key", 3, time.Minute)
+	if !result.Allowed {
+		t.Error("Запрос должен быть разрешен после сдвига окна")
+	}
+	result, _ = store.Allow(context.Background(), "key", 3, time.Minute)
+	if result.Allowed {
+		t.Error("Запрос должен быть отклонен с учетом предыдущего окна")
+	}
+
+	result, _ = store.Allow(context.Background(), "other", 3, time.Minute)
+	if !result.Allowed {
+		t.Error("Счетчики разных ключей не должны пересекаться")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gw, err := gateway.New(&config.Config{
+		Services: map[string]config.Service{"user-service": {URL: "http://user-service:8081"}},
+		Routes: []config.Route{{
+			Path:        "/login",
+			ServiceName: "user-service",
+			Config:      config.RouteConfig{MaxRequestsPerTime: 1, TimeWindow: 60},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания шлюза: %v", err)
+	}
+	limiter := NewLimiter(NewMemoryStore())
+
+	// Вместо auth.Middleware: Test-User - действительный токен пользователя, Test-Reject - неверный токен
+	r := gin.New()
+	r.NoRoute(gw.Resolve, limiter.Middleware(), func(c *gin.Context) {
+		if c.GetHeader("Test-Reject") != "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if userID := c.GetHeader("Test-User"); userID != "" {
+			c.Set(gateway.UserIDKey, userID)
+		}
+	}, limiter.UserMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(ip, user string, reject bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = ip + ":40000"
+		if user != "" {
+			req.Header.Set("Test-User", user)
+		}
+		if reject {
+			req.Header.Set("Test-Reject", "1")
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("10.0.0.1", "", false)
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Errorf("Ожидается X-RateLimit-Limit=1, получено: %s", w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	w = send("10.0.0.1", "", false)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Ожидается код 429, получен: %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Ожидается заголовок Retry-After")
+	}
+
+	// Запросы, отклоненные проверкой токена, тоже расходуют лимит IP
+	if w := send("10.0.0.2", "", true); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401, получен: %d", w.Code)
+	}
+	if w := send("10.0.0.2", "", true); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Подбор токенов должен ограничиваться, получен код: %d", w.Code)
+	}
+
+	// Лимит пользователя действует независимо от IP
+	if w := send("10.0.0.3", "42", false); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w := send("10.0.0.4", "42", false); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Лимит пользователя должен действовать с любого IP, получен код: %d", w.Code)
+	}
+	if w := send("10.0.0.5", "43", false); w.Code != http.StatusOK {
+		t.Errorf("Лимиты разных пользователей не должны пересекаться, получен код: %d", w.Code)
+	}
+}
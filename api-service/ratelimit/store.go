@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // до начала следующего окна
+	RetryAfter time.Duration // когда имеет смысл повторить отклоненный запрос
+}
+
+// Store хранит счетчики запросов. Реализация в памяти подходит для одного экземпляра шлюза,
+// при горизонтальном масштабировании ее можно заменить, например, на Redis.
+type Store interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+type counter struct {
+	start    time.Time
+	window   time.Duration
+	current  int
+	previous int
+}
+
+// MemoryStore реализует скользящее окно: счетчик предыдущего окна учитывается
+// пропорционально тому, какая его часть еще попадает в последние window секунд.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	now      func() time.Time
+	calls    int
+}
+
+const cleanupEvery = 1000
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) Allow(_ context.Context, key string, limit int, window time.Duration) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.calls++
+	if s.calls%cleanupEvery == 0 {
+		s.cleanup(now)
+	}
+
+	start := now.Truncate(window)
+	c, exists := s.counters[key]
+	if !exists {
+		c = &counter{start: start, window: window}
+		s.counters[key] = c
+	}
+	if !c.start.Equal(start) {
+		if c.start.Add(window).Equal(start) {
+			c.previous = c.current
+		} else {
+			c.previous = 0
+		}
+		c.current = 0
+		c.start = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimated := float64(c.previous)*weight + float64(c.current)
+
+	result := Result{
+		Limit:      limit,
+		ResetAfter: window - elapsed,
+	}
+
+	if estimated+1 > float64(limit) {
+		result.RetryAfter = retryAfter(c, limit, elapsed, window)
+		return result, nil
+	}
+
+	c.current++
+	result.Allowed = true
+	result.Remaining = limit - int(math.Ceil(estimated+1))
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	return result, nil
+}
+
+// retryAfter считает, через сколько вклад предыдущего окна уменьшится настолько,
+// что поместится еще один запрос
+func retryAfter(c *counter, limit int, elapsed, window time.Duration) time.Duration {
+	free := float64(limit - 1 - c.current)
+	if free < 0 || c.previous == 0 {
+		return window - elapsed
+	}
+	wait := time.Duration(float64(window)*(1-free/float64(c.previous))) - elapsed
+	if wait <= 0 || wait > window-elapsed {
+		return window - elapsed
+	}
+	return wait
+}
+
+func (s *MemoryStore) cleanup(now time.Time) {
+	for key, c := range s.counters {
+		if now.Sub(c.start) >= 2*c.window {
+			delete(s.counters, key)
+		}
+	}
+}
+
+var _ Store = (*MemoryStore)(nil)
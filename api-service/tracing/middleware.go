@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"net/http"
+
+	"api-service/gateway"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware открывает серверный спан запроса, названный по шаблону маршрута, а не по сырому пути.
+// Должен стоять после gateway.Resolve; контекст трассировки уходит в сервисы из gateway.Proxy.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := gateway.RouteFromContext(c)
+		name := c.Request.Method
+		if route != nil {
+			name += " " + route.Path
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		span.SetAttributes(
+			attribute.String("http.method", c.Request.Method),
+			attribute.String("http.target", c.Request.URL.Path),
+			attribute.String("request.id", c.GetString(requestIDKey)),
+		)
+		if route != nil {
+			span.SetAttributes(
+				attribute.String("http.route", route.Path),
+				attribute.String("gateway.upstream", route.ServiceName),
+			)
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
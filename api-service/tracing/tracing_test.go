@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	var forwarded string
+	r.GET("/profile", func(c *gin.Context) {
+		forwarded = c.Request.Header.Get(RequestIDHeader)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/profile", nil)
+	r.ServeHTTP(w, req)
+
+	requestID := w.Header().Get(RequestIDHeader)
+	if requestID == "" || requestID != forwarded {
+		t.Errorf("Сгенерированный X-Request-ID должен уходить в сервис и в ответ, получено: %q / %q", forwarded, requestID)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/profile", nil)
+	req.Header.Set(RequestIDHeader, "client-request-1")
+	r.ServeHTTP(w, req)
+
+	if forwarded != "client-request-1" || w.Header().Get(RequestIDHeader) != "client-request-1" {
+		t.Errorf("Ожидается X-Request-ID клиента, получено: %q / %q", forwarded, w.Header().Get(RequestIDHeader))
+	}
+}
@@ -0,0 +1,57 @@
+package transcoder
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// Method описывает, как REST-запрос превращается в вызов gRPC и как ответ возвращается клиенту
+type Method struct {
+	// Decode читает тело HTTP-запроса. Ошибка возвращается клиенту как 400.
+	Decode func(c *gin.Context) (proto.Message, error)
+	Invoke func(ctx context.Context, conn grpc.ClientConnInterface, req proto.Message) (proto.Message, error)
+	Encode func(c *gin.Context, resp proto.Message)
+}
+
+var methods = map[string]Method{}
+
+func register(name string, method Method) {
+	methods[name] = method
+}
+
+func Lookup(name string) (Method, bool) {
+	method, ok := methods[name]
+	return method, ok
+}
+
+// HTTPStatus переводит код gRPC в HTTP-статус, который вернул бы REST-обработчик сервиса
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
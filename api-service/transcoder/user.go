@@ -0,0 +1,189 @@
+package transcoder
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"api-service/pb/userpb"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Тела запросов и ответов повторяют REST API user-service (openapi.yml)
+
+type registerBody struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type loginBody struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type loginMFABody struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type refreshTokenBody struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type loginResponseBody struct {
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+}
+
+type updateProfileBody struct {
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	BirthDate *time.Time `json:"birth_date"`
+	Email     string     `json:"email"`
+	Phone     string     `json:"phone"`
+}
+
+type userBody struct {
+	ID            uint64    `json:"id"`
+	Login         string    `json:"login"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	TOTPEnabled   bool      `json:"totp_enabled"`
+	Role          string    `json:"role"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	BirthDate     time.Time `json:"birth_date"`
+	Phone         string    `json:"phone"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func init() {
+	register("UserService/Register", Method{
+		Decode: func(c *gin.Context) (proto.Message, error) {
+			var body registerBody
+			if err := c.ShouldBindJSON(&body); err != nil {
+				return nil, err
+			}
+			return &userpb.RegisterRequest{Login: body.Login, Password: body.Password, Email: body.Email}, nil
+		},
+		Invoke: func(ctx context.Context, conn grpc.ClientConnInterface, req proto.Message) (proto.Message, error) {
+			return userpb.NewUserServiceClient(conn).Register(ctx, req.(*userpb.RegisterRequest))
+		},
+		Encode: func(c *gin.Context, resp proto.Message) {
+			c.JSON(http.StatusCreated, gin.H{"message": "Пользователь успешно зарегистрирован"})
+		},
+	})
+
+	register("UserService/Login", Method{
+		Decode: func(c *gin.Context) (proto.Message, error) {
+			var body loginBody
+			if err := c.ShouldBindJSON(&body); err != nil {
+				return nil, err
+			}
+			return &userpb.LoginRequest{Login: body.Login, Password: body.Password}, nil
+		},
+		Invoke: func(ctx context.Context, conn grpc.ClientConnInterface, req proto.Message) (proto.Message, error) {
+			return userpb.NewUserServiceClient(conn).Login(ctx, req.(*userpb.LoginRequest))
+		},
+		Encode: encodeLoginResponse,
+	})
+
+	register("UserService/LoginMFA", Method{
+		Decode: func(c *gin.Context) (proto.Message, error) {
+			var body loginMFABody
+			if err := c.ShouldBindJSON(&body); err != nil {
+				return nil, err
+			}
+			return &userpb.LoginMFARequest{MfaToken: body.MFAToken, Code: body.Code}, nil
+		},
+		Invoke: func(ctx context.Context, conn grpc.ClientConnInterface, req proto.Message) (proto.Message, error) {
+			return userpb.NewUserServiceClient(conn).LoginMFA(ctx, req.(*userpb.LoginMFARequest))
+		},
+		Encode: encodeLoginResponse,
+	})
+
+	register("UserService/RefreshToken", Method{
+		Decode: func(c *gin.Context) (proto.Message, error) {
+			var body refreshTokenBody
+			if err := c.ShouldBindJSON(&body); err != nil {
+				return nil, err
+			}
+			return &userpb.RefreshTokenRequest{RefreshToken: body.RefreshToken}, nil
+		},
+		Invoke: func(ctx context.Context, conn grpc.ClientConnInterface, req proto.Message) (proto.Message, error) {
+			return userpb.NewUserServiceClient(conn).RefreshToken(ctx, req.(*userpb.RefreshTokenRequest))
+		},
+		Encode: encodeLoginResponse,
+	})
+
+	register("UserService/GetUserProfile", Method{
+		Decode: func(c *gin.Context) (proto.Message, error) {
+			return &userpb.GetUserProfileRequest{}, nil
+		},
+		Invoke: func(ctx context.Context, conn grpc.ClientConnInterface, req proto.Message) (proto.Message, error) {
+			return userpb.NewUserServiceClient(conn).GetUserProfile(ctx, req.(*userpb.GetUserProfileRequest))
+		},
+		Encode: func(c *gin.Context, resp proto.Message) {
+			user := resp.(*userpb.User)
+			c.JSON(http.StatusOK, userBody{
+				ID:            user.GetId(),
+				Login:         user.GetLogin(),
+				Email:         user.GetEmail(),
+				EmailVerified: user.GetEmailVerified(),
+				TOTPEnabled:   user.GetTotpEnabled(),
+				Role:          user.GetRole(),
+				FirstName:     user.GetFirstName(),
+				LastName:      user.GetLastName(),
+				BirthDate:     user.GetBirthDate().AsTime(),
+				Phone:         user.GetPhone(),
+				CreatedAt:     user.GetCreatedAt().AsTime(),
+				UpdatedAt:     user.GetUpdatedAt().AsTime(),
+			})
+		},
+	})
+
+	register("UserService/UpdateUserProfile", Method{
+		Decode: func(c *gin.Context) (proto.Message, error) {
+			var body updateProfileBody
+			if err := c.ShouldBindJSON(&body); err != nil {
+				return nil, err
+			}
+			req := &userpb.UpdateUserProfileRequest{
+				FirstName: body.FirstName,
+				LastName:  body.LastName,
+				Email:     body.Email,
+				Phone:     body.Phone,
+			}
+			if body.BirthDate != nil {
+				req.BirthDate = timestamppb.New(*body.BirthDate)
+			}
+			return req, nil
+		},
+		Invoke: func(ctx context.Context, conn grpc.ClientConnInterface, req proto.Message) (proto.Message, error) {
+			return userpb.NewUserServiceClient(conn).UpdateUserProfile(ctx, req.(*userpb.UpdateUserProfileRequest))
+		},
+		Encode: func(c *gin.Context, resp proto.Message) {
+			c.JSON(http.StatusOK, gin.H{"message": "Профиль успешно обновлен"})
+		},
+	})
+}
+
+func encodeLoginResponse(c *gin.Context, resp proto.Message) {
+	login := resp.(*userpb.LoginResponse)
+	c.JSON(http.StatusOK, loginResponseBody{
+		Token:        login.GetToken(),
+		RefreshToken: login.GetRefreshToken(),
+		ExpiresIn:    login.GetExpiresIn(),
+		MFARequired:  login.GetMfaRequired(),
+		MFAToken:     login.GetMfaToken(),
+	})
+}
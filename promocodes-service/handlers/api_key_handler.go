@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService services.APIKeyServiceInterface
+}
+
+func NewAPIKeyHandler(apiKeyService services.APIKeyServiceInterface) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), actor, companyID, req)
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(c.Request.Context(), actor, companyID)
+	if err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("key_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор ключа"})
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), actor, companyID, uint(id)); err != nil {
+		writeAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API-ключ отозван"})
+}
+
+func writeAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotCompanyOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CodeBatchHandler struct {
+	codeBatchService services.CodeBatchServiceInterface
+}
+
+func NewCodeBatchHandler(codeBatchService services.CodeBatchServiceInterface) *CodeBatchHandler {
+	return &CodeBatchHandler{codeBatchService: codeBatchService}
+}
+
+// CreateBatch отвечает 202: коды генерируются в фоне, прогресс виден в GET /code-batches/:id
+func (h *CodeBatchHandler) CreateBatch(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := promocodeIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.GenerateCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch, err := h.codeBatchService.CreateBatch(c.Request.Context(), actor, id, req)
+	if err != nil {
+		writeCodeBatchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, batch)
+}
+
+func (h *CodeBatchHandler) ListBatches(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := promocodeIDParam(c)
+	if !ok {
+		return
+	}
+
+	var filter models.CodeBatchFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batches, err := h.codeBatchService.ListBatches(c.Request.Context(), actor, id, filter)
+	if err != nil {
+		writeCodeBatchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batches)
+}
+
+func (h *CodeBatchHandler) GetBatch(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := codeBatchIDParam(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.codeBatchService.GetBatch(c.Request.Context(), actor, id)
+	if err != nil {
+		writeCodeBatchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *CodeBatchHandler) ExportBatch(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := codeBatchIDParam(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="code-batch-%d.csv"`, id))
+	err := h.codeBatchService.ExportBatch(c.Request.Context(), actor, id, c.Writer)
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		// Начатый ответ уже не заменить ошибкой, клиент получит обрезанный файл
+		log.Printf("Ошибка выгрузки партии кодов %d: %v", id, err)
+		return
+	}
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.Header().Del("Content-Disposition")
+	writeCodeBatchError(c, err)
+}
+
+func codeBatchIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор партии"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func writeCodeBatchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCodeAlphabet), errors.Is(err, services.ErrCodeTooLong),
+		errors.Is(err, services.ErrCodeSpaceTooSmall):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCodeBatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCodeBatchNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		writePromocodeError(c, err)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockCodeBatchService: промокод 1 и партии 1 (готова) и 2 (генерируется) принадлежат компании 1
+type MockCodeBatchService struct{}
+
+var _ services.CodeBatchServiceInterface = (*MockCodeBatchService)(nil)
+
+func (m *MockCodeBatchService) CreateBatch(ctx context.Context, actor *models.Identity, promocodeID uint, req models.GenerateCodesRequest) (*models.CodeBatch, error) {
+	if promocodeID != 1 {
+		return nil, services.ErrPromocodeNotFound
+	}
+	if req.Alphabet == "AAB" {
+		return nil, services.ErrInvalidCodeAlphabet
+	}
+	return &models.CodeBatch{ID: 2, PromocodeID: 1, Quantity: req.Quantity, Status: models.CodeBatchPending}, nil
+}
+
+func (m *MockCodeBatchService) GetBatch(ctx context.Context, actor *models.Identity, id uint) (*models.CodeBatch, error) {
+	switch id {
+	case 1:
+		return &models.CodeBatch{ID: 1, Quantity: 2, Generated: 2, Status: models.CodeBatchCompleted}, nil
+	case 2:
+		return &models.CodeBatch{ID: 2, Quantity: 1000, Generated: 400, Status: models.CodeBatchRunning}, nil
+	}
+	return nil, services.ErrCodeBatchNotFound
+}
+
+func (m *MockCodeBatchService) ListBatches(ctx context.Context, actor *models.Identity, promocodeID uint, filter models.CodeBatchFilter) (*models.CodeBatchList, error) {
+	return &models.CodeBatchList{Batches: []models.CodeBatch{}}, nil
+}
+
+func (m *MockCodeBatchService) ExportBatch(ctx context.Context, actor *models.Identity, id uint, w io.Writer) error {
+	if id != 1 {
+		return services.ErrCodeBatchNotReady
+	}
+	_, err := io.WriteString(w, "code,redeemed_at\nSPRA7K2,\nSPRQ9M4,2026-10-18T10:00:00Z\n")
+	return err
+}
+
+func TestCodeBatchHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewCodeBatchHandler(&MockCodeBatchService{})
+	r.Use(IdentityMiddleware())
+	r.POST("/promocodes/:id/code-batches", handler.CreateBatch)
+	r.GET("/promocodes/:id/code-batches", handler.ListBatches)
+	r.GET("/code-batches/:id", handler.GetBatch)
+	r.GET("/code-batches/:id/export", handler.ExportBatch)
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderUserID, "1")
+		req.Header.Set(HeaderUserCompanies, "1:owner")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("POST", "/promocodes/1/code-batches", models.GenerateCodesRequest{Quantity: 0, Length: 8}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 без количества, получен: %d", w.Code)
+	}
+	if w := send("POST", "/promocodes/1/code-batches", models.GenerateCodesRequest{Quantity: 10, Length: 8, Alphabet: "AAB"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного алфавита, получен: %d", w.Code)
+	}
+	if w := send("POST", "/promocodes/9/code-batches", models.GenerateCodesRequest{Quantity: 10, Length: 8}); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+	if w := send("POST", "/promocodes/1/code-batches", models.GenerateCodesRequest{Quantity: 1000, Length: 8}); w.Code != http.StatusAccepted {
+		t.Errorf("Ожидается код 202, получен: %d %s", w.Code, w.Body.String())
+	}
+
+	w := send("GET", "/code-batches/2", nil)
+	var batch models.CodeBatch
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if w.Code != http.StatusOK || batch.Generated != 400 || batch.Status != models.CodeBatchRunning {
+		t.Errorf("Ожидается прогресс партии, получено: %d %s", w.Code, w.Body.String())
+	}
+	if w := send("GET", "/promocodes/1/code-batches", nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+
+	w = send("GET", "/code-batches/2/export", nil)
+	if w.Code != http.StatusConflict || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Ожидается код 409 без вложения для незавершенной партии, получено: %d %v", w.Code, w.Header())
+	}
+	w = send("GET", "/code-batches/1/export", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" ||
+		w.Header().Get("Content-Disposition") != `attachment; filename="code-batch-1.csv"` {
+		t.Errorf("Ожидается CSV-файл, получено: %d %v", w.Code, w.Header())
+	}
+	if w.Body.String() != "code,redeemed_at\nSPRA7K2,\nSPRQ9M4,2026-10-18T10:00:00Z\n" {
+		t.Errorf("Неверное содержимое выгрузки: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentHandler struct {
+	commentService services.CommentServiceInterface
+}
+
+func NewCommentHandler(commentService services.CommentServiceInterface) *CommentHandler {
+	return &CommentHandler{commentService: commentService}
+}
+
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	promocodeID, ok := promocodeIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(c.Request.Context(), actor, promocodeID, req)
+	if err != nil {
+		writeCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	promocodeID, ok := promocodeIDParam(c)
+	if !ok {
+		return
+	}
+
+	var page models.CommentPage
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comments, err := h.commentService.ListComments(c.Request.Context(), actor, promocodeID, page)
+	if err != nil {
+		writeCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+func (h *CommentHandler) ListReplies(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := commentIDParam(c)
+	if !ok {
+		return
+	}
+
+	var page models.CommentPage
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	replies, err := h.commentService.ListReplies(c.Request.Context(), actor, id, page)
+	if err != nil {
+		writeCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, replies)
+}
+
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := commentIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.commentService.UpdateComment(c.Request.Context(), actor, id, req)
+	if err != nil {
+		writeCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := commentIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.commentService.DeleteComment(c.Request.Context(), actor, id); err != nil {
+		writeCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Комментарий удален"})
+}
+
+func commentIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор комментария"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func writeCommentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidParentComment), errors.Is(err, services.ErrInvalidCommentCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotCommentAuthor), errors.Is(err, services.ErrCommentDeleteDenied),
+		errors.Is(err, services.ErrCommentEditExpired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		writePromocodeError(c, err)
+	}
+}
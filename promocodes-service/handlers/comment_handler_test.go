@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockCommentService: промокод 1 существует, срок редактирования комментария 2 истек
+type MockCommentService struct {
+	comments map[uint]*models.Comment
+}
+
+var _ services.CommentServiceInterface = (*MockCommentService)(nil)
+
+func (m *MockCommentService) CreateComment(ctx context.Context, actor *models.Identity, promocodeID uint, req models.CreateCommentRequest) (*models.Comment, error) {
+	if promocodeID != 1 {
+		return nil, services.ErrPromocodeNotFound
+	}
+	if req.ParentID != nil && m.comments[*req.ParentID] == nil {
+		return nil, services.ErrInvalidParentComment
+	}
+	comment := &models.Comment{ID: uint(len(m.comments) + 1), PromocodeID: promocodeID, ParentID: req.ParentID, CreatorID: actor.UserID, Content: req.Content}
+	m.comments[comment.ID] = comment
+	return comment, nil
+}
+
+func (m *MockCommentService) ListComments(ctx context.Context, actor *models.Identity, promocodeID uint, page models.CommentPage) (*models.CommentList, error) {
+	if page.Cursor == "bad" {
+		return nil, services.ErrInvalidCommentCursor
+	}
+	list := &models.CommentList{Comments: []models.Comment{}}
+	for _, comment := range m.comments {
+		if comment.PromocodeID == promocodeID && comment.ParentID == nil {
+			list.Comments = append(list.Comments, *comment)
+		}
+	}
+	return list, nil
+}
+
+func (m *MockCommentService) ListReplies(ctx context.Context, actor *models.Identity, commentID uint, page models.CommentPage) (*models.CommentList, error) {
+	if m.comments[commentID] == nil {
+		return nil, services.ErrCommentNotFound
+	}
+	return &models.CommentList{Comments: []models.Comment{}}, nil
+}
+
+func (m *MockCommentService) UpdateComment(ctx context.Context, actor *models.Identity, id uint, req models.UpdateCommentRequest) (*models.Comment, error) {
+	comment, exists := m.comments[id]
+	if !exists {
+		return nil, services.ErrCommentNotFound
+	}
+	if comment.CreatorID != actor.UserID {
+		return nil, services.ErrNotCommentAuthor
+	}
+	if id == 2 {
+		return nil, services.ErrCommentEditExpired
+	}
+	comment.Content = req.Content
+	return comment, nil
+}
+
+func (m *MockCommentService) DeleteComment(ctx context.Context, actor *models.Identity, id uint) error {
+	comment, exists := m.comments[id]
+	if !exists {
+		return services.ErrCommentNotFound
+	}
+	if comment.CreatorID != actor.UserID && !actor.IsModerator() {
+		return services.ErrCommentDeleteDenied
+	}
+	delete(m.comments, id)
+	return nil
+}
+
+func TestCommentHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewCommentHandler(&MockCommentService{comments: make(map[uint]*models.Comment)})
+	r.Use(IdentityMiddleware())
+	r.GET("/promocodes/:id/comments", handler.ListComments)
+	r.POST("/promocodes/:id/comments", handler.CreateComment)
+	r.GET("/comments/:id/replies", handler.ListReplies)
+	r.PUT("/comments/:id", handler.UpdateComment)
+	r.DELETE("/comments/:id", handler.DeleteComment)
+
+	// roles - значение X-User-Roles, которое шлюз берет из токена
+	send := func(method, path, userID, roles string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderUserID, userID)
+		req.Header.Set(HeaderUserRoles, roles)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("POST", "/promocodes/1/comments", "1", "user", models.CreateCommentRequest{}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для пустого комментария, получен: %d", w.Code)
+	}
+	if w := send("POST", "/promocodes/42/comments", "1", "user", models.CreateCommentRequest{Content: "Работает?"}); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404 для отсутствующего промокода, получен: %d", w.Code)
+	}
+	for _, content := range []string{"Работает?", "Скидка применилась"} {
+		if w := send("POST", "/promocodes/1/comments", "1", "user", models.CreateCommentRequest{Content: content}); w.Code != http.StatusCreated {
+			t.Fatalf("Ожидается код 201, получен: %d %s", w.Code, w.Body.String())
+		}
+	}
+	missing := uint(42)
+	if w := send("POST", "/promocodes/1/comments", "2", "user", models.CreateCommentRequest{Content: "Ответ", ParentID: &missing}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для отсутствующего родителя, получен: %d", w.Code)
+	}
+
+	w := send("GET", "/promocodes/1/comments?limit=10", "2", "user", nil)
+	var list models.CommentList
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Comments) != 2 {
+		t.Errorf("Ожидается два комментария, получено: %d %s", w.Code, w.Body.String())
+	}
+	if w := send("GET", "/promocodes/1/comments?limit=1000", "2", "user", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для слишком большой страницы, получен: %d", w.Code)
+	}
+	if w := send("GET", "/promocodes/1/comments?cursor=bad", "2", "user", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного курсора, получен: %d", w.Code)
+	}
+	if w := send("GET", "/comments/42/replies", "2", "user", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+
+	if w := send("PUT", "/comments/1", "2", "user", models.UpdateCommentRequest{Content: "Чужое"}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для чужого комментария, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/comments/2", "1", "user", models.UpdateCommentRequest{Content: "Поздно"}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 после срока редактирования, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/comments/1", "1", "user", models.UpdateCommentRequest{Content: "Исправлено"}); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+
+	if w := send("DELETE", "/comments/abc", "3", "moderator", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного идентификатора, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/comments/1", "2", "user", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 при удалении чужого комментария, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/comments/1", "3", "user,moderator", nil); w.Code != http.StatusOK {
+		t.Errorf("Модератор может удалить комментарий, получен код: %d", w.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"promocodes-service/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовки, которыми шлюз передает проверенную личность пользователя
+const (
+	HeaderUserID        = "X-User-ID"
+	HeaderUserRoles     = "X-User-Roles"
+	HeaderUserCompanies = "X-User-Companies"
+	HeaderUserSegments  = "X-User-Segments"
+)
+
+const identityKey = "identity"
+
+// IdentityMiddleware читает личность пользователя из заголовков шлюза. Токен здесь не проверяется:
+// сервис доступен только из внутренней сети, а шлюз удаляет эти заголовки из клиентских запросов.
+func IdentityMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.GetHeader(HeaderUserID), 10, 64)
+		if err != nil || userID == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+			return
+		}
+
+		identity := &models.Identity{
+			UserID:    uint(userID),
+			Companies: parseCompanies(c.GetHeader(HeaderUserCompanies)),
+		}
+		if roles := c.GetHeader(HeaderUserRoles); roles != "" {
+			identity.Roles = strings.Split(roles, ",")
+		}
+		if segments := c.GetHeader(HeaderUserSegments); segments != "" {
+			identity.Segments = strings.Split(segments, ",")
+		}
+		c.Set(identityKey, identity)
+		c.Next()
+	}
+}
+
+// parseCompanies разбирает значение вида "12:owner,15:manager"; некорректные элементы пропускаются
+func parseCompanies(header string) map[uint]models.CompanyRole {
+	companies := make(map[uint]models.CompanyRole)
+	for _, part := range strings.Split(header, ",") {
+		pair := strings.SplitN(part, ":", 2)
+		if len(pair) != 2 || pair[1] == "" {
+			continue
+		}
+		companyID, err := strconv.ParseUint(pair[0], 10, 64)
+		if err != nil || companyID == 0 {
+			continue
+		}
+		companies[uint(companyID)] = models.CompanyRole(pair[1])
+	}
+	return companies
+}
+
+func identity(c *gin.Context) (*models.Identity, bool) {
+	value, exists := c.Get(identityKey)
+	if !exists {
+		return nil, false
+	}
+	identity, ok := value.(*models.Identity)
+	return identity, ok
+}
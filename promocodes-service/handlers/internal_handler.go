@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"promocodes-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderInternalToken передает общий секрет внутренних сервисов
+const HeaderInternalToken = "X-Internal-Token"
+
+// InternalAuthMiddleware пропускает запросы других сервисов с общим секретом token.
+// Шлюз маршруты /internal не проксирует.
+func InternalAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(HeaderInternalToken)), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// InternalHandler обслуживает запросы user-service
+type InternalHandler struct {
+	companyService services.CompanyServiceInterface
+}
+
+func NewInternalHandler(companyService services.CompanyServiceInterface) *InternalHandler {
+	return &InternalHandler{companyService: companyService}
+}
+
+func (h *InternalHandler) DeleteCompany(c *gin.Context) {
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.companyService.DeleteCompany(c.Request.Context(), companyID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
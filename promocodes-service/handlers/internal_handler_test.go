@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"promocodes-service/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockCompanyService запоминает компании, данные которых удалены
+type MockCompanyService struct {
+	deleted []uint
+}
+
+var _ services.CompanyServiceInterface = (*MockCompanyService)(nil)
+
+func (m *MockCompanyService) DeleteCompany(ctx context.Context, companyID uint) error {
+	m.deleted = append(m.deleted, companyID)
+	return nil
+}
+
+func TestInternalDeleteCompany(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	companyService := &MockCompanyService{}
+	router := gin.New()
+	internal := router.Group("/internal")
+	internal.Use(InternalAuthMiddleware("secret"))
+	internal.DELETE("/companies/:company_id", NewInternalHandler(companyService).DeleteCompany)
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"без токена", "", http.StatusUnauthorized},
+		{"чужой токен", "other", http.StatusUnauthorized},
+		{"токен сервиса", "secret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/internal/companies/7", nil)
+		if tt.token != "" {
+			req.Header.Set(HeaderInternalToken, tt.token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: ожидается статус %d, получено %d", tt.name, tt.status, w.Code)
+		}
+	}
+	if len(companyService.deleted) != 1 || companyService.deleted[0] != 7 {
+		t.Errorf("Данные компании должны удаляться только по запросу с токеном: %v", companyService.deleted)
+	}
+}
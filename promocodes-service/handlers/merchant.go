@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"promocodes-service/models"
+	"promocodes-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Заголовки интеграций компаний: API-ключ и ключ идемпотентности запроса
+const (
+	HeaderAPIKey         = "X-API-Key"
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed отмечает ответ, сохраненный при первом выполнении запроса
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+const (
+	apiKeyKey            = "apiKey"
+	idempotencyRecordKey = "idempotencyRecord"
+	// maxIdempotencyKeyLength хватает для UUID и составных ключей касс
+	maxIdempotencyKeyLength = 255
+)
+
+// APIKeyMiddleware пропускает запросы интеграций с действующим API-ключом компании.
+// Шлюз такие маршруты не проверяет: токена пользователя у кассы нет.
+func APIKeyMiddleware(apiKeyService services.APIKeyServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(HeaderAPIKey)
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "требуется заголовок " + HeaderAPIKey})
+			return
+		}
+
+		key, err := apiKeyService.Authenticate(c.Request.Context(), secret)
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(apiKeyKey, key)
+		c.Next()
+	}
+}
+
+// IdempotencyMiddleware сохраняет ответы на запросы с заголовком Idempotency-Key и отдает их на повторы,
+// не выполняя запрос снова. Ответы 5xx не сохраняются: такой запрос можно повторить с тем же ключом.
+// Должен стоять после APIKeyMiddleware: ключи идемпотентности у каждого API-ключа свои.
+func IdempotencyMiddleware(idempotencyService services.IdempotencyServiceInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "ключ идемпотентности длиннее 255 символов"})
+			return
+		}
+		apiKey, ok := apiKeyFromContext(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "не удалось прочитать тело запроса"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := idempotencyService.Begin(c.Request.Context(), apiKey.ID, key, requestHash(c.Request, body))
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, services.ErrIdempotentRequestInProgress):
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if record.StatusCode != 0 {
+			c.Header(HeaderIdempotentReplayed, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.Response)
+			c.Abort()
+			return
+		}
+
+		c.Set(idempotencyRecordKey, record)
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Касса могла уже отключиться, а ответ все равно нужно сохранить для ее повтора
+		ctx := context.Background()
+		if status := recorder.Status(); status >= http.StatusInternalServerError {
+			err = idempotencyService.Abandon(ctx, record)
+		} else {
+			err = idempotencyService.Complete(ctx, record, status, recorder.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("Ошибка сохранения ответа для ключа идемпотентности %q: %v", key, err)
+		}
+	}
+}
+
+// responseRecorder копирует тело ответа, чтобы сохранить его для повторов
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// requestHash связывает ключ идемпотентности с конкретным запросом
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotencyRecordID - запись, зарезервированная IdempotencyMiddleware, или 0 без Idempotency-Key
+func idempotencyRecordID(c *gin.Context) uint {
+	value, exists := c.Get(idempotencyRecordKey)
+	if !exists {
+		return 0
+	}
+	record, _ := value.(*models.IdempotencyRecord)
+	return record.ID
+}
+
+func apiKeyFromContext(c *gin.Context) (*models.APIKey, bool) {
+	value, exists := c.Get(apiKeyKey)
+	if !exists {
+		return nil, false
+	}
+	key, ok := value.(*models.APIKey)
+	return key, ok
+}
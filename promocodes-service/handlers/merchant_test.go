@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockAPIKeyService: действует только ключ pk_live ключ 5 компании 1
+type MockAPIKeyService struct{}
+
+var _ services.APIKeyServiceInterface = (*MockAPIKeyService)(nil)
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, actor *models.Identity, companyID uint, req models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	return nil, services.ErrNotCompanyOwner
+}
+
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context, actor *models.Identity, companyID uint) (*models.APIKeyList, error) {
+	return &models.APIKeyList{Keys: []models.APIKey{}}, nil
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, actor *models.Identity, companyID uint, id uint) error {
+	return services.ErrAPIKeyNotFound
+}
+
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	if secret != "pk_live" {
+		return nil, services.ErrInvalidAPIKey
+	}
+	return &models.APIKey{ID: 5, CompanyID: 1}, nil
+}
+
+// MockIdempotencyService хранит записи в памяти по ключу идемпотентности
+type MockIdempotencyService struct {
+	records map[string]*models.IdempotencyRecord
+}
+
+var _ services.IdempotencyServiceInterface = (*MockIdempotencyService)(nil)
+
+func (m *MockIdempotencyService) Begin(ctx context.Context, apiKeyID uint, key string, requestHash string) (*models.IdempotencyRecord, error) {
+	existing, exists := m.records[key]
+	if !exists {
+		record := &models.IdempotencyRecord{APIKeyID: apiKeyID, IdempotencyKey: key, RequestHash: requestHash}
+		m.records[key] = record
+		return record, nil
+	}
+	if existing.RequestHash != requestHash {
+		return nil, services.ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode == 0 {
+		return nil, services.ErrIdempotentRequestInProgress
+	}
+	return existing, nil
+}
+
+func (m *MockIdempotencyService) Complete(ctx context.Context, record *models.IdempotencyRecord, statusCode int, response []byte) error {
+	record.StatusCode = statusCode
+	record.Response = response
+	return nil
+}
+
+func (m *MockIdempotencyService) Abandon(ctx context.Context, record *models.IdempotencyRecord) error {
+	delete(m.records, record.IdempotencyKey)
+	return nil
+}
+
+func TestMerchantRedemption(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	redemptions := &MockRedemptionService{}
+	idempotency := &MockIdempotencyService{records: make(map[string]*models.IdempotencyRecord)}
+	handler := NewRedemptionHandler(redemptions)
+	r.POST("/merchant/redemptions", APIKeyMiddleware(&MockAPIKeyService{}), IdempotencyMiddleware(idempotency), handler.RedeemAtMerchant)
+
+	send := func(apiKey, idempotencyKey, code string) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(models.RedeemRequest{Code: code})
+		req, _ := http.NewRequest("POST", "/merchant/redemptions", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderAPIKey, apiKey)
+		req.Header.Set(HeaderIdempotencyKey, idempotencyKey)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("", "", "CASH"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401 без API-ключа, получен: %d", w.Code)
+	}
+	if w := send("pk_revoked", "", "CASH"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401 для недействительного ключа, получен: %d", w.Code)
+	}
+
+	first := send("pk_live", "order-1", "CASH")
+	var result models.MerchantRedemption
+	json.Unmarshal(first.Body.Bytes(), &result)
+	if first.Code != http.StatusCreated || result.Redemption.APIKeyID == nil || *result.Redemption.APIKeyID != 5 || result.Promocode.Type != models.PromocodeTypePercent {
+		t.Fatalf("Ожидается погашение кассой, получено: %d %s", first.Code, first.Body.String())
+	}
+
+	// Повтор после обрыва связи получает тот же ответ и не гасит код второй раз
+	replay := send("pk_live", "order-1", "CASH")
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() || replay.Header().Get(HeaderIdempotentReplayed) != "true" {
+		t.Errorf("Ожидается сохраненный ответ, получено: %d %s", replay.Code, replay.Body.String())
+	}
+	if redemptions.merchantCalls != 1 {
+		t.Errorf("Повтор не должен доходить до сервиса, вызовов: %d", redemptions.merchantCalls)
+	}
+	if w := send("pk_live", "order-1", "OTHER"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Ожидается код 422 для ключа с другим запросом, получен: %d", w.Code)
+	}
+
+	// Отказ тоже сохраняется: повтор получает тот же отказ
+	if w := send("pk_live", "order-2", "LAST"); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 для исчерпанного промокода, получен: %d", w.Code)
+	}
+	if w := send("pk_live", "order-2", "LAST"); w.Code != http.StatusConflict || w.Header().Get(HeaderIdempotentReplayed) != "true" || redemptions.merchantCalls != 2 {
+		t.Errorf("Ожидается сохраненный отказ, получено: %d (вызовов %d)", w.Code, redemptions.merchantCalls)
+	}
+
+	// Без ключа идемпотентности каждый запрос выполняется
+	send("pk_live", "", "CASH")
+	send("pk_live", "", "CASH")
+	if redemptions.merchantCalls != 4 {
+		t.Errorf("Ожидается четыре вызова сервиса, получено: %d", redemptions.merchantCalls)
+	}
+}
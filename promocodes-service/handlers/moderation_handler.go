@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModerationHandler struct {
+	moderationService services.ModerationServiceInterface
+}
+
+func NewModerationHandler(moderationService services.ModerationServiceInterface) *ModerationHandler {
+	return &ModerationHandler{moderationService: moderationService}
+}
+
+func (h *ModerationHandler) ListQueue(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	var filter models.ModerationFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := h.moderationService.ListQueue(c.Request.Context(), actor, filter)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *ModerationHandler) ListSubmissions(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	var filter models.ModerationFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := h.moderationService.ListSubmissions(c.Request.Context(), actor, filter)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *ModerationHandler) ClaimItem(c *gin.Context) {
+	h.changeItem(c, h.moderationService.ClaimItem)
+}
+
+func (h *ModerationHandler) ReleaseItem(c *gin.Context) {
+	h.changeItem(c, h.moderationService.ReleaseItem)
+}
+
+func (h *ModerationHandler) ApproveItem(c *gin.Context) {
+	h.changeItem(c, h.moderationService.ApproveItem)
+}
+
+func (h *ModerationHandler) RejectItem(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := moderationItemIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.RejectModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.moderationService.RejectItem(c.Request.Context(), actor, id, req.Reason)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *ModerationHandler) ListAudit(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	var filter models.ModerationAuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := h.moderationService.ListAudit(c.Request.Context(), actor, filter)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// changeItem обслуживает действия над заявкой, которым не нужно тело запроса
+func (h *ModerationHandler) changeItem(c *gin.Context, change func(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error)) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := moderationItemIDParam(c)
+	if !ok {
+		return
+	}
+
+	item, err := change(c.Request.Context(), actor, id)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func moderationItemIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор заявки"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func writeModerationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownModerationStatus), errors.Is(err, services.ErrUnknownModerationEntity):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotModerator):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrModerationItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrModerationItemClosed), errors.Is(err, services.ErrModerationItemSuperseded), errors.Is(err, services.ErrModerationItemClaimed),
+		errors.Is(err, services.ErrModerationItemNotClaimed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
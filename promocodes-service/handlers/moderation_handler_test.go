@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockModerationService: заявка 1 ждет решения, заявка 2 уже закрыта
+type MockModerationService struct {
+	claimedBy uint
+}
+
+var _ services.ModerationServiceInterface = (*MockModerationService)(nil)
+
+func (m *MockModerationService) ListQueue(ctx context.Context, actor *models.Identity, filter models.ModerationFilter) (*models.ModerationItemList, error) {
+	if !actor.IsModerator() {
+		return nil, services.ErrNotModerator
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, services.ErrUnknownModerationStatus
+	}
+	return &models.ModerationItemList{Items: []models.ModerationItem{{ID: 1, Status: models.ModerationPending}}, Total: 1}, nil
+}
+
+func (m *MockModerationService) ListSubmissions(ctx context.Context, actor *models.Identity, filter models.ModerationFilter) (*models.ModerationItemList, error) {
+	return &models.ModerationItemList{Items: []models.ModerationItem{}}, nil
+}
+
+func (m *MockModerationService) item(actor *models.Identity, id uint) (*models.ModerationItem, error) {
+	if !actor.IsModerator() {
+		return nil, services.ErrNotModerator
+	}
+	switch id {
+	case 1:
+		return &models.ModerationItem{ID: 1, Status: models.ModerationPending}, nil
+	case 2:
+		return nil, services.ErrModerationItemClosed
+	}
+	return nil, services.ErrModerationItemNotFound
+}
+
+func (m *MockModerationService) ClaimItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error) {
+	item, err := m.item(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.claimedBy != 0 && m.claimedBy != actor.UserID {
+		return nil, services.ErrModerationItemClaimed
+	}
+	m.claimedBy = actor.UserID
+	return item, nil
+}
+
+func (m *MockModerationService) ReleaseItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error) {
+	item, err := m.item(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.claimedBy != actor.UserID {
+		return nil, services.ErrModerationItemNotClaimed
+	}
+	m.claimedBy = 0
+	return item, nil
+}
+
+func (m *MockModerationService) ApproveItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error) {
+	item, err := m.item(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	item.Status = models.ModerationApproved
+	return item, nil
+}
+
+func (m *MockModerationService) RejectItem(ctx context.Context, actor *models.Identity, id uint, reason string) (*models.ModerationItem, error) {
+	item, err := m.item(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	item.Status, item.Reason = models.ModerationRejected, reason
+	return item, nil
+}
+
+func (m *MockModerationService) ListAudit(ctx context.Context, actor *models.Identity, filter models.ModerationAuditFilter) (*models.ModerationAuditList, error) {
+	if !actor.IsModerator() {
+		return nil, services.ErrNotModerator
+	}
+	return &models.ModerationAuditList{Records: []models.ModerationAudit{}}, nil
+}
+
+func TestModerationHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewModerationHandler(&MockModerationService{})
+	r.Use(IdentityMiddleware())
+	r.GET("/moderation/items", handler.ListQueue)
+	r.POST("/moderation/items/:id/claim", handler.ClaimItem)
+	r.POST("/moderation/items/:id/release", handler.ReleaseItem)
+	r.POST("/moderation/items/:id/approve", handler.ApproveItem)
+	r.POST("/moderation/items/:id/reject", handler.RejectItem)
+	r.GET("/moderation/audit", handler.ListAudit)
+	r.GET("/moderation/submissions", handler.ListSubmissions)
+
+	send := func(method, path, userID, roles string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderUserID, userID)
+		req.Header.Set(HeaderUserRoles, roles)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("GET", "/moderation/items", "1", "user", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для обычного пользователя, получен: %d", w.Code)
+	}
+	if w := send("GET", "/moderation/items?status=unknown", "5", "moderator", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для неизвестного статуса, получен: %d", w.Code)
+	}
+	if w := send("GET", "/moderation/items", "5", "moderator", nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w := send("GET", "/moderation/submissions", "1", "user", nil); w.Code != http.StatusOK {
+		t.Errorf("Автор видит свои заявки, получен код: %d", w.Code)
+	}
+	if w := send("GET", "/moderation/audit", "1", "user", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для журнала, получен: %d", w.Code)
+	}
+
+	if w := send("POST", "/moderation/items/abc/claim", "5", "moderator", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного идентификатора, получен: %d", w.Code)
+	}
+	if w := send("POST", "/moderation/items/42/claim", "5", "moderator", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+	if w := send("POST", "/moderation/items/1/claim", "5", "moderator", nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w := send("POST", "/moderation/items/1/claim", "6", "admin", nil); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 для занятой заявки, получен: %d", w.Code)
+	}
+	if w := send("POST", "/moderation/items/1/release", "6", "admin", nil); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 при освобождении чужой заявки, получен: %d", w.Code)
+	}
+	if w := send("POST", "/moderation/items/1/reject", "5", "moderator", models.RejectModerationRequest{}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 без причины отказа, получен: %d", w.Code)
+	}
+
+	w := send("POST", "/moderation/items/1/reject", "5", "moderator", models.RejectModerationRequest{Reason: "Реклама"})
+	var item models.ModerationItem
+	json.Unmarshal(w.Body.Bytes(), &item)
+	if w.Code != http.StatusOK || item.Status != models.ModerationRejected || item.Reason != "Реклама" {
+		t.Errorf("Ожидается отклоненная заявка с причиной, получено: %d %s", w.Code, w.Body.String())
+	}
+	if w := send("POST", "/moderation/items/2/approve", "5", "moderator", nil); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 для закрытой заявки, получен: %d", w.Code)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromocodeHandler struct {
+	promocodeService services.PromocodeServiceInterface
+}
+
+func NewPromocodeHandler(promocodeService services.PromocodeServiceInterface) *PromocodeHandler {
+	return &PromocodeHandler{promocodeService: promocodeService}
+}
+
+func (h *PromocodeHandler) CreatePromocode(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	var req models.CreatePromocodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promocode, err := h.promocodeService.CreatePromocode(c.Request.Context(), actor, req)
+	if err != nil {
+		writePromocodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, promocode)
+}
+
+func (h *PromocodeHandler) ListPromocodes(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	var filter models.PromocodeFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promocodes, err := h.promocodeService.ListPromocodes(c.Request.Context(), actor, filter)
+	if err != nil {
+		writePromocodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promocodes)
+}
+
+func (h *PromocodeHandler) GetPromocode(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := promocodeIDParam(c)
+	if !ok {
+		return
+	}
+
+	promocode, err := h.promocodeService.GetPromocode(c.Request.Context(), actor, id)
+	if err != nil {
+		writePromocodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promocode)
+}
+
+func (h *PromocodeHandler) UpdatePromocode(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := promocodeIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePromocodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promocode, err := h.promocodeService.UpdatePromocode(c.Request.Context(), actor, id, req)
+	if err != nil {
+		writePromocodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promocode)
+}
+
+func (h *PromocodeHandler) DeletePromocode(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := promocodeIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.promocodeService.DeletePromocode(c.Request.Context(), actor, id); err != nil {
+		writePromocodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Промокод удален"})
+}
+
+func promocodeIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор промокода"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func writePromocodeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownPromocodeType), errors.Is(err, services.ErrUnknownModerationStatus),
+		errors.Is(err, services.ErrInvalidValidityPeriod), errors.Is(err, services.ErrInvalidSegment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotCompanyPromoEditor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPromocodeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCodeTaken), errors.Is(err, services.ErrCompanyNotApproved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
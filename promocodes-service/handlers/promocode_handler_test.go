@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockPromocodeService разрешает изменения только владельцам компании
+type MockPromocodeService struct {
+	promocodes map[uint]*models.Promocode
+}
+
+var _ services.PromocodeServiceInterface = (*MockPromocodeService)(nil)
+
+func (m *MockPromocodeService) CreatePromocode(ctx context.Context, actor *models.Identity, req models.CreatePromocodeRequest) (*models.Promocode, error) {
+	if role, _ := actor.CompanyRole(req.CompanyID); role != models.CompanyRoleOwner {
+		return nil, services.ErrNotCompanyPromoEditor
+	}
+	for _, promocode := range m.promocodes {
+		if promocode.Code == req.Code {
+			return nil, services.ErrCodeTaken
+		}
+	}
+	promocode := &models.Promocode{ID: uint(len(m.promocodes) + 1), CompanyID: req.CompanyID, CreatorID: actor.UserID, Code: req.Code, Title: req.Title, Type: req.Type}
+	m.promocodes[promocode.ID] = promocode
+	return promocode, nil
+}
+
+func (m *MockPromocodeService) GetPromocode(ctx context.Context, actor *models.Identity, id uint) (*models.Promocode, error) {
+	promocode, exists := m.promocodes[id]
+	if !exists {
+		return nil, services.ErrPromocodeNotFound
+	}
+	return promocode, nil
+}
+
+func (m *MockPromocodeService) ListPromocodes(ctx context.Context, actor *models.Identity, filter models.PromocodeFilter) (*models.PromocodeList, error) {
+	list := &models.PromocodeList{Promocodes: []models.Promocode{}, Page: 1, PageSize: 20}
+	for _, promocode := range m.promocodes {
+		if filter.CompanyID == 0 || promocode.CompanyID == filter.CompanyID {
+			list.Promocodes = append(list.Promocodes, *promocode)
+		}
+	}
+	list.Total = int64(len(list.Promocodes))
+	return list, nil
+}
+
+func (m *MockPromocodeService) UpdatePromocode(ctx context.Context, actor *models.Identity, id uint, req models.UpdatePromocodeRequest) (*models.Promocode, error) {
+	promocode, err := m.GetPromocode(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if role, _ := actor.CompanyRole(promocode.CompanyID); role != models.CompanyRoleOwner {
+		return nil, services.ErrNotCompanyPromoEditor
+	}
+	if req.Type != "" && !req.Type.Valid() {
+		return nil, services.ErrUnknownPromocodeType
+	}
+	promocode.Title = req.Title
+	return promocode, nil
+}
+
+func (m *MockPromocodeService) DeletePromocode(ctx context.Context, actor *models.Identity, id uint) error {
+	promocode, err := m.GetPromocode(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if role, _ := actor.CompanyRole(promocode.CompanyID); role != models.CompanyRoleOwner {
+		return services.ErrNotCompanyPromoEditor
+	}
+	delete(m.promocodes, id)
+	return nil
+}
+
+func TestPromocodeHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewPromocodeHandler(&MockPromocodeService{promocodes: make(map[uint]*models.Promocode)})
+	r.Use(IdentityMiddleware())
+	r.POST("/promocodes", handler.CreatePromocode)
+	r.GET("/promocodes", handler.ListPromocodes)
+	r.GET("/promocodes/:id", handler.GetPromocode)
+	r.PUT("/promocodes/:id", handler.UpdatePromocode)
+	r.DELETE("/promocodes/:id", handler.DeletePromocode)
+
+	// companies - значение X-User-Companies, которое шлюз берет из токена
+	send := func(method, path, userID, companies string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderUserID, userID)
+		req.Header.Set(HeaderUserCompanies, companies)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	create := models.CreatePromocodeRequest{CompanyID: 12, Code: "COFFEE10", Title: "Скидка на кофе", Type: models.PromocodeTypePercent}
+
+	if w := send("GET", "/promocodes", "", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401 без X-User-ID, получен: %d", w.Code)
+	}
+	if w := send("POST", "/promocodes", "1", "12:owner", models.CreatePromocodeRequest{CompanyID: 12, Code: "no spaces!", Title: "Скидка", Type: models.PromocodeTypePercent}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного кода, получен: %d", w.Code)
+	}
+	if w := send("POST", "/promocodes", "1", "15:owner,12:analyst", create); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 без роли владельца в компании 12, получен: %d", w.Code)
+	}
+	w := send("POST", "/promocodes", "1", "15:manager,12:owner,bad,:owner", create)
+	var promocode models.Promocode
+	json.Unmarshal(w.Body.Bytes(), &promocode)
+	if w.Code != http.StatusCreated || promocode.ID != 1 || promocode.CreatorID != 1 {
+		t.Fatalf("Ожидается код 201 и промокод пользователя 1, получено: %d %s", w.Code, w.Body.String())
+	}
+	if w := send("POST", "/promocodes", "1", "12:owner", create); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 для занятого кода, получен: %d", w.Code)
+	}
+
+	w = send("GET", "/promocodes?company_id=12", "2", "", nil)
+	var list models.PromocodeList
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Total != 1 {
+		t.Errorf("Промокоды видны любому пользователю, получено: %d %s", w.Code, w.Body.String())
+	}
+	if w := send("GET", "/promocodes?page_size=1000", "2", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для слишком большой страницы, получен: %d", w.Code)
+	}
+	if w := send("GET", "/promocodes/abc", "2", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного идентификатора, получен: %d", w.Code)
+	}
+	if w := send("GET", "/promocodes/42", "2", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+
+	if w := send("PUT", "/promocodes/1", "2", "", models.UpdatePromocodeRequest{Title: "Чужой"}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для постороннего, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/promocodes/1", "1", "12:owner", models.UpdatePromocodeRequest{Title: "Кофе", Type: "bonus"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для неизвестного типа, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/promocodes/1", "1", "12:owner", models.UpdatePromocodeRequest{Title: "Кофе со скидкой"}); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/promocodes/1", "2", "12:analyst", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 при удалении аналитиком, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/promocodes/1", "1", "12:owner", nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"promocodes-service/models"
+	"promocodes-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RedemptionHandler struct {
+	redemptionService services.RedemptionServiceInterface
+}
+
+func NewRedemptionHandler(redemptionService services.RedemptionServiceInterface) *RedemptionHandler {
+	return &RedemptionHandler{redemptionService: redemptionService}
+}
+
+func (h *RedemptionHandler) Redeem(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	var req models.RedeemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	redemption, err := h.redemptionService.Redeem(c.Request.Context(), actor, req)
+	if err != nil {
+		writeRedemptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, redemption)
+}
+
+// RedeemAtMerchant - погашение кассой по API-ключу компании, см. APIKeyMiddleware
+func (h *RedemptionHandler) RedeemAtMerchant(c *gin.Context) {
+	apiKey, ok := apiKeyFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	var req models.RedeemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Погашение сохраняется вместе со ссылкой на ключ идемпотентности, см. IdempotencyMiddleware
+	req.IdempotencyRecordID = idempotencyRecordID(c)
+
+	redemption, err := h.redemptionService.RedeemAtMerchant(c.Request.Context(), apiKey, req)
+	if err != nil {
+		writeRedemptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, redemption)
+}
+
+func (h *RedemptionHandler) ListUserRedemptions(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	var filter models.RedemptionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	redemptions, err := h.redemptionService.ListUserRedemptions(c.Request.Context(), actor, filter)
+	if err != nil {
+		writeRedemptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, redemptions)
+}
+
+func (h *RedemptionHandler) ListPromocodeRedemptions(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	id, ok := promocodeIDParam(c)
+	if !ok {
+		return
+	}
+
+	var filter models.RedemptionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	redemptions, err := h.redemptionService.ListPromocodeRedemptions(c.Request.Context(), actor, id, filter)
+	if err != nil {
+		writeRedemptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, redemptions)
+}
+
+func writeRedemptionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSegmentNotAllowed), errors.Is(err, services.ErrNotCompanyMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPromocodeNotApproved), errors.Is(err, services.ErrPromocodeNotStarted), errors.Is(err, services.ErrPromocodeExpired),
+		errors.Is(err, services.ErrPromocodeExhausted), errors.Is(err, services.ErrRedemptionLimitReached),
+		errors.Is(err, services.ErrCodeAlreadyRedeemed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		writePromocodeError(c, err)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockRedemptionService: VIP доступен только сегменту vip, у LAST исчерпан лимит, DRAFT ждет модерации,
+// промокод 1 принадлежит компании 1.
+// merchantCalls считает погашения кассами, дошедшие до сервиса.
+type MockRedemptionService struct {
+	merchantCalls int
+}
+
+var _ services.RedemptionServiceInterface = (*MockRedemptionService)(nil)
+
+func (m *MockRedemptionService) Redeem(ctx context.Context, actor *models.Identity, req models.RedeemRequest) (*models.Redemption, error) {
+	switch req.Code {
+	case "VIP":
+		if !actor.InAnySegment([]string{"vip"}) {
+			return nil, services.ErrSegmentNotAllowed
+		}
+		return &models.Redemption{ID: 1, PromocodeID: 1, UserID: actor.UserID, Code: req.Code}, nil
+	case "LAST":
+		return nil, services.ErrPromocodeExhausted
+	case "DRAFT":
+		return nil, services.ErrPromocodeNotApproved
+	}
+	return nil, services.ErrPromocodeNotFound
+}
+
+func (m *MockRedemptionService) RedeemAtMerchant(ctx context.Context, apiKey *models.APIKey, req models.RedeemRequest) (*models.MerchantRedemption, error) {
+	m.merchantCalls++
+	switch req.Code {
+	case "CASH":
+		id := apiKey.ID
+		return &models.MerchantRedemption{
+			Redemption: models.Redemption{ID: uint(m.merchantCalls), PromocodeID: 1, APIKeyID: &id, CompanyID: apiKey.CompanyID, Code: req.Code},
+			Promocode:  models.Promocode{ID: 1, CompanyID: apiKey.CompanyID, Code: req.Code, Type: models.PromocodeTypePercent},
+		}, nil
+	case "LAST":
+		return nil, services.ErrPromocodeExhausted
+	}
+	return nil, services.ErrPromocodeNotFound
+}
+
+func (m *MockRedemptionService) ListUserRedemptions(ctx context.Context, actor *models.Identity, filter models.RedemptionFilter) (*models.RedemptionList, error) {
+	return &models.RedemptionList{Redemptions: []models.Redemption{}}, nil
+}
+
+func (m *MockRedemptionService) ListPromocodeRedemptions(ctx context.Context, actor *models.Identity, promocodeID uint, filter models.RedemptionFilter) (*models.RedemptionList, error) {
+	if promocodeID != 1 {
+		return nil, services.ErrPromocodeNotFound
+	}
+	if _, ok := actor.CompanyRole(1); !ok {
+		return nil, services.ErrNotCompanyMember
+	}
+	return &models.RedemptionList{Redemptions: []models.Redemption{}}, nil
+}
+
+func TestRedemptionHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewRedemptionHandler(&MockRedemptionService{})
+	r.Use(IdentityMiddleware())
+	r.POST("/redemptions", handler.Redeem)
+	r.GET("/redemptions", handler.ListUserRedemptions)
+	r.GET("/promocodes/:id/redemptions", handler.ListPromocodeRedemptions)
+
+	send := func(method, path, segments, companies string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderUserID, "7")
+		req.Header.Set(HeaderUserSegments, segments)
+		req.Header.Set(HeaderUserCompanies, companies)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("POST", "/redemptions", "", "", models.RedeemRequest{Code: "НЕКОД"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного кода, получен: %d", w.Code)
+	}
+	if w := send("POST", "/redemptions", "", "", models.RedeemRequest{Code: "UNKNOWN"}); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+	if w := send("POST", "/redemptions", "employees", "", models.RedeemRequest{Code: "VIP"}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для чужого сегмента, получен: %d", w.Code)
+	}
+	if w := send("POST", "/redemptions", "", "", models.RedeemRequest{Code: "LAST"}); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 для исчерпанного промокода, получен: %d", w.Code)
+	}
+	if w := send("POST", "/redemptions", "", "", models.RedeemRequest{Code: "DRAFT"}); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 для промокода на модерации, получен: %d", w.Code)
+	}
+	w := send("POST", "/redemptions", "employees,vip", "", models.RedeemRequest{Code: "VIP"})
+	var redemption models.Redemption
+	json.Unmarshal(w.Body.Bytes(), &redemption)
+	if w.Code != http.StatusCreated || redemption.UserID != 7 {
+		t.Errorf("Ожидается погашение от имени пользователя 7, получено: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := send("GET", "/redemptions?page_size=1000", "", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для слишком большой страницы, получен: %d", w.Code)
+	}
+	if w := send("GET", "/redemptions", "", "", nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w := send("GET", "/promocodes/1/redemptions", "", "", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для постороннего, получен: %d", w.Code)
+	}
+	if w := send("GET", "/promocodes/1/redemptions", "", "1:analyst", nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200 для аналитика компании, получен: %d", w.Code)
+	}
+}
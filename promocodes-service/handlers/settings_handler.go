@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"promocodes-service/models"
+	"promocodes-service/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SettingsHandler struct {
+	settingsService services.SettingsServiceInterface
+}
+
+func NewSettingsHandler(settingsService services.SettingsServiceInterface) *SettingsHandler {
+	return &SettingsHandler{settingsService: settingsService}
+}
+
+func (h *SettingsHandler) GetCompanySettings(c *gin.Context) {
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.settingsService.GetCompanySettings(c.Request.Context(), companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *SettingsHandler) UpdateCompanySettings(c *gin.Context) {
+	actor, ok := identity(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCompanySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.settingsService.UpdateCompanySettings(c.Request.Context(), actor, companyID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrNotCompanyOwner) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func companyIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("company_id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор компании"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const pingTimeout = 2 * time.Second
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Checker отвечает на /healthz и /readyz. Сервис не готов, пока не вызван SetReady(true)
+// (например, во время миграции базы) и пока база данных не отвечает.
+type Checker struct {
+	db    Pinger
+	ready int32
+}
+
+func NewChecker(db Pinger) *Checker {
+	return &Checker{db: db}
+}
+
+func (h *Checker) SetReady(ready bool) {
+	var value int32
+	if ready {
+		value = 1
+	}
+	atomic.StoreInt32(&h.ready, value)
+}
+
+func (h *Checker) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *Checker) Readiness(c *gin.Context) {
+	if atomic.LoadInt32(&h.ready) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "starting",
+			"checks": gin.H{"database": "migration in progress"},
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), pingTimeout)
+	defer cancel()
+	if err := h.db.PingContext(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "unavailable",
+			"checks": gin.H{"database": err.Error()},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"checks": gin.H{"database": "ok"},
+	})
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mockPinger struct {
+	err error
+}
+
+func (p *mockPinger) PingContext(ctx context.Context) error {
+	return p.err
+}
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pinger := &mockPinger{}
+	checker := NewChecker(pinger)
+	r := gin.New()
+	r.GET("/healthz", checker.Liveness)
+	r.GET("/readyz", checker.Readiness)
+
+	get := func(path string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("Liveness должен отвечать 200, получен: %d", code)
+	}
+
+	if code, response := get("/readyz"); code != http.StatusServiceUnavailable || response["status"] != "starting" {
+		t.Errorf("Во время миграции ожидается 503 starting, получено: %d %v", code, response)
+	}
+
+	checker.SetReady(true)
+	if code, _ := get("/readyz"); code != http.StatusOK {
+		t.Errorf("После миграции ожидается 200, получен: %d", code)
+	}
+
+	pinger.err = errors.New("connection refused")
+	if code, response := get("/readyz"); code != http.StatusServiceUnavailable || response["status"] != "unavailable" {
+		t.Errorf("При недоступной базе ожидается 503 unavailable, получено: %d %v", code, response)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"promocodes-service/handlers"
+	"promocodes-service/health"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/services"
+	"promocodes-service/tracing"
+	"promocodes-service/userservice"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	shutdownTracing, err := tracing.Init(context.Background(), "promocodes-service")
+	if err != nil {
+		log.Fatalf("Ошибка настройки трассировки: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	dsn := "host=" + os.Getenv("DB_HOST") +
+		" user=" + os.Getenv("DB_USER") +
+		" password=" + os.Getenv("DB_PASSWORD") +
+		" dbname=" + os.Getenv("DB_NAME") +
+		" port=" + os.Getenv("DB_PORT") +
+		" sslmode=disable"
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Не удалось подключиться к базе данных: %v", err)
+	}
+
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Ошибка подключения трассировки к GORM: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Не удалось получить соединение с базой данных: %v", err)
+	}
+	checker := health.NewChecker(sqlDB)
+
+	promocodeRepo := repository.NewPromocodeRepository(db)
+	settingsRepo := repository.NewCompanySettingsRepository(db)
+	moderationRepo := repository.NewModerationRepository(db)
+	codeRepo := repository.NewCodeRepository(db)
+
+	// Общий секрет для внутренних маршрутов сервисов, которые шлюз не проксирует
+	internalToken := os.Getenv("INTERNAL_API_TOKEN")
+	if internalToken == "" {
+		log.Fatalf("Не задан INTERNAL_API_TOKEN")
+	}
+	users := userservice.NewHTTPClient(stringFromEnv("USER_SERVICE_URL", "http://user-service:8081"),
+		internalToken, durationFromEnv("USER_SERVICE_TIMEOUT", 5*time.Second))
+
+	promocodeService := services.NewPromocodeService(promocodeRepo, moderationRepo, codeRepo, users)
+	promocodeHandler := handlers.NewPromocodeHandler(promocodeService)
+	commentRepo := repository.NewCommentRepository(db)
+	commentService := services.NewCommentService(commentRepo, promocodeRepo, settingsRepo, moderationRepo,
+		durationFromEnv("COMMENT_EDIT_WINDOW", 15*time.Minute))
+	commentHandler := handlers.NewCommentHandler(commentService)
+	settingsHandler := handlers.NewSettingsHandler(services.NewSettingsService(settingsRepo))
+	moderationService := services.NewModerationService(moderationRepo, promocodeRepo, commentRepo, users, durationFromEnv("MODERATION_CLAIM_TTL", 30*time.Minute))
+	moderationHandler := handlers.NewModerationHandler(moderationService)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	idempotencyService := services.NewIdempotencyService(repository.NewIdempotencyRepository(db),
+		durationFromEnv("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute), durationFromEnv("IDEMPOTENCY_TTL", 24*time.Hour))
+	redemptionHandler := handlers.NewRedemptionHandler(services.NewRedemptionService(promocodeRepo, repository.NewRedemptionRepository(db), codeRepo))
+	codeBatchService := services.NewCodeBatchService(promocodeRepo, codeRepo)
+	codeBatchHandler := handlers.NewCodeBatchHandler(codeBatchService)
+	internalHandler := handlers.NewInternalHandler(services.NewCompanyService(promocodeRepo, apiKeyRepo, settingsRepo))
+
+	r := gin.New()
+	r.Use(otelgin.Middleware("promocodes-service"), tracing.RequestID(), tracing.Logger(), gin.Recovery())
+
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/healthz", checker.Liveness)
+	r.GET("/readyz", checker.Readiness)
+
+	// Личность пользователя проверяет шлюз, сервис получает ее заголовками
+	protected := r.Group("/")
+	protected.Use(handlers.IdentityMiddleware())
+	{
+		protected.GET("/promocodes", promocodeHandler.ListPromocodes)
+		protected.GET("/promocodes/:id", promocodeHandler.GetPromocode)
+		protected.POST("/promocodes", promocodeHandler.CreatePromocode)
+		protected.PUT("/promocodes/:id", promocodeHandler.UpdatePromocode)
+		protected.DELETE("/promocodes/:id", promocodeHandler.DeletePromocode)
+
+		protected.POST("/redemptions", redemptionHandler.Redeem)
+		protected.GET("/redemptions", redemptionHandler.ListUserRedemptions)
+		protected.GET("/promocodes/:id/redemptions", redemptionHandler.ListPromocodeRedemptions)
+
+		protected.POST("/promocodes/:id/code-batches", codeBatchHandler.CreateBatch)
+		protected.GET("/promocodes/:id/code-batches", codeBatchHandler.ListBatches)
+		protected.GET("/code-batches/:id", codeBatchHandler.GetBatch)
+		protected.GET("/code-batches/:id/export", codeBatchHandler.ExportBatch)
+
+		protected.GET("/promocodes/:id/comments", commentHandler.ListComments)
+		protected.POST("/promocodes/:id/comments", commentHandler.CreateComment)
+		protected.GET("/comments/:id/replies", commentHandler.ListReplies)
+		protected.PUT("/comments/:id", commentHandler.UpdateComment)
+		protected.DELETE("/comments/:id", commentHandler.DeleteComment)
+
+		protected.GET("/company-settings/:company_id", settingsHandler.GetCompanySettings)
+		protected.PUT("/company-settings/:company_id", settingsHandler.UpdateCompanySettings)
+		protected.GET("/company-settings/:company_id/api-keys", apiKeyHandler.ListAPIKeys)
+		protected.POST("/company-settings/:company_id/api-keys", apiKeyHandler.CreateAPIKey)
+		protected.DELETE("/company-settings/:company_id/api-keys/:key_id", apiKeyHandler.RevokeAPIKey)
+
+		protected.GET("/moderation/items", moderationHandler.ListQueue)
+		protected.POST("/moderation/items/:id/claim", moderationHandler.ClaimItem)
+		protected.POST("/moderation/items/:id/release", moderationHandler.ReleaseItem)
+		protected.POST("/moderation/items/:id/approve", moderationHandler.ApproveItem)
+		protected.POST("/moderation/items/:id/reject", moderationHandler.RejectItem)
+		protected.GET("/moderation/audit", moderationHandler.ListAudit)
+		protected.GET("/moderation/submissions", moderationHandler.ListSubmissions)
+	}
+
+	// Кассы и другие интеграции компаний приходят без токена пользователя, с API-ключом компании
+	merchant := r.Group("/merchant")
+	merchant.Use(handlers.APIKeyMiddleware(apiKeyService), handlers.IdempotencyMiddleware(idempotencyService))
+	{
+		merchant.POST("/redemptions", redemptionHandler.RedeemAtMerchant)
+	}
+
+	internal := r.Group("/internal")
+	internal.Use(handlers.InternalAuthMiddleware(internalToken))
+	{
+		internal.DELETE("/companies/:company_id", internalHandler.DeleteCompany)
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8082"
+	}
+	// HTTP-сервер стартует до миграции, чтобы /readyz отвечал 503, пока она идет
+	go func() {
+		log.Fatal(r.Run(":" + port))
+	}()
+
+	if err := db.AutoMigrate(&models.Promocode{}, &models.Comment{}, &models.CompanySettings{},
+		&models.ModerationItem{}, &models.ModerationAudit{}, &models.Redemption{}, &models.CodeBatch{}, &models.UniqueCode{},
+		&models.APIKey{}, &models.IdempotencyRecord{}, &models.IssuedCode{}); err != nil {
+		log.Fatalf("Ошибка миграции базы данных: %v", err)
+	}
+	go codeBatchService.Run(context.Background(), time.Minute)
+	go idempotencyService.Run(context.Background(), time.Hour)
+	checker.SetReady(true)
+	log.Printf("Миграция базы данных завершена, сервис готов принимать запросы")
+
+	select {}
+}
+
+func stringFromEnv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Некорректное значение %s: %v", name, err)
+	}
+	return duration
+}
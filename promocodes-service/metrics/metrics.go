@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	PromocodeChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "promocodes_changes_total",
+		Help: "Изменения промокодов по операции: create, update, delete.",
+	}, []string{"operation"})
+
+	CommentChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "promocodes_comment_changes_total",
+		Help: "Изменения комментариев по операции: create, update, delete.",
+	}, []string{"operation"})
+
+	ModerationDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "promocodes_moderation_decisions_total",
+		Help: "Решения модераторов по виду контента и результату: approved, rejected.",
+	}, []string{"entity_type", "status"})
+
+	Redemptions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "promocodes_redemptions_total",
+		Help: "Попытки погашения промокодов по результату: redeemed или причина отказа.",
+	}, []string{"result"})
+
+	CodesGenerated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "promocodes_codes_generated_total",
+		Help: "Одноразовые коды, сохраненные фоновой генерацией партий.",
+	})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "promocodes_db_query_duration_seconds",
+		Help:    "Время выполнения методов репозиториев.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// ObserveDBQuery используется как defer metrics.ObserveDBQuery("PromocodeRepository.GetPromocodeByID", time.Now())
+func ObserveDBQuery(method string, start time.Time) {
+	DBQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
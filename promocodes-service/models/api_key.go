@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey - ключ, которым кассы и другие интеграции компании гасят ее промокоды без входа пользователя.
+// Сам ключ показывается один раз при создании, в базе хранится только его хеш.
+type APIKey struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CompanyID uint   `json:"company_id" gorm:"index;not null"`
+	Name      string `json:"name" gorm:"not null"`
+	// Prefix - начало ключа, по которому его можно узнать в списке
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;not null"`
+	CreatorID  uint       `json:"creator_id" gorm:"not null"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+// CreatedAPIKey - ответ на создание ключа; Key больше нигде не возвращается
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyList struct {
+	Keys []APIKey `json:"keys"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+)
+
+// CodeBatchStatus - этап фоновой генерации партии кодов
+type CodeBatchStatus string
+
+const (
+	CodeBatchPending   CodeBatchStatus = "pending"
+	CodeBatchRunning   CodeBatchStatus = "running"
+	CodeBatchCompleted CodeBatchStatus = "completed"
+	CodeBatchFailed    CodeBatchStatus = "failed"
+)
+
+// DefaultCodeAlphabet - алфавит по умолчанию без символов, которые легко перепутать: 0 и O, 1, I и L
+const DefaultCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// CodeBatch - задание на генерацию одноразовых кодов промокода. Код состоит из Prefix, Length
+// случайных символов Alphabet и, если CheckDigit, контрольного символа из того же алфавита.
+type CodeBatch struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	PromocodeID uint            `json:"promocode_id" gorm:"index;not null"`
+	CompanyID   uint            `json:"company_id" gorm:"not null"`
+	CreatorID   uint            `json:"creator_id" gorm:"not null"`
+	Quantity    int             `json:"quantity" gorm:"not null"`
+	Alphabet    string          `json:"alphabet" gorm:"not null"`
+	Length      int             `json:"length" gorm:"not null"`
+	Prefix      string          `json:"prefix" gorm:"not null;default:''"`
+	CheckDigit  bool            `json:"check_digit" gorm:"not null;default:false"`
+	Status      CodeBatchStatus `json:"status" gorm:"index;not null"`
+	// Generated - сколько кодов уже сохранено; меняется вместе с вставкой кодов
+	Generated  int        `json:"generated" gorm:"not null;default:0"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// UniqueCode - одноразовый код промокода из партии. Коды уникальны по всей базе
+// и не совпадают с общими кодами промокодов.
+type UniqueCode struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	PromocodeID uint       `json:"promocode_id" gorm:"index;not null"`
+	BatchID     uint       `json:"batch_id" gorm:"index;not null"`
+	Code        string     `json:"code" gorm:"uniqueIndex;not null"`
+	RedeemedBy  *uint      `json:"redeemed_by,omitempty"`
+	RedeemedAt  *time.Time `json:"redeemed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IssuedCode занимает код в общем пространстве промокодов и одноразовых кодов. Первичный ключ по коду
+// не дает двум транзакциям выдать один код, даже если каждая перед этим убедилась, что он свободен.
+type IssuedCode struct {
+	Code        string `gorm:"primaryKey"`
+	PromocodeID uint   `gorm:"index;not null"`
+	// BatchID - партия одноразового кода, 0 для общего кода промокода
+	BatchID uint `gorm:"not null;default:0"`
+}
+
+// GenerateCodesRequest - пустой алфавит заменяется на DefaultCodeAlphabet. Вместе с префиксом
+// и контрольным символом код должен уместиться в 32 символа.
+type GenerateCodesRequest struct {
+	Quantity   int    `json:"quantity" binding:"required,min=1,max=100000"`
+	Alphabet   string `json:"alphabet" binding:"omitempty,alphanum,min=2,max=36"`
+	Length     int    `json:"length" binding:"required,min=4,max=32"`
+	Prefix     string `json:"prefix" binding:"omitempty,alphanum,max=16"`
+	CheckDigit bool   `json:"check_digit"`
+}
+
+// CodeBatchFilter - параметры поиска партий. PromocodeID задает сервис, а не клиент.
+type CodeBatchFilter struct {
+	PromocodeID uint `form:"-"`
+	Page        int  `form:"page" binding:"omitempty,min=1"`
+	PageSize    int  `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type CodeBatchList struct {
+	Batches  []CodeBatch `json:"batches"`
+	Total    int64       `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+)
+
+// Comment - комментарий к промокоду. Обсуждение двухуровневое: ответ всегда привязан
+// к комментарию верхнего уровня, ответ на ответ попадает в ту же ветку.
+type Comment struct {
+	ID               uint             `json:"id" gorm:"primaryKey"`
+	PromocodeID      uint             `json:"promocode_id" gorm:"index;not null"`
+	ParentID         *uint            `json:"parent_id,omitempty" gorm:"index"`
+	CreatorID        uint             `json:"creator_id" gorm:"not null"`
+	Content          string           `json:"content" gorm:"not null"`
+	ModerationStatus ModerationStatus `json:"moderation_status" gorm:"not null;default:pending"`
+	// Hidden - комментарий виден только автору и модераторам: он ждет премодерации
+	// или отклонен модератором
+	Hidden    bool       `json:"hidden"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ModerationHash - отпечаток комментария, который проверяет модератор
+func (c *Comment) ModerationHash() string {
+	return moderationHash(c.PromocodeID, c.Content)
+}
+
+type CreateCommentRequest struct {
+	Content  string `json:"content" binding:"required,max=2000"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content" binding:"required,max=2000"`
+}
+
+// CommentPage - параметры постраничного просмотра комментариев. Cursor берется
+// из next_cursor предыдущей страницы.
+type CommentPage struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// CommentQuery - выборка комментариев для репозитория
+type CommentQuery struct {
+	PromocodeID uint
+	// ParentID - ветка ответов; 0 - комментарии верхнего уровня
+	ParentID uint
+	// ViewerID видит свои скрытые комментарии; IncludeHidden - все скрытые комментарии
+	ViewerID      uint
+	IncludeHidden bool
+	AfterID       uint
+	Limit         int
+}
+
+type CommentList struct {
+	Comments   []Comment `json:"comments"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// CompanySettings - настройки компании, которые нужны сервису промокодов
+type CompanySettings struct {
+	CompanyID uint `json:"company_id" gorm:"primaryKey;autoIncrement:false"`
+	// PremoderateComments - новые и измененные комментарии скрыты до проверки модератором
+	PremoderateComments bool      `json:"premoderate_comments"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type UpdateCompanySettingsRequest struct {
+	PremoderateComments *bool `json:"premoderate_comments" binding:"required"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyRecord - ответ на запрос интеграции с заголовком Idempotency-Key. Повтор запроса с тем же
+// ключом от того же API-ключа получает сохраненный ответ, а не выполняется снова.
+// Пока StatusCode равен нулю, первый запрос еще выполняется; резерв, который долго не завершается,
+// брошен упавшим экземпляром сервиса и переходит к повтору. Записи хранятся ограниченное время.
+type IdempotencyRecord struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	APIKeyID       uint   `json:"api_key_id" gorm:"uniqueIndex:idx_idempotency_records_key;not null"`
+	IdempotencyKey string `json:"idempotency_key" gorm:"uniqueIndex:idx_idempotency_records_key;not null"`
+	// RequestHash - хеш метода, пути и тела запроса: тот же ключ с другим запросом - ошибка клиента
+	RequestHash string    `json:"request_hash" gorm:"not null"`
+	StatusCode  int       `json:"status_code" gorm:"not null;default:0"`
+	Response    []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+	// UpdatedAt - время резервирования или последнего перехвата резерва
+	UpdatedAt time.Time `json:"updated_at"`
+}
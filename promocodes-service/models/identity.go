@@ -0,0 +1,59 @@
+package models
+
+// CompanyRole - роль пользователя в команде компании, выдается user-service
+type CompanyRole string
+
+const (
+	CompanyRoleOwner   CompanyRole = "owner"
+	CompanyRoleManager CompanyRole = "manager"
+	CompanyRoleAnalyst CompanyRole = "analyst"
+)
+
+// Глобальные роли из user-service, которые учитывает сервис промокодов
+const (
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Identity - пользователь, проверенный шлюзом. Шлюз передает его заголовками
+// X-User-ID, X-User-Roles, X-User-Companies и X-User-Segments, удаляя одноименные заголовки клиента.
+type Identity struct {
+	UserID    uint
+	Roles     []string
+	Companies map[uint]CompanyRole
+	Segments  []string
+}
+
+func (i *Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsModerator - пользователь может модерировать контент
+func (i *Identity) IsModerator() bool {
+	return i.HasRole(RoleModerator) || i.HasRole(RoleAdmin)
+}
+
+func (i *Identity) CompanyRole(companyID uint) (CompanyRole, bool) {
+	role, ok := i.Companies[companyID]
+	return role, ok
+}
+
+// InAnySegment - пользователь входит хотя бы в один из сегментов; пустой список сегментов не ограничивает
+func (i *Identity) InAnySegment(segments []string) bool {
+	if len(segments) == 0 {
+		return true
+	}
+	for _, segment := range segments {
+		for _, own := range i.Segments {
+			if own == segment {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// ModerationStatus - результат проверки контента модератором
+type ModerationStatus string
+
+const (
+	ModerationPending  ModerationStatus = "pending"
+	ModerationApproved ModerationStatus = "approved"
+	ModerationRejected ModerationStatus = "rejected"
+	// ModerationSuperseded - автор изменил контент до решения, и заявку заменила новая
+	ModerationSuperseded ModerationStatus = "superseded"
+)
+
+func (s ModerationStatus) Valid() bool {
+	switch s {
+	case ModerationPending, ModerationApproved, ModerationRejected, ModerationSuperseded:
+		return true
+	}
+	return false
+}
+
+// ModerationEntity - вид контента в очереди модерации
+type ModerationEntity string
+
+const (
+	ModerationEntityPromocode ModerationEntity = "promocode"
+	ModerationEntityComment   ModerationEntity = "comment"
+)
+
+func (e ModerationEntity) Valid() bool {
+	switch e {
+	case ModerationEntityPromocode, ModerationEntityComment:
+		return true
+	}
+	return false
+}
+
+// ModerationAction - действие модератора, которое попадает в журнал
+type ModerationAction string
+
+const (
+	ModerationActionClaim   ModerationAction = "claim"
+	ModerationActionRelease ModerationAction = "release"
+	ModerationActionApprove ModerationAction = "approve"
+	ModerationActionReject  ModerationAction = "reject"
+)
+
+// ModerationItem - заявка на проверку одной версии контента. Изменение контента закрывает
+// ожидающую заявку и создает новую, даже если прежнюю уже взял модератор.
+type ModerationItem struct {
+	ID         uint             `json:"id" gorm:"primaryKey"`
+	EntityType ModerationEntity `json:"entity_type" gorm:"index:idx_moderation_items_entity;not null"`
+	EntityID   uint             `json:"entity_id" gorm:"index:idx_moderation_items_entity;not null"`
+	CompanyID  uint             `json:"company_id"`
+	AuthorID   uint             `json:"author_id" gorm:"index;not null"`
+	Status     ModerationStatus `json:"status" gorm:"index;not null"`
+	// ContentHash - отпечаток версии контента, на которую подана заявка; решение по заявке
+	// принимается, только пока контент совпадает с ней
+	ContentHash string `json:"-" gorm:"not null;default:''"`
+	// ClaimedBy - модератор, который взял заявку в работу
+	ClaimedBy   *uint      `json:"claimed_by,omitempty"`
+	ClaimedAt   *time.Time `json:"claimed_at,omitempty"`
+	ModeratorID *uint      `json:"moderator_id,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ModerationAudit - запись журнала действий модераторов; при удалении контента журнал сохраняется
+type ModerationAudit struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	ItemID      uint             `json:"item_id" gorm:"index;not null"`
+	EntityType  ModerationEntity `json:"entity_type" gorm:"not null"`
+	EntityID    uint             `json:"entity_id" gorm:"not null"`
+	ModeratorID uint             `json:"moderator_id" gorm:"index;not null"`
+	Action      ModerationAction `json:"action" gorm:"not null"`
+	Reason      string           `json:"reason,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+type RejectModerationRequest struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
+
+// ModerationFilter - параметры поиска заявок. AuthorID задает сервис, а не клиент.
+type ModerationFilter struct {
+	Status     ModerationStatus `form:"status"`
+	EntityType ModerationEntity `form:"entity_type"`
+	AuthorID   uint             `form:"-"`
+	Page       int              `form:"page" binding:"omitempty,min=1"`
+	PageSize   int              `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type ModerationItemList struct {
+	Items    []ModerationItem `json:"items"`
+	Total    int64            `json:"total"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
+}
+
+type ModerationAuditFilter struct {
+	ItemID      uint             `form:"item_id"`
+	ModeratorID uint             `form:"moderator_id"`
+	EntityType  ModerationEntity `form:"entity_type"`
+	EntityID    uint             `form:"entity_id"`
+	Page        int              `form:"page" binding:"omitempty,min=1"`
+	PageSize    int              `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type ModerationAuditList struct {
+	Records  []ModerationAudit `json:"records"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+}
+
+// moderationHash - отпечаток полей контента, которые проверяет модератор
+func moderationHash(fields ...interface{}) string {
+	hash := sha256.New()
+	for _, field := range fields {
+		fmt.Fprintf(hash, "%v\x00", field)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// formatTime - запись необязательного времени для moderationHash
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PromocodeType - вид выгоды, которую дает промокод
+type PromocodeType string
+
+const (
+	// PromocodeTypePercent - скидка в процентах
+	PromocodeTypePercent PromocodeType = "percent"
+	// PromocodeTypeFixed - скидка фиксированной суммой
+	PromocodeTypeFixed PromocodeType = "fixed"
+	// PromocodeTypeGift - подарок к покупке
+	PromocodeTypeGift PromocodeType = "gift"
+)
+
+func (t PromocodeType) Valid() bool {
+	switch t {
+	case PromocodeTypePercent, PromocodeTypeFixed, PromocodeTypeGift:
+		return true
+	}
+	return false
+}
+
+// Promocode принадлежит компании из user-service; сама компания здесь не хранится,
+// связь держится только по идентификатору
+type Promocode struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	CompanyID   uint          `json:"company_id" gorm:"index;not null"`
+	CreatorID   uint          `json:"creator_id" gorm:"not null"`
+	Code        string        `json:"code" gorm:"uniqueIndex;not null"`
+	Title       string        `json:"title" gorm:"not null"`
+	Description string        `json:"description"`
+	Type        PromocodeType `json:"type" gorm:"not null"`
+	// ModerationStatus - результат проверки последней версии промокода
+	ModerationStatus ModerationStatus `json:"moderation_status" gorm:"index;not null;default:pending"`
+	// Условия погашения: пустые границы срока действия и нулевые лимиты не ограничивают.
+	// Промокод действует с ValidFrom включительно до ValidUntil.
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	// MaxRedemptions - сколько раз промокод можно погасить всего, MaxRedemptionsPerUser - одному пользователю
+	MaxRedemptions        int `json:"max_redemptions" gorm:"not null;default:0"`
+	MaxRedemptionsPerUser int `json:"max_redemptions_per_user" gorm:"not null;default:0"`
+	// Segments - сегменты пользователей, которым доступен промокод; пустой список - доступен всем
+	Segments Segments `json:"segments" gorm:"type:text;not null;default:''"`
+	// RedemptionCount меняется только при погашении
+	RedemptionCount int       `json:"redemption_count" gorm:"not null;default:0"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ModerationHash - отпечаток полей промокода, которые проверяет модератор
+func (p *Promocode) ModerationHash() string {
+	return moderationHash(p.CompanyID, p.Code, p.Title, p.Description, p.Type, formatTime(p.ValidFrom), formatTime(p.ValidUntil),
+		p.MaxRedemptions, p.MaxRedemptionsPerUser, strings.Join(p.Segments, ","))
+}
+
+type CreatePromocodeRequest struct {
+	CompanyID   uint          `json:"company_id" binding:"required"`
+	Code        string        `json:"code" binding:"required,alphanum,min=3,max=32"`
+	Title       string        `json:"title" binding:"required,min=3,max=200"`
+	Description string        `json:"description" binding:"max=2000"`
+	Type        PromocodeType `json:"type" binding:"required"`
+
+	ValidFrom             *time.Time `json:"valid_from"`
+	ValidUntil            *time.Time `json:"valid_until"`
+	MaxRedemptions        int        `json:"max_redemptions" binding:"min=0"`
+	MaxRedemptionsPerUser int        `json:"max_redemptions_per_user" binding:"min=0"`
+	Segments              []string   `json:"segments" binding:"max=20"`
+}
+
+// UpdatePromocodeRequest - незаполненные поля не меняются. Код и компания после создания не меняются.
+// Пустой список segments снимает ограничение по сегментам.
+type UpdatePromocodeRequest struct {
+	Title       string        `json:"title" binding:"omitempty,min=3,max=200"`
+	Description string        `json:"description" binding:"max=2000"`
+	Type        PromocodeType `json:"type"`
+
+	ValidFrom             *time.Time `json:"valid_from"`
+	ValidUntil            *time.Time `json:"valid_until"`
+	MaxRedemptions        *int       `json:"max_redemptions" binding:"omitempty,min=0"`
+	MaxRedemptionsPerUser *int       `json:"max_redemptions_per_user" binding:"omitempty,min=0"`
+	Segments              []string   `json:"segments" binding:"omitempty,max=20"`
+}
+
+// PromocodeFilter - параметры поиска промокодов
+type PromocodeFilter struct {
+	CompanyID        uint             `form:"company_id"`
+	Type             PromocodeType    `form:"type"`
+	ModerationStatus ModerationStatus `form:"moderation_status"`
+	Page             int              `form:"page" binding:"omitempty,min=1"`
+	PageSize         int              `form:"page_size" binding:"omitempty,min=1,max=100"`
+	// Visibility задает сервис по личности пользователя, из запроса она не читается
+	Visibility *PromocodeVisibility `form:"-"`
+}
+
+// PromocodeVisibility ограничивает поиск одобренными промокодами и промокодами компаний,
+// в командах которых состоит пользователь
+type PromocodeVisibility struct {
+	CompanyIDs []uint
+}
+
+type PromocodeList struct {
+	Promocodes []Promocode `json:"promocodes"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+}
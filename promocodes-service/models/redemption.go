@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// Redemption - погашение промокода пользователем. Записи сохраняются и после удаления промокода,
+// поэтому код копируется в запись.
+type Redemption struct {
+	ID          uint `json:"id" gorm:"primaryKey"`
+	PromocodeID uint `json:"promocode_id" gorm:"index:idx_redemptions_promocode_user;not null"`
+	// UserID равен нулю, если промокод погашен кассой по API-ключу APIKeyID: покупатель там анонимен
+	UserID    uint  `json:"user_id" gorm:"index:idx_redemptions_promocode_user;index;not null"`
+	APIKeyID  *uint `json:"api_key_id,omitempty" gorm:"index"`
+	CompanyID uint  `json:"company_id" gorm:"not null"`
+	// CodeID - погашенный одноразовый код; уникальный индекс не дает погасить его дважды
+	CodeID     *uint     `json:"code_id,omitempty" gorm:"uniqueIndex"`
+	Code       string    `json:"code" gorm:"not null"`
+	RedeemedAt time.Time `json:"redeemed_at" gorm:"not null"`
+	// IdempotencyRecordID - запись идемпотентности запроса кассы. Сохраняется вместе с погашением,
+	// поэтому повтор запроса, ответ на который не успели сохранить, находит погашение, а не гасит код снова.
+	IdempotencyRecordID *uint `json:"-" gorm:"uniqueIndex"`
+}
+
+// RedeemRequest - запрос погашения. IdempotencyRecordID задает обработчик по Idempotency-Key, а не клиент.
+type RedeemRequest struct {
+	Code                string `json:"code" binding:"required,alphanum,max=32"`
+	IdempotencyRecordID uint   `json:"-"`
+}
+
+// MerchantRedemption - ответ кассе: погашение и промокод, чтобы применить выгоду
+type MerchantRedemption struct {
+	Redemption Redemption `json:"redemption"`
+	Promocode  Promocode  `json:"promocode"`
+}
+
+// RedemptionFilter - параметры поиска погашений. PromocodeID и UserID задает сервис, а не клиент.
+type RedemptionFilter struct {
+	PromocodeID uint `form:"-"`
+	UserID      uint `form:"-"`
+	Page        int  `form:"page" binding:"omitempty,min=1"`
+	PageSize    int  `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type RedemptionList struct {
+	Redemptions []Redemption `json:"redemptions"`
+	Total       int64        `json:"total"`
+	Page        int          `json:"page"`
+	PageSize    int          `json:"page_size"`
+}
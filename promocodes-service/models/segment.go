@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Segments - сегменты пользователей из user-service (например, vip или employees).
+// В базе хранятся одной строкой через запятую.
+type Segments []string
+
+var segmentPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// ValidSegment - сегмент из строчных латинских букв, цифр, "_" и "-" длиной до 32 символов
+func ValidSegment(segment string) bool {
+	return segmentPattern.MatchString(segment)
+}
+
+// NormalizeSegments убирает повторы и сортирует сегменты
+func NormalizeSegments(segments []string) Segments {
+	seen := make(map[string]bool, len(segments))
+	normalized := Segments{}
+	for _, segment := range segments {
+		if !seen[segment] {
+			seen[segment] = true
+			normalized = append(normalized, segment)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func (s Segments) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+func (s *Segments) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("неподдерживаемый тип сегментов: %T", value)
+	}
+	*s = Segments{}
+	if raw != "" {
+		*s = strings.Split(raw, ",")
+	}
+	return nil
+}
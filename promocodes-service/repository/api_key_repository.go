@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/tracing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	ctx, span := tracing.Start(ctx, "APIKeyRepository.CreateAPIKey")
+	defer span.End()
+	defer metrics.ObserveDBQuery("APIKeyRepository.CreateAPIKey", time.Now())
+
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyRepository.GetAPIKeyByHash")
+	defer span.End()
+	defer metrics.ObserveDBQuery("APIKeyRepository.GetAPIKeyByHash", time.Now())
+
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context, companyID uint) ([]models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyRepository.ListAPIKeys")
+	defer span.End()
+	defer metrics.ObserveDBQuery("APIKeyRepository.ListAPIKeys", time.Now())
+
+	var keys []models.APIKey
+	err := r.db.WithContext(ctx).Where("company_id = ?", companyID).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, companyID uint, id uint, revokedAt time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyRepository.RevokeAPIKey")
+	defer span.End()
+	defer metrics.ObserveDBQuery("APIKeyRepository.RevokeAPIKey", time.Now())
+
+	result := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND company_id = ? AND revoked_at IS NULL", id, companyID).
+		Update("revoked_at", revokedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *APIKeyRepository) RevokeCompanyAPIKeys(ctx context.Context, companyID uint, revokedAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "APIKeyRepository.RevokeCompanyAPIKeys")
+	defer span.End()
+	defer metrics.ObserveDBQuery("APIKeyRepository.RevokeCompanyAPIKeys", time.Now())
+
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("company_id = ? AND revoked_at IS NULL", companyID).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "APIKeyRepository.TouchAPIKey")
+	defer span.End()
+	defer metrics.ObserveDBQuery("APIKeyRepository.TouchAPIKey", time.Now())
+
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+var _ APIKeyRepositoryInterface = (*APIKeyRepository)(nil)
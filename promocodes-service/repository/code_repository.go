@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/tracing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CodeRepository struct {
+	db *gorm.DB
+}
+
+func NewCodeRepository(db *gorm.DB) *CodeRepository {
+	return &CodeRepository{db: db}
+}
+
+func (r *CodeRepository) CreateBatch(ctx context.Context, batch *models.CodeBatch) error {
+	ctx, span := tracing.Start(ctx, "CodeRepository.CreateBatch")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CodeRepository.CreateBatch", time.Now())
+
+	return r.db.WithContext(ctx).Create(batch).Error
+}
+
+func (r *CodeRepository) GetBatch(ctx context.Context, id uint) (*models.CodeBatch, error) {
+	ctx, span := tracing.Start(ctx, "CodeRepository.GetBatch")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CodeRepository.GetBatch", time.Now())
+
+	var batch models.CodeBatch
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *CodeRepository) ListBatches(ctx context.Context, filter models.CodeBatchFilter) ([]models.CodeBatch, int64, error) {
+	ctx, span := tracing.Start(ctx, "CodeRepository.ListBatches")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CodeRepository.ListBatches", time.Now())
+
+	query := r.db.WithContext(ctx).Model(&models.CodeBatch{}).Where("promocode_id = ?", filter.PromocodeID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var batches []models.CodeBatch
+	err := query.Order("id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&batches).Error
+	return batches, total, err
+}
+
+func (r *CodeRepository) ListUnfinishedBatches(ctx context.Context) ([]models.CodeBatch, error) {
+	ctx, span := tracing.Start(ctx, "CodeRepository.ListUnfinishedBatches")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CodeRepository.ListUnfinishedBatches", time.Now())
+
+	var batches []models.CodeBatch
+	err := r.db.WithContext(ctx).
+		Where("status IN ?", []models.CodeBatchStatus{models.CodeBatchPending, models.CodeBatchRunning}).
+		Order("id").
+		Find(&batches).Error
+	return batches, err
+}
+
+func (r *CodeRepository) UpdateBatchStatus(ctx context.Context, batch *models.CodeBatch) error {
+	ctx, span := tracing.Start(ctx, "CodeRepository.UpdateBatchStatus")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CodeRepository.UpdateBatchStatus", time.Now())
+
+	return r.db.WithContext(ctx).Model(batch).Select("status", "error", "finished_at").Updates(batch).Error
+}
+
+func (r *CodeRepository) InsertCodes(ctx context.Context, batchID uint, codes []models.UniqueCode) (*models.CodeBatch, error) {
+	ctx, span := tracing.Start(ctx, "CodeRepository.InsertCodes")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CodeRepository.InsertCodes", time.Now())
+
+	var batch models.CodeBatch
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокировка партии не дает двум экземплярам сервиса сгенерировать больше кодов, чем заказано
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", batchID).First(&batch).Error; err != nil {
+			return err
+		}
+
+		if remaining := batch.Quantity - batch.Generated; len(codes) > remaining {
+			codes = codes[:remaining]
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+
+		// Коды занимаются в issued_codes: занятые промокодами и другими партиями остаются за ними
+		issued := make([]models.IssuedCode, len(codes))
+		values := make([]string, len(codes))
+		for i, code := range codes {
+			issued[i] = models.IssuedCode{Code: code.Code, PromocodeID: batch.PromocodeID, BatchID: batch.ID}
+			values[i] = code.Code
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&issued).Error; err != nil {
+			return err
+		}
+		var claimed []string
+		if err := tx.Model(&models.IssuedCode{}).Where("code IN ? AND batch_id = ?", values, batch.ID).Pluck("code", &claimed).Error; err != nil {
+			return err
+		}
+		owned := make(map[string]bool, len(claimed))
+		for _, code := range claimed {
+			owned[code] = true
+		}
+
+		fresh := make([]models.UniqueCode, 0, len(codes))
+		for _, code := range codes {
+			if owned[code.Code] {
+				fresh = append(fresh, code)
+			}
+		}
+		if len(fresh) == 0 {
+			return nil
+		}
+
+		// Повторы внутри партии отсекает уникальный индекс unique_codes
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fresh)
+		if result.Error != nil {
+			return result.Error
+		}
+		batch.Generated += int(result.RowsAffected)
+		return tx.Model(&batch).UpdateColumn("generated", batch.Generated).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *CodeRepository) ListCodes(ctx context.Context, batchID uint, afterID uint, limit int) ([]models.UniqueCode, error) {
+	ctx, span := tracing.Start(ctx, "CodeRepository.ListCodes")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CodeRepository.ListCodes", time.Now())
+
+	var codes []models.UniqueCode
+	err := r.db.WithContext(ctx).
+		Where("batch_id = ? AND id > ?", batchID, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&codes).Error
+	return codes, err
+}
+
+func (r *CodeRepository) GetUniqueCode(ctx context.Context, code string) (*models.UniqueCode, error) {
+	ctx, span := tracing.Start(ctx, "CodeRepository.GetUniqueCode")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CodeRepository.GetUniqueCode", time.Now())
+
+	var uniqueCode models.UniqueCode
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&uniqueCode).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &uniqueCode, nil
+}
+
+var _ CodeRepositoryInterface = (*CodeRepository)(nil)
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/tracing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+func (r *CommentRepository) CreateComment(ctx context.Context, comment *models.Comment) error {
+	ctx, span := tracing.Start(ctx, "CommentRepository.CreateComment")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CommentRepository.CreateComment", time.Now())
+
+	return r.db.WithContext(ctx).Create(comment).Error
+}
+
+func (r *CommentRepository) GetComment(ctx context.Context, id uint) (*models.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentRepository.GetComment")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CommentRepository.GetComment", time.Now())
+
+	var comment models.Comment
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&comment)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &comment, nil
+}
+
+func (r *CommentRepository) ListComments(ctx context.Context, query models.CommentQuery) ([]models.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentRepository.ListComments")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CommentRepository.ListComments", time.Now())
+
+	db := r.db.WithContext(ctx).Where("id > ?", query.AfterID)
+	if query.PromocodeID != 0 {
+		db = db.Where("promocode_id = ?", query.PromocodeID)
+	}
+	if query.ParentID != 0 {
+		db = db.Where("parent_id = ?", query.ParentID)
+	} else {
+		db = db.Where("parent_id IS NULL")
+	}
+	if !query.IncludeHidden {
+		db = db.Where("hidden = ? OR creator_id = ?", false, query.ViewerID)
+	}
+
+	var comments []models.Comment
+	err := db.Order("id").Limit(query.Limit).Find(&comments).Error
+	return comments, err
+}
+
+func (r *CommentRepository) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	ctx, span := tracing.Start(ctx, "CommentRepository.UpdateComment")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CommentRepository.UpdateComment", time.Now())
+
+	return r.db.WithContext(ctx).Save(comment).Error
+}
+
+func (r *CommentRepository) DeleteComment(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "CommentRepository.DeleteComment")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CommentRepository.DeleteComment", time.Now())
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		thread := tx.Model(&models.Comment{}).Select("id").Where("id = ? OR parent_id = ?", id, id)
+		err := tx.Where("entity_type = ? AND entity_id IN (?)", models.ModerationEntityComment, thread).
+			Delete(&models.ModerationItem{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ? OR parent_id = ?", id, id).Delete(&models.Comment{}).Error
+	})
+}
+
+var _ CommentRepositoryInterface = (*CommentRepository)(nil)
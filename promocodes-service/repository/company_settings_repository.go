@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/tracing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CompanySettingsRepository struct {
+	db *gorm.DB
+}
+
+func NewCompanySettingsRepository(db *gorm.DB) *CompanySettingsRepository {
+	return &CompanySettingsRepository{db: db}
+}
+
+func (r *CompanySettingsRepository) GetCompanySettings(ctx context.Context, companyID uint) (*models.CompanySettings, error) {
+	ctx, span := tracing.Start(ctx, "CompanySettingsRepository.GetCompanySettings")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanySettingsRepository.GetCompanySettings", time.Now())
+
+	var settings models.CompanySettings
+	result := r.db.WithContext(ctx).Where("company_id = ?", companyID).First(&settings)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &settings, nil
+}
+
+func (r *CompanySettingsRepository) SaveCompanySettings(ctx context.Context, settings *models.CompanySettings) error {
+	ctx, span := tracing.Start(ctx, "CompanySettingsRepository.SaveCompanySettings")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanySettingsRepository.SaveCompanySettings", time.Now())
+
+	return r.db.WithContext(ctx).Save(settings).Error
+}
+
+func (r *CompanySettingsRepository) DeleteCompanySettings(ctx context.Context, companyID uint) error {
+	ctx, span := tracing.Start(ctx, "CompanySettingsRepository.DeleteCompanySettings")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanySettingsRepository.DeleteCompanySettings", time.Now())
+
+	return r.db.WithContext(ctx).Where("company_id = ?", companyID).Delete(&models.CompanySettings{}).Error
+}
+
+var _ CompanySettingsRepositoryInterface = (*CompanySettingsRepository)(nil)
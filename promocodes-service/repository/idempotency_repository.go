@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/tracing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) CreateRecord(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.CreateRecord")
+	defer span.End()
+	defer metrics.ObserveDBQuery("IdempotencyRepository.CreateRecord", time.Now())
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *IdempotencyRepository) GetRecord(ctx context.Context, apiKeyID uint, key string) (*models.IdempotencyRecord, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.GetRecord")
+	defer span.End()
+	defer metrics.ObserveDBQuery("IdempotencyRepository.GetRecord", time.Now())
+
+	var record models.IdempotencyRecord
+	err := r.db.WithContext(ctx).Where("api_key_id = ? AND idempotency_key = ?", apiKeyID, key).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *IdempotencyRepository) CompleteRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.CompleteRecord")
+	defer span.End()
+	defer metrics.ObserveDBQuery("IdempotencyRepository.CompleteRecord", time.Now())
+
+	return r.db.WithContext(ctx).Model(record).Select("status_code", "response").Updates(record).Error
+}
+
+func (r *IdempotencyRepository) TakeOverRecord(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.TakeOverRecord")
+	defer span.End()
+	defer metrics.ObserveDBQuery("IdempotencyRepository.TakeOverRecord", time.Now())
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&models.IdempotencyRecord{}).
+		Where("id = ? AND status_code = 0 AND updated_at < ?", record.ID, staleBefore).
+		UpdateColumn("updated_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	record.UpdatedAt = now
+	return true, nil
+}
+
+func (r *IdempotencyRepository) DeleteRecordsBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.DeleteRecordsBefore")
+	defer span.End()
+	defer metrics.ObserveDBQuery("IdempotencyRepository.DeleteRecordsBefore", time.Now())
+
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&models.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *IdempotencyRepository) DeleteRecord(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyRepository.DeleteRecord")
+	defer span.End()
+	defer metrics.ObserveDBQuery("IdempotencyRepository.DeleteRecord", time.Now())
+
+	return r.db.WithContext(ctx).Delete(&models.IdempotencyRecord{}, id).Error
+}
+
+var _ IdempotencyRepositoryInterface = (*IdempotencyRepository)(nil)
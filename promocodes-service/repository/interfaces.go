@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"promocodes-service/models"
+	"time"
+)
+
+type PromocodeRepositoryInterface interface {
+	// CreatePromocode возвращает false, если код уже занят другим промокодом или одноразовым кодом.
+	// Уникальность проверяет база в той же транзакции, поэтому параллельные запросы не создадут дубликат.
+	CreatePromocode(ctx context.Context, promocode *models.Promocode) (bool, error)
+	GetPromocodeByID(ctx context.Context, id uint) (*models.Promocode, error)
+	GetPromocodeByCode(ctx context.Context, code string) (*models.Promocode, error)
+	// ListPromocodes возвращает страницу промокодов по фильтру и общее число подходящих
+	ListPromocodes(ctx context.Context, filter models.PromocodeFilter) ([]models.Promocode, int64, error)
+	UpdatePromocode(ctx context.Context, promocode *models.Promocode) error
+	// DeletePromocode удаляет промокод вместе с комментариями к нему и их заявками на модерацию,
+	// одноразовыми кодами и партиями
+	DeletePromocode(ctx context.Context, id uint) error
+	// DeleteCompanyPromocodes так же удаляет все промокоды компании
+	DeleteCompanyPromocodes(ctx context.Context, companyID uint) error
+}
+
+type CommentRepositoryInterface interface {
+	CreateComment(ctx context.Context, comment *models.Comment) error
+	GetComment(ctx context.Context, id uint) (*models.Comment, error)
+	// ListComments возвращает до query.Limit комментариев с ID больше query.AfterID по возрастанию ID
+	ListComments(ctx context.Context, query models.CommentQuery) ([]models.Comment, error)
+	UpdateComment(ctx context.Context, comment *models.Comment) error
+	// DeleteComment удаляет комментарий вместе с ответами на него и их заявками на модерацию
+	DeleteComment(ctx context.Context, id uint) error
+}
+
+type CompanySettingsRepositoryInterface interface {
+	// GetCompanySettings возвращает nil, если компания еще не меняла настройки
+	GetCompanySettings(ctx context.Context, companyID uint) (*models.CompanySettings, error)
+	SaveCompanySettings(ctx context.Context, settings *models.CompanySettings) error
+	DeleteCompanySettings(ctx context.Context, companyID uint) error
+}
+
+type ModerationRepositoryInterface interface {
+	// SubmitItem ставит текущую версию контента в очередь и закрывает ожидающую заявку на прежнюю версию
+	SubmitItem(ctx context.Context, item *models.ModerationItem) error
+	GetItem(ctx context.Context, id uint) (*models.ModerationItem, error)
+	ListItems(ctx context.Context, filter models.ModerationFilter) ([]models.ModerationItem, int64, error)
+	// ClaimItem, ReleaseItem и DecideItem меняют заявку, только если она еще ждет решения и не занята
+	// другим модератором (захват старше staleBefore не учитывается), и в той же транзакции пишут
+	// audit в журнал. Время действия берется из audit.CreatedAt. false - условие не выполнено.
+	ClaimItem(ctx context.Context, audit *models.ModerationAudit, staleBefore time.Time) (bool, error)
+	ReleaseItem(ctx context.Context, audit *models.ModerationAudit) (bool, error)
+	// DecideItem также переносит решение на сам контент. Если контент изменился после подачи заявки,
+	// заявка закрывается со статусом superseded и решение не принимается.
+	DecideItem(ctx context.Context, audit *models.ModerationAudit, status models.ModerationStatus, staleBefore time.Time) (bool, error)
+	ListAudit(ctx context.Context, filter models.ModerationAuditFilter) ([]models.ModerationAudit, int64, error)
+}
+
+type RedemptionRepositoryInterface interface {
+	// Redeem блокирует промокод redemption.PromocodeID и одноразовый код redemption.CodeID, если он задан,
+	// до конца транзакции и вызывает check с их текущим состоянием (промокод nil, если он или код удалены)
+	// и числом погашений промокода этим пользователем. Если check не вернул ошибку, сохраняет redemption,
+	// отмечает код погашенным и увеличивает счетчик погашений; ошибка check возвращается как есть.
+	Redeem(ctx context.Context, redemption *models.Redemption, check func(promocode *models.Promocode, code *models.UniqueCode, userRedemptions int64) error) error
+	// GetRedemptionByIdempotencyRecord возвращает nil, если по записи идемпотентности погашения не было
+	GetRedemptionByIdempotencyRecord(ctx context.Context, recordID uint) (*models.Redemption, error)
+	// ListRedemptions возвращает погашения от новых к старым
+	ListRedemptions(ctx context.Context, filter models.RedemptionFilter) ([]models.Redemption, int64, error)
+}
+
+type CodeRepositoryInterface interface {
+	CreateBatch(ctx context.Context, batch *models.CodeBatch) error
+	GetBatch(ctx context.Context, id uint) (*models.CodeBatch, error)
+	ListBatches(ctx context.Context, filter models.CodeBatchFilter) ([]models.CodeBatch, int64, error)
+	// ListUnfinishedBatches возвращает ожидающие и прерванные партии от старых к новым
+	ListUnfinishedBatches(ctx context.Context) ([]models.CodeBatch, error)
+	// UpdateBatchStatus сохраняет статус, ошибку и время завершения партии, не трогая счетчик кодов
+	UpdateBatchStatus(ctx context.Context, batch *models.CodeBatch) error
+	// InsertCodes блокирует партию batchID и сохраняет из codes столько, сколько ей не хватает. Коды,
+	// уже занятые другими кодами или общими кодами промокодов, пропускаются. Возвращает партию
+	// с обновленным счетчиком или nil, если ее удалили вместе с промокодом.
+	InsertCodes(ctx context.Context, batchID uint, codes []models.UniqueCode) (*models.CodeBatch, error)
+	// ListCodes возвращает до limit кодов партии с ID больше afterID по возрастанию ID
+	ListCodes(ctx context.Context, batchID uint, afterID uint, limit int) ([]models.UniqueCode, error)
+	// GetUniqueCode возвращает nil, если такого одноразового кода нет
+	GetUniqueCode(ctx context.Context, code string) (*models.UniqueCode, error)
+}
+
+type APIKeyRepositoryInterface interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	// GetAPIKeyByHash возвращает nil, если ключа нет; отозванные ключи тоже возвращаются
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, companyID uint) ([]models.APIKey, error)
+	// RevokeAPIKey отзывает действующий ключ компании; false - такого ключа нет или он уже отозван
+	RevokeAPIKey(ctx context.Context, companyID uint, id uint, revokedAt time.Time) (bool, error)
+	// RevokeCompanyAPIKeys отзывает все действующие ключи компании
+	RevokeCompanyAPIKeys(ctx context.Context, companyID uint, revokedAt time.Time) error
+	TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+type IdempotencyRepositoryInterface interface {
+	// CreateRecord сохраняет запись, только если у API-ключа еще нет записи с таким ключом идемпотентности;
+	// false - запись уже есть. Уникальный индекс пропускает только один из параллельных запросов.
+	CreateRecord(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	// GetRecord возвращает nil, если записи нет
+	GetRecord(ctx context.Context, apiKeyID uint, key string) (*models.IdempotencyRecord, error)
+	// CompleteRecord сохраняет код и тело ответа
+	CompleteRecord(ctx context.Context, record *models.IdempotencyRecord) error
+	// TakeOverRecord передает незавершенный резерв, не обновлявшийся с staleBefore, новому запросу;
+	// false - запрос завершился или резерв уже перехватил параллельный повтор
+	TakeOverRecord(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error)
+	// DeleteRecordsBefore удаляет записи, созданные раньше before, и возвращает их число
+	DeleteRecordsBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteRecord(ctx context.Context, id uint) error
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/tracing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+func (r *ModerationRepository) SubmitItem(ctx context.Context, item *models.ModerationItem) error {
+	ctx, span := tracing.Start(ctx, "ModerationRepository.SubmitItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("ModerationRepository.SubmitItem", time.Now())
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		hash, err := lockContent(tx, item.EntityType, item.EntityID)
+		if err != nil {
+			return err
+		}
+		// Решение по прежней заявке относилось бы к версии, которой уже нет, поэтому она закрывается,
+		// даже если ее взял модератор
+		err = tx.Model(&models.ModerationItem{}).
+			Where("entity_type = ? AND entity_id = ? AND status = ?", item.EntityType, item.EntityID, models.ModerationPending).
+			Update("status", models.ModerationSuperseded).Error
+		if err != nil {
+			return err
+		}
+		item.Status = models.ModerationPending
+		item.ContentHash = hash
+		return tx.Create(item).Error
+	})
+}
+
+func (r *ModerationRepository) GetItem(ctx context.Context, id uint) (*models.ModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "ModerationRepository.GetItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("ModerationRepository.GetItem", time.Now())
+
+	var item models.ModerationItem
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&item)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &item, nil
+}
+
+func (r *ModerationRepository) ListItems(ctx context.Context, filter models.ModerationFilter) ([]models.ModerationItem, int64, error) {
+	ctx, span := tracing.Start(ctx, "ModerationRepository.ListItems")
+	defer span.End()
+	defer metrics.ObserveDBQuery("ModerationRepository.ListItems", time.Now())
+
+	query := r.db.WithContext(ctx).Model(&models.ModerationItem{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.AuthorID != 0 {
+		query = query.Where("author_id = ?", filter.AuthorID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []models.ModerationItem
+	err := query.Order("id").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&items).Error
+	return items, total, err
+}
+
+func (r *ModerationRepository) ClaimItem(ctx context.Context, audit *models.ModerationAudit, staleBefore time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ModerationRepository.ClaimItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("ModerationRepository.ClaimItem", time.Now())
+
+	claimedAt := audit.CreatedAt
+	return r.withAudit(ctx, audit, func(tx *gorm.DB) *gorm.DB {
+		return available(tx, audit, staleBefore).
+			Updates(map[string]interface{}{"claimed_by": audit.ModeratorID, "claimed_at": &claimedAt})
+	})
+}
+
+func (r *ModerationRepository) ReleaseItem(ctx context.Context, audit *models.ModerationAudit) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ModerationRepository.ReleaseItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("ModerationRepository.ReleaseItem", time.Now())
+
+	return r.withAudit(ctx, audit, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.ModerationItem{}).
+			Where("id = ? AND status = ? AND claimed_by = ?", audit.ItemID, models.ModerationPending, audit.ModeratorID).
+			Updates(map[string]interface{}{"claimed_by": nil, "claimed_at": nil})
+	})
+}
+
+func (r *ModerationRepository) DecideItem(ctx context.Context, audit *models.ModerationAudit, status models.ModerationStatus, staleBefore time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ModerationRepository.DecideItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("ModerationRepository.DecideItem", time.Now())
+
+	decidedAt := audit.CreatedAt
+	return r.withAudit(ctx, audit, func(tx *gorm.DB) *gorm.DB {
+		hash, err := lockContent(tx, audit.EntityType, audit.EntityID)
+		if err != nil {
+			tx.AddError(err)
+			return tx
+		}
+		result := available(tx, audit, staleBefore).Where("content_hash = ?", hash).Updates(map[string]interface{}{
+			"status":       status,
+			"moderator_id": audit.ModeratorID,
+			"reason":       audit.Reason,
+			"decided_at":   &decidedAt,
+		})
+		if result.Error != nil {
+			return result
+		}
+		if result.RowsAffected == 0 {
+			// Контент изменили, а новая заявка еще не подана: решение по старой версии не принимается
+			if err := tx.Model(&models.ModerationItem{}).
+				Where("id = ? AND status = ? AND content_hash <> ?", audit.ItemID, models.ModerationPending, hash).
+				Update("status", models.ModerationSuperseded).Error; err != nil {
+				result.Error = err
+			}
+			return result
+		}
+
+		switch audit.EntityType {
+		case models.ModerationEntityPromocode:
+			err = tx.Model(&models.Promocode{}).Where("id = ?", audit.EntityID).
+				Update("moderation_status", status).Error
+		case models.ModerationEntityComment:
+			err = tx.Model(&models.Comment{}).Where("id = ?", audit.EntityID).
+				Updates(map[string]interface{}{"moderation_status": status, "hidden": status == models.ModerationRejected}).Error
+		}
+		if err != nil {
+			result.Error = err
+		}
+		return result
+	})
+}
+
+func (r *ModerationRepository) ListAudit(ctx context.Context, filter models.ModerationAuditFilter) ([]models.ModerationAudit, int64, error) {
+	ctx, span := tracing.Start(ctx, "ModerationRepository.ListAudit")
+	defer span.End()
+	defer metrics.ObserveDBQuery("ModerationRepository.ListAudit", time.Now())
+
+	query := r.db.WithContext(ctx).Model(&models.ModerationAudit{})
+	if filter.ItemID != 0 {
+		query = query.Where("item_id = ?", filter.ItemID)
+	}
+	if filter.ModeratorID != 0 {
+		query = query.Where("moderator_id = ?", filter.ModeratorID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []models.ModerationAudit
+	err := query.Order("id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&records).Error
+	return records, total, err
+}
+
+// withAudit выполняет update и при успехе пишет audit в той же транзакции
+func (r *ModerationRepository) withAudit(ctx context.Context, audit *models.ModerationAudit, update func(tx *gorm.DB) *gorm.DB) (bool, error) {
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := update(tx)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		changed = true
+		return tx.Create(audit).Error
+	})
+	return changed && err == nil, err
+}
+
+// available выбирает заявку audit.ItemID, если она ждет решения и не занята другим модератором
+func available(tx *gorm.DB, audit *models.ModerationAudit, staleBefore time.Time) *gorm.DB {
+	return tx.Model(&models.ModerationItem{}).
+		Where("id = ? AND status = ?", audit.ItemID, models.ModerationPending).
+		Where("claimed_by IS NULL OR claimed_by = ? OR claimed_at < ?", audit.ModeratorID, staleBefore)
+}
+
+// lockContent блокирует контент до конца транзакции и возвращает отпечаток его текущей версии.
+// Удаленный контент дает пустой отпечаток.
+func lockContent(tx *gorm.DB, entityType models.ModerationEntity, entityID uint) (string, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", entityID)
+	var content interface{ ModerationHash() string }
+	switch entityType {
+	case models.ModerationEntityPromocode:
+		content = &models.Promocode{}
+	case models.ModerationEntityComment:
+		content = &models.Comment{}
+	default:
+		return "", fmt.Errorf("неизвестный вид контента: %s", entityType)
+	}
+	if err := query.First(content).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return content.ModerationHash(), nil
+}
+
+var _ ModerationRepositoryInterface = (*ModerationRepository)(nil)
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/tracing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errCodeTaken откатывает транзакцию, в которой код оказался занят
+var errCodeTaken = errors.New("код уже занят")
+
+type PromocodeRepository struct {
+	db *gorm.DB
+}
+
+func NewPromocodeRepository(db *gorm.DB) *PromocodeRepository {
+	return &PromocodeRepository{db: db}
+}
+
+func (r *PromocodeRepository) CreatePromocode(ctx context.Context, promocode *models.Promocode) (bool, error) {
+	ctx, span := tracing.Start(ctx, "PromocodeRepository.CreatePromocode")
+	defer span.End()
+	defer metrics.ObserveDBQuery("PromocodeRepository.CreatePromocode", time.Now())
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).Create(promocode)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCodeTaken
+		}
+		// Параллельная транзакция с тем же кодом ждет здесь фиксации этой и ничего не вставляет
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.IssuedCode{Code: promocode.Code, PromocodeID: promocode.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errCodeTaken
+		}
+		return nil
+	})
+	if errors.Is(err, errCodeTaken) {
+		promocode.ID = 0
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *PromocodeRepository) GetPromocodeByID(ctx context.Context, id uint) (*models.Promocode, error) {
+	ctx, span := tracing.Start(ctx, "PromocodeRepository.GetPromocodeByID")
+	defer span.End()
+	defer metrics.ObserveDBQuery("PromocodeRepository.GetPromocodeByID", time.Now())
+
+	return r.first(r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+func (r *PromocodeRepository) GetPromocodeByCode(ctx context.Context, code string) (*models.Promocode, error) {
+	ctx, span := tracing.Start(ctx, "PromocodeRepository.GetPromocodeByCode")
+	defer span.End()
+	defer metrics.ObserveDBQuery("PromocodeRepository.GetPromocodeByCode", time.Now())
+
+	return r.first(r.db.WithContext(ctx).Where("code = ?", code))
+}
+
+func (r *PromocodeRepository) ListPromocodes(ctx context.Context, filter models.PromocodeFilter) ([]models.Promocode, int64, error) {
+	ctx, span := tracing.Start(ctx, "PromocodeRepository.ListPromocodes")
+	defer span.End()
+	defer metrics.ObserveDBQuery("PromocodeRepository.ListPromocodes", time.Now())
+
+	query := r.db.WithContext(ctx).Model(&models.Promocode{})
+	if filter.CompanyID != 0 {
+		query = query.Where("company_id = ?", filter.CompanyID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.ModerationStatus != "" {
+		query = query.Where("moderation_status = ?", filter.ModerationStatus)
+	}
+	if filter.Visibility != nil {
+		if len(filter.Visibility.CompanyIDs) > 0 {
+			query = query.Where("(moderation_status = ? OR company_id IN ?)", models.ModerationApproved, filter.Visibility.CompanyIDs)
+		} else {
+			query = query.Where("moderation_status = ?", models.ModerationApproved)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var promocodes []models.Promocode
+	err := query.Order("id").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&promocodes).Error
+	return promocodes, total, err
+}
+
+func (r *PromocodeRepository) UpdatePromocode(ctx context.Context, promocode *models.Promocode) error {
+	ctx, span := tracing.Start(ctx, "PromocodeRepository.UpdatePromocode")
+	defer span.End()
+	defer metrics.ObserveDBQuery("PromocodeRepository.UpdatePromocode", time.Now())
+
+	// Счетчик погашений меняет только RedemptionRepository.Redeem, иначе параллельное погашение потерялось бы
+	return r.db.WithContext(ctx).Omit("redemption_count").Save(promocode).Error
+}
+
+func (r *PromocodeRepository) DeletePromocode(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "PromocodeRepository.DeletePromocode")
+	defer span.End()
+	defer metrics.ObserveDBQuery("PromocodeRepository.DeletePromocode", time.Now())
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deletePromocodes(tx, []uint{id})
+	})
+}
+
+func (r *PromocodeRepository) DeleteCompanyPromocodes(ctx context.Context, companyID uint) error {
+	ctx, span := tracing.Start(ctx, "PromocodeRepository.DeleteCompanyPromocodes")
+	defer span.End()
+	defer metrics.ObserveDBQuery("PromocodeRepository.DeleteCompanyPromocodes", time.Now())
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Model(&models.Promocode{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("company_id = ?", companyID).Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		return deletePromocodes(tx, ids)
+	})
+}
+
+// deletePromocodes удаляет промокоды ids вместе с зависимыми записями внутри транзакции tx
+func deletePromocodes(tx *gorm.DB, ids []uint) error {
+	comments := tx.Model(&models.Comment{}).Select("id").Where("promocode_id IN ?", ids)
+	err := tx.Where("(entity_type = ? AND entity_id IN ?) OR (entity_type = ? AND entity_id IN (?))",
+		models.ModerationEntityPromocode, ids, models.ModerationEntityComment, comments).
+		Delete(&models.ModerationItem{}).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Where("promocode_id IN ?", ids).Delete(&models.Comment{}).Error; err != nil {
+		return err
+	}
+	// Погашения остаются в истории, а неиспользованные коды и партии больше не нужны
+	if err := tx.Where("promocode_id IN ?", ids).Delete(&models.UniqueCode{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("promocode_id IN ?", ids).Delete(&models.CodeBatch{}).Error; err != nil {
+		return err
+	}
+	// Коды удаленного промокода освобождаются
+	if err := tx.Where("promocode_id IN ?", ids).Delete(&models.IssuedCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Promocode{}, ids).Error
+}
+
+func (r *PromocodeRepository) first(query *gorm.DB) (*models.Promocode, error) {
+	var promocode models.Promocode
+	result := query.First(&promocode)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &promocode, nil
+}
+
+var _ PromocodeRepositoryInterface = (*PromocodeRepository)(nil)
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/tracing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RedemptionRepository struct {
+	db *gorm.DB
+}
+
+func NewRedemptionRepository(db *gorm.DB) *RedemptionRepository {
+	return &RedemptionRepository{db: db}
+}
+
+func (r *RedemptionRepository) Redeem(ctx context.Context, redemption *models.Redemption, check func(promocode *models.Promocode, code *models.UniqueCode, userRedemptions int64) error) error {
+	ctx, span := tracing.Start(ctx, "RedemptionRepository.Redeem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("RedemptionRepository.Redeem", time.Now())
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокировка строки промокода выстраивает параллельные погашения в очередь,
+		// поэтому лимиты проверяются по данным, которые никто не меняет до конца транзакции
+		var promocode models.Promocode
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", redemption.PromocodeID).First(&promocode).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return check(nil, nil, 0)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Одноразовый код блокируется после промокода, в том же порядке, что и при любом погашении
+		var code *models.UniqueCode
+		if redemption.CodeID != nil {
+			code = &models.UniqueCode{}
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", *redemption.CodeID).First(code).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return check(nil, nil, 0)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		var userRedemptions int64
+		err = tx.Model(&models.Redemption{}).
+			Where("promocode_id = ? AND user_id = ?", redemption.PromocodeID, redemption.UserID).
+			Count(&userRedemptions).Error
+		if err != nil {
+			return err
+		}
+		if err := check(&promocode, code, userRedemptions); err != nil {
+			return err
+		}
+
+		if err := tx.Create(redemption).Error; err != nil {
+			return err
+		}
+		if code != nil {
+			err := tx.Model(code).Updates(map[string]interface{}{
+				"redeemed_by": redemption.UserID,
+				"redeemed_at": redemption.RedeemedAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Promocode{}).Where("id = ?", promocode.ID).
+			UpdateColumn("redemption_count", gorm.Expr("redemption_count + 1")).Error
+	})
+}
+
+func (r *RedemptionRepository) GetRedemptionByIdempotencyRecord(ctx context.Context, recordID uint) (*models.Redemption, error) {
+	ctx, span := tracing.Start(ctx, "RedemptionRepository.GetRedemptionByIdempotencyRecord")
+	defer span.End()
+	defer metrics.ObserveDBQuery("RedemptionRepository.GetRedemptionByIdempotencyRecord", time.Now())
+
+	var redemption models.Redemption
+	err := r.db.WithContext(ctx).Where("idempotency_record_id = ?", recordID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+func (r *RedemptionRepository) ListRedemptions(ctx context.Context, filter models.RedemptionFilter) ([]models.Redemption, int64, error) {
+	ctx, span := tracing.Start(ctx, "RedemptionRepository.ListRedemptions")
+	defer span.End()
+	defer metrics.ObserveDBQuery("RedemptionRepository.ListRedemptions", time.Now())
+
+	query := r.db.WithContext(ctx).Model(&models.Redemption{})
+	if filter.PromocodeID != 0 {
+		query = query.Where("promocode_id = ?", filter.PromocodeID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var redemptions []models.Redemption
+	err := query.Order("id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&redemptions).Error
+	return redemptions, total, err
+}
+
+var _ RedemptionRepositoryInterface = (*RedemptionRepository)(nil)
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+	"time"
+)
+
+const (
+	// apiKeyPrefix отличает API-ключи от токенов доступа, например при поиске утечек в логах
+	apiKeyPrefix = "pk_"
+	// apiKeyTouchInterval - как часто обновлять время последнего использования ключа
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("недействительный API-ключ")
+	ErrAPIKeyNotFound = errors.New("API-ключ не найден")
+)
+
+type APIKeyService struct {
+	apiKeyRepo repository.APIKeyRepositoryInterface
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepositoryInterface) *APIKeyService {
+	return &APIKeyService{apiKeyRepo: apiKeyRepo}
+}
+
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, actor *models.Identity, companyID uint, req models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer span.End()
+
+	if err := authorizeOwner(actor, companyID); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(buf)
+	key := &models.APIKey{
+		CompanyID: companyID,
+		Name:      req.Name,
+		Prefix:    secret[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(secret),
+		CreatorID: actor.UserID,
+	}
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return &models.CreatedAPIKey{APIKey: *key, Key: secret}, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, actor *models.Identity, companyID uint) (*models.APIKeyList, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.ListAPIKeys")
+	defer span.End()
+
+	if err := authorizeOwner(actor, companyID); err != nil {
+		return nil, err
+	}
+	keys, err := s.apiKeyRepo.ListAPIKeys(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	return &models.APIKeyList{Keys: keys}, nil
+}
+
+// RevokeAPIKey действует сразу: ключ проверяется по базе при каждом запросе
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, actor *models.Identity, companyID uint, id uint) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer span.End()
+
+	if err := authorizeOwner(actor, companyID); err != nil {
+		return err
+	}
+	revoked, err := s.apiKeyRepo.RevokeAPIKey(ctx, companyID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		// Время использования справочное, из-за него запрос не отклоняется
+		if err := s.apiKeyRepo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			log.Printf("Ошибка обновления времени использования API-ключа %d: %v", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+// authorizeOwner разрешает действие владельцам компании и администраторам
+func authorizeOwner(actor *models.Identity, companyID uint) error {
+	if role, _ := actor.CompanyRole(companyID); role != models.CompanyRoleOwner && !actor.HasRole(models.RoleAdmin) {
+		return ErrNotCompanyOwner
+	}
+	return nil
+}
+
+// hashAPIKey - ключи случайные и длинные, поэтому для хранения достаточно SHA-256 без соли
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+var _ APIKeyServiceInterface = (*APIKeyService)(nil)
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockAPIKeyRepository struct {
+	keys []models.APIKey
+}
+
+var _ repository.APIKeyRepositoryInterface = (*MockAPIKeyRepository)(nil)
+
+func (r *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	key.ID = uint(len(r.keys) + 1)
+	key.CreatedAt = time.Now()
+	r.keys = append(r.keys, *key)
+	return nil
+}
+
+func (r *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	for _, key := range r.keys {
+		if key.KeyHash == hash {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockAPIKeyRepository) ListAPIKeys(ctx context.Context, companyID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	for _, key := range r.keys {
+		if key.CompanyID == companyID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, companyID uint, id uint, revokedAt time.Time) (bool, error) {
+	for i := range r.keys {
+		if r.keys[i].ID == id && r.keys[i].CompanyID == companyID && r.keys[i].RevokedAt == nil {
+			r.keys[i].RevokedAt = &revokedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockAPIKeyRepository) RevokeCompanyAPIKeys(ctx context.Context, companyID uint, revokedAt time.Time) error {
+	for i := range r.keys {
+		if r.keys[i].CompanyID == companyID && r.keys[i].RevokedAt == nil {
+			r.keys[i].RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time) error {
+	r.keys[id-1].LastUsedAt = &usedAt
+	return nil
+}
+
+type MockIdempotencyRepository struct {
+	records []models.IdempotencyRecord
+}
+
+var _ repository.IdempotencyRepositoryInterface = (*MockIdempotencyRepository)(nil)
+
+func (r *MockIdempotencyRepository) CreateRecord(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	if existing, _ := r.GetRecord(ctx, record.APIKeyID, record.IdempotencyKey); existing != nil {
+		return false, nil
+	}
+	record.ID = uint(len(r.records) + 1)
+	record.CreatedAt = time.Now()
+	record.UpdatedAt = record.CreatedAt
+	r.records = append(r.records, *record)
+	return true, nil
+}
+
+func (r *MockIdempotencyRepository) GetRecord(ctx context.Context, apiKeyID uint, key string) (*models.IdempotencyRecord, error) {
+	for _, record := range r.records {
+		if record.ID != 0 && record.APIKeyID == apiKeyID && record.IdempotencyKey == key {
+			return &record, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockIdempotencyRepository) CompleteRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	r.records[record.ID-1].StatusCode = record.StatusCode
+	r.records[record.ID-1].Response = record.Response
+	return nil
+}
+
+func (r *MockIdempotencyRepository) TakeOverRecord(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	stored := &r.records[record.ID-1]
+	if stored.ID == 0 || stored.StatusCode != 0 || !stored.UpdatedAt.Before(staleBefore) {
+		return false, nil
+	}
+	stored.UpdatedAt = time.Now()
+	record.UpdatedAt = stored.UpdatedAt
+	return true, nil
+}
+
+func (r *MockIdempotencyRepository) DeleteRecordsBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for i, record := range r.records {
+		if record.ID != 0 && record.CreatedAt.Before(before) {
+			r.records[i] = models.IdempotencyRecord{}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// rewind сдвигает время создания и резервирования записи id в прошлое
+func (r *MockIdempotencyRepository) rewind(id uint, d time.Duration) {
+	r.records[id-1].CreatedAt = r.records[id-1].CreatedAt.Add(-d)
+	r.records[id-1].UpdatedAt = r.records[id-1].UpdatedAt.Add(-d)
+}
+
+// DeleteRecord оставляет пустую запись на месте, чтобы идентификаторы совпадали с индексами
+func (r *MockIdempotencyRepository) DeleteRecord(ctx context.Context, id uint) error {
+	r.records[id-1] = models.IdempotencyRecord{}
+	return nil
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	service := NewAPIKeyService(&MockAPIKeyRepository{})
+	ctx := context.Background()
+
+	if _, err := service.CreateAPIKey(ctx, testAnalyst, 2, models.CreateAPIKeyRequest{Name: "Касса"}); err != ErrNotCompanyOwner {
+		t.Errorf("Менеджер не должен создавать API-ключи, получено: %v", err)
+	}
+	created, err := service.CreateAPIKey(ctx, testOwner, 1, models.CreateAPIKeyRequest{Name: "Касса на Тверской"})
+	if err != nil {
+		t.Fatalf("Ожидается успешное создание ключа, получено: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, "pk_") || !strings.HasPrefix(created.Key, created.Prefix) || created.KeyHash == created.Key {
+		t.Errorf("Неверный ключ: %+v", created)
+	}
+
+	key, err := service.Authenticate(ctx, created.Key)
+	if err != nil || key.ID != created.ID || key.CompanyID != 1 {
+		t.Fatalf("Ожидается ключ компании 1, получено: %+v, %v", key, err)
+	}
+	if list, _ := service.ListAPIKeys(ctx, testAdmin, 1); len(list.Keys) != 1 || list.Keys[0].LastUsedAt == nil {
+		t.Errorf("Ожидается ключ с временем использования, получено: %+v", list)
+	}
+	if _, err := service.Authenticate(ctx, created.Key+"0"); err != ErrInvalidAPIKey {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrInvalidAPIKey, err)
+	}
+
+	if err := service.RevokeAPIKey(ctx, testOwner, 2, created.ID); err != ErrNotCompanyOwner {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrNotCompanyOwner, err)
+	}
+	if err := service.RevokeAPIKey(ctx, testOwner, 1, created.ID); err != nil {
+		t.Fatalf("Ожидается отзыв ключа, получено: %v", err)
+	}
+	if err := service.RevokeAPIKey(ctx, testOwner, 1, created.ID); err != ErrAPIKeyNotFound {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrAPIKeyNotFound, err)
+	}
+	if _, err := service.Authenticate(ctx, created.Key); err != ErrInvalidAPIKey {
+		t.Errorf("Отозванный ключ не должен действовать, получено: %v", err)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	service := NewIdempotencyService(&MockIdempotencyRepository{}, time.Minute, 24*time.Hour)
+	ctx := context.Background()
+
+	record, err := service.Begin(ctx, 1, "order-1", "hash")
+	if err != nil || record.StatusCode != 0 {
+		t.Fatalf("Ожидается новая запись, получено: %+v, %v", record, err)
+	}
+	if _, err := service.Begin(ctx, 1, "order-1", "hash"); err != ErrIdempotentRequestInProgress {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrIdempotentRequestInProgress, err)
+	}
+	service.Complete(ctx, record, 201, []byte(`{"id":1}`))
+
+	replay, err := service.Begin(ctx, 1, "order-1", "hash")
+	if err != nil || replay.StatusCode != 201 || string(replay.Response) != `{"id":1}` {
+		t.Errorf("Ожидается сохраненный ответ, получено: %+v, %v", replay, err)
+	}
+	if _, err := service.Begin(ctx, 1, "order-1", "other"); err != ErrIdempotencyKeyReused {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrIdempotencyKeyReused, err)
+	}
+	// Ключи идемпотентности разных касс не пересекаются
+	if _, err := service.Begin(ctx, 2, "order-1", "other"); err != nil {
+		t.Errorf("Ожидается новая запись для другого ключа, получено: %v", err)
+	}
+
+	// После сбоя запрос можно повторить с тем же ключом
+	failed, _ := service.Begin(ctx, 1, "order-2", "hash")
+	service.Abandon(ctx, failed)
+	if record, err := service.Begin(ctx, 1, "order-2", "hash"); err != nil || record.StatusCode != 0 {
+		t.Errorf("Ожидается новая попытка, получено: %+v, %v", record, err)
+	}
+}
+
+func TestIdempotencyStaleReservation(t *testing.T) {
+	repo := &MockIdempotencyRepository{}
+	service := NewIdempotencyService(repo, time.Minute, 24*time.Hour)
+	ctx := context.Background()
+
+	// Экземпляр зарезервировал ключ и упал, не сохранив ответ
+	crashed, _ := service.Begin(ctx, 1, "order-1", "hash")
+	repo.rewind(crashed.ID, 30*time.Second)
+	if _, err := service.Begin(ctx, 1, "order-1", "hash"); err != ErrIdempotentRequestInProgress {
+		t.Errorf("До истечения блокировки резерв не перехватывается, получено: %v", err)
+	}
+	repo.rewind(crashed.ID, time.Minute)
+	retry, err := service.Begin(ctx, 1, "order-1", "hash")
+	if err != nil || retry.ID != crashed.ID || retry.StatusCode != 0 {
+		t.Fatalf("Повтор должен получить брошенный резерв, получено: %+v, %v", retry, err)
+	}
+	// Перехваченный резерв снова занят: параллельный повтор ждет
+	if _, err := service.Begin(ctx, 1, "order-1", "hash"); err != ErrIdempotentRequestInProgress {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrIdempotentRequestInProgress, err)
+	}
+	if _, err := service.Begin(ctx, 1, "order-1", "other"); err != ErrIdempotencyKeyReused {
+		t.Errorf("Чужой запрос не должен перехватывать резерв, получено: %v", err)
+	}
+	service.Complete(ctx, retry, 201, []byte(`{"id":1}`))
+	repo.rewind(crashed.ID, time.Hour)
+	if replay, err := service.Begin(ctx, 1, "order-1", "hash"); err != nil || replay.StatusCode != 201 {
+		t.Errorf("Завершенный запрос не перехватывается, получено: %+v, %v", replay, err)
+	}
+}
+
+func TestIdempotencyRecordsExpire(t *testing.T) {
+	repo := &MockIdempotencyRepository{}
+	service := NewIdempotencyService(repo, time.Minute, 24*time.Hour)
+	ctx := context.Background()
+
+	old, _ := service.Begin(ctx, 1, "order-1", "hash")
+	service.Complete(ctx, old, 201, []byte(`{"id":1}`))
+	fresh, _ := service.Begin(ctx, 1, "order-2", "hash")
+	service.Complete(ctx, fresh, 201, []byte(`{"id":2}`))
+	repo.rewind(old.ID, 25*time.Hour)
+
+	service.deleteExpired(ctx)
+	if record, _ := repo.GetRecord(ctx, 1, "order-1"); record != nil {
+		t.Errorf("Запись старше ttl должна удаляться, получено: %+v", record)
+	}
+	if record, _ := repo.GetRecord(ctx, 1, "order-2"); record == nil {
+		t.Error("Свежая запись должна сохраняться")
+	}
+	// После удаления ключ можно использовать снова
+	if record, err := service.Begin(ctx, 1, "order-1", "other"); err != nil || record.StatusCode != 0 {
+		t.Errorf("Ожидается новая запись, получено: %+v, %v", record, err)
+	}
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+	"strings"
+	"time"
+)
+
+const (
+	// maxCodeLength совпадает с ограничением на длину кода при погашении
+	maxCodeLength = 32
+	// codeSpaceFactor - во сколько раз возможных кодов должно быть больше заказанных, чтобы случайные коды редко совпадали
+	codeSpaceFactor = 10
+	codeChunkSize   = 1000
+	// maxIdleChunks - сколько порций подряд может не дать ни одного нового кода, прежде чем партия завершится ошибкой
+	maxIdleChunks = 5
+)
+
+var (
+	ErrCodeBatchNotFound   = errors.New("партия кодов не найдена")
+	ErrInvalidCodeAlphabet = errors.New("алфавит кодов должен состоять из неповторяющихся латинских букв и цифр")
+	ErrCodeTooLong         = errors.New("префикс, случайная часть и контрольный символ вместе не должны превышать 32 символа")
+	ErrCodeSpaceTooSmall   = errors.New("слишком мало возможных кодов для такого количества: увеличьте длину или алфавит")
+	ErrCodeBatchNotReady   = errors.New("коды партии еще не сгенерированы")
+)
+
+type CodeBatchService struct {
+	promocodeRepo repository.PromocodeRepositoryInterface
+	codeRepo      repository.CodeRepositoryInterface
+	// wakeup будит Run, когда появляется новая партия
+	wakeup chan struct{}
+}
+
+func NewCodeBatchService(promocodeRepo repository.PromocodeRepositoryInterface, codeRepo repository.CodeRepositoryInterface) *CodeBatchService {
+	return &CodeBatchService{
+		promocodeRepo: promocodeRepo,
+		codeRepo:      codeRepo,
+		wakeup:        make(chan struct{}, 1),
+	}
+}
+
+// CreateBatch ставит партию в очередь; сами коды генерирует Run в фоне
+func (s *CodeBatchService) CreateBatch(ctx context.Context, actor *models.Identity, promocodeID uint, req models.GenerateCodesRequest) (*models.CodeBatch, error) {
+	ctx, span := tracing.Start(ctx, "CodeBatchService.CreateBatch")
+	defer span.End()
+
+	// Коды сравниваются без учета регистра, поэтому алфавит и префикс хранятся заглавными
+	alphabet := strings.ToUpper(req.Alphabet)
+	if alphabet == "" {
+		alphabet = models.DefaultCodeAlphabet
+	}
+	if !validAlphabet(alphabet) {
+		return nil, ErrInvalidCodeAlphabet
+	}
+	prefix := strings.ToUpper(req.Prefix)
+	length := len(prefix) + req.Length
+	if req.CheckDigit {
+		length++
+	}
+	if length > maxCodeLength {
+		return nil, ErrCodeTooLong
+	}
+	if math.Pow(float64(len(alphabet)), float64(req.Length)) < float64(req.Quantity)*codeSpaceFactor {
+		return nil, ErrCodeSpaceTooSmall
+	}
+
+	promocode, err := s.promocodeRepo.GetPromocodeByID(ctx, promocodeID)
+	if err != nil {
+		return nil, err
+	}
+	if promocode == nil {
+		return nil, ErrPromocodeNotFound
+	}
+	if err := authorize(actor, promocode.CompanyID); err != nil {
+		return nil, err
+	}
+
+	batch := &models.CodeBatch{
+		PromocodeID: promocode.ID,
+		CompanyID:   promocode.CompanyID,
+		CreatorID:   actor.UserID,
+		Quantity:    req.Quantity,
+		Alphabet:    alphabet,
+		Length:      req.Length,
+		Prefix:      prefix,
+		CheckDigit:  req.CheckDigit,
+		Status:      models.CodeBatchPending,
+	}
+	if err := s.codeRepo.CreateBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+	return batch, nil
+}
+
+func (s *CodeBatchService) GetBatch(ctx context.Context, actor *models.Identity, id uint) (*models.CodeBatch, error) {
+	ctx, span := tracing.Start(ctx, "CodeBatchService.GetBatch")
+	defer span.End()
+
+	batch, err := s.codeRepo.GetBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, ErrCodeBatchNotFound
+	}
+	if err := authorize(actor, batch.CompanyID); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func (s *CodeBatchService) ListBatches(ctx context.Context, actor *models.Identity, promocodeID uint, filter models.CodeBatchFilter) (*models.CodeBatchList, error) {
+	ctx, span := tracing.Start(ctx, "CodeBatchService.ListBatches")
+	defer span.End()
+
+	promocode, err := s.promocodeRepo.GetPromocodeByID(ctx, promocodeID)
+	if err != nil {
+		return nil, err
+	}
+	if promocode == nil {
+		return nil, ErrPromocodeNotFound
+	}
+	if err := authorize(actor, promocode.CompanyID); err != nil {
+		return nil, err
+	}
+
+	filter.PromocodeID = promocodeID
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	batches, total, err := s.codeRepo.ListBatches(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if batches == nil {
+		batches = []models.CodeBatch{}
+	}
+	return &models.CodeBatchList{
+		Batches:  batches,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+// ExportBatch пишет коды готовой партии в w в формате CSV: код и время погашения, если код уже погашен.
+// Ошибки доступа и готовности возвращаются до того, как в w что-либо записано.
+func (s *CodeBatchService) ExportBatch(ctx context.Context, actor *models.Identity, id uint, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "CodeBatchService.ExportBatch")
+	defer span.End()
+
+	batch, err := s.GetBatch(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if batch.Status != models.CodeBatchCompleted {
+		return ErrCodeBatchNotReady
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"code", "redeemed_at"}); err != nil {
+		return err
+	}
+	var afterID uint
+	for {
+		codes, err := s.codeRepo.ListCodes(ctx, batch.ID, afterID, codeChunkSize)
+		if err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			break
+		}
+		for _, code := range codes {
+			redeemedAt := ""
+			if code.RedeemedAt != nil {
+				redeemedAt = code.RedeemedAt.UTC().Format(time.RFC3339)
+			}
+			if err := writer.Write([]string{code.Code, redeemedAt}); err != nil {
+				return err
+			}
+		}
+		afterID = codes[len(codes)-1].ID
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Run генерирует коды ожидающих партий сразу после их создания, а раз в interval подбирает партии,
+// прерванные перезапуском сервиса. Работает, пока не отменен ctx.
+func (s *CodeBatchService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.processPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *CodeBatchService) processPending(ctx context.Context) {
+	batches, err := s.codeRepo.ListUnfinishedBatches(ctx)
+	if err != nil {
+		log.Printf("Ошибка получения партий кодов: %v", err)
+		return
+	}
+	for _, batch := range batches {
+		if err := s.generate(ctx, batch); err != nil {
+			log.Printf("Ошибка генерации кодов партии %d: %v", batch.ID, err)
+		}
+	}
+}
+
+// generate досоздает коды партии порциями. Счетчик Generated сохраняется вместе с каждой порцией,
+// поэтому прерванная партия продолжается с того же места.
+func (s *CodeBatchService) generate(ctx context.Context, batch models.CodeBatch) error {
+	ctx, span := tracing.Start(ctx, "CodeBatchService.generate")
+	defer span.End()
+
+	if batch.Status == models.CodeBatchPending {
+		batch.Status = models.CodeBatchRunning
+		if err := s.codeRepo.UpdateBatchStatus(ctx, &batch); err != nil {
+			return err
+		}
+	}
+
+	idle := 0
+	for batch.Generated < batch.Quantity {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		count := batch.Quantity - batch.Generated
+		if count > codeChunkSize {
+			count = codeChunkSize
+		}
+		codes, err := newCodes(&batch, count)
+		if err != nil {
+			return err
+		}
+		updated, err := s.codeRepo.InsertCodes(ctx, batch.ID, codes)
+		if err != nil {
+			return err
+		}
+		if updated == nil {
+			// Партию удалили вместе с промокодом
+			return nil
+		}
+
+		inserted := updated.Generated - batch.Generated
+		metrics.CodesGenerated.Add(float64(inserted))
+		if inserted > 0 {
+			idle = 0
+		} else if idle++; idle == maxIdleChunks {
+			return s.finish(ctx, &batch, models.CodeBatchFailed, "не удалось подобрать новые уникальные коды: увеличьте длину или алфавит")
+		}
+		batch.Generated = updated.Generated
+	}
+	return s.finish(ctx, &batch, models.CodeBatchCompleted, "")
+}
+
+func (s *CodeBatchService) finish(ctx context.Context, batch *models.CodeBatch, status models.CodeBatchStatus, reason string) error {
+	finishedAt := time.Now()
+	batch.Status = status
+	batch.Error = reason
+	batch.FinishedAt = &finishedAt
+	return s.codeRepo.UpdateBatchStatus(ctx, batch)
+}
+
+// newCodes генерирует count различных кодов партии. Случайная часть берется из crypto/rand:
+// одноразовые коды - это секреты, которые нельзя подобрать по соседним.
+func newCodes(batch *models.CodeBatch, count int) ([]models.UniqueCode, error) {
+	seen := make(map[string]bool, count)
+	codes := make([]models.UniqueCode, 0, count)
+	for len(codes) < count {
+		body, err := randomString(batch.Alphabet, batch.Length)
+		if err != nil {
+			return nil, err
+		}
+		code := batch.Prefix + body
+		if batch.CheckDigit {
+			code += string(checkCharacter(body, batch.Alphabet))
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, models.UniqueCode{PromocodeID: batch.PromocodeID, BatchID: batch.ID, Code: code})
+	}
+	return codes, nil
+}
+
+// randomString выбирает символы alphabet равновероятно: байты, на которых деление с остатком
+// дало бы перекос в сторону первых символов, отбрасываются
+func randomString(alphabet string, length int) (string, error) {
+	limit := 256 - 256%len(alphabet)
+	result := make([]byte, 0, length)
+	buf := make([]byte, length*2)
+	for len(result) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(result) < length {
+				result = append(result, alphabet[int(b)%len(alphabet)])
+			}
+		}
+	}
+	return string(result), nil
+}
+
+// checkCharacter - контрольный символ по алгоритму Луна для алфавита из n символов (Luhn mod N).
+// Он ловит любую опечатку в одном символе случайной части кода. Свертка Луна нужна только
+// для четного n: при нечетном удвоение по модулю n и так не склеивает разные символы.
+func checkCharacter(body, alphabet string) byte {
+	n := len(alphabet)
+	factor := 2
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, body[i])
+		if n%2 == 0 {
+			addend = addend/n + addend%n
+		}
+		sum += addend
+		factor = 3 - factor
+	}
+	return alphabet[(n-sum%n)%n]
+}
+
+func validAlphabet(alphabet string) bool {
+	if len(alphabet) < 2 {
+		return false
+	}
+	seen := make(map[rune]bool, len(alphabet))
+	for _, r := range alphabet {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') || seen[r] {
+			return false
+		}
+		seen[r] = true
+	}
+	return true
+}
+
+var _ CodeBatchServiceInterface = (*CodeBatchService)(nil)
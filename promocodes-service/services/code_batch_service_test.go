@@ -0,0 +1,285 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"strings"
+	"testing"
+)
+
+// MockCodeRepository, как и настоящий репозиторий, пропускает коды, занятые другими кодами или общими кодами промокодов
+type MockCodeRepository struct {
+	promocodes *MockPromocodeRepository
+	batches    map[uint]*models.CodeBatch
+	codes      []models.UniqueCode
+	byCode     map[string]int
+	batchID    uint
+}
+
+var _ repository.CodeRepositoryInterface = (*MockCodeRepository)(nil)
+
+func NewMockCodeRepository(promocodes *MockPromocodeRepository) *MockCodeRepository {
+	codes := &MockCodeRepository{
+		promocodes: promocodes,
+		batches:    make(map[uint]*models.CodeBatch),
+		byCode:     make(map[string]int),
+	}
+	promocodes.codes = codes
+	return codes
+}
+
+func (r *MockCodeRepository) CreateBatch(ctx context.Context, batch *models.CodeBatch) error {
+	r.batchID++
+	batch.ID = r.batchID
+	stored := *batch
+	r.batches[batch.ID] = &stored
+	return nil
+}
+
+func (r *MockCodeRepository) GetBatch(ctx context.Context, id uint) (*models.CodeBatch, error) {
+	batch, exists := r.batches[id]
+	if !exists {
+		return nil, nil
+	}
+	stored := *batch
+	return &stored, nil
+}
+
+func (r *MockCodeRepository) ListBatches(ctx context.Context, filter models.CodeBatchFilter) ([]models.CodeBatch, int64, error) {
+	var batches []models.CodeBatch
+	for id := r.batchID; id > 0; id-- {
+		if batch, exists := r.batches[id]; exists && batch.PromocodeID == filter.PromocodeID {
+			batches = append(batches, *batch)
+		}
+	}
+	return batches, int64(len(batches)), nil
+}
+
+func (r *MockCodeRepository) ListUnfinishedBatches(ctx context.Context) ([]models.CodeBatch, error) {
+	var batches []models.CodeBatch
+	for id := uint(1); id <= r.batchID; id++ {
+		if batch, exists := r.batches[id]; exists && (batch.Status == models.CodeBatchPending || batch.Status == models.CodeBatchRunning) {
+			batches = append(batches, *batch)
+		}
+	}
+	return batches, nil
+}
+
+func (r *MockCodeRepository) UpdateBatchStatus(ctx context.Context, batch *models.CodeBatch) error {
+	stored := r.batches[batch.ID]
+	stored.Status = batch.Status
+	stored.Error = batch.Error
+	stored.FinishedAt = batch.FinishedAt
+	return nil
+}
+
+func (r *MockCodeRepository) InsertCodes(ctx context.Context, batchID uint, codes []models.UniqueCode) (*models.CodeBatch, error) {
+	batch, exists := r.batches[batchID]
+	if !exists {
+		return nil, nil
+	}
+	for _, code := range codes {
+		if batch.Generated == batch.Quantity {
+			break
+		}
+		if shared, _ := r.promocodes.GetPromocodeByCode(ctx, code.Code); shared != nil {
+			continue
+		}
+		if _, taken := r.byCode[code.Code]; taken {
+			continue
+		}
+		code.ID = uint(len(r.codes) + 1)
+		r.codes = append(r.codes, code)
+		r.byCode[code.Code] = len(r.codes) - 1
+		batch.Generated++
+	}
+	stored := *batch
+	return &stored, nil
+}
+
+func (r *MockCodeRepository) ListCodes(ctx context.Context, batchID uint, afterID uint, limit int) ([]models.UniqueCode, error) {
+	var codes []models.UniqueCode
+	for _, code := range r.codes {
+		if code.BatchID == batchID && code.ID > afterID && len(codes) < limit {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+func (r *MockCodeRepository) GetUniqueCode(ctx context.Context, code string) (*models.UniqueCode, error) {
+	index, exists := r.byCode[code]
+	if !exists {
+		return nil, nil
+	}
+	stored := r.codes[index]
+	return &stored, nil
+}
+
+type testCodeBatches struct {
+	service    *CodeBatchService
+	promocodes *MockPromocodeRepository
+	codes      *MockCodeRepository
+	promocode  *models.Promocode
+}
+
+// newTestCodeBatchService создает промокод компании 1, для которого генерируются партии
+func newTestCodeBatchService() *testCodeBatches {
+	promocodes := NewMockPromocodeRepository()
+	codes := NewMockCodeRepository(promocodes)
+	promocode := &models.Promocode{CompanyID: 1, Code: "SPRING", Type: models.PromocodeTypePercent}
+	promocodes.CreatePromocode(context.Background(), promocode)
+	return &testCodeBatches{
+		service:    NewCodeBatchService(promocodes, codes),
+		promocodes: promocodes,
+		codes:      codes,
+		promocode:  promocode,
+	}
+}
+
+func TestGenerateCodeBatch(t *testing.T) {
+	env := newTestCodeBatchService()
+	ctx := context.Background()
+
+	batch, err := env.service.CreateBatch(ctx, testOwner, env.promocode.ID, models.GenerateCodesRequest{
+		Quantity: 2500, Length: 8, Prefix: "spr", CheckDigit: true,
+	})
+	if err != nil {
+		t.Fatalf("Ожидается успешное создание партии, получено: %v", err)
+	}
+	if batch.Status != models.CodeBatchPending || batch.Prefix != "SPR" || batch.Alphabet != models.DefaultCodeAlphabet {
+		t.Errorf("Неверная партия: %+v", batch)
+	}
+	if err := env.service.ExportBatch(ctx, testOwner, batch.ID, &bytes.Buffer{}); err != ErrCodeBatchNotReady {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrCodeBatchNotReady, err)
+	}
+
+	env.service.processPending(ctx)
+	batch, _ = env.service.GetBatch(ctx, testOwner, batch.ID)
+	if batch.Status != models.CodeBatchCompleted || batch.Generated != 2500 || batch.FinishedAt == nil {
+		t.Fatalf("Ожидается завершенная партия из 2500 кодов, получено: %+v", batch)
+	}
+
+	var out bytes.Buffer
+	if err := env.service.ExportBatch(ctx, testOwner, batch.ID, &out); err != nil {
+		t.Fatalf("Ожидается успешная выгрузка, получено: %v", err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil || len(records) != 2501 || records[0][0] != "code" {
+		t.Fatalf("Ожидается CSV с заголовком и 2500 кодами, получено: %d строк, %v", len(records), err)
+	}
+	seen := make(map[string]bool)
+	for _, record := range records[1:] {
+		code := record[0]
+		if seen[code] {
+			t.Fatalf("Код %s встречается дважды", code)
+		}
+		seen[code] = true
+		body := code[3 : len(code)-1]
+		if len(code) != 12 || !strings.HasPrefix(code, "SPR") || code[len(code)-1] != checkCharacter(body, batch.Alphabet) {
+			t.Fatalf("Неверный код: %s", code)
+		}
+	}
+}
+
+func TestCodeBatchValidation(t *testing.T) {
+	env := newTestCodeBatchService()
+	ctx := context.Background()
+	id := env.promocode.ID
+
+	tests := []struct {
+		name  string
+		actor *models.Identity
+		id    uint
+		req   models.GenerateCodesRequest
+		err   error
+	}{
+		{"повтор в алфавите", testOwner, id, models.GenerateCodesRequest{Quantity: 10, Length: 8, Alphabet: "ABCA"}, ErrInvalidCodeAlphabet},
+		{"слишком длинный код", testOwner, id, models.GenerateCodesRequest{Quantity: 10, Length: 24, Prefix: "SPRING2026", CheckDigit: true}, ErrCodeTooLong},
+		{"мало возможных кодов", testOwner, id, models.GenerateCodesRequest{Quantity: 1001, Length: 4, Alphabet: "0123456789"}, ErrCodeSpaceTooSmall},
+		{"аналитик", testAnalyst, id, models.GenerateCodesRequest{Quantity: 10, Length: 8}, ErrNotCompanyPromoEditor},
+		{"нет промокода", testOwner, 99, models.GenerateCodesRequest{Quantity: 10, Length: 8}, ErrPromocodeNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := env.service.CreateBatch(ctx, tt.actor, tt.id, tt.req); err != tt.err {
+			t.Errorf("%s: ожидается ошибка %v, получено: %v", tt.name, tt.err, err)
+		}
+	}
+
+	batch, err := env.service.CreateBatch(ctx, testAdmin, id, models.GenerateCodesRequest{Quantity: 10, Length: 6, Alphabet: "abcdef"})
+	if err != nil || batch.Alphabet != "ABCDEF" {
+		t.Errorf("Алфавит должен приводиться к верхнему регистру, получено: %+v, %v", batch, err)
+	}
+	if _, err := env.service.GetBatch(ctx, testAnalyst, batch.ID); err != ErrNotCompanyPromoEditor {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrNotCompanyPromoEditor, err)
+	}
+}
+
+func TestCodeBatchSpaceExhausted(t *testing.T) {
+	env := newTestCodeBatchService()
+	ctx := context.Background()
+
+	// Алфавит из двух символов и длина 4 дают 16 кодов, и все они уже заняты
+	first, _ := env.service.CreateBatch(ctx, testOwner, env.promocode.ID, models.GenerateCodesRequest{Quantity: 1, Length: 4, Alphabet: "AB"})
+	for _, code := range []string{"AAAA", "AAAB", "AABA", "AABB", "ABAA", "ABAB", "ABBA", "ABBB", "BAAA", "BAAB", "BABA", "BABB", "BBAA", "BBAB", "BBBA", "BBBB"} {
+		env.codes.codes = append(env.codes.codes, models.UniqueCode{ID: uint(len(env.codes.codes) + 1), BatchID: 100, Code: code})
+		env.codes.byCode[code] = len(env.codes.codes) - 1
+	}
+
+	env.service.processPending(ctx)
+	batch, _ := env.service.GetBatch(ctx, testOwner, first.ID)
+	if batch.Status != models.CodeBatchFailed || batch.Error == "" || batch.Generated != 0 {
+		t.Errorf("Ожидается партия, завершенная ошибкой, получено: %+v", batch)
+	}
+}
+
+func TestSharedCodeNotTakenByUniqueCode(t *testing.T) {
+	env := newTestCodeBatchService()
+	ctx := context.Background()
+	batch, _ := env.service.CreateBatch(ctx, testOwner, env.promocode.ID, models.GenerateCodesRequest{Quantity: 1, Length: 8})
+	env.service.processPending(ctx)
+
+	code := env.codes.codes[0].Code
+	if code == "" || env.codes.codes[0].BatchID != batch.ID {
+		t.Fatalf("Ожидается сгенерированный код, получено: %+v", env.codes.codes)
+	}
+	promocodes := NewPromocodeService(env.promocodes, NewMockModerationRepository(env.promocodes, nil), env.codes, &MockCompanyClient{})
+	_, err := promocodes.CreatePromocode(ctx, testOwner, models.CreatePromocodeRequest{
+		CompanyID: 1, Code: strings.ToLower(code), Title: "Совпадающий код", Type: models.PromocodeTypeGift,
+	})
+	if err != ErrCodeTaken {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrCodeTaken, err)
+	}
+}
+
+func TestCheckCharacter(t *testing.T) {
+	tests := []struct {
+		alphabet string
+		body     string
+	}{
+		{models.DefaultCodeAlphabet, "K7M2Q9XA"},
+		{"0123456789", "7992739871"},
+	}
+	for _, tt := range tests {
+		check := checkCharacter(tt.body, tt.alphabet)
+		// Любая опечатка в одном символе меняет контрольный символ
+		for i := 0; i < len(tt.body); i++ {
+			for j := 0; j < len(tt.alphabet); j++ {
+				if tt.alphabet[j] == tt.body[i] {
+					continue
+				}
+				typo := tt.body[:i] + string(tt.alphabet[j]) + tt.body[i+1:]
+				if checkCharacter(typo, tt.alphabet) == check {
+					t.Fatalf("Опечатка %s не обнаружена контрольным символом", typo)
+				}
+			}
+		}
+	}
+	// Для десятичного алфавита это обычный алгоритм Луна
+	if check := checkCharacter("7992739871", "0123456789"); check != '3' {
+		t.Errorf("Ожидается контрольная цифра 3, получено: %c", check)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrCommentNotFound      = errors.New("комментарий не найден")
+	ErrInvalidParentComment = errors.New("комментарий, на который дается ответ, не найден или относится к другому промокоду")
+	ErrNotCommentAuthor     = errors.New("изменять комментарий может только его автор")
+	ErrCommentDeleteDenied  = errors.New("удалять комментарий могут только его автор и модераторы")
+	ErrCommentEditExpired   = errors.New("срок редактирования комментария истек")
+	ErrInvalidCommentCursor = errors.New("некорректный курсор")
+)
+
+type CommentService struct {
+	commentRepo    repository.CommentRepositoryInterface
+	promocodeRepo  repository.PromocodeRepositoryInterface
+	settingsRepo   repository.CompanySettingsRepositoryInterface
+	moderationRepo repository.ModerationRepositoryInterface
+	editWindow     time.Duration
+}
+
+// NewCommentService: editWindow - сколько времени после публикации автор может изменить комментарий
+func NewCommentService(commentRepo repository.CommentRepositoryInterface, promocodeRepo repository.PromocodeRepositoryInterface,
+	settingsRepo repository.CompanySettingsRepositoryInterface, moderationRepo repository.ModerationRepositoryInterface, editWindow time.Duration) *CommentService {
+	return &CommentService{
+		commentRepo:    commentRepo,
+		promocodeRepo:  promocodeRepo,
+		settingsRepo:   settingsRepo,
+		moderationRepo: moderationRepo,
+		editWindow:     editWindow,
+	}
+}
+
+func (s *CommentService) CreateComment(ctx context.Context, actor *models.Identity, promocodeID uint, req models.CreateCommentRequest) (*models.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.CreateComment")
+	defer span.End()
+
+	promocode, err := visiblePromocode(ctx, s.promocodeRepo, actor, promocodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.Comment{
+		PromocodeID: promocodeID,
+		CreatorID:   actor.UserID,
+		Content:     req.Content,
+	}
+	if req.ParentID != nil {
+		parent, err := s.visibleComment(ctx, actor, *req.ParentID)
+		if err == ErrCommentNotFound || (err == nil && parent.PromocodeID != promocodeID) {
+			return nil, ErrInvalidParentComment
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Ответ на ответ попадает в ветку того же комментария верхнего уровня
+		parentID := parent.ID
+		if parent.ParentID != nil {
+			parentID = *parent.ParentID
+		}
+		comment.ParentID = &parentID
+	}
+	if err := s.applyModeration(ctx, promocode.CompanyID, comment); err != nil {
+		return nil, err
+	}
+
+	if err := s.commentRepo.CreateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+	if err := submitForModeration(ctx, s.moderationRepo, models.ModerationEntityComment, comment.ID, promocode.CompanyID, actor.UserID); err != nil {
+		return nil, err
+	}
+	metrics.CommentChanges.WithLabelValues("create").Inc()
+	return comment, nil
+}
+
+func (s *CommentService) ListComments(ctx context.Context, actor *models.Identity, promocodeID uint, page models.CommentPage) (*models.CommentList, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.ListComments")
+	defer span.End()
+
+	promocode, err := visiblePromocode(ctx, s.promocodeRepo, actor, promocodeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.list(ctx, actor, models.CommentQuery{PromocodeID: promocode.ID}, page)
+}
+
+func (s *CommentService) ListReplies(ctx context.Context, actor *models.Identity, commentID uint, page models.CommentPage) (*models.CommentList, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.ListReplies")
+	defer span.End()
+
+	if _, err := s.visibleComment(ctx, actor, commentID); err != nil {
+		return nil, err
+	}
+	return s.list(ctx, actor, models.CommentQuery{ParentID: commentID}, page)
+}
+
+func (s *CommentService) UpdateComment(ctx context.Context, actor *models.Identity, id uint, req models.UpdateCommentRequest) (*models.Comment, error) {
+	ctx, span := tracing.Start(ctx, "CommentService.UpdateComment")
+	defer span.End()
+
+	comment, err := s.visibleComment(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.CreatorID != actor.UserID {
+		return nil, ErrNotCommentAuthor
+	}
+	now := time.Now()
+	if now.Sub(comment.CreatedAt) > s.editWindow {
+		return nil, ErrCommentEditExpired
+	}
+
+	promocode, err := s.promocodeRepo.GetPromocodeByID(ctx, comment.PromocodeID)
+	if err != nil {
+		return nil, err
+	}
+	if promocode == nil {
+		return nil, ErrPromocodeNotFound
+	}
+
+	comment.Content = req.Content
+	comment.EditedAt = &now
+	// Измененный текст модератор еще не видел
+	if err := s.applyModeration(ctx, promocode.CompanyID, comment); err != nil {
+		return nil, err
+	}
+	if err := s.commentRepo.UpdateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+	if err := submitForModeration(ctx, s.moderationRepo, models.ModerationEntityComment, comment.ID, promocode.CompanyID, actor.UserID); err != nil {
+		return nil, err
+	}
+	metrics.CommentChanges.WithLabelValues("update").Inc()
+	return comment, nil
+}
+
+func (s *CommentService) DeleteComment(ctx context.Context, actor *models.Identity, id uint) error {
+	ctx, span := tracing.Start(ctx, "CommentService.DeleteComment")
+	defer span.End()
+
+	comment, err := s.visibleComment(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if comment.CreatorID != actor.UserID && !actor.IsModerator() {
+		return ErrCommentDeleteDenied
+	}
+
+	if err := s.commentRepo.DeleteComment(ctx, id); err != nil {
+		return err
+	}
+	metrics.CommentChanges.WithLabelValues("delete").Inc()
+	return nil
+}
+
+// visibleComment скрывает непроверенные комментарии от всех, кроме автора и модераторов,
+// а комментарии к скрытым от actor промокодам - от всех, кто не видит промокод
+func (s *CommentService) visibleComment(ctx context.Context, actor *models.Identity, id uint) (*models.Comment, error) {
+	comment, err := s.commentRepo.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil || (comment.Hidden && comment.CreatorID != actor.UserID && !actor.IsModerator()) {
+		return nil, ErrCommentNotFound
+	}
+	if _, err := visiblePromocode(ctx, s.promocodeRepo, actor, comment.PromocodeID); err != nil {
+		if err == ErrPromocodeNotFound {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return comment, nil
+}
+
+// applyModeration отправляет новую или измененную версию комментария на проверку. Если компания
+// включила премодерацию, до решения модератора комментарий скрыт от других пользователей.
+func (s *CommentService) applyModeration(ctx context.Context, companyID uint, comment *models.Comment) error {
+	settings, err := s.settingsRepo.GetCompanySettings(ctx, companyID)
+	if err != nil {
+		return err
+	}
+	comment.ModerationStatus = models.ModerationPending
+	comment.Hidden = settings != nil && settings.PremoderateComments
+	return nil
+}
+
+func (s *CommentService) list(ctx context.Context, actor *models.Identity, query models.CommentQuery, page models.CommentPage) (*models.CommentList, error) {
+	afterID, err := decodeCommentCursor(page.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit := page.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	query.ViewerID = actor.UserID
+	query.IncludeHidden = actor.IsModerator()
+	query.AfterID = afterID
+	// Лишний комментарий показывает, есть ли следующая страница
+	query.Limit = limit + 1
+	comments, err := s.commentRepo.ListComments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.CommentList{Comments: comments}
+	if len(comments) > limit {
+		list.Comments = comments[:limit]
+		list.NextCursor = encodeCommentCursor(list.Comments[limit-1].ID)
+	}
+	if list.Comments == nil {
+		list.Comments = []models.Comment{}
+	}
+	return list, nil
+}
+
+// Курсор непрозрачен для клиента: внутри ID последнего комментария страницы
+func encodeCommentCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCommentCursor(cursor string) (uint, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCommentCursor
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCommentCursor
+	}
+	return uint(id), nil
+}
+
+var _ CommentServiceInterface = (*CommentService)(nil)
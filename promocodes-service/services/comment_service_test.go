@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"testing"
+	"time"
+)
+
+type MockCommentRepository struct {
+	comments  map[uint]*models.Comment
+	idCounter uint
+}
+
+var _ repository.CommentRepositoryInterface = (*MockCommentRepository)(nil)
+
+func (r *MockCommentRepository) CreateComment(ctx context.Context, comment *models.Comment) error {
+	r.idCounter++
+	comment.ID = r.idCounter
+	comment.CreatedAt = time.Now()
+	stored := *comment
+	r.comments[comment.ID] = &stored
+	return nil
+}
+
+func (r *MockCommentRepository) GetComment(ctx context.Context, id uint) (*models.Comment, error) {
+	comment, exists := r.comments[id]
+	if !exists {
+		return nil, nil
+	}
+	stored := *comment
+	return &stored, nil
+}
+
+func (r *MockCommentRepository) ListComments(ctx context.Context, query models.CommentQuery) ([]models.Comment, error) {
+	var comments []models.Comment
+	for id := query.AfterID + 1; id <= r.idCounter && len(comments) < query.Limit; id++ {
+		comment, exists := r.comments[id]
+		if !exists || (query.PromocodeID != 0 && comment.PromocodeID != query.PromocodeID) {
+			continue
+		}
+		if (query.ParentID == 0 && comment.ParentID != nil) || (query.ParentID != 0 && (comment.ParentID == nil || *comment.ParentID != query.ParentID)) {
+			continue
+		}
+		if !query.IncludeHidden && comment.Hidden && comment.CreatorID != query.ViewerID {
+			continue
+		}
+		comments = append(comments, *comment)
+	}
+	return comments, nil
+}
+
+func (r *MockCommentRepository) UpdateComment(ctx context.Context, comment *models.Comment) error {
+	stored := *comment
+	r.comments[comment.ID] = &stored
+	return nil
+}
+
+func (r *MockCommentRepository) DeleteComment(ctx context.Context, id uint) error {
+	for _, comment := range r.comments {
+		if comment.ID == id || (comment.ParentID != nil && *comment.ParentID == id) {
+			delete(r.comments, comment.ID)
+		}
+	}
+	return nil
+}
+
+type MockCompanySettingsRepository struct {
+	settings map[uint]*models.CompanySettings
+}
+
+var _ repository.CompanySettingsRepositoryInterface = (*MockCompanySettingsRepository)(nil)
+
+func (r *MockCompanySettingsRepository) GetCompanySettings(ctx context.Context, companyID uint) (*models.CompanySettings, error) {
+	settings, exists := r.settings[companyID]
+	if !exists {
+		return nil, nil
+	}
+	stored := *settings
+	return &stored, nil
+}
+
+func (r *MockCompanySettingsRepository) SaveCompanySettings(ctx context.Context, settings *models.CompanySettings) error {
+	stored := *settings
+	r.settings[settings.CompanyID] = &stored
+	return nil
+}
+
+func (r *MockCompanySettingsRepository) DeleteCompanySettings(ctx context.Context, companyID uint) error {
+	delete(r.settings, companyID)
+	return nil
+}
+
+var testModerator = &models.Identity{UserID: 5, Roles: []string{models.RoleModerator}}
+
+type testComments struct {
+	service    *CommentService
+	settings   *SettingsService
+	promocodes *MockPromocodeRepository
+	comments   *MockCommentRepository
+	moderation *MockModerationRepository
+	promocode  *models.Promocode
+}
+
+// newTestCommentService создает одобренный промокод компании 1, комментарии к которому пишет пользователь 4
+func newTestCommentService() *testComments {
+	promocodes := NewMockPromocodeRepository()
+	comments := &MockCommentRepository{comments: make(map[uint]*models.Comment)}
+	moderation := NewMockModerationRepository(promocodes, comments)
+	promocode, _ := NewPromocodeService(promocodes, moderation, NewMockCodeRepository(promocodes), &MockCompanyClient{}).CreatePromocode(context.Background(), testOwner,
+		models.CreatePromocodeRequest{CompanyID: 1, Code: "COFFEE10", Title: "Скидка на кофе", Type: models.PromocodeTypePercent})
+	promocode.ModerationStatus = models.ModerationApproved
+	promocodes.UpdatePromocode(context.Background(), promocode)
+	settingsRepo := &MockCompanySettingsRepository{settings: make(map[uint]*models.CompanySettings)}
+	return &testComments{
+		service:    NewCommentService(comments, promocodes, settingsRepo, moderation, 15*time.Minute),
+		settings:   NewSettingsService(settingsRepo),
+		promocodes: promocodes,
+		comments:   comments,
+		moderation: moderation,
+		promocode:  promocode,
+	}
+}
+
+func TestCommentThreads(t *testing.T) {
+	env := newTestCommentService()
+	ctx := context.Background()
+
+	root, err := env.service.CreateComment(ctx, testStranger, env.promocode.ID, models.CreateCommentRequest{Content: "Работает?"})
+	if err != nil || root.ParentID != nil || root.Hidden || root.ModerationStatus != models.ModerationPending {
+		t.Fatalf("Ожидается опубликованный комментарий верхнего уровня, получено: %+v, %v", root, err)
+	}
+	if _, err := env.service.CreateComment(ctx, testStranger, 42, models.CreateCommentRequest{Content: "Нет"}); err != ErrPromocodeNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего промокода, получено: %v", err)
+	}
+	reply, _ := env.service.CreateComment(ctx, testOwner, env.promocode.ID, models.CreateCommentRequest{Content: "Да", ParentID: &root.ID})
+	nested, _ := env.service.CreateComment(ctx, testStranger, env.promocode.ID, models.CreateCommentRequest{Content: "Спасибо", ParentID: &reply.ID})
+	if nested.ParentID == nil || *nested.ParentID != root.ID {
+		t.Errorf("Ответ на ответ должен попасть в ветку комментария %d, получено: %v", root.ID, nested.ParentID)
+	}
+	missing := uint(42)
+	if _, err := env.service.CreateComment(ctx, testStranger, env.promocode.ID, models.CreateCommentRequest{Content: "?", ParentID: &missing}); err != ErrInvalidParentComment {
+		t.Errorf("Ожидается ошибка отсутствующего родителя, получено: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		env.service.CreateComment(ctx, testAnalyst, env.promocode.ID, models.CreateCommentRequest{Content: "Еще вопрос"})
+	}
+	page, err := env.service.ListComments(ctx, testStranger, env.promocode.ID, models.CommentPage{Limit: 3})
+	if err != nil || len(page.Comments) != 3 || page.Comments[0].ID != root.ID || page.NextCursor == "" {
+		t.Fatalf("Ожидается первая страница из трех комментариев верхнего уровня, получено: %+v, %v", page, err)
+	}
+	page, _ = env.service.ListComments(ctx, testStranger, env.promocode.ID, models.CommentPage{Cursor: page.NextCursor, Limit: 3})
+	if len(page.Comments) != 2 || page.NextCursor != "" {
+		t.Errorf("Ожидается последняя страница из двух комментариев, получено: %+v", page)
+	}
+	if _, err := env.service.ListComments(ctx, testStranger, env.promocode.ID, models.CommentPage{Cursor: "!!"}); err != ErrInvalidCommentCursor {
+		t.Errorf("Ожидается ошибка некорректного курсора, получено: %v", err)
+	}
+	replies, _ := env.service.ListReplies(ctx, testStranger, root.ID, models.CommentPage{})
+	if len(replies.Comments) != 2 {
+		t.Errorf("Ожидается два ответа, получено: %+v", replies.Comments)
+	}
+
+	if err := env.service.DeleteComment(ctx, testAnalyst, root.ID); err != ErrCommentDeleteDenied {
+		t.Errorf("Чужой комментарий удалить нельзя, получено: %v", err)
+	}
+	if err := env.service.DeleteComment(ctx, testModerator, root.ID); err != nil {
+		t.Fatalf("Модератор может удалить комментарий, получено: %v", err)
+	}
+	if len(env.comments.comments) != 4 {
+		t.Errorf("Комментарий должен удаляться вместе с ответами, осталось: %d", len(env.comments.comments))
+	}
+}
+
+func TestCommentsOnUnapprovedPromocode(t *testing.T) {
+	env := newTestCommentService()
+	ctx := context.Background()
+	env.promocode.ModerationStatus = models.ModerationPending
+	env.promocodes.UpdatePromocode(ctx, env.promocode)
+
+	if _, err := env.service.CreateComment(ctx, testStranger, env.promocode.ID, models.CreateCommentRequest{Content: "Работает?"}); err != ErrPromocodeNotFound {
+		t.Errorf("Посторонний не может комментировать непроверенный промокод, получено: %v", err)
+	}
+	if _, err := env.service.ListComments(ctx, testStranger, env.promocode.ID, models.CommentPage{}); err != ErrPromocodeNotFound {
+		t.Errorf("Посторонний не видит комментарии к непроверенному промокоду, получено: %v", err)
+	}
+
+	// Команда компании и модераторы видят промокод до одобрения
+	comment, err := env.service.CreateComment(ctx, testAnalyst, env.promocode.ID, models.CreateCommentRequest{Content: "Проверим до запуска"})
+	if err != nil {
+		t.Fatalf("Участник команды может комментировать промокод компании, получено: %v", err)
+	}
+	if list, err := env.service.ListComments(ctx, testModerator, env.promocode.ID, models.CommentPage{}); err != nil || len(list.Comments) != 1 {
+		t.Errorf("Модератор видит комментарии, получено: %+v, %v", list, err)
+	}
+	if _, err := env.service.ListReplies(ctx, testStranger, comment.ID, models.CommentPage{}); err != ErrCommentNotFound {
+		t.Errorf("Посторонний не видит ответы к комментарию непроверенного промокода, получено: %v", err)
+	}
+	if err := env.service.DeleteComment(ctx, testStranger, comment.ID); err != ErrCommentNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего комментария, получено: %v", err)
+	}
+}
+
+func TestEditCommentWindow(t *testing.T) {
+	env := newTestCommentService()
+	ctx := context.Background()
+	comment, _ := env.service.CreateComment(ctx, testStranger, env.promocode.ID, models.CreateCommentRequest{Content: "Опечатка"})
+
+	if _, err := env.service.UpdateComment(ctx, testOwner, comment.ID, models.UpdateCommentRequest{Content: "Чужое"}); err != ErrNotCommentAuthor {
+		t.Errorf("Изменять комментарий может только автор, получено: %v", err)
+	}
+	updated, err := env.service.UpdateComment(ctx, testStranger, comment.ID, models.UpdateCommentRequest{Content: "Исправлено"})
+	if err != nil || updated.Content != "Исправлено" || updated.EditedAt == nil {
+		t.Errorf("Ожидается успешное изменение, получено: %+v, %v", updated, err)
+	}
+
+	env.comments.comments[comment.ID].CreatedAt = time.Now().Add(-time.Hour)
+	if _, err := env.service.UpdateComment(ctx, testStranger, comment.ID, models.UpdateCommentRequest{Content: "Поздно"}); err != ErrCommentEditExpired {
+		t.Errorf("Ожидается ошибка истекшего срока редактирования, получено: %v", err)
+	}
+}
+
+func TestCommentPremoderation(t *testing.T) {
+	env := newTestCommentService()
+	ctx := context.Background()
+	enabled := true
+
+	if _, err := env.settings.UpdateCompanySettings(ctx, testAnalyst, 1, models.UpdateCompanySettingsRequest{PremoderateComments: &enabled}); err != ErrNotCompanyOwner {
+		t.Errorf("Настройки меняет только владелец, получено: %v", err)
+	}
+	if _, err := env.settings.UpdateCompanySettings(ctx, testOwner, 1, models.UpdateCompanySettingsRequest{PremoderateComments: &enabled}); err != nil {
+		t.Fatalf("Ожидается включение премодерации, получено: %v", err)
+	}
+
+	hidden, _ := env.service.CreateComment(ctx, testStranger, env.promocode.ID, models.CreateCommentRequest{Content: "Ждет проверки"})
+	if !hidden.Hidden || hidden.ModerationStatus != models.ModerationPending {
+		t.Fatalf("Комментарий должен ждать модерации: %+v", hidden)
+	}
+	if list, _ := env.service.ListComments(ctx, testAnalyst, env.promocode.ID, models.CommentPage{}); len(list.Comments) != 0 {
+		t.Errorf("Непроверенный комментарий скрыт от других, получено: %+v", list.Comments)
+	}
+	if list, _ := env.service.ListComments(ctx, testStranger, env.promocode.ID, models.CommentPage{}); len(list.Comments) != 1 {
+		t.Errorf("Автор видит свой комментарий, получено: %+v", list.Comments)
+	}
+	if list, _ := env.service.ListComments(ctx, testModerator, env.promocode.ID, models.CommentPage{}); len(list.Comments) != 1 {
+		t.Errorf("Модератор видит скрытый комментарий, получено: %+v", list.Comments)
+	}
+	if _, err := env.service.CreateComment(ctx, testAnalyst, env.promocode.ID, models.CreateCommentRequest{Content: "Ответ", ParentID: &hidden.ID}); err != ErrInvalidParentComment {
+		t.Errorf("На скрытый комментарий нельзя ответить, получено: %v", err)
+	}
+
+	// Заявка 1 - промокод, заявка 2 - комментарий
+	moderation := newTestModerationService(env)
+	if _, err := moderation.ApproveItem(ctx, testModerator, 2); err != nil {
+		t.Fatalf("Ожидается одобрение комментария, получено: %v", err)
+	}
+	if list, _ := env.service.ListComments(ctx, testAnalyst, env.promocode.ID, models.CommentPage{}); len(list.Comments) != 1 || list.Comments[0].ModerationStatus != models.ModerationApproved {
+		t.Errorf("Одобренный комментарий виден всем, получено: %+v", list.Comments)
+	}
+
+	// Измененный текст снова уходит на проверку
+	edited, _ := env.service.UpdateComment(ctx, testStranger, hidden.ID, models.UpdateCommentRequest{Content: "Новый текст"})
+	if !edited.Hidden || edited.ModerationStatus != models.ModerationPending {
+		t.Errorf("Измененный комментарий должен снова ждать модерации: %+v", edited)
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+	"time"
+)
+
+// CompanyService убирает данные компаний, удаленных в user-service
+type CompanyService struct {
+	promocodeRepo repository.PromocodeRepositoryInterface
+	apiKeyRepo    repository.APIKeyRepositoryInterface
+	settingsRepo  repository.CompanySettingsRepositoryInterface
+}
+
+func NewCompanyService(promocodeRepo repository.PromocodeRepositoryInterface, apiKeyRepo repository.APIKeyRepositoryInterface, settingsRepo repository.CompanySettingsRepositoryInterface) *CompanyService {
+	return &CompanyService{
+		promocodeRepo: promocodeRepo,
+		apiKeyRepo:    apiKeyRepo,
+		settingsRepo:  settingsRepo,
+	}
+}
+
+func (s *CompanyService) DeleteCompany(ctx context.Context, companyID uint) error {
+	ctx, span := tracing.Start(ctx, "CompanyService.DeleteCompany")
+	defer span.End()
+
+	// Сначала ключи: кассы не должны гасить промокоды, пока остальное удаляется
+	if err := s.apiKeyRepo.RevokeCompanyAPIKeys(ctx, companyID, time.Now()); err != nil {
+		return err
+	}
+	if err := s.promocodeRepo.DeleteCompanyPromocodes(ctx, companyID); err != nil {
+		return err
+	}
+	return s.settingsRepo.DeleteCompanySettings(ctx, companyID)
+}
+
+var _ CompanyServiceInterface = (*CompanyService)(nil)
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"promocodes-service/models"
+	"testing"
+)
+
+func TestDeleteCompany(t *testing.T) {
+	promocodes := NewMockPromocodeRepository()
+	apiKeys := &MockAPIKeyRepository{}
+	settings := &MockCompanySettingsRepository{settings: make(map[uint]*models.CompanySettings)}
+	service := NewCompanyService(promocodes, apiKeys, settings)
+	ctx := context.Background()
+
+	for _, promocode := range []*models.Promocode{{CompanyID: 1, Code: "COFFEE10"}, {CompanyID: 1, Code: "TEA5"}, {CompanyID: 2, Code: "CAKE"}} {
+		promocodes.CreatePromocode(ctx, promocode)
+	}
+	apiKeys.CreateAPIKey(ctx, &models.APIKey{CompanyID: 1, KeyHash: "first"})
+	apiKeys.CreateAPIKey(ctx, &models.APIKey{CompanyID: 2, KeyHash: "second"})
+	settings.SaveCompanySettings(ctx, &models.CompanySettings{CompanyID: 1, PremoderateComments: true})
+
+	if err := service.DeleteCompany(ctx, 1); err != nil {
+		t.Fatalf("Ожидается успешное удаление данных компании, получено: %v", err)
+	}
+	if len(promocodes.promocodes) != 1 || promocodes.promocodes[3] == nil {
+		t.Errorf("Должны остаться только промокоды другой компании: %+v", promocodes.promocodes)
+	}
+	if apiKeys.keys[0].RevokedAt == nil || apiKeys.keys[1].RevokedAt != nil {
+		t.Errorf("Должны быть отозваны только ключи удаленной компании: %+v", apiKeys.keys)
+	}
+	if settings.settings[1] != nil {
+		t.Error("Настройки удаленной компании должны быть удалены")
+	}
+
+	// user-service повторяет вызов после сбоя
+	if err := service.DeleteCompany(ctx, 1); err != nil {
+		t.Errorf("Повторное удаление должно быть успешным, получено: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyReused        = errors.New("ключ идемпотентности уже использован для другого запроса")
+	ErrIdempotentRequestInProgress = errors.New("запрос с этим ключом идемпотентности еще выполняется, повторите его позже")
+)
+
+// IdempotencyService хранит ответы ttl. Резерв, не завершенный за lockTimeout, считается брошенным:
+// lockTimeout должен быть больше времени, за которое запрос гарантированно завершается.
+type IdempotencyService struct {
+	idempotencyRepo repository.IdempotencyRepositoryInterface
+	lockTimeout     time.Duration
+	ttl             time.Duration
+}
+
+func NewIdempotencyService(idempotencyRepo repository.IdempotencyRepositoryInterface, lockTimeout, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		lockTimeout:     lockTimeout,
+		ttl:             ttl,
+	}
+}
+
+func (s *IdempotencyService) Begin(ctx context.Context, apiKeyID uint, key string, requestHash string) (*models.IdempotencyRecord, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	record := &models.IdempotencyRecord{APIKeyID: apiKeyID, IdempotencyKey: key, RequestHash: requestHash}
+	created, err := s.idempotencyRepo.CreateRecord(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return record, nil
+	}
+
+	existing, err := s.idempotencyRepo.GetRecord(ctx, apiKeyID, key)
+	if err != nil {
+		return nil, err
+	}
+	// Записи нет, если первый запрос только что завершился ошибкой и снял резерв
+	if existing == nil {
+		return nil, ErrIdempotentRequestInProgress
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode != 0 {
+		return existing, nil
+	}
+	// Экземпляр, зарезервировавший ключ, упал, не сохранив ответ: запрос выполняется заново
+	if time.Since(existing.UpdatedAt) >= s.lockTimeout {
+		takenOver, err := s.idempotencyRepo.TakeOverRecord(ctx, existing, time.Now().Add(-s.lockTimeout))
+		if err != nil {
+			return nil, err
+		}
+		if takenOver {
+			return existing, nil
+		}
+	}
+	return nil, ErrIdempotentRequestInProgress
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, record *models.IdempotencyRecord, statusCode int, response []byte) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Complete")
+	defer span.End()
+
+	record.StatusCode = statusCode
+	record.Response = response
+	return s.idempotencyRepo.CompleteRecord(ctx, record)
+}
+
+func (s *IdempotencyService) Abandon(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Abandon")
+	defer span.End()
+
+	return s.idempotencyRepo.DeleteRecord(ctx, record.ID)
+}
+
+// Run раз в interval удаляет записи старше ttl: после этого ключ идемпотентности можно использовать снова
+func (s *IdempotencyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.deleteExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *IdempotencyService) deleteExpired(ctx context.Context) {
+	deleted, err := s.idempotencyRepo.DeleteRecordsBefore(ctx, time.Now().Add(-s.ttl))
+	if err != nil {
+		log.Printf("Ошибка удаления устаревших записей идемпотентности: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Удалено устаревших записей идемпотентности: %d", deleted)
+	}
+}
+
+var _ IdempotencyServiceInterface = (*IdempotencyService)(nil)
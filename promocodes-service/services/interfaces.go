@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"io"
+	"promocodes-service/models"
+)
+
+type PromocodeServiceInterface interface {
+	// CreatePromocode, UpdatePromocode и DeletePromocode доступны владельцам и менеджерам компании.
+	// Новые и измененные промокоды попадают в очередь модерации. Создавать промокоды можно только
+	// компаниям, которые одобрил модератор User Service.
+	CreatePromocode(ctx context.Context, actor *models.Identity, req models.CreatePromocodeRequest) (*models.Promocode, error)
+	// GetPromocode и ListPromocodes показывают промокоды до одобрения только команде компании и модераторам
+	GetPromocode(ctx context.Context, actor *models.Identity, id uint) (*models.Promocode, error)
+	ListPromocodes(ctx context.Context, actor *models.Identity, filter models.PromocodeFilter) (*models.PromocodeList, error)
+	UpdatePromocode(ctx context.Context, actor *models.Identity, id uint, req models.UpdatePromocodeRequest) (*models.Promocode, error)
+	DeletePromocode(ctx context.Context, actor *models.Identity, id uint) error
+}
+
+type CommentServiceInterface interface {
+	CreateComment(ctx context.Context, actor *models.Identity, promocodeID uint, req models.CreateCommentRequest) (*models.Comment, error)
+	// Комментарии к промокоду, который модератор еще не одобрил, видят и пишут только команда компании
+	// и модераторы. ListComments и ListReplies показывают скрытые комментарии только их авторам и модераторам.
+	ListComments(ctx context.Context, actor *models.Identity, promocodeID uint, page models.CommentPage) (*models.CommentList, error)
+	ListReplies(ctx context.Context, actor *models.Identity, commentID uint, page models.CommentPage) (*models.CommentList, error)
+	UpdateComment(ctx context.Context, actor *models.Identity, id uint, req models.UpdateCommentRequest) (*models.Comment, error)
+	DeleteComment(ctx context.Context, actor *models.Identity, id uint) error
+}
+
+type SettingsServiceInterface interface {
+	GetCompanySettings(ctx context.Context, companyID uint) (*models.CompanySettings, error)
+	// UpdateCompanySettings доступен владельцам компании и администраторам
+	UpdateCompanySettings(ctx context.Context, actor *models.Identity, companyID uint, req models.UpdateCompanySettingsRequest) (*models.CompanySettings, error)
+}
+
+type CompanyServiceInterface interface {
+	// DeleteCompany отзывает API-ключи компании и удаляет ее промокоды и настройки.
+	// Повторный вызов ничего не меняет, поэтому user-service может повторять его после сбоя.
+	DeleteCompany(ctx context.Context, companyID uint) error
+}
+
+type ModerationServiceInterface interface {
+	// ListQueue, ClaimItem, ReleaseItem, ApproveItem, RejectItem и ListAudit доступны модераторам и администраторам
+	ListQueue(ctx context.Context, actor *models.Identity, filter models.ModerationFilter) (*models.ModerationItemList, error)
+	ListSubmissions(ctx context.Context, actor *models.Identity, filter models.ModerationFilter) (*models.ModerationItemList, error)
+	ClaimItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error)
+	ReleaseItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error)
+	ApproveItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error)
+	RejectItem(ctx context.Context, actor *models.Identity, id uint, reason string) (*models.ModerationItem, error)
+	ListAudit(ctx context.Context, actor *models.Identity, filter models.ModerationAuditFilter) (*models.ModerationAuditList, error)
+}
+
+type RedemptionServiceInterface interface {
+	Redeem(ctx context.Context, actor *models.Identity, req models.RedeemRequest) (*models.Redemption, error)
+	// RedeemAtMerchant гасит промокод компании ключа apiKey от имени анонимного покупателя
+	RedeemAtMerchant(ctx context.Context, apiKey *models.APIKey, req models.RedeemRequest) (*models.MerchantRedemption, error)
+	ListUserRedemptions(ctx context.Context, actor *models.Identity, filter models.RedemptionFilter) (*models.RedemptionList, error)
+	// ListPromocodeRedemptions доступен участникам команды компании и администраторам
+	ListPromocodeRedemptions(ctx context.Context, actor *models.Identity, promocodeID uint, filter models.RedemptionFilter) (*models.RedemptionList, error)
+}
+
+type CodeBatchServiceInterface interface {
+	// Все операции с партиями доступны владельцам и менеджерам компании и администраторам
+	CreateBatch(ctx context.Context, actor *models.Identity, promocodeID uint, req models.GenerateCodesRequest) (*models.CodeBatch, error)
+	GetBatch(ctx context.Context, actor *models.Identity, id uint) (*models.CodeBatch, error)
+	ListBatches(ctx context.Context, actor *models.Identity, promocodeID uint, filter models.CodeBatchFilter) (*models.CodeBatchList, error)
+	ExportBatch(ctx context.Context, actor *models.Identity, id uint, w io.Writer) error
+}
+
+type APIKeyServiceInterface interface {
+	// CreateAPIKey, ListAPIKeys и RevokeAPIKey доступны владельцам компании и администраторам
+	CreateAPIKey(ctx context.Context, actor *models.Identity, companyID uint, req models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, actor *models.Identity, companyID uint) (*models.APIKeyList, error)
+	RevokeAPIKey(ctx context.Context, actor *models.Identity, companyID uint, id uint) error
+	// Authenticate возвращает действующий ключ или ErrInvalidAPIKey
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+}
+
+type IdempotencyServiceInterface interface {
+	// Begin резервирует ключ идемпотентности за запросом и возвращает новую запись. Если запрос с этим
+	// ключом уже выполнен, возвращает запись с сохраненным ответом (StatusCode не ноль), который нужно
+	// отдать клиенту вместо повторного выполнения. Брошенный резерв возвращается повтору для выполнения заново.
+	Begin(ctx context.Context, apiKeyID uint, key string, requestHash string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord, statusCode int, response []byte) error
+	// Abandon снимает резерв с запроса, который не удалось выполнить, чтобы его повтор выполнился заново
+	Abandon(ctx context.Context, record *models.IdempotencyRecord) error
+}
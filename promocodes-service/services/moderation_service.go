@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+	"promocodes-service/userservice"
+	"time"
+)
+
+var (
+	ErrNotModerator             = errors.New("действие доступно только модераторам")
+	ErrModerationItemNotFound   = errors.New("заявка на модерацию не найдена")
+	ErrModerationItemClosed     = errors.New("по заявке уже принято решение")
+	ErrModerationItemSuperseded = errors.New("автор изменил контент, заявку заменила новая")
+	ErrModerationItemClaimed    = errors.New("заявку взял в работу другой модератор")
+	ErrModerationItemNotClaimed = errors.New("вы не брали эту заявку в работу")
+	ErrUnknownModerationStatus  = errors.New("неизвестный статус модерации")
+	ErrUnknownModerationEntity  = errors.New("неизвестный вид контента")
+)
+
+type ModerationService struct {
+	moderationRepo repository.ModerationRepositoryInterface
+	promocodeRepo  repository.PromocodeRepositoryInterface
+	commentRepo    repository.CommentRepositoryInterface
+	users          userservice.Client
+	claimTTL       time.Duration
+}
+
+// NewModerationService: claimTTL - через сколько захват заявки перестает мешать другим модераторам;
+// users отправляет авторам письма о решениях
+func NewModerationService(moderationRepo repository.ModerationRepositoryInterface, promocodeRepo repository.PromocodeRepositoryInterface, commentRepo repository.CommentRepositoryInterface, users userservice.Client, claimTTL time.Duration) *ModerationService {
+	return &ModerationService{
+		moderationRepo: moderationRepo,
+		promocodeRepo:  promocodeRepo,
+		commentRepo:    commentRepo,
+		users:          users,
+		claimTTL:       claimTTL,
+	}
+}
+
+// ListQueue по умолчанию показывает заявки, которые ждут решения, в порядке поступления
+func (s *ModerationService) ListQueue(ctx context.Context, actor *models.Identity, filter models.ModerationFilter) (*models.ModerationItemList, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.ListQueue")
+	defer span.End()
+
+	if !actor.IsModerator() {
+		return nil, ErrNotModerator
+	}
+	if filter.Status == "" {
+		filter.Status = models.ModerationPending
+	}
+	filter.AuthorID = 0
+	return s.listItems(ctx, filter)
+}
+
+// ListSubmissions показывает автору его заявки вместе с решениями и причинами отказа
+func (s *ModerationService) ListSubmissions(ctx context.Context, actor *models.Identity, filter models.ModerationFilter) (*models.ModerationItemList, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.ListSubmissions")
+	defer span.End()
+
+	filter.AuthorID = actor.UserID
+	return s.listItems(ctx, filter)
+}
+
+func (s *ModerationService) ClaimItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.ClaimItem")
+	defer span.End()
+
+	audit, err := s.newAudit(ctx, actor, id, models.ModerationActionClaim, "")
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := s.moderationRepo.ClaimItem(ctx, audit, audit.CreatedAt.Add(-s.claimTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, s.conflict(ctx, id, ErrModerationItemClaimed)
+	}
+	return s.getItem(ctx, id)
+}
+
+func (s *ModerationService) ReleaseItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.ReleaseItem")
+	defer span.End()
+
+	audit, err := s.newAudit(ctx, actor, id, models.ModerationActionRelease, "")
+	if err != nil {
+		return nil, err
+	}
+	released, err := s.moderationRepo.ReleaseItem(ctx, audit)
+	if err != nil {
+		return nil, err
+	}
+	if !released {
+		return nil, s.conflict(ctx, id, ErrModerationItemNotClaimed)
+	}
+	return s.getItem(ctx, id)
+}
+
+func (s *ModerationService) ApproveItem(ctx context.Context, actor *models.Identity, id uint) (*models.ModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.ApproveItem")
+	defer span.End()
+
+	return s.decide(ctx, actor, id, models.ModerationApproved, models.ModerationActionApprove, "")
+}
+
+func (s *ModerationService) RejectItem(ctx context.Context, actor *models.Identity, id uint, reason string) (*models.ModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.RejectItem")
+	defer span.End()
+
+	return s.decide(ctx, actor, id, models.ModerationRejected, models.ModerationActionReject, reason)
+}
+
+func (s *ModerationService) ListAudit(ctx context.Context, actor *models.Identity, filter models.ModerationAuditFilter) (*models.ModerationAuditList, error) {
+	ctx, span := tracing.Start(ctx, "ModerationService.ListAudit")
+	defer span.End()
+
+	if !actor.IsModerator() {
+		return nil, ErrNotModerator
+	}
+	if filter.EntityType != "" && !filter.EntityType.Valid() {
+		return nil, ErrUnknownModerationEntity
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+
+	records, total, err := s.moderationRepo.ListAudit(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		records = []models.ModerationAudit{}
+	}
+	return &models.ModerationAuditList{
+		Records:  records,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+func (s *ModerationService) decide(ctx context.Context, actor *models.Identity, id uint, status models.ModerationStatus, action models.ModerationAction, reason string) (*models.ModerationItem, error) {
+	audit, err := s.newAudit(ctx, actor, id, action, reason)
+	if err != nil {
+		return nil, err
+	}
+	decided, err := s.moderationRepo.DecideItem(ctx, audit, status, audit.CreatedAt.Add(-s.claimTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !decided {
+		return nil, s.conflict(ctx, id, ErrModerationItemClaimed)
+	}
+	metrics.ModerationDecisions.WithLabelValues(string(audit.EntityType), string(status)).Inc()
+	item, err := s.getItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Решение уже сохранено, поэтому ошибка отправки письма его не отменяет
+	if err := s.notifyAuthor(ctx, item); err != nil {
+		log.Printf("Ошибка уведомления автора о решении по заявке %d: %v", item.ID, err)
+	}
+	return item, nil
+}
+
+// notifyAuthor сообщает автору решение модератора по заявке и причину отказа
+func (s *ModerationService) notifyAuthor(ctx context.Context, item *models.ModerationItem) error {
+	promocodeID := item.EntityID
+	subject := "Промокод %s"
+	if item.EntityType == models.ModerationEntityComment {
+		comment, err := s.commentRepo.GetComment(ctx, item.EntityID)
+		if err != nil || comment == nil {
+			return err
+		}
+		promocodeID = comment.PromocodeID
+		subject = "Комментарий к промокоду %s"
+	}
+	promocode, err := s.promocodeRepo.GetPromocodeByID(ctx, promocodeID)
+	if err != nil || promocode == nil {
+		return err
+	}
+	subject = fmt.Sprintf(subject, promocode.Code)
+
+	notification := userservice.Notification{}
+	if item.Status == models.ModerationApproved {
+		notification.Subject = subject + " прошел модерацию"
+		notification.Body = fmt.Sprintf("Здравствуйте!\n\n%s прошел модерацию и опубликован.", subject)
+	} else {
+		notification.Subject = subject + " отклонен модератором"
+		notification.Body = fmt.Sprintf("Здравствуйте!\n\n%s отклонен модератором.\nПричина: %s\n\nИсправьте его, и он снова попадет на проверку.", subject, item.Reason)
+	}
+	return s.users.NotifyUser(ctx, item.AuthorID, notification)
+}
+
+// newAudit готовит запись журнала о действии модератора над заявкой id
+func (s *ModerationService) newAudit(ctx context.Context, actor *models.Identity, id uint, action models.ModerationAction, reason string) (*models.ModerationAudit, error) {
+	if !actor.IsModerator() {
+		return nil, ErrNotModerator
+	}
+	item, err := s.getItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != models.ModerationPending {
+		return nil, closedError(item)
+	}
+	return &models.ModerationAudit{
+		ItemID:      item.ID,
+		EntityType:  item.EntityType,
+		EntityID:    item.EntityID,
+		ModeratorID: actor.UserID,
+		Action:      action,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// conflict объясняет, почему условное изменение заявки не прошло: по ней уже приняли решение,
+// контент изменился или заявку держит другой модератор (busy)
+func (s *ModerationService) conflict(ctx context.Context, id uint, busy error) error {
+	item, err := s.getItem(ctx, id)
+	if err != nil {
+		return err
+	}
+	if item.Status != models.ModerationPending {
+		return closedError(item)
+	}
+	return busy
+}
+
+// closedError - ошибка для заявки, которая больше не ждет решения
+func closedError(item *models.ModerationItem) error {
+	if item.Status == models.ModerationSuperseded {
+		return ErrModerationItemSuperseded
+	}
+	return ErrModerationItemClosed
+}
+
+func (s *ModerationService) getItem(ctx context.Context, id uint) (*models.ModerationItem, error) {
+	item, err := s.moderationRepo.GetItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrModerationItemNotFound
+	}
+	return item, nil
+}
+
+func (s *ModerationService) listItems(ctx context.Context, filter models.ModerationFilter) (*models.ModerationItemList, error) {
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, ErrUnknownModerationStatus
+	}
+	if filter.EntityType != "" && !filter.EntityType.Valid() {
+		return nil, ErrUnknownModerationEntity
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+
+	items, total, err := s.moderationRepo.ListItems(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []models.ModerationItem{}
+	}
+	return &models.ModerationItemList{
+		Items:    items,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+// submitForModeration ставит новую или измененную версию контента в очередь модерации
+func submitForModeration(ctx context.Context, moderationRepo repository.ModerationRepositoryInterface, entityType models.ModerationEntity, entityID, companyID, authorID uint) error {
+	return moderationRepo.SubmitItem(ctx, &models.ModerationItem{
+		EntityType: entityType,
+		EntityID:   entityID,
+		CompanyID:  companyID,
+		AuthorID:   authorID,
+	})
+}
+
+var _ ModerationServiceInterface = (*ModerationService)(nil)
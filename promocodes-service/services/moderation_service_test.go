@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MockModerationRepository переносит решения на промокоды и комментарии из соседних моков
+type MockModerationRepository struct {
+	items      map[uint]*models.ModerationItem
+	audit      []models.ModerationAudit
+	idCounter  uint
+	promocodes *MockPromocodeRepository
+	comments   *MockCommentRepository
+}
+
+var _ repository.ModerationRepositoryInterface = (*MockModerationRepository)(nil)
+
+func NewMockModerationRepository(promocodes *MockPromocodeRepository, comments *MockCommentRepository) *MockModerationRepository {
+	return &MockModerationRepository{items: make(map[uint]*models.ModerationItem), promocodes: promocodes, comments: comments}
+}
+
+func (r *MockModerationRepository) SubmitItem(ctx context.Context, item *models.ModerationItem) error {
+	for _, pending := range r.items {
+		if pending.EntityType == item.EntityType && pending.EntityID == item.EntityID && pending.Status == models.ModerationPending {
+			pending.Status = models.ModerationSuperseded
+		}
+	}
+	r.idCounter++
+	item.ID = r.idCounter
+	item.Status = models.ModerationPending
+	item.ContentHash = r.contentHash(item.EntityType, item.EntityID)
+	stored := *item
+	r.items[item.ID] = &stored
+	return nil
+}
+
+func (r *MockModerationRepository) GetItem(ctx context.Context, id uint) (*models.ModerationItem, error) {
+	item, exists := r.items[id]
+	if !exists {
+		return nil, nil
+	}
+	stored := *item
+	return &stored, nil
+}
+
+func (r *MockModerationRepository) ListItems(ctx context.Context, filter models.ModerationFilter) ([]models.ModerationItem, int64, error) {
+	var items []models.ModerationItem
+	for id := uint(1); id <= r.idCounter; id++ {
+		item, exists := r.items[id]
+		if !exists || (filter.Status != "" && item.Status != filter.Status) || (filter.EntityType != "" && item.EntityType != filter.EntityType) ||
+			(filter.AuthorID != 0 && item.AuthorID != filter.AuthorID) {
+			continue
+		}
+		items = append(items, *item)
+	}
+	return items, int64(len(items)), nil
+}
+
+func (r *MockModerationRepository) available(audit *models.ModerationAudit, staleBefore time.Time) *models.ModerationItem {
+	item, exists := r.items[audit.ItemID]
+	if !exists || item.Status != models.ModerationPending {
+		return nil
+	}
+	if item.ClaimedBy != nil && *item.ClaimedBy != audit.ModeratorID && !item.ClaimedAt.Before(staleBefore) {
+		return nil
+	}
+	return item
+}
+
+func (r *MockModerationRepository) ClaimItem(ctx context.Context, audit *models.ModerationAudit, staleBefore time.Time) (bool, error) {
+	item := r.available(audit, staleBefore)
+	if item == nil {
+		return false, nil
+	}
+	moderatorID, claimedAt := audit.ModeratorID, audit.CreatedAt
+	item.ClaimedBy, item.ClaimedAt = &moderatorID, &claimedAt
+	r.audit = append(r.audit, *audit)
+	return true, nil
+}
+
+func (r *MockModerationRepository) ReleaseItem(ctx context.Context, audit *models.ModerationAudit) (bool, error) {
+	item, exists := r.items[audit.ItemID]
+	if !exists || item.Status != models.ModerationPending || item.ClaimedBy == nil || *item.ClaimedBy != audit.ModeratorID {
+		return false, nil
+	}
+	item.ClaimedBy, item.ClaimedAt = nil, nil
+	r.audit = append(r.audit, *audit)
+	return true, nil
+}
+
+func (r *MockModerationRepository) DecideItem(ctx context.Context, audit *models.ModerationAudit, status models.ModerationStatus, staleBefore time.Time) (bool, error) {
+	item := r.available(audit, staleBefore)
+	if item == nil {
+		return false, nil
+	}
+	if item.ContentHash != r.contentHash(audit.EntityType, audit.EntityID) {
+		item.Status = models.ModerationSuperseded
+		return false, nil
+	}
+	moderatorID, decidedAt := audit.ModeratorID, audit.CreatedAt
+	item.Status, item.ModeratorID, item.Reason, item.DecidedAt = status, &moderatorID, audit.Reason, &decidedAt
+	switch audit.EntityType {
+	case models.ModerationEntityPromocode:
+		if promocode, exists := r.promocodes.promocodes[audit.EntityID]; exists {
+			promocode.ModerationStatus = status
+		}
+	case models.ModerationEntityComment:
+		if comment, exists := r.comments.comments[audit.EntityID]; exists {
+			comment.ModerationStatus = status
+			comment.Hidden = status == models.ModerationRejected
+		}
+	}
+	r.audit = append(r.audit, *audit)
+	return true, nil
+}
+
+// contentHash - отпечаток текущей версии контента из соседних моков
+func (r *MockModerationRepository) contentHash(entityType models.ModerationEntity, entityID uint) string {
+	switch entityType {
+	case models.ModerationEntityPromocode:
+		if promocode, exists := r.promocodes.promocodes[entityID]; exists {
+			return promocode.ModerationHash()
+		}
+	case models.ModerationEntityComment:
+		if comment, exists := r.comments.comments[entityID]; exists {
+			return comment.ModerationHash()
+		}
+	}
+	return ""
+}
+
+func (r *MockModerationRepository) ListAudit(ctx context.Context, filter models.ModerationAuditFilter) ([]models.ModerationAudit, int64, error) {
+	var records []models.ModerationAudit
+	for i := len(r.audit) - 1; i >= 0; i-- {
+		record := r.audit[i]
+		if (filter.ItemID != 0 && record.ItemID != filter.ItemID) || (filter.ModeratorID != 0 && record.ModeratorID != filter.ModeratorID) {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, int64(len(records)), nil
+}
+
+var testSecondModerator = &models.Identity{UserID: 6, Roles: []string{models.RoleModerator}}
+
+func newTestModerationService(env *testComments) *ModerationService {
+	return NewModerationService(env.moderation, env.promocodes, env.comments, &MockCompanyClient{}, 30*time.Minute)
+}
+
+func TestModerationNotifiesAuthors(t *testing.T) {
+	env := newTestCommentService()
+	service := newTestModerationService(env)
+	users := service.users.(*MockCompanyClient)
+	ctx := context.Background()
+	env.service.CreateComment(ctx, testStranger, env.promocode.ID, models.CreateCommentRequest{Content: "Работает?"})
+
+	if _, err := service.ApproveItem(ctx, testModerator, 1); err != nil {
+		t.Fatalf("Ожидается одобрение, получено: %v", err)
+	}
+	if _, err := service.RejectItem(ctx, testModerator, 2, "Реклама"); err != nil {
+		t.Fatalf("Ожидается отклонение, получено: %v", err)
+	}
+
+	approved := users.notifications[testOwner.UserID]
+	if len(approved) != 1 || !strings.Contains(approved[0].Subject, "COFFEE10") || !strings.Contains(approved[0].Subject, "прошел модерацию") {
+		t.Errorf("Автор промокода должен узнать об одобрении, получено: %+v", approved)
+	}
+	rejected := users.notifications[testStranger.UserID]
+	if len(rejected) != 1 || !strings.Contains(rejected[0].Subject, "Комментарий к промокоду COFFEE10") || !strings.Contains(rejected[0].Body, "Причина: Реклама") {
+		t.Errorf("Автор комментария должен узнать об отказе и его причине, получено: %+v", rejected)
+	}
+}
+
+func TestModerationQueue(t *testing.T) {
+	env := newTestCommentService()
+	service := newTestModerationService(env)
+	ctx := context.Background()
+	comment, _ := env.service.CreateComment(ctx, testStranger, env.promocode.ID, models.CreateCommentRequest{Content: "Работает?"})
+
+	if _, err := service.ListQueue(ctx, testStranger, models.ModerationFilter{}); err != ErrNotModerator {
+		t.Errorf("Очередь видна только модераторам, получено: %v", err)
+	}
+	queue, err := service.ListQueue(ctx, testModerator, models.ModerationFilter{})
+	if err != nil || queue.Total != 2 || queue.Items[0].EntityType != models.ModerationEntityPromocode || queue.Items[1].EntityID != comment.ID {
+		t.Fatalf("Ожидается промокод и комментарий в очереди, получено: %+v, %v", queue, err)
+	}
+	promocodeItem, commentItem := queue.Items[0], queue.Items[1]
+
+	if _, err := service.ClaimItem(ctx, testStranger, promocodeItem.ID); err != ErrNotModerator {
+		t.Errorf("Брать заявки могут только модераторы, получено: %v", err)
+	}
+	claimed, err := service.ClaimItem(ctx, testModerator, promocodeItem.ID)
+	if err != nil || claimed.ClaimedBy == nil || *claimed.ClaimedBy != testModerator.UserID {
+		t.Fatalf("Ожидается захват заявки, получено: %+v, %v", claimed, err)
+	}
+	if _, err := service.ClaimItem(ctx, testSecondModerator, promocodeItem.ID); err != ErrModerationItemClaimed {
+		t.Errorf("Занятую заявку нельзя взять, получено: %v", err)
+	}
+	if _, err := service.ApproveItem(ctx, testSecondModerator, promocodeItem.ID); err != ErrModerationItemClaimed {
+		t.Errorf("По занятой заявке решает только взявший ее модератор, получено: %v", err)
+	}
+	if _, err := service.ReleaseItem(ctx, testSecondModerator, promocodeItem.ID); err != ErrModerationItemNotClaimed {
+		t.Errorf("Освободить можно только свою заявку, получено: %v", err)
+	}
+
+	if _, err := service.ApproveItem(ctx, testModerator, promocodeItem.ID); err != nil {
+		t.Fatalf("Ожидается одобрение, получено: %v", err)
+	}
+	if env.promocodes.promocodes[env.promocode.ID].ModerationStatus != models.ModerationApproved {
+		t.Error("Решение должно переноситься на промокод")
+	}
+	if _, err := service.RejectItem(ctx, testModerator, promocodeItem.ID, "Поздно"); err != ErrModerationItemClosed {
+		t.Errorf("Повторное решение невозможно, получено: %v", err)
+	}
+
+	// Захват, который держится дольше claimTTL, не мешает другим модераторам
+	service.ClaimItem(ctx, testModerator, commentItem.ID)
+	staleAt := time.Now().Add(-time.Hour)
+	env.moderation.items[commentItem.ID].ClaimedAt = &staleAt
+	if _, err := service.RejectItem(ctx, testSecondModerator, commentItem.ID, "Реклама"); err != nil {
+		t.Fatalf("Ожидается отклонение после устаревшего захвата, получено: %v", err)
+	}
+	if stored := env.comments.comments[comment.ID]; stored.ModerationStatus != models.ModerationRejected || !stored.Hidden {
+		t.Errorf("Отклоненный комментарий должен быть скрыт: %+v", stored)
+	}
+
+	audit, _ := service.ListAudit(ctx, testModerator, models.ModerationAuditFilter{ItemID: commentItem.ID})
+	if audit.Total != 2 || audit.Records[0].Action != models.ModerationActionReject || audit.Records[0].ModeratorID != testSecondModerator.UserID || audit.Records[0].Reason != "Реклама" {
+		t.Errorf("Журнал должен содержать захват и отклонение, получено: %+v", audit.Records)
+	}
+	if _, err := service.ListAudit(ctx, testOwner, models.ModerationAuditFilter{}); err != ErrNotModerator {
+		t.Errorf("Журнал виден только модераторам, получено: %v", err)
+	}
+}
+
+func TestModerationResubmission(t *testing.T) {
+	env := newTestCommentService()
+	service := newTestModerationService(env)
+	ctx := context.Background()
+	promocodes := NewPromocodeService(env.promocodes, env.moderation, NewMockCodeRepository(env.promocodes), &MockCompanyClient{})
+
+	// Изменение после захвата закрывает заявку: модератор не видел новую версию
+	service.ClaimItem(ctx, testModerator, 1)
+	promocodes.UpdatePromocode(ctx, testOwner, env.promocode.ID, models.UpdatePromocodeRequest{Title: "Кофе со скидкой"})
+	if _, err := service.ApproveItem(ctx, testModerator, 1); err != ErrModerationItemSuperseded {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrModerationItemSuperseded, err)
+	}
+	queue, _ := service.ListQueue(ctx, testModerator, models.ModerationFilter{})
+	if queue.Total != 1 || queue.Items[0].ID != 2 || queue.Items[0].ClaimedBy != nil {
+		t.Fatalf("Ожидается новая свободная заявка, получено: %+v", queue.Items)
+	}
+	service.RejectItem(ctx, testModerator, 2, "Неполное описание")
+
+	submissions, _ := service.ListSubmissions(ctx, testOwner, models.ModerationFilter{})
+	if submissions.Total != 2 || submissions.Items[0].Status != models.ModerationSuperseded ||
+		submissions.Items[1].Status != models.ModerationRejected || submissions.Items[1].Reason != "Неполное описание" {
+		t.Errorf("Автор видит решение и причину, получено: %+v", submissions.Items)
+	}
+	if submissions, _ := service.ListSubmissions(ctx, testStranger, models.ModerationFilter{}); submissions.Total != 0 {
+		t.Errorf("Чужие заявки не видны, получено: %+v", submissions.Items)
+	}
+
+	updated, _ := promocodes.UpdatePromocode(ctx, testOwner, env.promocode.ID, models.UpdatePromocodeRequest{Description: "Только по утрам"})
+	if updated.ModerationStatus != models.ModerationPending {
+		t.Errorf("Исправленный промокод снова ждет проверки, получено: %s", updated.ModerationStatus)
+	}
+	if queue, _ := service.ListQueue(ctx, testModerator, models.ModerationFilter{}); queue.Total != 1 || queue.Items[0].ID != 3 {
+		t.Errorf("Ожидается новая заявка, получено: %+v", queue.Items)
+	}
+
+	// Контент изменился, а новая заявка еще не подана: решение по старой версии не принимается
+	env.promocodes.promocodes[env.promocode.ID].Title = "Кофе без очереди"
+	if _, err := service.ApproveItem(ctx, testModerator, 3); err != ErrModerationItemSuperseded {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrModerationItemSuperseded, err)
+	}
+	if env.promocodes.promocodes[env.promocode.ID].ModerationStatus != models.ModerationPending {
+		t.Error("Непроверенная версия не должна одобряться")
+	}
+	if _, err := service.ListQueue(ctx, testModerator, models.ModerationFilter{Status: "unknown"}); err != ErrUnknownModerationStatus {
+		t.Errorf("Ожидается ошибка неизвестного статуса, получено: %v", err)
+	}
+}
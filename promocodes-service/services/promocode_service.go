@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+	"promocodes-service/userservice"
+	"strings"
+)
+
+const defaultPageSize = 20
+
+var (
+	ErrPromocodeNotFound     = errors.New("промокод не найден")
+	ErrCodeTaken             = errors.New("промокод с таким кодом уже существует")
+	ErrUnknownPromocodeType  = errors.New("неизвестный тип промокода")
+	ErrNotCompanyPromoEditor = errors.New("промокодами компании управляют только ее владельцы и менеджеры")
+	ErrInvalidValidityPeriod = errors.New("срок действия промокода должен заканчиваться позже, чем начинается")
+	ErrInvalidSegment        = errors.New("сегмент может содержать только строчные латинские буквы, цифры, \"_\" и \"-\" (до 32 символов)")
+	ErrCompanyNotApproved    = errors.New("промокоды можно создавать только после одобрения компании модератором")
+)
+
+// Роли в команде, которым разрешено изменять промокоды компании; аналитики их только просматривают
+var editorRoles = []models.CompanyRole{models.CompanyRoleOwner, models.CompanyRoleManager}
+
+type PromocodeService struct {
+	promocodeRepo  repository.PromocodeRepositoryInterface
+	moderationRepo repository.ModerationRepositoryInterface
+	codeRepo       repository.CodeRepositoryInterface
+	companies      userservice.Client
+}
+
+func NewPromocodeService(promocodeRepo repository.PromocodeRepositoryInterface, moderationRepo repository.ModerationRepositoryInterface, codeRepo repository.CodeRepositoryInterface, companies userservice.Client) *PromocodeService {
+	return &PromocodeService{
+		promocodeRepo:  promocodeRepo,
+		moderationRepo: moderationRepo,
+		codeRepo:       codeRepo,
+		companies:      companies,
+	}
+}
+
+func (s *PromocodeService) CreatePromocode(ctx context.Context, actor *models.Identity, req models.CreatePromocodeRequest) (*models.Promocode, error) {
+	ctx, span := tracing.Start(ctx, "PromocodeService.CreatePromocode")
+	defer span.End()
+
+	if !req.Type.Valid() {
+		return nil, ErrUnknownPromocodeType
+	}
+	if err := validateSegments(req.Segments); err != nil {
+		return nil, err
+	}
+	if err := authorize(actor, req.CompanyID); err != nil {
+		return nil, err
+	}
+	// Статус компании меняется в User Service, поэтому берется оттуда, а не из токена
+	company, err := s.companies.GetCompany(ctx, req.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	if company == nil || company.ModerationStatus != models.ModerationApproved {
+		return nil, ErrCompanyNotApproved
+	}
+
+	// Коды сравниваются без учета регистра: покупатель может ввести их как угодно
+	code := strings.ToUpper(req.Code)
+	existing, err := s.promocodeRepo.GetPromocodeByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCodeTaken
+	}
+	// Общий код не должен совпадать с одноразовыми кодами из партий. Проверки выше лишь дают быстрый ответ:
+	// параллельный запрос мог занять код после них, это обнаружит CreatePromocode
+	uniqueCode, err := s.codeRepo.GetUniqueCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if uniqueCode != nil {
+		return nil, ErrCodeTaken
+	}
+
+	promocode := &models.Promocode{
+		CompanyID:   req.CompanyID,
+		CreatorID:   actor.UserID,
+		Code:        code,
+		Title:       req.Title,
+		Description: req.Description,
+		Type:        req.Type,
+		// Новый промокод ждет проверки модератором
+		ModerationStatus:      models.ModerationPending,
+		ValidFrom:             req.ValidFrom,
+		ValidUntil:            req.ValidUntil,
+		MaxRedemptions:        req.MaxRedemptions,
+		MaxRedemptionsPerUser: req.MaxRedemptionsPerUser,
+		Segments:              models.NormalizeSegments(req.Segments),
+	}
+	if err := validatePeriod(promocode); err != nil {
+		return nil, err
+	}
+	created, err := s.promocodeRepo.CreatePromocode(ctx, promocode)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrCodeTaken
+	}
+	if err := submitForModeration(ctx, s.moderationRepo, models.ModerationEntityPromocode, promocode.ID, promocode.CompanyID, actor.UserID); err != nil {
+		return nil, err
+	}
+	metrics.PromocodeChanges.WithLabelValues("create").Inc()
+	return promocode, nil
+}
+
+func (s *PromocodeService) GetPromocode(ctx context.Context, actor *models.Identity, id uint) (*models.Promocode, error) {
+	ctx, span := tracing.Start(ctx, "PromocodeService.GetPromocode")
+	defer span.End()
+
+	return visiblePromocode(ctx, s.promocodeRepo, actor, id)
+}
+
+func (s *PromocodeService) getPromocode(ctx context.Context, id uint) (*models.Promocode, error) {
+	promocode, err := s.promocodeRepo.GetPromocodeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promocode == nil {
+		return nil, ErrPromocodeNotFound
+	}
+	return promocode, nil
+}
+
+func (s *PromocodeService) ListPromocodes(ctx context.Context, actor *models.Identity, filter models.PromocodeFilter) (*models.PromocodeList, error) {
+	ctx, span := tracing.Start(ctx, "PromocodeService.ListPromocodes")
+	defer span.End()
+
+	if filter.Type != "" && !filter.Type.Valid() {
+		return nil, ErrUnknownPromocodeType
+	}
+	if filter.ModerationStatus != "" && !filter.ModerationStatus.Valid() {
+		return nil, ErrUnknownModerationStatus
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+	// Модераторы видят все промокоды, остальные - одобренные и промокоды своих компаний,
+	// в том числе при поиске по moderation_status
+	filter.Visibility = nil
+	if !actor.IsModerator() {
+		filter.Visibility = &models.PromocodeVisibility{}
+		for companyID := range actor.Companies {
+			filter.Visibility.CompanyIDs = append(filter.Visibility.CompanyIDs, companyID)
+		}
+	}
+
+	promocodes, total, err := s.promocodeRepo.ListPromocodes(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if promocodes == nil {
+		promocodes = []models.Promocode{}
+	}
+	return &models.PromocodeList{
+		Promocodes: promocodes,
+		Total:      total,
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+	}, nil
+}
+
+func (s *PromocodeService) UpdatePromocode(ctx context.Context, actor *models.Identity, id uint, req models.UpdatePromocodeRequest) (*models.Promocode, error) {
+	ctx, span := tracing.Start(ctx, "PromocodeService.UpdatePromocode")
+	defer span.End()
+
+	if req.Type != "" && !req.Type.Valid() {
+		return nil, ErrUnknownPromocodeType
+	}
+	if err := validateSegments(req.Segments); err != nil {
+		return nil, err
+	}
+	promocode, err := s.getPromocode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(actor, promocode.CompanyID); err != nil {
+		return nil, err
+	}
+
+	if req.Title != "" {
+		promocode.Title = req.Title
+	}
+	if req.Description != "" {
+		promocode.Description = req.Description
+	}
+	if req.Type != "" {
+		promocode.Type = req.Type
+	}
+	if req.ValidFrom != nil {
+		promocode.ValidFrom = req.ValidFrom
+	}
+	if req.ValidUntil != nil {
+		promocode.ValidUntil = req.ValidUntil
+	}
+	if req.MaxRedemptions != nil {
+		promocode.MaxRedemptions = *req.MaxRedemptions
+	}
+	if req.MaxRedemptionsPerUser != nil {
+		promocode.MaxRedemptionsPerUser = *req.MaxRedemptionsPerUser
+	}
+	if req.Segments != nil {
+		promocode.Segments = models.NormalizeSegments(req.Segments)
+	}
+	if err := validatePeriod(promocode); err != nil {
+		return nil, err
+	}
+	// Измененную версию модератор еще не видел
+	promocode.ModerationStatus = models.ModerationPending
+	if err := s.promocodeRepo.UpdatePromocode(ctx, promocode); err != nil {
+		return nil, err
+	}
+	if err := submitForModeration(ctx, s.moderationRepo, models.ModerationEntityPromocode, promocode.ID, promocode.CompanyID, actor.UserID); err != nil {
+		return nil, err
+	}
+	metrics.PromocodeChanges.WithLabelValues("update").Inc()
+	return promocode, nil
+}
+
+func (s *PromocodeService) DeletePromocode(ctx context.Context, actor *models.Identity, id uint) error {
+	ctx, span := tracing.Start(ctx, "PromocodeService.DeletePromocode")
+	defer span.End()
+
+	promocode, err := s.getPromocode(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := authorize(actor, promocode.CompanyID); err != nil {
+		return err
+	}
+
+	if err := s.promocodeRepo.DeletePromocode(ctx, id); err != nil {
+		return err
+	}
+	metrics.PromocodeChanges.WithLabelValues("delete").Inc()
+	return nil
+}
+
+// authorize разрешает изменения владельцам и менеджерам компании, а также администраторам.
+// Роли в командах берутся из токена, поэтому исключенный из команды участник теряет доступ
+// не позже, чем истечет его access-токен.
+func authorize(actor *models.Identity, companyID uint) error {
+	if actor.HasRole(models.RoleAdmin) {
+		return nil
+	}
+	role, ok := actor.CompanyRole(companyID)
+	if !ok {
+		return ErrNotCompanyPromoEditor
+	}
+	for _, editorRole := range editorRoles {
+		if role == editorRole {
+			return nil
+		}
+	}
+	return ErrNotCompanyPromoEditor
+}
+
+// canSeeUnapproved - пользователь видит промокоды компании до одобрения: модераторы, администраторы
+// и любые участники ее команды
+// visiblePromocode возвращает промокод, если actor может его видеть. Непроверенный промокод
+// для посторонних не существует, как и все, что к нему относится.
+func visiblePromocode(ctx context.Context, promocodeRepo repository.PromocodeRepositoryInterface, actor *models.Identity, id uint) (*models.Promocode, error) {
+	promocode, err := promocodeRepo.GetPromocodeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promocode == nil || (promocode.ModerationStatus != models.ModerationApproved && !canSeeUnapproved(actor, promocode.CompanyID)) {
+		return nil, ErrPromocodeNotFound
+	}
+	return promocode, nil
+}
+
+func canSeeUnapproved(actor *models.Identity, companyID uint) bool {
+	if actor.IsModerator() {
+		return true
+	}
+	_, ok := actor.CompanyRole(companyID)
+	return ok
+}
+
+func validateSegments(segments []string) error {
+	for _, segment := range segments {
+		if !models.ValidSegment(segment) {
+			return ErrInvalidSegment
+		}
+	}
+	return nil
+}
+
+func validatePeriod(promocode *models.Promocode) error {
+	if promocode.ValidFrom != nil && promocode.ValidUntil != nil && !promocode.ValidUntil.After(*promocode.ValidFrom) {
+		return ErrInvalidValidityPeriod
+	}
+	return nil
+}
+
+var _ PromocodeServiceInterface = (*PromocodeService)(nil)
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/userservice"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MockPromocodeRepository, как и настоящий репозиторий, не создает промокод с кодом, занятым
+// другим промокодом или одноразовым кодом из codes
+type MockPromocodeRepository struct {
+	promocodes map[uint]*models.Promocode
+	codes      *MockCodeRepository
+	idCounter  uint
+}
+
+var _ repository.PromocodeRepositoryInterface = (*MockPromocodeRepository)(nil)
+
+func NewMockPromocodeRepository() *MockPromocodeRepository {
+	return &MockPromocodeRepository{promocodes: make(map[uint]*models.Promocode)}
+}
+
+func (r *MockPromocodeRepository) CreatePromocode(ctx context.Context, promocode *models.Promocode) (bool, error) {
+	if existing, _ := r.GetPromocodeByCode(ctx, promocode.Code); existing != nil {
+		return false, nil
+	}
+	if r.codes != nil {
+		if _, taken := r.codes.byCode[promocode.Code]; taken {
+			return false, nil
+		}
+	}
+	r.idCounter++
+	promocode.ID = r.idCounter
+	stored := *promocode
+	r.promocodes[promocode.ID] = &stored
+	return true, nil
+}
+
+func (r *MockPromocodeRepository) GetPromocodeByID(ctx context.Context, id uint) (*models.Promocode, error) {
+	promocode, exists := r.promocodes[id]
+	if !exists {
+		return nil, nil
+	}
+	stored := *promocode
+	return &stored, nil
+}
+
+func (r *MockPromocodeRepository) GetPromocodeByCode(ctx context.Context, code string) (*models.Promocode, error) {
+	for _, promocode := range r.promocodes {
+		if promocode.Code == code {
+			stored := *promocode
+			return &stored, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockPromocodeRepository) ListPromocodes(ctx context.Context, filter models.PromocodeFilter) ([]models.Promocode, int64, error) {
+	var matched []models.Promocode
+	for id := uint(1); id <= r.idCounter; id++ {
+		promocode, exists := r.promocodes[id]
+		if !exists || (filter.CompanyID != 0 && promocode.CompanyID != filter.CompanyID) || (filter.Type != "" && promocode.Type != filter.Type) ||
+			(filter.ModerationStatus != "" && promocode.ModerationStatus != filter.ModerationStatus) {
+			continue
+		}
+		if filter.Visibility != nil && promocode.ModerationStatus != models.ModerationApproved && !containsCompany(filter.Visibility.CompanyIDs, promocode.CompanyID) {
+			continue
+		}
+		matched = append(matched, *promocode)
+	}
+	start := (filter.Page - 1) * filter.PageSize
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + filter.PageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[start:end], int64(len(matched)), nil
+}
+
+func containsCompany(companyIDs []uint, companyID uint) bool {
+	for _, id := range companyIDs {
+		if id == companyID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MockPromocodeRepository) UpdatePromocode(ctx context.Context, promocode *models.Promocode) error {
+	stored := *promocode
+	r.promocodes[promocode.ID] = &stored
+	return nil
+}
+
+func (r *MockPromocodeRepository) DeletePromocode(ctx context.Context, id uint) error {
+	delete(r.promocodes, id)
+	return nil
+}
+
+func (r *MockPromocodeRepository) DeleteCompanyPromocodes(ctx context.Context, companyID uint) error {
+	for id, promocode := range r.promocodes {
+		if promocode.CompanyID == companyID {
+			delete(r.promocodes, id)
+		}
+	}
+	return nil
+}
+
+// MockCompanyClient считает одобренными все компании, кроме перечисленных в statuses
+type MockCompanyClient struct {
+	statuses      map[uint]models.ModerationStatus
+	notifications map[uint][]userservice.Notification
+}
+
+var _ userservice.Client = (*MockCompanyClient)(nil)
+
+func (c *MockCompanyClient) GetCompany(ctx context.Context, companyID uint) (*userservice.Company, error) {
+	status, exists := c.statuses[companyID]
+	if !exists {
+		status = models.ModerationApproved
+	}
+	if status == "" {
+		return nil, nil
+	}
+	return &userservice.Company{ID: companyID, ModerationStatus: status}, nil
+}
+
+func (c *MockCompanyClient) NotifyUser(ctx context.Context, userID uint, notification userservice.Notification) error {
+	if c.notifications == nil {
+		c.notifications = make(map[uint][]userservice.Notification)
+	}
+	c.notifications[userID] = append(c.notifications[userID], notification)
+	return nil
+}
+
+// Пользователь 1 - владелец компании 1, 2 - ее аналитик и менеджер компании 2, 3 - администратор
+var (
+	testOwner    = &models.Identity{UserID: 1, Companies: map[uint]models.CompanyRole{1: models.CompanyRoleOwner}}
+	testAnalyst  = &models.Identity{UserID: 2, Companies: map[uint]models.CompanyRole{1: models.CompanyRoleAnalyst, 2: models.CompanyRoleManager}}
+	testAdmin    = &models.Identity{UserID: 3, Roles: []string{models.RoleAdmin}}
+	testStranger = &models.Identity{UserID: 4}
+)
+
+func newTestPromocodeService() *PromocodeService {
+	promocodes := NewMockPromocodeRepository()
+	return NewPromocodeService(promocodes, NewMockModerationRepository(promocodes, nil), NewMockCodeRepository(promocodes), &MockCompanyClient{})
+}
+
+func TestCreatePromocode(t *testing.T) {
+	service := newTestPromocodeService()
+	req := models.CreatePromocodeRequest{CompanyID: 1, Code: "coffee10", Title: "Скидка на кофе", Type: models.PromocodeTypePercent}
+
+	promocode, err := service.CreatePromocode(context.Background(), testOwner, req)
+	if err != nil {
+		t.Fatalf("Ожидается успешное создание промокода, получено: %v", err)
+	}
+	if promocode.ID == 0 || promocode.Code != "COFFEE10" || promocode.CreatorID != 1 || promocode.CompanyID != 1 || promocode.ModerationStatus != models.ModerationPending {
+		t.Errorf("Неверный промокод: %+v", promocode)
+	}
+	if _, err := service.CreatePromocode(context.Background(), testOwner, req); err != ErrCodeTaken {
+		t.Errorf("Ожидается ошибка занятого кода без учета регистра, получено: %v", err)
+	}
+
+	req.Code = "TEA5"
+	if _, err := service.CreatePromocode(context.Background(), testAnalyst, req); err != ErrNotCompanyPromoEditor {
+		t.Errorf("Аналитик не может создавать промокоды, получено: %v", err)
+	}
+	if _, err := service.CreatePromocode(context.Background(), testStranger, req); err != ErrNotCompanyPromoEditor {
+		t.Errorf("Посторонний не может создавать промокоды, получено: %v", err)
+	}
+	req.CompanyID = 2
+	if _, err := service.CreatePromocode(context.Background(), testAnalyst, req); err != nil {
+		t.Errorf("Менеджер может создавать промокоды своей компании, получено: %v", err)
+	}
+	req.Code, req.Type = "GIFT", "bonus"
+	if _, err := service.CreatePromocode(context.Background(), testAdmin, req); err != ErrUnknownPromocodeType {
+		t.Errorf("Ожидается ошибка неизвестного типа, получено: %v", err)
+	}
+}
+
+// racingPromocodeRepository создает промокод с тем же кодом от имени параллельного запроса
+// между проверками сервиса и вставкой
+type racingPromocodeRepository struct {
+	*MockPromocodeRepository
+}
+
+func (r racingPromocodeRepository) CreatePromocode(ctx context.Context, promocode *models.Promocode) (bool, error) {
+	rival := *promocode
+	r.MockPromocodeRepository.CreatePromocode(ctx, &rival)
+	return r.MockPromocodeRepository.CreatePromocode(ctx, promocode)
+}
+
+func TestCreatePromocodeCompanyNotApproved(t *testing.T) {
+	service := newTestPromocodeService()
+	// Компания 1 ждет модерации, компании 2 в User Service нет
+	service.companies = &MockCompanyClient{statuses: map[uint]models.ModerationStatus{1: models.ModerationPending, 2: ""}}
+	req := models.CreatePromocodeRequest{CompanyID: 1, Code: "COFFEE10", Title: "Скидка на кофе", Type: models.PromocodeTypePercent}
+
+	if _, err := service.CreatePromocode(context.Background(), testOwner, req); err != ErrCompanyNotApproved {
+		t.Errorf("Ожидается запрет до одобрения компании, получено: %v", err)
+	}
+	req.CompanyID = 2
+	if _, err := service.CreatePromocode(context.Background(), testAnalyst, req); err != ErrCompanyNotApproved {
+		t.Errorf("Ожидается запрет для удаленной компании, получено: %v", err)
+	}
+
+	service.companies = &MockCompanyClient{statuses: map[uint]models.ModerationStatus{1: models.ModerationRejected}}
+	req.CompanyID = 1
+	if _, err := service.CreatePromocode(context.Background(), testOwner, req); err != ErrCompanyNotApproved {
+		t.Errorf("Ожидается запрет для отклоненной компании, получено: %v", err)
+	}
+}
+
+func TestCreatePromocodeCodeTakenConcurrently(t *testing.T) {
+	promocodes := NewMockPromocodeRepository()
+	service := NewPromocodeService(racingPromocodeRepository{promocodes}, NewMockModerationRepository(promocodes, nil), NewMockCodeRepository(promocodes), &MockCompanyClient{})
+
+	_, err := service.CreatePromocode(context.Background(), testOwner, models.CreatePromocodeRequest{CompanyID: 1, Code: "RACE", Title: "Гонка", Type: models.PromocodeTypeFixed})
+	if err != ErrCodeTaken {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrCodeTaken, err)
+	}
+	if len(promocodes.promocodes) != 1 {
+		t.Errorf("Ожидается только промокод параллельного запроса, получено: %d", len(promocodes.promocodes))
+	}
+}
+
+func TestUpdateAndDeletePromocode(t *testing.T) {
+	service := newTestPromocodeService()
+	promocode, _ := service.CreatePromocode(context.Background(), testOwner, models.CreatePromocodeRequest{
+		CompanyID: 1, Code: "COFFEE10", Title: "Скидка на кофе", Description: "Только по утрам", Type: models.PromocodeTypePercent,
+	})
+
+	if _, err := service.UpdatePromocode(context.Background(), testAnalyst, promocode.ID, models.UpdatePromocodeRequest{Title: "Чужое"}); err != ErrNotCompanyPromoEditor {
+		t.Errorf("Аналитик не может изменять промокоды, получено: %v", err)
+	}
+	updated, err := service.UpdatePromocode(context.Background(), testOwner, promocode.ID, models.UpdatePromocodeRequest{Title: "Кофе со скидкой"})
+	if err != nil || updated.Title != "Кофе со скидкой" || updated.Description != "Только по утрам" || updated.Type != models.PromocodeTypePercent {
+		t.Errorf("Ожидается изменение только названия, получено: %+v, %v", updated, err)
+	}
+	if _, err := service.UpdatePromocode(context.Background(), testAdmin, promocode.ID, models.UpdatePromocodeRequest{Type: models.PromocodeTypeGift}); err != nil {
+		t.Errorf("Администратор может изменять любые промокоды, получено: %v", err)
+	}
+	if _, err := service.UpdatePromocode(context.Background(), testOwner, 42, models.UpdatePromocodeRequest{Title: "Нет"}); err != ErrPromocodeNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего промокода, получено: %v", err)
+	}
+
+	if err := service.DeletePromocode(context.Background(), testStranger, promocode.ID); err != ErrNotCompanyPromoEditor {
+		t.Errorf("Посторонний не может удалять промокоды, получено: %v", err)
+	}
+	if err := service.DeletePromocode(context.Background(), testOwner, promocode.ID); err != nil {
+		t.Fatalf("Ожидается успешное удаление, получено: %v", err)
+	}
+	if _, err := service.GetPromocode(context.Background(), testOwner, promocode.ID); err != ErrPromocodeNotFound {
+		t.Errorf("Промокод должен быть удален, получено: %v", err)
+	}
+}
+
+func TestPromocodeRedemptionConditions(t *testing.T) {
+	service := newTestPromocodeService()
+	from := time.Now()
+	until := from.Add(7 * 24 * time.Hour)
+	req := models.CreatePromocodeRequest{CompanyID: 1, Code: "VIP20", Title: "Скидка для своих", Type: models.PromocodeTypePercent,
+		ValidFrom: &until, ValidUntil: &from, MaxRedemptions: 100, MaxRedemptionsPerUser: 1, Segments: []string{"vip", "employees", "vip"}}
+
+	if _, err := service.CreatePromocode(context.Background(), testOwner, req); err != ErrInvalidValidityPeriod {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrInvalidValidityPeriod, err)
+	}
+	req.ValidFrom, req.ValidUntil = &from, &until
+	promocode, err := service.CreatePromocode(context.Background(), testOwner, req)
+	if err != nil || strings.Join(promocode.Segments, ",") != "employees,vip" || promocode.MaxRedemptionsPerUser != 1 {
+		t.Fatalf("Неверные условия погашения: %+v, %v", promocode, err)
+	}
+
+	if _, err := service.UpdatePromocode(context.Background(), testOwner, promocode.ID, models.UpdatePromocodeRequest{Segments: []string{"VIP"}}); err != ErrInvalidSegment {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrInvalidSegment, err)
+	}
+	unlimited := 0
+	updated, err := service.UpdatePromocode(context.Background(), testOwner, promocode.ID, models.UpdatePromocodeRequest{MaxRedemptions: &unlimited, Segments: []string{}})
+	if err != nil || updated.MaxRedemptions != 0 || len(updated.Segments) != 0 || updated.MaxRedemptionsPerUser != 1 || updated.ValidUntil == nil {
+		t.Errorf("Ожидается снятие общего лимита и сегментов без изменения остальных условий, получено: %+v, %v", updated, err)
+	}
+}
+
+func TestListPromocodes(t *testing.T) {
+	service := newTestPromocodeService()
+	for _, code := range []string{"ONE", "TWO", "THREE"} {
+		service.CreatePromocode(context.Background(), testAdmin, models.CreatePromocodeRequest{CompanyID: 1, Code: code, Title: "Промокод " + code, Type: models.PromocodeTypeFixed})
+	}
+	service.CreatePromocode(context.Background(), testAdmin, models.CreatePromocodeRequest{CompanyID: 2, Code: "OTHER", Title: "Другая компания", Type: models.PromocodeTypeGift})
+
+	list, err := service.ListPromocodes(context.Background(), testAdmin, models.PromocodeFilter{CompanyID: 1, PageSize: 2})
+	if err != nil || list.Total != 3 || len(list.Promocodes) != 2 || list.Page != 1 {
+		t.Errorf("Ожидается первая страница из двух промокодов компании, получено: %+v, %v", list, err)
+	}
+	list, _ = service.ListPromocodes(context.Background(), testAdmin, models.PromocodeFilter{Type: models.PromocodeTypeGift})
+	if list.Total != 1 || list.Promocodes[0].Code != "OTHER" || list.PageSize != defaultPageSize {
+		t.Errorf("Ожидается один подарочный промокод, получено: %+v", list)
+	}
+	if list, _ := service.ListPromocodes(context.Background(), testAdmin, models.PromocodeFilter{CompanyID: 42}); list.Promocodes == nil {
+		t.Error("Пустой результат должен быть пустым списком, а не nil")
+	}
+}
+
+func TestPromocodeVisibility(t *testing.T) {
+	service := newTestPromocodeService()
+	repo := service.promocodeRepo.(*MockPromocodeRepository)
+	statuses := map[string]models.ModerationStatus{
+		"APPROVED": models.ModerationApproved,
+		"PENDING":  models.ModerationPending,
+		"REJECTED": models.ModerationRejected,
+	}
+	ids := make(map[string]uint)
+	for _, code := range []string{"APPROVED", "PENDING", "REJECTED"} {
+		promocode, _ := service.CreatePromocode(context.Background(), testOwner, models.CreatePromocodeRequest{CompanyID: 1, Code: code, Title: "Промокод " + code, Type: models.PromocodeTypeFixed})
+		repo.promocodes[promocode.ID].ModerationStatus = statuses[code]
+		ids[code] = promocode.ID
+	}
+
+	// Команда компании, в том числе аналитик, и модераторы видят все промокоды компании
+	for _, actor := range []*models.Identity{testOwner, testAnalyst, testModerator, testAdmin} {
+		if _, err := service.GetPromocode(context.Background(), actor, ids["PENDING"]); err != nil {
+			t.Errorf("Пользователь %d должен видеть промокод на модерации, получено: %v", actor.UserID, err)
+		}
+		if list, _ := service.ListPromocodes(context.Background(), actor, models.PromocodeFilter{CompanyID: 1}); list.Total != 3 {
+			t.Errorf("Пользователь %d должен видеть все промокоды компании, получено: %d", actor.UserID, list.Total)
+		}
+	}
+
+	for _, code := range []string{"PENDING", "REJECTED"} {
+		if _, err := service.GetPromocode(context.Background(), testStranger, ids[code]); err != ErrPromocodeNotFound {
+			t.Errorf("Постороннему промокод %s не должен показываться, получено: %v", code, err)
+		}
+	}
+	if _, err := service.GetPromocode(context.Background(), testStranger, ids["APPROVED"]); err != nil {
+		t.Errorf("Одобренный промокод виден всем, получено: %v", err)
+	}
+	list, _ := service.ListPromocodes(context.Background(), testStranger, models.PromocodeFilter{CompanyID: 1})
+	if list.Total != 1 || list.Promocodes[0].Code != "APPROVED" {
+		t.Errorf("Постороннему в списке показываются только одобренные промокоды, получено: %+v", list)
+	}
+	// Фильтр по статусу не открывает доступ к непроверенным промокодам
+	if list, _ := service.ListPromocodes(context.Background(), testStranger, models.PromocodeFilter{ModerationStatus: models.ModerationPending}); list.Total != 0 {
+		t.Errorf("Фильтр moderation_status не должен показывать постороннему промокоды на модерации, получено: %+v", list)
+	}
+	if list, _ := service.ListPromocodes(context.Background(), testStranger, models.PromocodeFilter{Visibility: &models.PromocodeVisibility{CompanyIDs: []uint{1}}}); list.Total != 1 {
+		t.Errorf("Ограничение видимости из запроса не должно учитываться, получено: %+v", list)
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/metrics"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPromocodeNotApproved   = errors.New("промокод еще не одобрен модератором или отклонен")
+	ErrPromocodeNotStarted    = errors.New("срок действия промокода еще не начался")
+	ErrPromocodeExpired       = errors.New("срок действия промокода истек")
+	ErrPromocodeExhausted     = errors.New("промокод больше нельзя использовать: лимит погашений исчерпан")
+	ErrCodeAlreadyRedeemed    = errors.New("этот код уже использован")
+	ErrRedemptionLimitReached = errors.New("вы уже использовали этот промокод максимальное число раз")
+	ErrSegmentNotAllowed      = errors.New("промокод недоступен для вашей категории пользователей")
+	ErrNotCompanyMember       = errors.New("погашения промокода видны только команде компании")
+)
+
+type RedemptionService struct {
+	promocodeRepo  repository.PromocodeRepositoryInterface
+	redemptionRepo repository.RedemptionRepositoryInterface
+	codeRepo       repository.CodeRepositoryInterface
+}
+
+func NewRedemptionService(promocodeRepo repository.PromocodeRepositoryInterface, redemptionRepo repository.RedemptionRepositoryInterface, codeRepo repository.CodeRepositoryInterface) *RedemptionService {
+	return &RedemptionService{
+		promocodeRepo:  promocodeRepo,
+		redemptionRepo: redemptionRepo,
+		codeRepo:       codeRepo,
+	}
+}
+
+// Redeem гасит промокод от имени actor по общему коду промокода или по одноразовому коду из партии.
+// Условия проверяются внутри транзакции погашения, поэтому два параллельных запроса не могут
+// оба занять последнее погашение или один и тот же одноразовый код.
+func (s *RedemptionService) Redeem(ctx context.Context, actor *models.Identity, req models.RedeemRequest) (*models.Redemption, error) {
+	ctx, span := tracing.Start(ctx, "RedemptionService.Redeem")
+	defer span.End()
+
+	redemption, _, err := s.redeem(ctx, req, actor, nil)
+	return redemption, err
+}
+
+// RedeemAtMerchant проверяет и гасит код одним вызовом кассы. Касса знает только код: покупатель анонимен,
+// поэтому личный лимит погашений к нему не применяется, а промокоды для сегментов недоступны.
+// Коды других компаний для ключа не существуют.
+func (s *RedemptionService) RedeemAtMerchant(ctx context.Context, apiKey *models.APIKey, req models.RedeemRequest) (*models.MerchantRedemption, error) {
+	ctx, span := tracing.Start(ctx, "RedemptionService.RedeemAtMerchant")
+	defer span.End()
+
+	if req.IdempotencyRecordID != 0 {
+		// Повтор запроса, который погасил код, но упал до сохранения ответа: отдаем то же погашение
+		existing, err := s.redemptionRepo.GetRedemptionByIdempotencyRecord(ctx, req.IdempotencyRecordID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			promocode, err := s.promocodeRepo.GetPromocodeByID(ctx, existing.PromocodeID)
+			if err != nil {
+				return nil, err
+			}
+			if promocode == nil {
+				return nil, ErrPromocodeNotFound
+			}
+			return &models.MerchantRedemption{Redemption: *existing, Promocode: *promocode}, nil
+		}
+	}
+
+	redemption, promocode, err := s.redeem(ctx, req, &models.Identity{}, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &models.MerchantRedemption{Redemption: *redemption, Promocode: *promocode}, nil
+}
+
+// redeem гасит код от имени actor, а если задан apiKey - от имени кассы компании ключа
+func (s *RedemptionService) redeem(ctx context.Context, req models.RedeemRequest, actor *models.Identity, apiKey *models.APIKey) (*models.Redemption, *models.Promocode, error) {
+	code := strings.ToUpper(req.Code)
+	promocode, err := s.promocodeRepo.GetPromocodeByCode(ctx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	var uniqueCode *models.UniqueCode
+	if promocode == nil {
+		uniqueCode, err = s.codeRepo.GetUniqueCode(ctx, code)
+		if err != nil {
+			return nil, nil, err
+		}
+		if uniqueCode != nil {
+			promocode, err = s.promocodeRepo.GetPromocodeByID(ctx, uniqueCode.PromocodeID)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if promocode == nil || (apiKey != nil && promocode.CompanyID != apiKey.CompanyID) {
+		return nil, nil, ErrPromocodeNotFound
+	}
+
+	now := time.Now()
+	redemption := &models.Redemption{
+		PromocodeID: promocode.ID,
+		UserID:      actor.UserID,
+		CompanyID:   promocode.CompanyID,
+		Code:        code,
+		RedeemedAt:  now,
+	}
+	if uniqueCode != nil {
+		redemption.CodeID = &uniqueCode.ID
+	}
+	if apiKey != nil {
+		redemption.APIKeyID = &apiKey.ID
+	}
+	if req.IdempotencyRecordID != 0 {
+		redemption.IdempotencyRecordID = &req.IdempotencyRecordID
+	}
+	err = s.redemptionRepo.Redeem(ctx, redemption, func(current *models.Promocode, currentCode *models.UniqueCode, userRedemptions int64) error {
+		if current == nil {
+			return ErrPromocodeNotFound
+		}
+		if currentCode != nil && currentCode.RedeemedAt != nil {
+			return ErrCodeAlreadyRedeemed
+		}
+		if apiKey != nil {
+			// Все погашения кассами записаны на анонимного пользователя, личного лимита у него нет
+			userRedemptions = 0
+		}
+		promocode = current
+		return checkRedemption(current, actor, userRedemptions, now)
+	})
+	if err != nil {
+		metrics.Redemptions.WithLabelValues(redemptionResult(err)).Inc()
+		return nil, nil, err
+	}
+	metrics.Redemptions.WithLabelValues("redeemed").Inc()
+	return redemption, promocode, nil
+}
+
+// ListUserRedemptions - история погашений самого actor
+func (s *RedemptionService) ListUserRedemptions(ctx context.Context, actor *models.Identity, filter models.RedemptionFilter) (*models.RedemptionList, error) {
+	ctx, span := tracing.Start(ctx, "RedemptionService.ListUserRedemptions")
+	defer span.End()
+
+	filter.UserID = actor.UserID
+	return s.list(ctx, filter)
+}
+
+// ListPromocodeRedemptions показывает погашения промокода любому участнику команды компании и администраторам
+func (s *RedemptionService) ListPromocodeRedemptions(ctx context.Context, actor *models.Identity, promocodeID uint, filter models.RedemptionFilter) (*models.RedemptionList, error) {
+	ctx, span := tracing.Start(ctx, "RedemptionService.ListPromocodeRedemptions")
+	defer span.End()
+
+	promocode, err := s.promocodeRepo.GetPromocodeByID(ctx, promocodeID)
+	if err != nil {
+		return nil, err
+	}
+	if promocode == nil {
+		return nil, ErrPromocodeNotFound
+	}
+	if _, ok := actor.CompanyRole(promocode.CompanyID); !ok && !actor.HasRole(models.RoleAdmin) {
+		return nil, ErrNotCompanyMember
+	}
+
+	filter.PromocodeID = promocodeID
+	return s.list(ctx, filter)
+}
+
+func (s *RedemptionService) list(ctx context.Context, filter models.RedemptionFilter) (*models.RedemptionList, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+
+	redemptions, total, err := s.redemptionRepo.ListRedemptions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if redemptions == nil {
+		redemptions = []models.Redemption{}
+	}
+	return &models.RedemptionList{
+		Redemptions: redemptions,
+		Total:       total,
+		Page:        filter.Page,
+		PageSize:    filter.PageSize,
+	}, nil
+}
+
+// checkRedemption проверяет условия погашения по актуальному состоянию промокода
+func checkRedemption(promocode *models.Promocode, actor *models.Identity, userRedemptions int64, now time.Time) error {
+	// Статус читается под блокировкой: промокод, снятый с публикации правкой, гасить уже нельзя
+	if promocode.ModerationStatus != models.ModerationApproved {
+		return ErrPromocodeNotApproved
+	}
+	if promocode.ValidFrom != nil && now.Before(*promocode.ValidFrom) {
+		return ErrPromocodeNotStarted
+	}
+	if promocode.ValidUntil != nil && !now.Before(*promocode.ValidUntil) {
+		return ErrPromocodeExpired
+	}
+	if !actor.InAnySegment(promocode.Segments) {
+		return ErrSegmentNotAllowed
+	}
+	if promocode.MaxRedemptions > 0 && promocode.RedemptionCount >= promocode.MaxRedemptions {
+		return ErrPromocodeExhausted
+	}
+	if promocode.MaxRedemptionsPerUser > 0 && userRedemptions >= int64(promocode.MaxRedemptionsPerUser) {
+		return ErrRedemptionLimitReached
+	}
+	return nil
+}
+
+// redemptionResult - метка метрики для отклоненного погашения
+func redemptionResult(err error) string {
+	switch err {
+	case ErrPromocodeNotFound:
+		return "not_found"
+	case ErrPromocodeNotApproved:
+		return "not_approved"
+	case ErrPromocodeNotStarted:
+		return "not_started"
+	case ErrPromocodeExpired:
+		return "expired"
+	case ErrSegmentNotAllowed:
+		return "segment"
+	case ErrPromocodeExhausted:
+		return "exhausted"
+	case ErrCodeAlreadyRedeemed:
+		return "code_used"
+	case ErrRedemptionLimitReached:
+		return "user_limit"
+	}
+	return "error"
+}
+
+var _ RedemptionServiceInterface = (*RedemptionService)(nil)
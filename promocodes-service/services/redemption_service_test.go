@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockRedemptionRepository держит мьютекс на время check, как настоящий репозиторий держит блокировку строки.
+// Счетчики погашений хранятся отдельно, чтобы не менять промокоды, которые параллельно читает сервис.
+type MockRedemptionRepository struct {
+	mu          sync.Mutex
+	promocodes  *MockPromocodeRepository
+	codes       *MockCodeRepository
+	redemptions []models.Redemption
+	counts      map[uint]int
+}
+
+var _ repository.RedemptionRepositoryInterface = (*MockRedemptionRepository)(nil)
+
+func (r *MockRedemptionRepository) Redeem(ctx context.Context, redemption *models.Redemption, check func(promocode *models.Promocode, code *models.UniqueCode, userRedemptions int64) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	promocode, exists := r.promocodes.promocodes[redemption.PromocodeID]
+	if !exists {
+		return check(nil, nil, 0)
+	}
+	var code *models.UniqueCode
+	if redemption.CodeID != nil {
+		stored := r.codes.codes[*redemption.CodeID-1]
+		code = &stored
+	}
+	var userRedemptions int64
+	for _, stored := range r.redemptions {
+		if stored.PromocodeID == redemption.PromocodeID && stored.UserID == redemption.UserID {
+			userRedemptions++
+		}
+	}
+	current := *promocode
+	current.RedemptionCount = r.counts[current.ID]
+	if err := check(&current, code, userRedemptions); err != nil {
+		return err
+	}
+
+	if code != nil {
+		r.codes.codes[code.ID-1].RedeemedBy = &redemption.UserID
+		r.codes.codes[code.ID-1].RedeemedAt = &redemption.RedeemedAt
+	}
+	redemption.ID = uint(len(r.redemptions) + 1)
+	r.redemptions = append(r.redemptions, *redemption)
+	r.counts[current.ID]++
+	return nil
+}
+
+func (r *MockRedemptionRepository) GetRedemptionByIdempotencyRecord(ctx context.Context, recordID uint) (*models.Redemption, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, redemption := range r.redemptions {
+		if redemption.IdempotencyRecordID != nil && *redemption.IdempotencyRecordID == recordID {
+			return &redemption, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRedemptionRepository) ListRedemptions(ctx context.Context, filter models.RedemptionFilter) ([]models.Redemption, int64, error) {
+	var redemptions []models.Redemption
+	for i := len(r.redemptions) - 1; i >= 0; i-- {
+		redemption := r.redemptions[i]
+		if (filter.PromocodeID == 0 || redemption.PromocodeID == filter.PromocodeID) && (filter.UserID == 0 || redemption.UserID == filter.UserID) {
+			redemptions = append(redemptions, redemption)
+		}
+	}
+	return redemptions, int64(len(redemptions)), nil
+}
+
+type testRedemptions struct {
+	service     *RedemptionService
+	promocodes  *MockPromocodeRepository
+	codes       *MockCodeRepository
+	redemptions *MockRedemptionRepository
+}
+
+func newTestRedemptionService() *testRedemptions {
+	promocodes := NewMockPromocodeRepository()
+	codes := NewMockCodeRepository(promocodes)
+	redemptions := &MockRedemptionRepository{promocodes: promocodes, codes: codes, counts: make(map[uint]int)}
+	return &testRedemptions{
+		service:     NewRedemptionService(promocodes, redemptions, codes),
+		promocodes:  promocodes,
+		codes:       codes,
+		redemptions: redemptions,
+	}
+}
+
+func (env *testRedemptions) createPromocode(promocode models.Promocode) *models.Promocode {
+	promocode.CompanyID = 1
+	promocode.Type = models.PromocodeTypePercent
+	if promocode.ModerationStatus == "" {
+		promocode.ModerationStatus = models.ModerationApproved
+	}
+	env.promocodes.CreatePromocode(context.Background(), &promocode)
+	return &promocode
+}
+
+func TestRedeemLimits(t *testing.T) {
+	env := newTestRedemptionService()
+	ctx := context.Background()
+	env.createPromocode(models.Promocode{Code: "COFFEE10", MaxRedemptions: 3, MaxRedemptionsPerUser: 2})
+
+	redemption, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: "coffee10"})
+	if err != nil {
+		t.Fatalf("Ожидается успешное погашение, получено: %v", err)
+	}
+	if redemption.UserID != testStranger.UserID || redemption.Code != "COFFEE10" || redemption.RedeemedAt.IsZero() {
+		t.Errorf("Неверная запись о погашении: %+v", redemption)
+	}
+	if _, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: "COFFEE10"}); err != nil {
+		t.Fatalf("Ожидается второе погашение, получено: %v", err)
+	}
+	if _, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: "COFFEE10"}); err != ErrRedemptionLimitReached {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrRedemptionLimitReached, err)
+	}
+	if _, err := env.service.Redeem(ctx, testOwner, models.RedeemRequest{Code: "COFFEE10"}); err != nil {
+		t.Fatalf("Ожидается погашение другим пользователем, получено: %v", err)
+	}
+	if _, err := env.service.Redeem(ctx, testAdmin, models.RedeemRequest{Code: "COFFEE10"}); err != ErrPromocodeExhausted {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrPromocodeExhausted, err)
+	}
+	if _, err := env.service.Redeem(ctx, testAdmin, models.RedeemRequest{Code: "UNKNOWN"}); err != ErrPromocodeNotFound {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrPromocodeNotFound, err)
+	}
+
+	history, _ := env.service.ListUserRedemptions(ctx, testStranger, models.RedemptionFilter{})
+	if history.Total != 2 {
+		t.Errorf("Ожидается два погашения в истории пользователя, получено: %d", history.Total)
+	}
+}
+
+func TestRedeemConditions(t *testing.T) {
+	env := newTestRedemptionService()
+	ctx := context.Background()
+	tomorrow := time.Now().Add(24 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	env.createPromocode(models.Promocode{Code: "SOON", ValidFrom: &tomorrow})
+	env.createPromocode(models.Promocode{Code: "OLD", ValidUntil: &yesterday})
+	env.createPromocode(models.Promocode{Code: "VIPONLY", ValidFrom: &yesterday, ValidUntil: &tomorrow, Segments: models.Segments{"employees", "vip"}})
+
+	if _, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: "SOON"}); err != ErrPromocodeNotStarted {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrPromocodeNotStarted, err)
+	}
+	if _, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: "OLD"}); err != ErrPromocodeExpired {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrPromocodeExpired, err)
+	}
+	if _, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: "VIPONLY"}); err != ErrSegmentNotAllowed {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrSegmentNotAllowed, err)
+	}
+	vip := &models.Identity{UserID: 7, Segments: []string{"new", "vip"}}
+	if _, err := env.service.Redeem(ctx, vip, models.RedeemRequest{Code: "VIPONLY"}); err != nil {
+		t.Errorf("Пользователь из сегмента vip должен погасить промокод, получено: %v", err)
+	}
+	if len(env.redemptions.redemptions) != 1 {
+		t.Errorf("Отклоненные погашения не должны сохраняться, получено: %d", len(env.redemptions.redemptions))
+	}
+}
+
+func TestRedeemConcurrently(t *testing.T) {
+	env := newTestRedemptionService()
+	promocode := env.createPromocode(models.Promocode{Code: "LAST", MaxRedemptions: 1})
+
+	// Десять пользователей одновременно борются за единственное погашение
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for userID := uint(10); userID < 20; userID++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			_, err := env.service.Redeem(context.Background(), &models.Identity{UserID: userID}, models.RedeemRequest{Code: "LAST"})
+			errs <- err
+		}(userID)
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else if err != ErrPromocodeExhausted {
+			t.Errorf("Ожидается ошибка %v, получено: %v", ErrPromocodeExhausted, err)
+		}
+	}
+	if succeeded != 1 || env.redemptions.counts[promocode.ID] != 1 {
+		t.Errorf("Ожидается ровно одно погашение, получено: %d (счетчик %d)", succeeded, env.redemptions.counts[promocode.ID])
+	}
+}
+
+func TestRedeemUniqueCode(t *testing.T) {
+	env := newTestRedemptionService()
+	ctx := context.Background()
+	promocode := env.createPromocode(models.Promocode{Code: "SPRING", MaxRedemptionsPerUser: 1})
+	batches := NewCodeBatchService(env.promocodes, env.codes)
+	batches.CreateBatch(ctx, testOwner, promocode.ID, models.GenerateCodesRequest{Quantity: 3, Length: 8})
+	batches.processPending(ctx)
+	first, second := env.codes.codes[0], env.codes.codes[1]
+
+	redemption, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: strings.ToLower(first.Code)})
+	if err != nil {
+		t.Fatalf("Ожидается погашение одноразового кода, получено: %v", err)
+	}
+	if redemption.PromocodeID != promocode.ID || redemption.Code != first.Code || redemption.CodeID == nil || *redemption.CodeID != first.ID {
+		t.Errorf("Неверная запись о погашении: %+v", redemption)
+	}
+	if stored, _ := env.codes.GetUniqueCode(ctx, first.Code); stored.RedeemedBy == nil || *stored.RedeemedBy != testStranger.UserID {
+		t.Errorf("Код должен быть отмечен погашенным, получено: %+v", stored)
+	}
+	if _, err := env.service.Redeem(ctx, testOwner, models.RedeemRequest{Code: first.Code}); err != ErrCodeAlreadyRedeemed {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrCodeAlreadyRedeemed, err)
+	}
+	// Условия промокода действуют и для одноразовых кодов
+	if _, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: second.Code}); err != ErrRedemptionLimitReached {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrRedemptionLimitReached, err)
+	}
+	if _, err := env.service.Redeem(ctx, testOwner, models.RedeemRequest{Code: second.Code}); err != nil {
+		t.Errorf("Ожидается погашение второго кода, получено: %v", err)
+	}
+	if env.redemptions.counts[promocode.ID] != 2 {
+		t.Errorf("Ожидается два погашения промокода, получено: %d", env.redemptions.counts[promocode.ID])
+	}
+}
+
+func TestListPromocodeRedemptions(t *testing.T) {
+	env := newTestRedemptionService()
+	ctx := context.Background()
+	promocode := env.createPromocode(models.Promocode{Code: "COFFEE10"})
+	env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: "COFFEE10"})
+
+	if _, err := env.service.ListPromocodeRedemptions(ctx, testStranger, promocode.ID, models.RedemptionFilter{}); err != ErrNotCompanyMember {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrNotCompanyMember, err)
+	}
+	list, err := env.service.ListPromocodeRedemptions(ctx, testAnalyst, promocode.ID, models.RedemptionFilter{})
+	if err != nil || list.Total != 1 || list.Redemptions[0].UserID != testStranger.UserID {
+		t.Errorf("Аналитик компании должен видеть погашения, получено: %+v, %v", list, err)
+	}
+}
+
+func TestRedeemAtMerchant(t *testing.T) {
+	env := newTestRedemptionService()
+	ctx := context.Background()
+	promocode := env.createPromocode(models.Promocode{Code: "CASH", MaxRedemptionsPerUser: 1})
+	env.createPromocode(models.Promocode{Code: "VIPONLY", Segments: models.Segments{"vip"}})
+	other := &models.Promocode{CompanyID: 2, Code: "OTHER", Type: models.PromocodeTypeGift}
+	env.promocodes.CreatePromocode(ctx, other)
+	key := &models.APIKey{ID: 5, CompanyID: 1}
+
+	result, err := env.service.RedeemAtMerchant(ctx, key, models.RedeemRequest{Code: "cash"})
+	if err != nil {
+		t.Fatalf("Ожидается погашение кассой, получено: %v", err)
+	}
+	if result.Redemption.UserID != 0 || result.Redemption.APIKeyID == nil || *result.Redemption.APIKeyID != 5 || result.Promocode.ID != promocode.ID {
+		t.Errorf("Неверный результат погашения: %+v", result)
+	}
+	// Личный лимит относится к пользователям, а не к кассе
+	if _, err := env.service.RedeemAtMerchant(ctx, key, models.RedeemRequest{Code: "CASH"}); err != nil {
+		t.Errorf("Касса должна гасить промокод повторно, получено: %v", err)
+	}
+	if _, err := env.service.RedeemAtMerchant(ctx, key, models.RedeemRequest{Code: "OTHER"}); err != ErrPromocodeNotFound {
+		t.Errorf("Промокод другой компании не должен гаситься, получено: %v", err)
+	}
+	if _, err := env.service.RedeemAtMerchant(ctx, key, models.RedeemRequest{Code: "VIPONLY"}); err != ErrSegmentNotAllowed {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrSegmentNotAllowed, err)
+	}
+}
+
+func TestRedeemUnapproved(t *testing.T) {
+	env := newTestRedemptionService()
+	ctx := context.Background()
+	env.createPromocode(models.Promocode{Code: "PENDING", ModerationStatus: models.ModerationPending})
+	env.createPromocode(models.Promocode{Code: "REJECTED", ModerationStatus: models.ModerationRejected})
+	key := &models.APIKey{ID: 5, CompanyID: 1}
+
+	for _, code := range []string{"PENDING", "REJECTED"} {
+		if _, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: code}); err != ErrPromocodeNotApproved {
+			t.Errorf("Промокод %s не должен гаситься пользователем, получено: %v", code, err)
+		}
+		if _, err := env.service.RedeemAtMerchant(ctx, key, models.RedeemRequest{Code: code}); err != ErrPromocodeNotApproved {
+			t.Errorf("Промокод %s не должен гаситься кассой, получено: %v", code, err)
+		}
+	}
+	if len(env.redemptions.redemptions) != 0 {
+		t.Errorf("Непроверенные промокоды не должны погашаться, получено: %d", len(env.redemptions.redemptions))
+	}
+
+	// После правки промокод снова ждет модерации, и гасить его уже нельзя
+	promocode, _ := env.promocodes.GetPromocodeByCode(ctx, "PENDING")
+	promocode.ModerationStatus = models.ModerationApproved
+	env.promocodes.UpdatePromocode(ctx, promocode)
+	if _, err := env.service.Redeem(ctx, testStranger, models.RedeemRequest{Code: "PENDING"}); err != nil {
+		t.Fatalf("Одобренный промокод должен погашаться, получено: %v", err)
+	}
+	promocode.ModerationStatus = models.ModerationPending
+	env.promocodes.UpdatePromocode(ctx, promocode)
+	if _, err := env.service.Redeem(ctx, testOwner, models.RedeemRequest{Code: "PENDING"}); err != ErrPromocodeNotApproved {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrPromocodeNotApproved, err)
+	}
+}
+
+func TestRedeemAtMerchantRetryAfterCrash(t *testing.T) {
+	env := newTestRedemptionService()
+	ctx := context.Background()
+	promocode := env.createPromocode(models.Promocode{Code: "CASH"})
+	key := &models.APIKey{ID: 5, CompanyID: 1}
+
+	// Первая попытка погасила код, но экземпляр упал до сохранения ответа; повтор перехватил резерв 9
+	first, err := env.service.RedeemAtMerchant(ctx, key, models.RedeemRequest{Code: "CASH", IdempotencyRecordID: 9})
+	if err != nil {
+		t.Fatalf("Ожидается погашение кассой, получено: %v", err)
+	}
+	retry, err := env.service.RedeemAtMerchant(ctx, key, models.RedeemRequest{Code: "CASH", IdempotencyRecordID: 9})
+	if err != nil || retry.Redemption.ID != first.Redemption.ID || retry.Promocode.ID != promocode.ID {
+		t.Errorf("Повтор должен получить то же погашение, получено: %+v, %v", retry, err)
+	}
+	if env.redemptions.counts[promocode.ID] != 1 {
+		t.Errorf("Повтор не должен гасить код второй раз, погашений: %d", env.redemptions.counts[promocode.ID])
+	}
+
+	if _, err := env.service.RedeemAtMerchant(ctx, key, models.RedeemRequest{Code: "CASH", IdempotencyRecordID: 10}); err != nil {
+		t.Errorf("Запрос с другим ключом идемпотентности выполняется, получено: %v", err)
+	}
+	if env.redemptions.counts[promocode.ID] != 2 {
+		t.Errorf("Ожидается два погашения, получено: %d", env.redemptions.counts[promocode.ID])
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"promocodes-service/models"
+	"promocodes-service/repository"
+	"promocodes-service/tracing"
+)
+
+var ErrNotCompanyOwner = errors.New("настройки компании изменяют только ее владельцы")
+
+type SettingsService struct {
+	settingsRepo repository.CompanySettingsRepositoryInterface
+}
+
+func NewSettingsService(settingsRepo repository.CompanySettingsRepositoryInterface) *SettingsService {
+	return &SettingsService{settingsRepo: settingsRepo}
+}
+
+func (s *SettingsService) GetCompanySettings(ctx context.Context, companyID uint) (*models.CompanySettings, error) {
+	ctx, span := tracing.Start(ctx, "SettingsService.GetCompanySettings")
+	defer span.End()
+
+	settings, err := s.settingsRepo.GetCompanySettings(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		// Компания еще не меняла настройки: действуют значения по умолчанию
+		return &models.CompanySettings{CompanyID: companyID}, nil
+	}
+	return settings, nil
+}
+
+func (s *SettingsService) UpdateCompanySettings(ctx context.Context, actor *models.Identity, companyID uint, req models.UpdateCompanySettingsRequest) (*models.CompanySettings, error) {
+	ctx, span := tracing.Start(ctx, "SettingsService.UpdateCompanySettings")
+	defer span.End()
+
+	if err := authorizeOwner(actor, companyID); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.GetCompanySettings(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	settings.PremoderateComments = *req.PremoderateComments
+	if err := s.settingsRepo.SaveCompanySettings(ctx, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+var _ SettingsServiceInterface = (*SettingsService)(nil)
@@ -0,0 +1,110 @@
+package userservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"promocodes-service/models"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// HeaderInternalToken передает общий секрет внутренних сервисов (INTERNAL_API_TOKEN)
+const HeaderInternalToken = "X-Internal-Token"
+
+// Company - поля компании из User Service, которые нужны сервису промокодов
+type Company struct {
+	ID               uint                    `json:"id"`
+	ModerationStatus models.ModerationStatus `json:"moderation_status"`
+}
+
+// Notification - письмо пользователю; адрес знает только User Service
+type Notification struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Client читает компании из User Service и отправляет письма пользователям через него
+type Client interface {
+	// GetCompany возвращает nil, если компании нет
+	GetCompany(ctx context.Context, companyID uint) (*Company, error)
+	NotifyUser(ctx context.Context, userID uint, notification Notification) error
+}
+
+// HTTPClient обращается к внутренним маршрутам User Service, которые шлюз не проксирует
+type HTTPClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewHTTPClient(baseURL, token string, timeout time.Duration) *HTTPClient {
+	return &HTTPClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *HTTPClient) GetCompany(ctx context.Context, companyID uint) (*Company, error) {
+	var company Company
+	found, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/internal/companies/%d", companyID), nil, &company)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// NotifyUser не считает ошибкой удаленного пользователя: письмо отправлять некому
+func (c *HTTPClient) NotifyUser(ctx context.Context, userID uint, notification Notification) error {
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/internal/users/%d/notifications", userID), notification, nil)
+	return err
+}
+
+// do выполняет запрос с телом in и разбирает ответ в out; false - User Service ответил 404
+func (c *HTTPClient) do(ctx context.Context, method, path string, in, out interface{}) (bool, error) {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return false, err
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return false, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(HeaderInternalToken, c.token)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("user-service недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("user-service ответил %d на %s %s: %s", resp.StatusCode, method, path, strings.TrimSpace(string(message)))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return false, fmt.Errorf("некорректный ответ user-service: %w", err)
+		}
+	}
+	return true, nil
+}
+
+var _ Client = (*HTTPClient)(nil)
@@ -0,0 +1,65 @@
+package userservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"promocodes-service/models"
+	"testing"
+	"time"
+)
+
+func TestGetCompany(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderInternalToken) != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/internal/companies/7" {
+			http.Error(w, `{"error":"компания не найдена"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id":7,"name":"Кофейня","moderation_status":"approved"}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "secret", time.Second)
+	company, err := client.GetCompany(context.Background(), 7)
+	if err != nil || company == nil || company.ID != 7 || company.ModerationStatus != models.ModerationApproved {
+		t.Fatalf("Ожидается одобренная компания 7, получено: %+v, %v", company, err)
+	}
+	if company, err := client.GetCompany(context.Background(), 42); company != nil || err != nil {
+		t.Errorf("Для отсутствующей компании ожидается nil без ошибки, получено: %+v, %v", company, err)
+	}
+	if _, err := NewHTTPClient(server.URL, "wrong", time.Second).GetCompany(context.Background(), 7); err == nil {
+		t.Error("Ответ с ошибкой должен возвращать ошибку")
+	}
+}
+
+func TestNotifyUser(t *testing.T) {
+	var path string
+	var notification Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&notification)
+		if r.URL.Path == "/internal/users/42/notifications" {
+			http.Error(w, `{"error":"пользователь не найден"}`, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "secret", time.Second)
+	sent := Notification{Subject: "Промокод COFFEE10 прошел модерацию", Body: "Здравствуйте!"}
+	if err := client.NotifyUser(context.Background(), 7, sent); err != nil {
+		t.Fatalf("Ожидается успешная отправка, получено: %v", err)
+	}
+	if path != "/internal/users/7/notifications" || notification != sent {
+		t.Errorf("Неверный запрос: %s %+v", path, notification)
+	}
+	if err := client.NotifyUser(context.Background(), 42, sent); err != nil {
+		t.Errorf("Удаленному пользователю письмо не отправляется без ошибки, получено: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"user-service/models"
+	"user-service/pb/userpb"
+	"user-service/services"
+	"user-service/tracing"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userIDKey struct{}
+
+// Методы, для которых требуется токен в метаданных authorization
+var protectedMethods = map[string]bool{
+	"/user.v1.UserService/GetUserProfile":    true,
+	"/user.v1.UserService/UpdateUserProfile": true,
+}
+
+type Server struct {
+	userpb.UnimplementedUserServiceServer
+	userService services.UserServiceInterface
+	// trustedProxies - адреса шлюза, которому разрешено передавать адрес клиента в метаданных
+	trustedProxies []*net.IPNet
+}
+
+func NewServer(userService services.UserServiceInterface) *Server {
+	return &Server{userService: userService}
+}
+
+// SetTrustedProxies задает адреса или подсети шлюза, как одноименный метод gin.Engine.
+// Без них метаданные x-forwarded-* игнорируются и адресом клиента считается адрес соединения.
+func (s *Server) SetTrustedProxies(proxies []string) error {
+	trusted := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("некорректный адрес прокси: %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("некорректная подсеть прокси: %q", proxy)
+		}
+		trusted = append(trusted, network)
+	}
+	s.trustedProxies = trusted
+	return nil
+}
+
+func (s *Server) Register(ctx context.Context, req *userpb.RegisterRequest) (*userpb.RegisterResponse, error) {
+	registerReq := models.RegisterRequest{
+		Login:    req.GetLogin(),
+		Password: req.GetPassword(),
+		Email:    req.GetEmail(),
+	}
+	if err := binding.Validator.ValidateStruct(registerReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.userService.Register(ctx, registerReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &userpb.RegisterResponse{}, nil
+}
+
+func (s *Server) Login(ctx context.Context, req *userpb.LoginRequest) (*userpb.LoginResponse, error) {
+	loginReq := models.LoginRequest{
+		Login:    req.GetLogin(),
+		Password: req.GetPassword(),
+	}
+	if err := binding.Validator.ValidateStruct(loginReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp, err := s.userService.Login(ctx, loginReq, s.clientFromContext(ctx))
+	if err != nil {
+		return nil, loginError(err)
+	}
+	return toProtoLoginResponse(resp), nil
+}
+
+func (s *Server) LoginMFA(ctx context.Context, req *userpb.LoginMFARequest) (*userpb.LoginResponse, error) {
+	mfaReq := models.MFALoginRequest{
+		MFAToken: req.GetMfaToken(),
+		Code:     req.GetCode(),
+	}
+	if err := binding.Validator.ValidateStruct(mfaReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp, err := s.userService.LoginMFA(ctx, mfaReq, s.clientFromContext(ctx))
+	if err != nil {
+		return nil, loginError(err)
+	}
+	return toProtoLoginResponse(resp), nil
+}
+
+func (s *Server) RefreshToken(ctx context.Context, req *userpb.RefreshTokenRequest) (*userpb.LoginResponse, error) {
+	refreshReq := models.RefreshTokenRequest{RefreshToken: req.GetRefreshToken()}
+	if err := binding.Validator.ValidateStruct(refreshReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp, err := s.userService.RefreshToken(ctx, refreshReq.RefreshToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return toProtoLoginResponse(resp), nil
+}
+
+func (s *Server) GetUserProfile(ctx context.Context, req *userpb.GetUserProfileRequest) (*userpb.User, error) {
+	user, err := s.userService.GetUserProfile(ctx, userIDFromContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "пользователь не найден")
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *Server) UpdateUserProfile(ctx context.Context, req *userpb.UpdateUserProfileRequest) (*userpb.UpdateUserProfileResponse, error) {
+	updateReq := models.UpdateProfileRequest{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     req.GetEmail(),
+		Phone:     req.GetPhone(),
+	}
+	if req.BirthDate != nil {
+		birthDate := req.GetBirthDate().AsTime()
+		updateReq.BirthDate = &birthDate
+	}
+	if err := binding.Validator.ValidateStruct(updateReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.userService.UpdateUserProfile(ctx, userIDFromContext(ctx), updateReq); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &userpb.UpdateUserProfileResponse{}, nil
+}
+
+func (s *Server) ValidateToken(ctx context.Context, req *userpb.ValidateTokenRequest) (*userpb.ValidateTokenResponse, error) {
+	claims, err := s.userService.ValidateToken(ctx, req.GetToken())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return &userpb.ValidateTokenResponse{UserId: uint64(claims.UserID)}, nil
+}
+
+// AuthInterceptor - аналог UserHandler.AuthMiddleware для gRPC
+func (s *Server) AuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !protectedMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 || values[0] == "" {
+			return nil, status.Error(codes.Unauthenticated, "требуется заголовок авторизации")
+		}
+
+		tokenParts := strings.Split(values[0], " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			return nil, status.Error(codes.Unauthenticated, "неверный формат заголовка авторизации")
+		}
+
+		claims, err := s.userService.ValidateToken(ctx, tokenParts[1])
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(context.WithValue(ctx, userIDKey{}, claims.UserID), req)
+	}
+}
+
+// LoggingInterceptor пишет в лог каждый вызов вместе с X-Request-ID, полученным от шлюза
+func LoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(tracing.RequestIDHeader); len(values) > 0 {
+				requestID = values[0]
+			}
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		log.Printf("[gRPC] %s | %s | %v | %s", requestID, status.Code(err), time.Since(start), info.FullMethod)
+		return resp, err
+	}
+}
+
+func userIDFromContext(ctx context.Context) uint {
+	userID, _ := ctx.Value(userIDKey{}).(uint)
+	return userID
+}
+
+// loginError переводит ошибку входа в статус: ResourceExhausted, пока вход временно запрещен
+func loginError(err error) error {
+	var throttled *services.ThrottledError
+	if errors.As(err, &throttled) {
+		// Время до следующей попытки передается в RetryInfo, шлюз превращает его в Retry-After
+		st := status.New(codes.ResourceExhausted, err.Error())
+		if detailed, detailsErr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(throttled.RetryAfter)}); detailsErr == nil {
+			st = detailed
+		}
+		return st.Err()
+	}
+	return status.Error(codes.Unauthenticated, err.Error())
+}
+
+// clientFromContext берет адрес и User-Agent клиента из метаданных, если соединение пришло от шлюза,
+// а иначе - адрес соединения: прямой вызов не может подменить адрес, по которому считаются попытки входа
+func (s *Server) clientFromContext(ctx context.Context) models.ClientInfo {
+	var client models.ClientInfo
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return client
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return client
+	}
+	client.IP = host
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || !s.isTrustedProxy(net.ParseIP(host)) {
+		return client
+	}
+	if values := md.Get("x-forwarded-for"); len(values) > 0 {
+		if ip := strings.TrimSpace(values[len(values)-1]); net.ParseIP(ip) != nil {
+			client.IP = ip
+		}
+	}
+	if values := md.Get("x-forwarded-user-agent"); len(values) > 0 {
+		client.UserAgent = values[0]
+	}
+	return client
+}
+
+func (s *Server) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range s.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func toProtoLoginResponse(resp *models.LoginResponse) *userpb.LoginResponse {
+	return &userpb.LoginResponse{
+		Token:        resp.Token,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    resp.ExpiresIn,
+		MfaRequired:  resp.MFARequired,
+		MfaToken:     resp.MFAToken,
+	}
+}
+
+func toProtoUser(user *models.User) *userpb.User {
+	return &userpb.User{
+		Id:            uint64(user.ID),
+		Login:         user.Login,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		TotpEnabled:   user.TOTPEnabled,
+		Role:          string(user.Role),
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		BirthDate:     timestamppb.New(user.BirthDate),
+		Phone:         user.Phone,
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+	}
+}
+
+var _ userpb.UserServiceServer = (*Server)(nil)
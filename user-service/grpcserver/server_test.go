@@ -0,0 +1,273 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"user-service/models"
+	"user-service/pb/userpb"
+	"user-service/services"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type MockUserService struct {
+	RegisterFunc          func(models.RegisterRequest) error
+	LoginFunc             func(models.LoginRequest) (*models.LoginResponse, error)
+	LoginMFAFunc          func(models.MFALoginRequest) (*models.LoginResponse, error)
+	RefreshTokenFunc      func(string) (*models.LoginResponse, error)
+	GetUserProfileFunc    func(uint) (*models.User, error)
+	UpdateUserProfileFunc func(uint, models.UpdateProfileRequest) error
+	ValidateTokenFunc     func(string) (*models.TokenClaims, error)
+	ChangePasswordFunc    func(uint, models.ChangePasswordRequest) error
+	ForgotPasswordFunc    func(string) error
+	ResetPasswordFunc     func(models.ResetPasswordRequest) error
+	AssignRoleFunc        func(uint, models.Role) error
+}
+
+var _ services.UserServiceInterface = (*MockUserService)(nil)
+
+func (m *MockUserService) Register(ctx context.Context, req models.RegisterRequest) error {
+	return m.RegisterFunc(req)
+}
+
+func (m *MockUserService) Login(ctx context.Context, req models.LoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	return m.LoginFunc(req)
+}
+
+func (m *MockUserService) LoginMFA(ctx context.Context, req models.MFALoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	return m.LoginMFAFunc(req)
+}
+
+func (m *MockUserService) RefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
+	return m.RefreshTokenFunc(refreshToken)
+}
+
+func (m *MockUserService) GetUserProfile(ctx context.Context, userID uint) (*models.User, error) {
+	return m.GetUserProfileFunc(userID)
+}
+
+func (m *MockUserService) UpdateUserProfile(ctx context.Context, userID uint, req models.UpdateProfileRequest) error {
+	return m.UpdateUserProfileFunc(userID, req)
+}
+
+func (m *MockUserService) ValidateToken(ctx context.Context, token string) (*models.TokenClaims, error) {
+	return m.ValidateTokenFunc(token)
+}
+
+func (m *MockUserService) AssignRole(ctx context.Context, userID uint, role models.Role) error {
+	return m.AssignRoleFunc(userID, role)
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, userID uint, req models.ChangePasswordRequest, client models.ClientInfo) error {
+	return m.ChangePasswordFunc(userID, req)
+}
+
+func (m *MockUserService) ForgotPassword(ctx context.Context, email string) error {
+	return m.ForgotPasswordFunc(email)
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error {
+	return m.ResetPasswordFunc(req)
+}
+
+func newTestClient(t *testing.T, userService services.UserServiceInterface) userpb.UserServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := NewServer(userService)
+	server := grpc.NewServer(grpc.UnaryInterceptor(grpcServer.AuthInterceptor()))
+	userpb.RegisterUserServiceServer(server, grpcServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("Ошибка подключения к gRPC серверу: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return userpb.NewUserServiceClient(conn)
+}
+
+func TestRegisterValidation(t *testing.T) {
+	client := newTestClient(t, &MockUserService{
+		RegisterFunc: func(req models.RegisterRequest) error {
+			return nil
+		},
+	})
+
+	_, err := client.Register(context.Background(), &userpb.RegisterRequest{
+		Login:    "testuser",
+		Password: "password123",
+		Email:    "test@example.com",
+	})
+	if err != nil {
+		t.Errorf("Ожидается успешная регистрация, получено: %v", err)
+	}
+
+	_, err = client.Register(context.Background(), &userpb.RegisterRequest{
+		Login:    "abc",
+		Password: "password123",
+		Email:    "test@example.com",
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Ожидается InvalidArgument для короткого логина, получено: %v", err)
+	}
+}
+
+func TestLoginError(t *testing.T) {
+	client := newTestClient(t, &MockUserService{
+		LoginFunc: func(req models.LoginRequest) (*models.LoginResponse, error) {
+			return nil, errors.New("неверный логин или пароль")
+		},
+	})
+
+	_, err := client.Login(context.Background(), &userpb.LoginRequest{Login: "testuser", Password: "wrong"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Ожидается Unauthenticated, получено: %v", err)
+	}
+	if status.Convert(err).Message() != "неверный логин или пароль" {
+		t.Errorf("Сообщение ошибки сервиса должно сохраняться, получено: %s", status.Convert(err).Message())
+	}
+}
+
+func TestLoginThrottled(t *testing.T) {
+	client := newTestClient(t, &MockUserService{
+		LoginFunc: func(req models.LoginRequest) (*models.LoginResponse, error) {
+			return nil, &services.ThrottledError{RetryAfter: time.Minute}
+		},
+	})
+
+	_, err := client.Login(context.Background(), &userpb.LoginRequest{Login: "testuser", Password: "password123"})
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("Ожидается ResourceExhausted, получено: %v", err)
+	}
+	var retryDelay time.Duration
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retryDelay = info.GetRetryDelay().AsDuration()
+		}
+	}
+	if retryDelay != time.Minute {
+		t.Errorf("Ожидается RetryInfo с задержкой 1m, получено: %v", retryDelay)
+	}
+}
+
+func TestGetUserProfileRequiresToken(t *testing.T) {
+	client := newTestClient(t, &MockUserService{
+		ValidateTokenFunc: func(token string) (*models.TokenClaims, error) {
+			if token != "valid_token" {
+				return nil, errors.New("недействительный токен")
+			}
+			return &models.TokenClaims{UserID: 1, SessionID: "session", JTI: "jti"}, nil
+		},
+		GetUserProfileFunc: func(userID uint) (*models.User, error) {
+			return &models.User{ID: userID, Login: "testuser"}, nil
+		},
+	})
+
+	_, err := client.GetUserProfile(context.Background(), &userpb.GetUserProfileRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Ожидается Unauthenticated без токена, получено: %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer valid_token")
+	user, err := client.GetUserProfile(ctx, &userpb.GetUserProfileRequest{})
+	if err != nil {
+		t.Fatalf("Ожидается успешное получение профиля, получено: %v", err)
+	}
+	if user.GetId() != 1 || user.GetLogin() != "testuser" {
+		t.Errorf("Неверный профиль: %v", user)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	client := newTestClient(t, &MockUserService{
+		RefreshTokenFunc: func(refreshToken string) (*models.LoginResponse, error) {
+			if refreshToken != "valid_refresh" {
+				return nil, services.ErrInvalidRefreshToken
+			}
+			return &models.LoginResponse{Token: "new_token", RefreshToken: "new_refresh", ExpiresIn: 900}, nil
+		},
+	})
+
+	resp, err := client.RefreshToken(context.Background(), &userpb.RefreshTokenRequest{RefreshToken: "valid_refresh"})
+	if err != nil {
+		t.Fatalf("Ожидается успешное обновление токена, получено: %v", err)
+	}
+	if resp.GetToken() != "new_token" || resp.GetRefreshToken() != "new_refresh" || resp.GetExpiresIn() != 900 {
+		t.Errorf("Неверный ответ: %v", resp)
+	}
+
+	_, err = client.RefreshToken(context.Background(), &userpb.RefreshTokenRequest{RefreshToken: "stolen_refresh"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Ожидается Unauthenticated, получено: %v", err)
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	client := newTestClient(t, &MockUserService{
+		LoginFunc: func(req models.LoginRequest) (*models.LoginResponse, error) {
+			return &models.LoginResponse{MFARequired: true, MFAToken: "challenge"}, nil
+		},
+		LoginMFAFunc: func(req models.MFALoginRequest) (*models.LoginResponse, error) {
+			if req.MFAToken != "challenge" || req.Code != "123456" {
+				return nil, services.ErrInvalidMFACode
+			}
+			return &models.LoginResponse{Token: "token", RefreshToken: "refresh", ExpiresIn: 900}, nil
+		},
+	})
+
+	resp, err := client.Login(context.Background(), &userpb.LoginRequest{Login: "testuser", Password: "password123"})
+	if err != nil || !resp.GetMfaRequired() || resp.GetMfaToken() != "challenge" || resp.GetToken() != "" {
+		t.Fatalf("Ожидается первый шаг входа с mfa_token, получено: %v, %v", resp, err)
+	}
+
+	_, err = client.LoginMFA(context.Background(), &userpb.LoginMFARequest{MfaToken: "challenge", Code: "000000"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Ожидается Unauthenticated для неверного кода, получено: %v", err)
+	}
+	_, err = client.LoginMFA(context.Background(), &userpb.LoginMFARequest{MfaToken: "challenge"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Ожидается InvalidArgument без кода, получено: %v", err)
+	}
+	resp, err = client.LoginMFA(context.Background(), &userpb.LoginMFARequest{MfaToken: "challenge", Code: "123456"})
+	if err != nil || resp.GetToken() != "token" {
+		t.Errorf("Ожидается выдача токенов, получено: %v, %v", resp, err)
+	}
+}
+
+func TestClientFromContext(t *testing.T) {
+	server := NewServer(&MockUserService{})
+	if err := server.SetTrustedProxies([]string{"api-service"}); err == nil {
+		t.Error("Имя хоста не должно приниматься как адрес прокси")
+	}
+	if err := server.SetTrustedProxies([]string{"172.28.0.10"}); err != nil {
+		t.Fatalf("Ошибка настройки доверенных прокси: %v", err)
+	}
+
+	incoming := func(peerIP string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(peerIP), Port: 40000}})
+		return metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "203.0.113.7", "x-forwarded-user-agent", "test-agent"))
+	}
+
+	if client := server.clientFromContext(incoming("172.28.0.10")); client.IP != "203.0.113.7" || client.UserAgent != "test-agent" {
+		t.Errorf("Метаданные шлюза должны приниматься, получено: %+v", client)
+	}
+	// Прямой вызов не может выдать себя за другой адрес и обойти ограничение попыток входа
+	if client := server.clientFromContext(incoming("172.28.0.20")); client.IP != "172.28.0.20" || client.UserAgent != "" {
+		t.Errorf("Ожидается адрес соединения для недоверенного источника, получено: %+v", client)
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	userService  services.UserServiceInterface
+	adminService services.AdminServiceInterface
+}
+
+func NewAdminHandler(userService services.UserServiceInterface, adminService services.AdminServiceInterface) *AdminHandler {
+	return &AdminHandler{
+		userService:  userService,
+		adminService: adminService,
+	}
+}
+
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var filter models.UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := h.adminService.ListUsers(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) BlockUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.BlockUser(c.Request.Context(), c.GetUint("userID"), userID); err != nil {
+		writeAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пользователь заблокирован"})
+}
+
+func (h *AdminHandler) UnblockUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.UnblockUser(c.Request.Context(), userID); err != nil {
+		writeAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пользователь разблокирован"})
+}
+
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.DeleteUser(c.Request.Context(), c.GetUint("userID"), userID); err != nil {
+		writeAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пользователь удален"})
+}
+
+func (h *AdminHandler) ResetPassword(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	password, err := h.adminService.ForceResetPassword(c.Request.Context(), userID)
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ResetPasswordResponse{TemporaryPassword: password})
+}
+
+func (h *AdminHandler) AssignRole(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.AssignRole(c.Request.Context(), userID, req.Role); err != nil {
+		writeAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Роль назначена"})
+}
+
+func (h *AdminHandler) SetSegments(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetSegmentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.adminService.SetSegments(c.Request.Context(), userID, req.Segments)
+	if err != nil {
+		writeAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func userIDParam(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор пользователя"})
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+func writeAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownRole), errors.Is(err, services.ErrCannotModifySelf),
+		errors.Is(err, services.ErrInvalidSegment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAssignRoleRequiresPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	var assigned models.Role
+	mockService := &MockUserService{
+		ValidateTokenFunc: func(token string) (*models.TokenClaims, error) {
+			return &models.TokenClaims{UserID: 1, Roles: []models.Role{models.Role(token)}}, nil
+		},
+		AssignRoleFunc: func(userID uint, role models.Role) error {
+			if !role.Valid() {
+				return services.ErrUnknownRole
+			}
+			if userID != 2 {
+				return services.ErrUserNotFound
+			}
+			assigned = role
+			return nil
+		},
+	}
+	userHandler := NewUserHandler(mockService)
+	handler := NewAdminHandler(mockService, &MockAdminService{})
+
+	admin := r.Group("/admin")
+	admin.Use(userHandler.AuthMiddleware())
+	admin.PUT("/users/:id/role", RequirePermission(models.PermissionAssignRoles), handler.AssignRole)
+
+	send := func(role models.Role, path string, newRole models.Role) int {
+		body, _ := json.Marshal(models.AssignRoleRequest{Role: newRole})
+		req, _ := http.NewRequest("PUT", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		// В тестовом моке токен - это роль пользователя
+		req.Header.Set("Authorization", "Bearer "+string(role))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(models.RoleModerator, "/admin/users/2/role", models.RoleAdmin); code != http.StatusForbidden {
+		t.Errorf("Модератор не может назначать роли, ожидается 403, получен: %d", code)
+	}
+	if code := send(models.RoleAdmin, "/admin/users/2/role", "superuser"); code != http.StatusBadRequest {
+		t.Errorf("Ожидается 400 для неизвестной роли, получен: %d", code)
+	}
+	if code := send(models.RoleAdmin, "/admin/users/3/role", models.RoleModerator); code != http.StatusNotFound {
+		t.Errorf("Ожидается 404 для неизвестного пользователя, получен: %d", code)
+	}
+	if code := send(models.RoleAdmin, "/admin/users/2/role", models.RoleModerator); code != http.StatusOK || assigned != models.RoleModerator {
+		t.Errorf("Ожидается назначение роли moderator, получено: %d %s", code, assigned)
+	}
+}
+
+type MockAdminService struct {
+	ListUsersFunc          func(models.UserFilter) (*models.UserList, error)
+	GetUserFunc            func(uint) (*models.User, error)
+	BlockUserFunc          func(uint, uint) error
+	UnblockUserFunc        func(uint) error
+	DeleteUserFunc         func(uint, uint) error
+	ForceResetPasswordFunc func(uint) (string, error)
+	SetSegmentsFunc        func(uint, []string) (*models.User, error)
+}
+
+var _ services.AdminServiceInterface = (*MockAdminService)(nil)
+
+func (m *MockAdminService) ListUsers(ctx context.Context, filter models.UserFilter) (*models.UserList, error) {
+	return m.ListUsersFunc(filter)
+}
+
+func (m *MockAdminService) GetUser(ctx context.Context, userID uint) (*models.User, error) {
+	return m.GetUserFunc(userID)
+}
+
+func (m *MockAdminService) BlockUser(ctx context.Context, adminID, userID uint) error {
+	return m.BlockUserFunc(adminID, userID)
+}
+
+func (m *MockAdminService) UnblockUser(ctx context.Context, userID uint) error {
+	return m.UnblockUserFunc(userID)
+}
+
+func (m *MockAdminService) DeleteUser(ctx context.Context, adminID, userID uint) error {
+	return m.DeleteUserFunc(adminID, userID)
+}
+
+func (m *MockAdminService) ForceResetPassword(ctx context.Context, userID uint) (string, error) {
+	return m.ForceResetPasswordFunc(userID)
+}
+
+func (m *MockAdminService) SetSegments(ctx context.Context, userID uint, segments []string) (*models.User, error) {
+	return m.SetSegmentsFunc(userID, segments)
+}
+
+func TestAdminUserManagement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	var filter models.UserFilter
+	adminService := &MockAdminService{
+		ListUsersFunc: func(f models.UserFilter) (*models.UserList, error) {
+			filter = f
+			return &models.UserList{Users: []models.User{{ID: 2, Login: "testuser"}}, Total: 1, Page: 1, PageSize: 20}, nil
+		},
+		BlockUserFunc: func(adminID, userID uint) error {
+			if adminID == userID {
+				return services.ErrCannotModifySelf
+			}
+			if userID != 2 {
+				return services.ErrUserNotFound
+			}
+			return nil
+		},
+		ForceResetPasswordFunc: func(userID uint) (string, error) {
+			return "temporary", nil
+		},
+		SetSegmentsFunc: func(userID uint, segments []string) (*models.User, error) {
+			for _, segment := range segments {
+				if !models.ValidSegment(segment) {
+					return nil, services.ErrInvalidSegment
+				}
+			}
+			return &models.User{ID: userID, Segments: models.NormalizeSegments(segments)}, nil
+		},
+	}
+	userService := &MockUserService{
+		ValidateTokenFunc: func(token string) (*models.TokenClaims, error) {
+			return &models.TokenClaims{UserID: 1, Roles: []models.Role{models.Role(token)}}, nil
+		},
+	}
+	userHandler := NewUserHandler(userService)
+	handler := NewAdminHandler(userService, adminService)
+
+	admin := r.Group("/admin")
+	admin.Use(userHandler.AuthMiddleware())
+	admin.GET("/users", RequirePermission(models.PermissionViewUsers), handler.ListUsers)
+	admin.POST("/users/:id/block", RequirePermission(models.PermissionManageUsers), handler.BlockUser)
+	admin.POST("/users/:id/reset-password", RequirePermission(models.PermissionManageUsers), handler.ResetPassword)
+	admin.PUT("/users/:id/segments", RequirePermission(models.PermissionManageUsers), handler.SetSegments)
+
+	send := func(role models.Role, method, path string, body ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(strings.Join(body, "")))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+string(role))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(models.RoleAdmin, "GET", "/admin/users?login=test&role=user&page=2&page_size=10")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if filter.LoginPrefix != "test" || filter.Role != models.RoleUser || filter.Page != 2 || filter.PageSize != 10 {
+		t.Errorf("Параметры фильтра не переданы сервису: %+v", filter)
+	}
+	if w := send(models.RoleAdmin, "GET", "/admin/users?page_size=1000"); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается 400 для слишком большого размера страницы, получен: %d", w.Code)
+	}
+	if w := send(models.RoleUser, "GET", "/admin/users"); w.Code != http.StatusForbidden {
+		t.Errorf("Обычный пользователь не может просматривать список, ожидается 403, получен: %d", w.Code)
+	}
+
+	if w := send(models.RoleModerator, "POST", "/admin/users/2/block"); w.Code != http.StatusForbidden {
+		t.Errorf("Модератор не может блокировать пользователей, ожидается 403, получен: %d", w.Code)
+	}
+	if w := send(models.RoleAdmin, "POST", "/admin/users/1/block"); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается 400 при блокировке самого себя, получен: %d", w.Code)
+	}
+	if w := send(models.RoleAdmin, "POST", "/admin/users/3/block"); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается 404 для неизвестного пользователя, получен: %d", w.Code)
+	}
+	if w := send(models.RoleAdmin, "POST", "/admin/users/2/block"); w.Code != http.StatusOK {
+		t.Errorf("Ожидается успешная блокировка, получен: %d", w.Code)
+	}
+
+	w = send(models.RoleAdmin, "POST", "/admin/users/2/reset-password")
+	var response models.ResetPasswordResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.TemporaryPassword != "temporary" {
+		t.Errorf("Ожидается временный пароль в ответе, получено: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := send(models.RoleAdmin, "PUT", "/admin/users/2/segments", `{"segments":["VIP"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается 400 для некорректного сегмента, получен: %d", w.Code)
+	}
+	w = send(models.RoleAdmin, "PUT", "/admin/users/2/segments", `{"segments":["vip","employees","vip"]}`)
+	var user models.User
+	json.Unmarshal(w.Body.Bytes(), &user)
+	if w.Code != http.StatusOK || strings.Join(user.Segments, ",") != "employees,vip" {
+		t.Errorf("Ожидаются сегменты employees и vip, получено: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/moderation", func(c *gin.Context) {
+		c.Set("tokenClaims", &models.TokenClaims{UserID: 1, Roles: []models.Role{models.Role(c.GetHeader("X-Test-Role"))}})
+	}, RequireRole(models.RoleModerator, models.RoleAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for role, expected := range map[models.Role]int{
+		models.RoleUser:      http.StatusForbidden,
+		models.RoleModerator: http.StatusOK,
+		models.RoleAdmin:     http.StatusOK,
+	} {
+		req, _ := http.NewRequest("GET", "/moderation", nil)
+		req.Header.Set("X-Test-Role", string(role))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != expected {
+			t.Errorf("Роль %s: ожидается %d, получен: %d", role, expected, w.Code)
+		}
+	}
+}
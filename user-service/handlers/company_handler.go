@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CompanyHandler struct {
+	companyService services.CompanyServiceInterface
+}
+
+func NewCompanyHandler(companyService services.CompanyServiceInterface) *CompanyHandler {
+	return &CompanyHandler{companyService: companyService}
+}
+
+func (h *CompanyHandler) CreateCompany(c *gin.Context) {
+	var req models.CreateCompanyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, err := h.companyService.CreateCompany(c.Request.Context(), c.GetUint("userID"), req)
+	if err != nil {
+		writeCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, company)
+}
+
+// ListCompanies возвращает компании текущего пользователя
+func (h *CompanyHandler) ListCompanies(c *gin.Context) {
+	companies, err := h.companyService.ListUserCompanies(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		writeCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, companies)
+}
+
+// ListUserCompanies возвращает компании пользователя из пути, для административного API
+func (h *CompanyHandler) ListUserCompanies(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	companies, err := h.companyService.ListUserCompanies(c.Request.Context(), userID)
+	if err != nil {
+		writeCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, companies)
+}
+
+func (h *CompanyHandler) GetCompany(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	company, err := h.companyService.GetCompany(c.Request.Context(), claims, companyID)
+	if err != nil {
+		writeCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, company)
+}
+
+func (h *CompanyHandler) UpdateCompany(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCompanyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, err := h.companyService.UpdateCompany(c.Request.Context(), claims, companyID, req)
+	if err != nil {
+		writeCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, company)
+}
+
+func (h *CompanyHandler) DeleteCompany(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.companyService.DeleteCompany(c.Request.Context(), claims, companyID); err != nil {
+		writeCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Компания удалена"})
+}
+
+func (h *CompanyHandler) SetSubscription(c *gin.Context) {
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, err := h.companyService.SetSubscription(c.Request.Context(), companyID, req.SubscriptionLevel)
+	if err != nil {
+		writeCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, company)
+}
+
+func companyIDParam(c *gin.Context) (uint, bool) {
+	companyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный идентификатор компании"})
+		return 0, false
+	}
+	return uint(companyID), true
+}
+
+func writeCompanyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownSubscriptionLevel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotCompanyOwner), errors.Is(err, services.ErrNotCompanyMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCompanyNotFound), errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockCompanyService struct {
+	companies map[uint]*models.Company
+}
+
+var _ services.CompanyServiceInterface = (*MockCompanyService)(nil)
+
+func (m *MockCompanyService) CreateCompany(ctx context.Context, ownerID uint, req models.CreateCompanyRequest) (*models.Company, error) {
+	company := &models.Company{ID: uint(len(m.companies) + 1), OwnerID: ownerID, Name: req.Name, SubscriptionLevel: models.SubscriptionFree}
+	m.companies[company.ID] = company
+	return company, nil
+}
+
+// GetCompany показывает непроверенную компанию только ее владельцу
+func (m *MockCompanyService) GetCompany(ctx context.Context, actor *models.TokenClaims, companyID uint) (*models.Company, error) {
+	company, err := m.LookupCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company.ModerationStatus != models.ModerationApproved && company.OwnerID != actor.UserID {
+		return nil, services.ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+func (m *MockCompanyService) LookupCompany(ctx context.Context, companyID uint) (*models.Company, error) {
+	company, exists := m.companies[companyID]
+	if !exists {
+		return nil, services.ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+func (m *MockCompanyService) ListUserCompanies(ctx context.Context, userID uint) ([]models.Company, error) {
+	companies := []models.Company{}
+	for _, company := range m.companies {
+		if company.OwnerID == userID {
+			companies = append(companies, *company)
+		}
+	}
+	return companies, nil
+}
+
+func (m *MockCompanyService) UpdateCompany(ctx context.Context, actor *models.TokenClaims, companyID uint, req models.UpdateCompanyRequest) (*models.Company, error) {
+	company, err := m.LookupCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company.OwnerID != actor.UserID {
+		return nil, services.ErrNotCompanyOwner
+	}
+	company.Name = req.Name
+	return company, nil
+}
+
+func (m *MockCompanyService) DeleteCompany(ctx context.Context, actor *models.TokenClaims, companyID uint) error {
+	company, err := m.LookupCompany(ctx, companyID)
+	if err != nil {
+		return err
+	}
+	if company.OwnerID != actor.UserID {
+		return services.ErrNotCompanyOwner
+	}
+	delete(m.companies, companyID)
+	return nil
+}
+
+func (m *MockCompanyService) SetSubscription(ctx context.Context, companyID uint, level models.SubscriptionLevel) (*models.Company, error) {
+	if !level.Valid() {
+		return nil, services.ErrUnknownSubscriptionLevel
+	}
+	company, err := m.LookupCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	company.SubscriptionLevel = level
+	return company, nil
+}
+
+func TestCompanyHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewCompanyHandler(&MockCompanyService{companies: make(map[uint]*models.Company)})
+	// В тестах идентификатор пользователя передается заголовком
+	auth := func(c *gin.Context) {
+		claims := &models.TokenClaims{UserID: 1}
+		if c.GetHeader("X-Test-User") == "2" {
+			claims.UserID = 2
+		}
+		c.Set("userID", claims.UserID)
+		c.Set("tokenClaims", claims)
+	}
+	r.POST("/companies", auth, handler.CreateCompany)
+	r.GET("/companies", auth, handler.ListCompanies)
+	r.GET("/companies/:id", auth, handler.GetCompany)
+	r.PUT("/companies/:id", auth, handler.UpdateCompany)
+	r.DELETE("/companies/:id", auth, handler.DeleteCompany)
+	r.PUT("/admin/companies/:id/subscription", handler.SetSubscription)
+
+	send := func(method, path, user string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("POST", "/companies", "1", models.CreateCompanyRequest{Name: "К"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для короткого названия, получен: %d", w.Code)
+	}
+	w := send("POST", "/companies", "1", models.CreateCompanyRequest{Name: "Кофейня"})
+	var company models.Company
+	json.Unmarshal(w.Body.Bytes(), &company)
+	if w.Code != http.StatusCreated || company.ID != 1 || company.OwnerID != 1 {
+		t.Fatalf("Ожидается код 201 и компания пользователя 1, получено: %d %s", w.Code, w.Body.String())
+	}
+
+	w = send("GET", "/companies", "1", nil)
+	var companies []models.Company
+	json.Unmarshal(w.Body.Bytes(), &companies)
+	if w.Code != http.StatusOK || len(companies) != 1 {
+		t.Errorf("Ожидается одна компания, получено: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := send("GET", "/companies/abc", "1", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного идентификатора, получен: %d", w.Code)
+	}
+	if w := send("GET", "/companies/42", "1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+	if w := send("GET", "/companies/1", "1", nil); w.Code != http.StatusOK {
+		t.Errorf("Владелец видит компанию до модерации, получен код: %d", w.Code)
+	}
+	if w := send("GET", "/companies/1", "2", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404 для непроверенной чужой компании, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/companies/1", "2", models.UpdateCompanyRequest{Name: "Чужая"}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для чужой компании, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/companies/1", "1", models.UpdateCompanyRequest{Name: "Кофейня на углу"}); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/admin/companies/1/subscription", "", models.SetSubscriptionRequest{SubscriptionLevel: "platinum"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для неизвестного уровня подписки, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/companies/1", "2", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 при удалении чужой компании, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/companies/1", "1", nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompanyModerationHandler обслуживает очередь проверки компаний; маршруты закрыты правом content:moderate
+type CompanyModerationHandler struct {
+	moderationService services.CompanyModerationServiceInterface
+}
+
+func NewCompanyModerationHandler(moderationService services.CompanyModerationServiceInterface) *CompanyModerationHandler {
+	return &CompanyModerationHandler{moderationService: moderationService}
+}
+
+func (h *CompanyModerationHandler) ListQueue(c *gin.Context) {
+	var filter models.CompanyModerationFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := h.moderationService.ListQueue(c.Request.Context(), filter)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *CompanyModerationHandler) ClaimItem(c *gin.Context) {
+	h.changeItem(c, h.moderationService.ClaimItem)
+}
+
+func (h *CompanyModerationHandler) ReleaseItem(c *gin.Context) {
+	h.changeItem(c, h.moderationService.ReleaseItem)
+}
+
+func (h *CompanyModerationHandler) ApproveItem(c *gin.Context) {
+	h.changeItem(c, h.moderationService.ApproveItem)
+}
+
+func (h *CompanyModerationHandler) RejectItem(c *gin.Context) {
+	id, ok := uintParam(c, "id", "некорректный идентификатор заявки")
+	if !ok {
+		return
+	}
+
+	var req models.RejectModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.moderationService.RejectItem(c.Request.Context(), c.GetUint("userID"), id, req.Reason)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *CompanyModerationHandler) ListAudit(c *gin.Context) {
+	var filter models.CompanyModerationAuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := h.moderationService.ListAudit(c.Request.Context(), filter)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// changeItem обслуживает действия над заявкой, которым не нужно тело запроса
+func (h *CompanyModerationHandler) changeItem(c *gin.Context, change func(ctx context.Context, moderatorID, id uint) (*models.CompanyModerationItem, error)) {
+	id, ok := uintParam(c, "id", "некорректный идентификатор заявки")
+	if !ok {
+		return
+	}
+
+	item, err := change(c.Request.Context(), c.GetUint("userID"), id)
+	if err != nil {
+		writeModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func writeModerationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownModerationStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrModerationItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrModerationItemClosed), errors.Is(err, services.ErrModerationItemSuperseded), errors.Is(err, services.ErrModerationItemClaimed),
+		errors.Is(err, services.ErrModerationItemNotClaimed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
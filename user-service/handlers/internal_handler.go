@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderInternalToken передает общий секрет внутренних сервисов
+const HeaderInternalToken = "X-Internal-Token"
+
+// InternalAuthMiddleware пропускает запросы других сервисов с общим секретом token.
+// Шлюз маршруты /internal не проксирует.
+func InternalAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(HeaderInternalToken)), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// InternalHandler обслуживает запросы promocodes-service
+type InternalHandler struct {
+	companyService      services.CompanyServiceInterface
+	notificationService services.NotificationServiceInterface
+}
+
+func NewInternalHandler(companyService services.CompanyServiceInterface, notificationService services.NotificationServiceInterface) *InternalHandler {
+	return &InternalHandler{
+		companyService:      companyService,
+		notificationService: notificationService,
+	}
+}
+
+// GetCompany отдает компанию вместе со статусом модерации без проверки доступа пользователя
+func (h *InternalHandler) GetCompany(c *gin.Context) {
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return
+	}
+
+	company, err := h.companyService.LookupCompany(c.Request.Context(), companyID)
+	if err != nil {
+		writeCompanyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, company)
+}
+
+func (h *InternalHandler) NotifyUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.NotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.notificationService.NotifyUser(c.Request.Context(), userID, req); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockNotificationService знает только пользователя 1
+type MockNotificationService struct {
+	sent []models.NotificationRequest
+}
+
+var _ services.NotificationServiceInterface = (*MockNotificationService)(nil)
+
+func (m *MockNotificationService) NotifyUser(ctx context.Context, userID uint, req models.NotificationRequest) error {
+	if userID != 1 {
+		return services.ErrUserNotFound
+	}
+	m.sent = append(m.sent, req)
+	return nil
+}
+
+func TestInternalGetCompany(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	companyService := &MockCompanyService{companies: map[uint]*models.Company{
+		1: {ID: 1, OwnerID: 1, Name: "Кофейня", ModerationStatus: models.ModerationPending},
+	}}
+	internal := r.Group("/internal")
+	internal.Use(InternalAuthMiddleware("secret"))
+	internal.GET("/companies/:id", NewInternalHandler(companyService, &MockNotificationService{}).GetCompany)
+
+	send := func(path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set(HeaderInternalToken, token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("/internal/companies/1", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401 без токена, получен: %d", w.Code)
+	}
+	if w := send("/internal/companies/1", "other"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401 с чужим токеном, получен: %d", w.Code)
+	}
+	w := send("/internal/companies/1", "secret")
+	var company models.Company
+	json.Unmarshal(w.Body.Bytes(), &company)
+	if w.Code != http.StatusOK || company.ModerationStatus != models.ModerationPending {
+		t.Errorf("Сервис должен видеть компанию до модерации, получено: %d %s", w.Code, w.Body.String())
+	}
+	if w := send("/internal/companies/42", "secret"); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+}
+
+func TestInternalNotifyUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	notifications := &MockNotificationService{}
+	internal := r.Group("/internal")
+	internal.Use(InternalAuthMiddleware("secret"))
+	internal.POST("/users/:id/notifications", NewInternalHandler(&MockCompanyService{}, notifications).NotifyUser)
+
+	send := func(path string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderInternalToken, "secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	message := models.NotificationRequest{Subject: "Промокод прошел модерацию", Body: "Здравствуйте!"}
+	if w := send("/internal/users/1/notifications", models.NotificationRequest{Subject: "Без текста"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 без текста письма, получен: %d", w.Code)
+	}
+	if w := send("/internal/users/1/notifications", message); w.Code != http.StatusNoContent || len(notifications.sent) != 1 {
+		t.Errorf("Ожидается код 204 и отправленное письмо, получено: %d, %+v", w.Code, notifications.sent)
+	}
+	if w := send("/internal/users/42/notifications", message); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type KeyHandler struct {
+	keyService services.KeyServiceInterface
+}
+
+func NewKeyHandler(keyService services.KeyServiceInterface) *KeyHandler {
+	return &KeyHandler{
+		keyService: keyService,
+	}
+}
+
+// JWKS публикует открытые ключи для проверки access-токенов (RFC 7517)
+func (h *KeyHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keyService.JWKS())
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MembershipHandler struct {
+	membershipService services.MembershipServiceInterface
+}
+
+func NewMembershipHandler(membershipService services.MembershipServiceInterface) *MembershipHandler {
+	return &MembershipHandler{membershipService: membershipService}
+}
+
+func (h *MembershipHandler) ListMembers(c *gin.Context) {
+	claims, companyID, ok := companyRequest(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.membershipService.ListMembers(c.Request.Context(), claims, companyID)
+	if err != nil {
+		writeMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *MembershipHandler) UpdateMember(c *gin.Context) {
+	claims, companyID, ok := companyRequest(c)
+	if !ok {
+		return
+	}
+	userID, ok := uintParam(c, "user_id", "некорректный идентификатор пользователя")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.membershipService.UpdateMemberRole(c.Request.Context(), claims, companyID, userID, req.Role)
+	if err != nil {
+		writeMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *MembershipHandler) RemoveMember(c *gin.Context) {
+	claims, companyID, ok := companyRequest(c)
+	if !ok {
+		return
+	}
+	userID, ok := uintParam(c, "user_id", "некорректный идентификатор пользователя")
+	if !ok {
+		return
+	}
+
+	if err := h.membershipService.RemoveMember(c.Request.Context(), claims, companyID, userID); err != nil {
+		writeMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Участник исключен из команды"})
+}
+
+func (h *MembershipHandler) Invite(c *gin.Context) {
+	claims, companyID, ok := companyRequest(c)
+	if !ok {
+		return
+	}
+
+	var req models.InviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := h.membershipService.Invite(c.Request.Context(), claims, companyID, req)
+	if err != nil {
+		writeMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func (h *MembershipHandler) ListInvitations(c *gin.Context) {
+	claims, companyID, ok := companyRequest(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.membershipService.ListInvitations(c.Request.Context(), claims, companyID)
+	if err != nil {
+		writeMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (h *MembershipHandler) RevokeInvitation(c *gin.Context) {
+	claims, companyID, ok := companyRequest(c)
+	if !ok {
+		return
+	}
+	invitationID, ok := uintParam(c, "invitation_id", "некорректный идентификатор приглашения")
+	if !ok {
+		return
+	}
+
+	if err := h.membershipService.RevokeInvitation(c.Request.Context(), claims, companyID, invitationID); err != nil {
+		writeMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Приглашение отозвано"})
+}
+
+func (h *MembershipHandler) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.membershipService.AcceptInvitation(c.Request.Context(), c.GetUint("userID"), req.Token)
+	if err != nil {
+		writeMembershipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// companyRequest извлекает данные токена и идентификатор компании из пути
+func companyRequest(c *gin.Context) (*models.TokenClaims, uint, bool) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return nil, 0, false
+	}
+	companyID, ok := companyIDParam(c)
+	if !ok {
+		return nil, 0, false
+	}
+	return claims, companyID, true
+}
+
+func uintParam(c *gin.Context, name, message string) (uint, bool) {
+	value, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return uint(value), true
+}
+
+func writeMembershipError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownCompanyRole), errors.Is(err, services.ErrInvalidActionToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvitationEmailMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCompanyMemberNotFound), errors.Is(err, services.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyMember), errors.Is(err, services.ErrLastOwner), errors.Is(err, services.ErrInvitationClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		writeCompanyError(c, err)
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockMembershipService: в компании 1 пользователь 1 - владелец, пользователь 2 - аналитик
+type MockMembershipService struct {
+	members     map[uint]models.CompanyRole
+	invitations map[uint]bool
+}
+
+var _ services.MembershipServiceInterface = (*MockMembershipService)(nil)
+
+func (m *MockMembershipService) access(actor *models.TokenClaims, companyID uint, ownerOnly bool) error {
+	if companyID != 1 {
+		return services.ErrCompanyNotFound
+	}
+	role, exists := m.members[actor.UserID]
+	if !exists {
+		return services.ErrNotCompanyMember
+	}
+	if ownerOnly && role != models.CompanyRoleOwner {
+		return services.ErrNotCompanyOwner
+	}
+	return nil
+}
+
+func (m *MockMembershipService) ListMembers(ctx context.Context, actor *models.TokenClaims, companyID uint) ([]models.CompanyMember, error) {
+	if err := m.access(actor, companyID, false); err != nil {
+		return nil, err
+	}
+	members := []models.CompanyMember{}
+	for userID, role := range m.members {
+		members = append(members, models.CompanyMember{CompanyID: companyID, UserID: userID, Role: role})
+	}
+	return members, nil
+}
+
+func (m *MockMembershipService) UpdateMemberRole(ctx context.Context, actor *models.TokenClaims, companyID, userID uint, role models.CompanyRole) (*models.CompanyMember, error) {
+	if !role.Valid() {
+		return nil, services.ErrUnknownCompanyRole
+	}
+	if err := m.access(actor, companyID, true); err != nil {
+		return nil, err
+	}
+	if _, exists := m.members[userID]; !exists {
+		return nil, services.ErrCompanyMemberNotFound
+	}
+	m.members[userID] = role
+	return &models.CompanyMember{CompanyID: companyID, UserID: userID, Role: role}, nil
+}
+
+func (m *MockMembershipService) RemoveMember(ctx context.Context, actor *models.TokenClaims, companyID, userID uint) error {
+	if err := m.access(actor, companyID, actor.UserID != userID); err != nil {
+		return err
+	}
+	if m.members[userID] == models.CompanyRoleOwner {
+		return services.ErrLastOwner
+	}
+	delete(m.members, userID)
+	return nil
+}
+
+func (m *MockMembershipService) Invite(ctx context.Context, actor *models.TokenClaims, companyID uint, req models.InviteMemberRequest) (*models.CompanyInvitation, error) {
+	if err := m.access(actor, companyID, true); err != nil {
+		return nil, err
+	}
+	invitation := &models.CompanyInvitation{ID: uint(len(m.invitations) + 1), CompanyID: companyID, Email: req.Email, Role: req.Role}
+	m.invitations[invitation.ID] = true
+	return invitation, nil
+}
+
+func (m *MockMembershipService) ListInvitations(ctx context.Context, actor *models.TokenClaims, companyID uint) ([]models.CompanyInvitation, error) {
+	if err := m.access(actor, companyID, true); err != nil {
+		return nil, err
+	}
+	return []models.CompanyInvitation{}, nil
+}
+
+func (m *MockMembershipService) RevokeInvitation(ctx context.Context, actor *models.TokenClaims, companyID, invitationID uint) error {
+	if err := m.access(actor, companyID, true); err != nil {
+		return err
+	}
+	pending, exists := m.invitations[invitationID]
+	if !exists {
+		return services.ErrInvitationNotFound
+	}
+	if !pending {
+		return services.ErrInvitationClosed
+	}
+	m.invitations[invitationID] = false
+	return nil
+}
+
+func (m *MockMembershipService) AcceptInvitation(ctx context.Context, userID uint, token string) (*models.CompanyMember, error) {
+	switch token {
+	case "valid":
+		m.members[userID] = models.CompanyRoleManager
+		return &models.CompanyMember{CompanyID: 1, UserID: userID, Role: models.CompanyRoleManager}, nil
+	case "other-email":
+		return nil, services.ErrInvitationEmailMismatch
+	}
+	return nil, services.ErrInvalidActionToken
+}
+
+func TestMembershipHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewMembershipHandler(&MockMembershipService{
+		members:     map[uint]models.CompanyRole{1: models.CompanyRoleOwner, 2: models.CompanyRoleAnalyst},
+		invitations: make(map[uint]bool),
+	})
+	// В тестах идентификатор пользователя передается заголовком
+	auth := func(c *gin.Context) {
+		claims := &models.TokenClaims{UserID: 1}
+		switch c.GetHeader("X-Test-User") {
+		case "2":
+			claims.UserID = 2
+		case "3":
+			claims.UserID = 3
+		}
+		c.Set("userID", claims.UserID)
+		c.Set("tokenClaims", claims)
+	}
+	r.GET("/companies/:id/members", auth, handler.ListMembers)
+	r.PUT("/companies/:id/members/:user_id", auth, handler.UpdateMember)
+	r.DELETE("/companies/:id/members/:user_id", auth, handler.RemoveMember)
+	r.POST("/companies/:id/invitations", auth, handler.Invite)
+	r.GET("/companies/:id/invitations", auth, handler.ListInvitations)
+	r.DELETE("/companies/:id/invitations/:invitation_id", auth, handler.RevokeInvitation)
+	r.POST("/invitations/accept", auth, handler.AcceptInvitation)
+
+	send := func(method, path, user string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("GET", "/companies/1/members", "2", nil); w.Code != http.StatusOK {
+		t.Errorf("Участник видит команду, получен код: %d", w.Code)
+	}
+	if w := send("GET", "/companies/1/members", "3", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для постороннего, получен: %d", w.Code)
+	}
+	if w := send("GET", "/companies/42/members", "1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+
+	if w := send("POST", "/companies/1/invitations", "1", models.InviteMemberRequest{Email: "not-an-email", Role: models.CompanyRoleManager}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного email, получен: %d", w.Code)
+	}
+	if w := send("POST", "/companies/1/invitations", "2", models.InviteMemberRequest{Email: "new@example.com", Role: models.CompanyRoleManager}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для аналитика, получен: %d", w.Code)
+	}
+	if w := send("POST", "/companies/1/invitations", "1", models.InviteMemberRequest{Email: "new@example.com", Role: models.CompanyRoleManager}); w.Code != http.StatusCreated {
+		t.Errorf("Ожидается код 201, получен: %d %s", w.Code, w.Body.String())
+	}
+	if w := send("DELETE", "/companies/1/invitations/abc", "1", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для некорректного идентификатора, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/companies/1/invitations/1", "1", nil); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/companies/1/invitations/1", "1", nil); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 для отозванного приглашения, получен: %d", w.Code)
+	}
+
+	if w := send("POST", "/invitations/accept", "3", models.AcceptInvitationRequest{Token: "other-email"}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для приглашения на чужой email, получен: %d", w.Code)
+	}
+	if w := send("POST", "/invitations/accept", "3", models.AcceptInvitationRequest{Token: "forged"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для недействительного токена, получен: %d", w.Code)
+	}
+	if w := send("POST", "/invitations/accept", "3", models.AcceptInvitationRequest{Token: "valid"}); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+
+	if w := send("PUT", "/companies/1/members/3", "1", models.UpdateMemberRequest{Role: "boss"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для неизвестной роли, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/companies/1/members/42", "1", models.UpdateMemberRequest{Role: models.CompanyRoleAnalyst}); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается код 404, получен: %d", w.Code)
+	}
+	if w := send("PUT", "/companies/1/members/3", "1", models.UpdateMemberRequest{Role: models.CompanyRoleAnalyst}); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/companies/1/members/1", "1", nil); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 для последнего владельца, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/companies/1/members/3", "2", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 при исключении другого участника аналитиком, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/companies/1/members/3", "3", nil); w.Code != http.StatusOK {
+		t.Errorf("Участник может выйти из команды, получен код: %d", w.Code)
+	}
+}
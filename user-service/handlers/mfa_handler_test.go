@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockMFAService struct {
+	enabled bool
+}
+
+var _ services.MFAServiceInterface = (*MockMFAService)(nil)
+
+func (m *MockMFAService) Enroll(ctx context.Context, userID uint) (*models.TOTPEnrollment, error) {
+	if m.enabled {
+		return nil, services.ErrMFAAlreadyEnabled
+	}
+	return &models.TOTPEnrollment{Secret: "JBSWY3DPEHPK3PXP", ProvisioningURI: "otpauth://totp/SOAProject:testuser?secret=JBSWY3DPEHPK3PXP"}, nil
+}
+
+func (m *MockMFAService) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	if code != "123456" {
+		return nil, services.ErrInvalidMFACode
+	}
+	m.enabled = true
+	return []string{"abcd-efgh"}, nil
+}
+
+func (m *MockMFAService) Disable(ctx context.Context, userID uint, code string) error {
+	if !m.enabled {
+		return services.ErrMFANotEnabled
+	}
+	m.enabled = false
+	return nil
+}
+
+func (m *MockMFAService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	return []string{"ijkl-mnop"}, nil
+}
+
+func (m *MockMFAService) IssueChallenge(ctx context.Context, user *models.User) (string, error) {
+	return "challenge", nil
+}
+
+func (m *MockMFAService) ChallengeUser(ctx context.Context, token string) (*models.User, error) {
+	return nil, services.ErrInvalidActionToken
+}
+
+func (m *MockMFAService) CompleteChallenge(ctx context.Context, token, code string) (*models.User, error) {
+	return nil, services.ErrInvalidActionToken
+}
+
+func TestMFAHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewMFAHandler(&MockMFAService{})
+	setUser := func(c *gin.Context) { c.Set("userID", uint(1)) }
+	r.POST("/2fa/enroll", setUser, handler.Enroll)
+	r.POST("/2fa/confirm", setUser, handler.Confirm)
+	r.POST("/2fa/disable", setUser, handler.Disable)
+
+	send := func(path string, body interface{}) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("/2fa/enroll", nil)
+	var enrollment models.TOTPEnrollment
+	json.Unmarshal(w.Body.Bytes(), &enrollment)
+	if w.Code != http.StatusOK || enrollment.ProvisioningURI == "" {
+		t.Errorf("Ожидается ссылка для QR-кода, получено: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := send("/2fa/confirm", models.TOTPCodeRequest{Code: "000000"}); w.Code != http.StatusForbidden {
+		t.Errorf("Ожидается код 403 для неверного кода, получен: %d", w.Code)
+	}
+	w = send("/2fa/confirm", models.TOTPCodeRequest{Code: "123456"})
+	var codes models.RecoveryCodesResponse
+	json.Unmarshal(w.Body.Bytes(), &codes)
+	if w.Code != http.StatusOK || len(codes.RecoveryCodes) != 1 {
+		t.Errorf("Ожидаются коды восстановления, получено: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := send("/2fa/enroll", nil); w.Code != http.StatusConflict {
+		t.Errorf("Ожидается код 409 при включенной 2FA, получен: %d", w.Code)
+	}
+	if w := send("/2fa/disable", models.TOTPCodeRequest{}); w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 без кода, получен: %d", w.Code)
+	}
+	if w := send("/2fa/disable", models.TOTPCodeRequest{Code: "123456"}); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+}
+
+func TestLoginMFAHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewUserHandler(&MockUserService{
+		LoginMFAFunc: func(req models.MFALoginRequest) (*models.LoginResponse, error) {
+			if req.MFAToken != "challenge" || req.Code != "123456" {
+				return nil, services.ErrInvalidMFACode
+			}
+			return &models.LoginResponse{Token: "test_token", RefreshToken: "test_refresh", ExpiresIn: 900}, nil
+		},
+	})
+	r.POST("/login/2fa", handler.LoginMFA)
+
+	send := func(body models.MFALoginRequest) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/login/2fa", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(models.MFALoginRequest{MFAToken: "challenge", Code: "000000"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидается код 401 для неверного кода, получен: %d", w.Code)
+	}
+	w := send(models.MFALoginRequest{MFAToken: "challenge", Code: "123456"})
+	var response models.LoginResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Token != "test_token" {
+		t.Errorf("Ожидается выдача токенов, получено: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"user-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole пропускает запрос, если у пользователя есть хотя бы одна из ролей.
+// Используется после UserHandler.AuthMiddleware.
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := tokenClaims(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+			return
+		}
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "недостаточно прав"})
+	}
+}
+
+// RequirePermission пропускает запрос, если право есть хотя бы у одной роли пользователя
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := tokenClaims(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+			return
+		}
+		if !claims.HasPermission(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "недостаточно прав"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail закрывает маршрут для учетных записей с неподтвержденным email,
+// если это предписывает политика. При политике allow пропускает все запросы.
+func RequireVerifiedEmail(policy models.VerificationPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if policy == models.VerificationPolicyAllow {
+			c.Next()
+			return
+		}
+		claims, ok := tokenClaims(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+			return
+		}
+		if !claims.EmailVerified {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "требуется подтвердить email"})
+			return
+		}
+		c.Next()
+	}
+}
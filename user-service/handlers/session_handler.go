@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler работает за UserHandler.AuthMiddleware, который кладет в контекст tokenClaims
+type SessionHandler struct {
+	tokenService services.TokenServiceInterface
+}
+
+func NewSessionHandler(tokenService services.TokenServiceInterface) *SessionHandler {
+	return &SessionHandler{
+		tokenService: tokenService,
+	}
+}
+
+func (h *SessionHandler) Logout(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	if err := h.tokenService.Logout(c.Request.Context(), claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Сессия завершена"})
+}
+
+func (h *SessionHandler) LogoutAll(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	if err := h.tokenService.LogoutAll(c.Request.Context(), claims.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Все сессии завершены"})
+}
+
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	sessions, err := h.tokenService.ListSessions(c.Request.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "не авторизован"})
+		return
+	}
+
+	err := h.tokenService.RevokeSession(c.Request.Context(), claims.UserID, c.Param("id"))
+	if errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Сессия завершена"})
+}
+
+func tokenClaims(c *gin.Context) (*models.TokenClaims, bool) {
+	value, exists := c.Get("tokenClaims")
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*models.TokenClaims)
+	return claims, ok
+}
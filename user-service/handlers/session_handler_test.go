@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockTokenService struct {
+	sessions  map[string]uint
+	loggedOut []string
+}
+
+var _ services.TokenServiceInterface = (*MockTokenService)(nil)
+
+func (m *MockTokenService) IssueTokens(ctx context.Context, user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	return nil, nil
+}
+
+func (m *MockTokenService) Refresh(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
+	return nil, nil
+}
+
+func (m *MockTokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*models.TokenClaims, error) {
+	return nil, nil
+}
+
+func (m *MockTokenService) Logout(ctx context.Context, claims *models.TokenClaims) error {
+	m.loggedOut = append(m.loggedOut, claims.SessionID)
+	return nil
+}
+
+func (m *MockTokenService) LogoutAll(ctx context.Context, userID uint) error {
+	return nil
+}
+
+func (m *MockTokenService) ListSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.Session, error) {
+	var sessions []models.Session
+	for id, owner := range m.sessions {
+		if owner == userID {
+			sessions = append(sessions, models.Session{ID: id, Current: id == currentSessionID})
+		}
+	}
+	return sessions, nil
+}
+
+func (m *MockTokenService) RevokeSession(ctx context.Context, userID uint, sessionID string) error {
+	if m.sessions[sessionID] != userID {
+		return services.ErrSessionNotFound
+	}
+	delete(m.sessions, sessionID)
+	return nil
+}
+
+func newSessionRouter(tokenService services.TokenServiceInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	userHandler := NewUserHandler(&MockUserService{
+		ValidateTokenFunc: func(token string) (*models.TokenClaims, error) {
+			return &models.TokenClaims{UserID: 1, SessionID: token, JTI: "jti"}, nil
+		},
+	})
+	handler := NewSessionHandler(tokenService)
+
+	protected := r.Group("/")
+	protected.Use(userHandler.AuthMiddleware())
+	protected.POST("/logout", handler.Logout)
+	protected.GET("/sessions", handler.ListSessions)
+	protected.DELETE("/sessions/:id", handler.RevokeSession)
+	return r
+}
+
+func TestSessionHandlers(t *testing.T) {
+	tokenService := &MockTokenService{sessions: map[string]uint{"phone": 1, "laptop": 1, "other": 2}}
+	r := newSessionRouter(tokenService)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer phone")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "/sessions")
+	var sessions []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &sessions)
+	if w.Code != http.StatusOK || len(sessions) != 2 {
+		t.Errorf("Ожидается 2 сессии пользователя, получено: %d %v", w.Code, sessions)
+	}
+
+	if w := send("DELETE", "/sessions/other"); w.Code != http.StatusNotFound {
+		t.Errorf("Ожидается 404 для чужой сессии, получен: %d", w.Code)
+	}
+	if w := send("DELETE", "/sessions/laptop"); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+
+	if w := send("POST", "/logout"); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	if len(tokenService.loggedOut) != 1 || tokenService.loggedOut[0] != "phone" {
+		t.Errorf("Ожидается завершение текущей сессии, получено: %v", tokenService.loggedOut)
+	}
+}
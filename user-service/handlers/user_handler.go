@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"user-service/models"
 	"user-service/services"
@@ -26,7 +29,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	err := h.userService.Register(req)
+	err := h.userService.Register(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -42,13 +45,58 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.userService.Login(req)
+	client := models.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	resp, err := h.userService.Login(c.Request.Context(), req, client)
+	if err != nil {
+		writeLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *UserHandler) LoginMFA(c *gin.Context) {
+	var req models.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client := models.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	resp, err := h.userService.LoginMFA(c.Request.Context(), req, client)
+	if err != nil {
+		writeLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// writeLoginError отвечает 429 с Retry-After, пока вход временно запрещен, иначе 401
+func writeLoginError(c *gin.Context, err error) {
+	var throttled *services.ThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
+
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.userService.RefreshToken(c.Request.Context(), req.RefreshToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.LoginResponse{Token: token})
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
@@ -58,7 +106,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserProfile(userID.(uint))
+	user, err := h.userService.GetUserProfile(c.Request.Context(), userID.(uint))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -80,7 +128,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	err := h.userService.UpdateUserProfile(userID.(uint), req)
+	err := h.userService.UpdateUserProfile(c.Request.Context(), userID.(uint), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -89,6 +137,62 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Профиль успешно обновлен"})
 }
 
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client := models.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	err := h.userService.ChangePassword(c.Request.Context(), c.GetUint("userID"), req, client)
+	var throttled *services.ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		writeLoginError(c, err)
+	case errors.Is(err, services.ErrWrongPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Пароль изменен, войдите заново"})
+	}
+}
+
+// ForgotPassword отвечает одинаково вне зависимости от того, зарегистрирован ли адрес
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "внутренняя ошибка сервера"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Если адрес зарегистрирован, на него отправлена ссылка для сброса пароля"})
+}
+
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.userService.ResetPassword(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, services.ErrInvalidActionToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Пароль изменен"})
+	}
+}
+
 func (h *UserHandler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -105,14 +209,15 @@ func (h *UserHandler) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		userID, err := h.userService.ValidateToken(tokenParts[1])
+		claims, err := h.userService.ValidateToken(c.Request.Context(), tokenParts[1])
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		c.Set("userID", userID)
+		c.Set("userID", claims.UserID)
+		c.Set("tokenClaims", claims)
 		c.Next()
 	}
 }
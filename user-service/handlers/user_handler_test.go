@@ -3,10 +3,12 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"user-service/models"
 	"user-service/services"
 
@@ -15,34 +17,64 @@ import (
 
 type MockUserService struct {
     RegisterFunc func(models.RegisterRequest) error
-    LoginFunc func(models.LoginRequest) (string, error)
+    LoginFunc func(models.LoginRequest) (*models.LoginResponse, error)
+    LoginMFAFunc func(models.MFALoginRequest) (*models.LoginResponse, error)
+    RefreshTokenFunc func(string) (*models.LoginResponse, error)
     GetUserProfileFunc func(uint) (*models.User, error)
     UpdateUserProfileFunc func(uint, models.UpdateProfileRequest) error
-    ValidateTokenFunc func(string) (uint, error)
+    ValidateTokenFunc func(string) (*models.TokenClaims, error)
+    ChangePasswordFunc func(uint, models.ChangePasswordRequest) error
+    ForgotPasswordFunc func(string) error
+    ResetPasswordFunc func(models.ResetPasswordRequest) error
+    AssignRoleFunc func(uint, models.Role) error
 }
 
 var _ services.UserServiceInterface = (*MockUserService)(nil)
 
-func (m *MockUserService) Register(req models.RegisterRequest) error {
+func (m *MockUserService) Register(ctx context.Context, req models.RegisterRequest) error {
     return m.RegisterFunc(req)
 }
 
-func (m *MockUserService) Login(req models.LoginRequest) (string, error) {
+func (m *MockUserService) Login(ctx context.Context, req models.LoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
     return m.LoginFunc(req)
 }
 
-func (m *MockUserService) GetUserProfile(userID uint) (*models.User, error) {
+func (m *MockUserService) LoginMFA(ctx context.Context, req models.MFALoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+    return m.LoginMFAFunc(req)
+}
+
+func (m *MockUserService) RefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
+    return m.RefreshTokenFunc(refreshToken)
+}
+
+func (m *MockUserService) GetUserProfile(ctx context.Context, userID uint) (*models.User, error) {
     return m.GetUserProfileFunc(userID)
 }
 
-func (m *MockUserService) UpdateUserProfile(userID uint, req models.UpdateProfileRequest) error {
+func (m *MockUserService) UpdateUserProfile(ctx context.Context, userID uint, req models.UpdateProfileRequest) error {
     return m.UpdateUserProfileFunc(userID, req)
 }
 
-func (m *MockUserService) ValidateToken(token string) (uint, error) {
+func (m *MockUserService) ValidateToken(ctx context.Context, token string) (*models.TokenClaims, error) {
     return m.ValidateTokenFunc(token)
 }
 
+func (m *MockUserService) AssignRole(ctx context.Context, userID uint, role models.Role) error {
+    return m.AssignRoleFunc(userID, role)
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, userID uint, req models.ChangePasswordRequest, client models.ClientInfo) error {
+    return m.ChangePasswordFunc(userID, req)
+}
+
+func (m *MockUserService) ForgotPassword(ctx context.Context, email string) error {
+    return m.ForgotPasswordFunc(email)
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error {
+    return m.ResetPasswordFunc(req)
+}
+
 
 func TestRegisterHandler(t *testing.T) {
     gin.SetMode(gin.TestMode)
@@ -85,8 +117,8 @@ func TestLoginHandler(t *testing.T) {
     r := gin.Default()
     
     mockService := &MockUserService{
-        LoginFunc: func(req models.LoginRequest) (string, error) {
-            return "test_token", nil
+        LoginFunc: func(req models.LoginRequest) (*models.LoginResponse, error) {
+            return &models.LoginResponse{Token: "test_token", RefreshToken: "test_refresh", ExpiresIn: 900}, nil
         },
     }
     
@@ -113,4 +145,145 @@ func TestLoginHandler(t *testing.T) {
     if response.Token != "test_token" {
         t.Errorf("Ожидается токен test_token, получен: %s", response.Token)
     }
+    if response.RefreshToken != "test_refresh" || response.ExpiresIn != 900 {
+        t.Errorf("Ожидается refresh-токен и время жизни, получено: %+v", response)
+    }
+}
+
+func TestLoginHandlerThrottled(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+
+    mockService := &MockUserService{
+        LoginFunc: func(req models.LoginRequest) (*models.LoginResponse, error) {
+            return nil, &services.ThrottledError{RetryAfter: 1500 * time.Millisecond}
+        },
+    }
+    r.POST("/login", NewUserHandler(mockService).Login)
+
+    reqBody, _ := json.Marshal(models.LoginRequest{Login: "testuser", Password: "password123"})
+    req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(reqBody))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+
+    if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+        t.Errorf("Ожидается код 429 с Retry-After: 2, получено: %d %q", w.Code, w.Header().Get("Retry-After"))
+    }
+}
+
+func TestRefreshTokenHandler(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := gin.Default()
+
+    mockService := &MockUserService{
+        RefreshTokenFunc: func(refreshToken string) (*models.LoginResponse, error) {
+            if refreshToken != "valid_refresh" {
+                return nil, services.ErrInvalidRefreshToken
+            }
+            return &models.LoginResponse{Token: "new_token", RefreshToken: "new_refresh", ExpiresIn: 900}, nil
+        },
+    }
+
+    handler := NewUserHandler(mockService)
+
+    r.POST("/token/refresh", handler.RefreshToken)
+
+    send := func(refreshToken string) *httptest.ResponseRecorder {
+        reqBody, _ := json.Marshal(models.RefreshTokenRequest{RefreshToken: refreshToken})
+        req, _ := http.NewRequest("POST", "/token/refresh", bytes.NewBuffer(reqBody))
+        req.Header.Set("Content-Type", "application/json")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    w := send("valid_refresh")
+    if w.Code != http.StatusOK {
+        t.Errorf("Ожидается код 200, получен: %d", w.Code)
+    }
+    var response models.LoginResponse
+    json.Unmarshal(w.Body.Bytes(), &response)
+    if response.Token != "new_token" || response.RefreshToken != "new_refresh" {
+        t.Errorf("Ожидается новая пара токенов, получено: %+v", response)
+    }
+
+    if w := send("stolen_refresh"); w.Code != http.StatusUnauthorized {
+        t.Errorf("Ожидается код 401 для недействительного токена, получен: %d", w.Code)
+    }
+    if w := send(""); w.Code != http.StatusBadRequest {
+        t.Errorf("Ожидается код 400 без токена, получен: %d", w.Code)
+    }
+}
+
+func TestPasswordHandlers(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+
+    var forgotEmails []string
+    mockService := &MockUserService{
+        ChangePasswordFunc: func(userID uint, req models.ChangePasswordRequest) error {
+            if req.CurrentPassword == "locked" {
+                return &services.ThrottledError{RetryAfter: 90 * time.Second}
+            }
+            if req.CurrentPassword != "password123" {
+                return services.ErrWrongPassword
+            }
+            return nil
+        },
+        ForgotPasswordFunc: func(email string) error {
+            forgotEmails = append(forgotEmails, email)
+            return nil
+        },
+        ResetPasswordFunc: func(req models.ResetPasswordRequest) error {
+            if req.Token != "valid_token" {
+                return services.ErrInvalidActionToken
+            }
+            return nil
+        },
+    }
+    handler := NewUserHandler(mockService)
+
+    r.PUT("/password", func(c *gin.Context) { c.Set("userID", uint(1)) }, handler.ChangePassword)
+    r.POST("/password/forgot", handler.ForgotPassword)
+    r.POST("/password/reset", handler.ResetPassword)
+
+    send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+        reqBody, _ := json.Marshal(body)
+        req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+        req.Header.Set("Content-Type", "application/json")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    if w := send("PUT", "/password", models.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "newpassword"}); w.Code != http.StatusForbidden {
+        t.Errorf("Ожидается код 403 для неверного текущего пароля, получен: %d", w.Code)
+    }
+    if w := send("PUT", "/password", models.ChangePasswordRequest{CurrentPassword: "locked", NewPassword: "newpassword"}); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "90" {
+        t.Errorf("Ожидается код 429 с Retry-After при подборе пароля, получено: %d %q", w.Code, w.Header().Get("Retry-After"))
+    }
+    if w := send("PUT", "/password", models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "123"}); w.Code != http.StatusBadRequest {
+        t.Errorf("Ожидается код 400 для короткого пароля, получен: %d", w.Code)
+    }
+    if w := send("PUT", "/password", models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword"}); w.Code != http.StatusOK {
+        t.Errorf("Ожидается код 200, получен: %d", w.Code)
+    }
+
+    known := send("POST", "/password/forgot", models.ForgotPasswordRequest{Email: "test@example.com"})
+    unknown := send("POST", "/password/forgot", models.ForgotPasswordRequest{Email: "nobody@example.com"})
+    if known.Code != http.StatusAccepted || known.Code != unknown.Code || known.Body.String() != unknown.Body.String() {
+        t.Errorf("Ответы не должны раскрывать существование учетной записи: %d %s / %d %s",
+            known.Code, known.Body.String(), unknown.Code, unknown.Body.String())
+    }
+    if len(forgotEmails) != 2 {
+        t.Errorf("Ожидается 2 вызова сервиса, получено: %d", len(forgotEmails))
+    }
+
+    if w := send("POST", "/password/reset", models.ResetPasswordRequest{Token: "used_token", NewPassword: "newpassword"}); w.Code != http.StatusBadRequest {
+        t.Errorf("Ожидается код 400 для недействительного токена, получен: %d", w.Code)
+    }
+    if w := send("POST", "/password/reset", models.ResetPasswordRequest{Token: "valid_token", NewPassword: "newpassword"}); w.Code != http.StatusOK {
+        t.Errorf("Ожидается код 200, получен: %d", w.Code)
+    }
 }
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VerificationHandler struct {
+	verificationService services.VerificationServiceInterface
+}
+
+func NewVerificationHandler(verificationService services.VerificationServiceInterface) *VerificationHandler {
+	return &VerificationHandler{
+		verificationService: verificationService,
+	}
+}
+
+// VerifyEmail принимает токен из ссылки в письме: в параметре запроса для GET или в теле для POST
+func (h *VerificationHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.verificationService.VerifyEmail(c.Request.Context(), req.Token)
+	switch {
+	case errors.Is(err, services.ErrInvalidActionToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Email подтвержден"})
+	}
+}
+
+func (h *VerificationHandler) ResendVerification(c *gin.Context) {
+	err := h.verificationService.ResendVerification(c.Request.Context(), c.GetUint("userID"))
+	switch {
+	case errors.Is(err, services.ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrResendTooSoon):
+		c.Header("Retry-After", strconv.Itoa(int(h.verificationService.ResendInterval().Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Письмо отправлено"})
+	}
+}
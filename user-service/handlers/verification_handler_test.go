@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-service/models"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockVerificationService struct {
+	VerifyEmailFunc        func(string) error
+	ResendVerificationFunc func(uint) error
+}
+
+var _ services.VerificationServiceInterface = (*MockVerificationService)(nil)
+
+func (m *MockVerificationService) SendVerification(ctx context.Context, user *models.User) error {
+	return nil
+}
+
+func (m *MockVerificationService) VerifyEmail(ctx context.Context, token string) error {
+	return m.VerifyEmailFunc(token)
+}
+
+func (m *MockVerificationService) ResendVerification(ctx context.Context, userID uint) error {
+	return m.ResendVerificationFunc(userID)
+}
+
+func (m *MockVerificationService) ResendInterval() time.Duration {
+	return time.Minute
+}
+
+func (m *MockVerificationService) SendPasswordReset(ctx context.Context, user *models.User) error {
+	return nil
+}
+
+func (m *MockVerificationService) ConsumePasswordReset(ctx context.Context, token string) (*models.User, error) {
+	return nil, services.ErrInvalidActionToken
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handler := NewVerificationHandler(&MockVerificationService{
+		VerifyEmailFunc: func(token string) error {
+			if token != "valid_token" {
+				return services.ErrInvalidActionToken
+			}
+			return nil
+		},
+	})
+	r.GET("/email/verify", handler.VerifyEmail)
+	r.POST("/email/verify", handler.VerifyEmail)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/email/verify?token=valid_token", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200 для ссылки из письма, получен: %d", w.Code)
+	}
+
+	body, _ := json.Marshal(models.VerifyEmailRequest{Token: "used_token"})
+	req := httptest.NewRequest("POST", "/email/verify", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 для недействительного токена, получен: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/email/verify", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидается код 400 без токена, получен: %d", w.Code)
+	}
+}
+
+func TestResendVerificationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	sent := false
+	handler := NewVerificationHandler(&MockVerificationService{
+		ResendVerificationFunc: func(userID uint) error {
+			if sent {
+				return services.ErrResendTooSoon
+			}
+			sent = true
+			return nil
+		},
+	})
+	r.POST("/email/verify/resend", func(c *gin.Context) { c.Set("userID", uint(1)) }, handler.ResendVerification)
+
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/email/verify/resend", nil))
+		return w
+	}
+
+	if w := send(); w.Code != http.StatusOK {
+		t.Errorf("Ожидается код 200, получен: %d", w.Code)
+	}
+	w := send()
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("Ожидается код 429 с Retry-After, получено: %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		policy   models.VerificationPolicy
+		verified bool
+		expected int
+	}{
+		{models.VerificationPolicyAllow, false, http.StatusOK},
+		{models.VerificationPolicyRestrict, false, http.StatusForbidden},
+		{models.VerificationPolicyRestrict, true, http.StatusOK},
+	} {
+		verified := tc.verified
+		r := gin.New()
+		r.GET("/companies", func(c *gin.Context) {
+			c.Set("tokenClaims", &models.TokenClaims{UserID: 1, EmailVerified: verified})
+		}, RequireVerifiedEmail(tc.policy), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/companies", nil))
+		if w.Code != tc.expected {
+			t.Errorf("Политика %s, подтвержден %v: ожидается %d, получен: %d", tc.policy, tc.verified, tc.expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"user-service/metrics"
+)
+
+var (
+	ErrQueueFull = errors.New("очередь писем переполнена")
+	ErrClosed    = errors.New("отправка писем остановлена")
+)
+
+// AsyncMailer ставит письма в очередь ограниченного размера и отправляет их через next
+// фиксированным числом воркеров, поэтому запрос не ждет почтовый сервер. Ошибки доставки
+// попадают в лог и метрики. Close дожидается отправки писем, которые уже в очереди.
+type AsyncMailer struct {
+	next   Mailer
+	queue  chan Message
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewAsyncMailer(next Mailer, queueSize, workers int) *AsyncMailer {
+	m := &AsyncMailer{
+		next:  next,
+		queue: make(chan Message, queueSize),
+	}
+	m.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go m.work()
+	}
+	return m
+}
+
+// Send не блокируется: если очередь заполнена, письмо отклоняется с ErrQueueFull
+func (m *AsyncMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		metrics.EmailsSent.WithLabelValues("failure").Inc()
+		return ErrQueueFull
+	}
+}
+
+// Close перестает принимать письма и ждет, пока воркеры отправят очередь
+func (m *AsyncMailer) Close() {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+func (m *AsyncMailer) work() {
+	defer m.wg.Done()
+	for msg := range m.queue {
+		// Запрос, поставивший письмо, к этому моменту может быть уже завершен
+		if err := m.next.Send(context.Background(), msg); err != nil {
+			metrics.EmailsSent.WithLabelValues("failure").Inc()
+			log.Printf("Не удалось отправить письмо \"%s\": %v", msg.Subject, err)
+			continue
+		}
+		metrics.EmailsSent.WithLabelValues("success").Inc()
+	}
+}
+
+var _ Mailer = (*AsyncMailer)(nil)
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// blockingMailer сообщает в started о начале отправки и не завершает ее, пока открыт release
+type blockingMailer struct {
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []Message
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg Message) error {
+	m.started <- struct{}{}
+	<-m.release
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestAsyncMailer(t *testing.T) {
+	next := &blockingMailer{started: make(chan struct{}, 3), release: make(chan struct{})}
+	m := NewAsyncMailer(next, 2, 1)
+
+	// Одно письмо забирает воркер, еще два помещаются в очередь
+	if err := m.Send(context.Background(), Message{Subject: "1"}); err != nil {
+		t.Fatalf("Ожидается постановка в очередь, получено: %v", err)
+	}
+	<-next.started
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = m.Send(context.Background(), Message{Subject: "следующее"})
+	}
+	if err != ErrQueueFull {
+		t.Errorf("Переполненная очередь отклоняет письма, получено: %v", err)
+	}
+
+	close(next.release)
+	m.Close()
+	if len(next.sent) != 3 {
+		t.Errorf("Close дожидается отправки очереди, отправлено: %d", len(next.sent))
+	}
+	if err := m.Send(context.Background(), Message{}); err != ErrClosed {
+		t.Errorf("После Close письма не принимаются, получено: %v", err)
+	}
+	m.Close()
+}
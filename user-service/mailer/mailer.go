@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма пользователям. Реализация выбирается при старте сервиса:
+// LogMailer для разработки, SMTPMailer для реальной доставки.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer не отправляет письма, а записывает их в w (stdout или файл),
+// чтобы при разработке можно было перейти по ссылке из письма
+type LogMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{w: w}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "--- %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+var _ Mailer = (*LogMailer)(nil)
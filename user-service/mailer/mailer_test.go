@@ -0,0 +1,144 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer принимает одно письмо по минимальному подмножеству SMTP и передает его в канал
+type fakeSMTPServer struct {
+	listener net.Listener
+	messages chan []byte
+	rcpt     chan string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Не удалось запустить тестовый SMTP-сервер: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, messages: make(chan []byte, 1), rcpt: make(chan string, 1)}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP test")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(command, "MAIL FROM"):
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO"):
+			s.rcpt <- strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>")
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data bytes.Buffer
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.messages <- data.Bytes()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	m := NewSMTPMailer(server.listener.Addr().String(), "noreply@example.com", "", "")
+
+	err := m.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Подтверждение email",
+		Body:    "Перейдите по ссылке: http://localhost/email/verify?token=abc",
+	})
+	if err != nil {
+		t.Fatalf("Ожидается успешная отправка, получено: %v", err)
+	}
+
+	if rcpt := <-server.rcpt; rcpt != "user@example.com" {
+		t.Errorf("Неверный получатель: %s", rcpt)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(<-server.messages))
+	if err != nil {
+		t.Fatalf("Письмо должно быть корректным сообщением: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Подтверждение email" {
+		t.Errorf("Неверная тема письма: %s", subject)
+	}
+	if msg.Header.Get("From") != "noreply@example.com" {
+		t.Errorf("Неверный отправитель: %s", msg.Header.Get("From"))
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if !strings.Contains(string(body), "http://localhost/email/verify?token=abc") {
+		t.Errorf("Тело письма должно содержать ссылку, получено: %s", body)
+	}
+}
+
+func TestSMTPMailerUnavailable(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	listener.Close()
+
+	m := NewSMTPMailer(addr, "noreply@example.com", "", "")
+	if err := m.Send(context.Background(), Message{To: "user@example.com"}); err == nil {
+		t.Error("Ожидается ошибка при недоступном SMTP-сервере")
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(&buf)
+
+	m.Send(context.Background(), Message{To: "user@example.com", Subject: "Тема", Body: "Текст письма"})
+
+	for _, expected := range []string{"To: user@example.com", "Subject: Тема", "Текст письма"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Ожидается %q в журнале писем, получено: %s", expected, buf.String())
+		}
+	}
+}
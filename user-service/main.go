@@ -1,21 +1,44 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"user-service/grpcserver"
 	"user-service/handlers"
+	"user-service/health"
+	"user-service/mailer"
 	"user-service/models"
+	"user-service/pb/userpb"
+	"user-service/promocodes"
 	"user-service/repository"
 	"user-service/services"
+	"user-service/tracing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 func main() {
+	shutdownTracing, err := tracing.Init(context.Background(), "user-service")
+	if err != nil {
+		log.Fatalf("Ошибка настройки трассировки: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	dsn := "host=" + os.Getenv("DB_HOST") +
 		" user=" + os.Getenv("DB_USER") +
 		" password=" + os.Getenv("DB_PASSWORD") +
@@ -28,36 +51,337 @@ func main() {
 		log.Fatalf("Не удалось подключиться к базе данных: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.User{})
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Ошибка подключения трассировки к GORM: %v", err)
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Ошибка миграции базы данных: %v", err)
+		log.Fatalf("Не удалось получить соединение с базой данных: %v", err)
 	}
+	checker := health.NewChecker(sqlDB)
 
 	userRepo := repository.NewUserRepository(db)
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "my_secret_key"
+	// Открытый ключ после ротации публикуется еще gracePeriod, поэтому он должен быть больше времени жизни access-токена
+	accessTTL := 15 * time.Minute
+	rotationPeriod := durationFromEnv("JWT_KEY_ROTATION_PERIOD", 7*24*time.Hour)
+	gracePeriod := durationFromEnv("JWT_KEY_GRACE_PERIOD", 24*time.Hour)
+	if gracePeriod <= accessTTL {
+		log.Fatalf("JWT_KEY_GRACE_PERIOD должен быть больше времени жизни access-токена (%v)", accessTTL)
+	}
+	keyRepo := repository.NewKeyRepository(db)
+	keyService := services.NewKeyService(keyRepo, rotationPeriod, gracePeriod)
+
+	tokenRepo := repository.NewTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	memberRepo := repository.NewCompanyMemberRepository(db)
+	tokenService := services.NewTokenService(tokenRepo, sessionRepo, userRepo, memberRepo, keyService, accessTTL, 30*24*time.Hour)
+
+	// Секрет подписи ссылок из писем и токенов второго шага входа; при его смене
+	// ранее выданные токены перестают работать
+	actionTokenSecret := os.Getenv("ACTION_TOKEN_SECRET")
+	if actionTokenSecret == "" {
+		log.Fatalf("Не задан ACTION_TOKEN_SECRET")
+	}
+	signer := services.NewActionTokenSigner(actionTokenSecret)
+	policy := models.VerificationPolicy(os.Getenv("EMAIL_VERIFICATION_POLICY"))
+	if policy == "" {
+		policy = models.VerificationPolicyRestrict
 	}
-	userService := services.NewUserService(userRepo, jwtSecret, 24*time.Hour)
+	if !policy.Valid() {
+		log.Fatalf("Некорректное значение EMAIL_VERIFICATION_POLICY: %s", policy)
+	}
+	consumedRepo := repository.NewConsumedTokenRepository(db)
+	// Письма уходят в фоне, чтобы запросы не ждали почтовый сервер; при остановке очередь дописывается
+	m := mailer.NewAsyncMailer(newMailer(), intFromEnv("MAIL_QUEUE_SIZE", 1000), intFromEnv("MAIL_WORKERS", 4))
+	verificationService := services.NewVerificationService(userRepo, consumedRepo, m,
+		signer, services.VerificationConfig{
+			VerifyURL:       stringFromEnv("EMAIL_VERIFY_URL", "http://localhost:8080/email/verify"),
+			ResetURL:        stringFromEnv("PASSWORD_RESET_URL", "http://localhost:8080/password/reset"),
+			VerificationTTL: durationFromEnv("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+			ResetTTL:        durationFromEnv("PASSWORD_RESET_TTL", time.Hour),
+			ResendInterval:  durationFromEnv("EMAIL_RESEND_INTERVAL", time.Minute),
+		})
+	recoveryRepo := repository.NewRecoveryCodeRepository(db)
+	mfaService := services.NewMFAService(userRepo, recoveryRepo, consumedRepo, signer,
+		stringFromEnv("MFA_ISSUER", "SOAProject"), 5*time.Minute)
+	throttler := services.NewLoginThrottler(repository.NewLoginAttemptRepository(db), services.LoginThrottleConfig{
+		FreeAttempts:    intFromEnv("LOGIN_FREE_ATTEMPTS", 2),
+		MaxFailures:     intFromEnv("LOGIN_MAX_FAILURES", 5),
+		LockoutDuration: durationFromEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		IPFreeAttempts:  intFromEnv("LOGIN_IP_FREE_ATTEMPTS", 20),
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		Window:          durationFromEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+	})
+	userService := services.NewUserService(userRepo, tokenService, verificationService, mfaService, throttler, policy)
 
 	userHandler := handlers.NewUserHandler(userService)
+	sessionHandler := handlers.NewSessionHandler(tokenService)
+	keyHandler := handlers.NewKeyHandler(keyService)
+	verificationHandler := handlers.NewVerificationHandler(verificationService)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+	// Общий секрет для внутренних маршрутов сервисов, которые шлюз не проксирует
+	internalToken := os.Getenv("INTERNAL_API_TOKEN")
+	if internalToken == "" {
+		log.Fatalf("Не задан INTERNAL_API_TOKEN")
+	}
+	promocodesClient := promocodes.NewHTTPClient(stringFromEnv("PROMOCODES_SERVICE_URL", "http://promocodes-service:8082"),
+		internalToken, durationFromEnv("PROMOCODES_SERVICE_TIMEOUT", 10*time.Second))
+	companyRepo := repository.NewCompanyRepository(db)
+	adminService := services.NewAdminService(userRepo, companyRepo, memberRepo, tokenService, promocodesClient)
+	adminHandler := handlers.NewAdminHandler(userService, adminService)
+	companyModerationRepo := repository.NewCompanyModerationRepository(db)
+	companyService := services.NewCompanyService(companyRepo, memberRepo, userRepo, companyModerationRepo, tokenService, promocodesClient)
+	companyHandler := handlers.NewCompanyHandler(companyService)
+	membershipService := services.NewMembershipService(companyRepo, memberRepo, repository.NewCompanyInvitationRepository(db),
+		userRepo, tokenService, m, signer, services.InvitationConfig{
+			AcceptURL: stringFromEnv("COMPANY_INVITE_URL", "http://localhost:8080/invitations/accept"),
+			TTL:       durationFromEnv("COMPANY_INVITATION_TTL", 72*time.Hour),
+		})
+	membershipHandler := handlers.NewMembershipHandler(membershipService)
+	companyModerationService := services.NewCompanyModerationService(companyRepo, companyModerationRepo, userRepo, m,
+		durationFromEnv("MODERATION_CLAIM_TTL", 30*time.Minute))
+	companyModerationHandler := handlers.NewCompanyModerationHandler(companyModerationService)
 
-	r := gin.Default()
+	// Сервис доступен только через шлюз: адрес клиента из X-Forwarded-For принимается лишь от него,
+	// иначе подбор пароля обходил бы ограничение попыток подменой заголовка
+	trustedProxies := listFromEnv("TRUSTED_PROXIES")
+
+	r := gin.New()
+	r.Use(otelgin.Middleware("user-service"), tracing.RequestID(), tracing.Logger(), gin.Recovery())
+	r.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Ошибка настройки доверенных прокси: %v", err)
+	}
+
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/healthz", checker.Liveness)
+	r.GET("/readyz", checker.Readiness)
+	r.GET("/.well-known/jwks.json", keyHandler.JWKS)
 
 	r.POST("/register", userHandler.Register)
 	r.POST("/login", userHandler.Login)
+	r.POST("/login/2fa", userHandler.LoginMFA)
+	r.POST("/token/refresh", userHandler.RefreshToken)
+	r.POST("/password/forgot", userHandler.ForgotPassword)
+	r.POST("/password/reset", userHandler.ResetPassword)
+	r.GET("/email/verify", verificationHandler.VerifyEmail)
+	r.POST("/email/verify", verificationHandler.VerifyEmail)
 
 	protected := r.Group("/")
 	protected.Use(userHandler.AuthMiddleware())
 	{
 		protected.GET("/profile", userHandler.GetProfile)
 		protected.PUT("/profile", userHandler.UpdateProfile)
+		protected.PUT("/password", userHandler.ChangePassword)
+		protected.POST("/logout", sessionHandler.Logout)
+		protected.POST("/logout/all", sessionHandler.LogoutAll)
+		protected.GET("/sessions", sessionHandler.ListSessions)
+		protected.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+		protected.POST("/email/verify/resend", verificationHandler.ResendVerification)
+		protected.POST("/2fa/enroll", mfaHandler.Enroll)
+		protected.POST("/2fa/confirm", mfaHandler.Confirm)
+		protected.POST("/2fa/disable", mfaHandler.Disable)
+		protected.POST("/2fa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+		protected.GET("/companies", companyHandler.ListCompanies)
+		protected.GET("/companies/:id", companyHandler.GetCompany)
+		protected.POST("/companies", handlers.RequireVerifiedEmail(policy), companyHandler.CreateCompany)
+		protected.PUT("/companies/:id", companyHandler.UpdateCompany)
+		protected.DELETE("/companies/:id", companyHandler.DeleteCompany)
+		protected.GET("/companies/:id/members", membershipHandler.ListMembers)
+		protected.PUT("/companies/:id/members/:user_id", membershipHandler.UpdateMember)
+		protected.DELETE("/companies/:id/members/:user_id", membershipHandler.RemoveMember)
+		protected.GET("/companies/:id/invitations", membershipHandler.ListInvitations)
+		protected.POST("/companies/:id/invitations", membershipHandler.Invite)
+		protected.DELETE("/companies/:id/invitations/:invitation_id", membershipHandler.RevokeInvitation)
+		protected.POST("/invitations/accept", membershipHandler.AcceptInvitation)
 	}
 
+	admin := r.Group("/admin")
+	admin.Use(userHandler.AuthMiddleware(), handlers.RequireVerifiedEmail(policy))
+	{
+		admin.GET("/users", handlers.RequirePermission(models.PermissionViewUsers), adminHandler.ListUsers)
+		admin.GET("/users/:id", handlers.RequirePermission(models.PermissionViewUsers), adminHandler.GetUser)
+		admin.POST("/users/:id/block", handlers.RequirePermission(models.PermissionManageUsers), adminHandler.BlockUser)
+		admin.POST("/users/:id/unblock", handlers.RequirePermission(models.PermissionManageUsers), adminHandler.UnblockUser)
+		admin.POST("/users/:id/reset-password", handlers.RequirePermission(models.PermissionManageUsers), adminHandler.ResetPassword)
+		admin.DELETE("/users/:id", handlers.RequirePermission(models.PermissionManageUsers), adminHandler.DeleteUser)
+		admin.PUT("/users/:id/role", handlers.RequirePermission(models.PermissionAssignRoles), adminHandler.AssignRole)
+		admin.PUT("/users/:id/segments", handlers.RequirePermission(models.PermissionManageUsers), adminHandler.SetSegments)
+		admin.GET("/users/:id/companies", handlers.RequirePermission(models.PermissionViewUsers), companyHandler.ListUserCompanies)
+		admin.PUT("/companies/:id/subscription", handlers.RequirePermission(models.PermissionManageAllCompanies), companyHandler.SetSubscription)
+	}
+
+	internal := r.Group("/internal")
+	internal.Use(handlers.InternalAuthMiddleware(internalToken))
+	{
+		internalHandler := handlers.NewInternalHandler(companyService, services.NewNotificationService(userRepo, m))
+		internal.GET("/companies/:id", internalHandler.GetCompany)
+		internal.POST("/users/:id/notifications", internalHandler.NotifyUser)
+	}
+
+	// Промокоды и комментарии модерируются в promocodes-service, шлюз направляет сюда только /moderation/companies
+	moderation := r.Group("/moderation/companies")
+	moderation.Use(userHandler.AuthMiddleware(), handlers.RequireVerifiedEmail(policy), handlers.RequirePermission(models.PermissionModerateContent))
+	{
+		moderation.GET("", companyModerationHandler.ListQueue)
+		moderation.GET("/audit", companyModerationHandler.ListAudit)
+		moderation.POST("/:id/claim", companyModerationHandler.ClaimItem)
+		moderation.POST("/:id/release", companyModerationHandler.ReleaseItem)
+		moderation.POST("/:id/approve", companyModerationHandler.ApproveItem)
+		moderation.POST("/:id/reject", companyModerationHandler.RejectItem)
+	}
+
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9081"
+	}
+	listener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		log.Fatalf("Не удалось открыть порт gRPC: %v", err)
+	}
+	grpcServer := grpcserver.NewServer(userService)
+	if err := grpcServer.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Ошибка настройки доверенных прокси: %v", err)
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		otelgrpc.UnaryServerInterceptor(),
+		grpcserver.LoggingInterceptor(),
+		grpcServer.AuthInterceptor(),
+	))
+	userpb.RegisterUserServiceServer(server, grpcServer)
+	go func() {
+		log.Fatal(server.Serve(listener))
+	}()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8081"
 	}
-	log.Fatal(r.Run(":" + port))
+	// HTTP-сервер стартует до миграции, чтобы /readyz отвечал 503, пока она идет
+	httpServer := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	err = db.AutoMigrate(&models.User{}, &models.AuthToken{}, &models.Session{}, &models.RevokedToken{}, &models.SigningKey{}, &models.ConsumedToken{}, &models.RecoveryCode{}, &models.LoginAttempt{}, &models.Company{}, &models.CompanyMember{}, &models.CompanyInvitation{}, &models.CompanyModerationItem{}, &models.CompanyModerationAudit{})
+	if err != nil {
+		log.Fatalf("Ошибка миграции базы данных: %v", err)
+	}
+	if err := keyService.RotateIfNeeded(context.Background()); err != nil {
+		log.Fatalf("Ошибка загрузки ключей подписи: %v", err)
+	}
+	go keyService.Run(context.Background(), time.Minute)
+	bootstrapAdmin(userRepo, userService)
+	checker.SetReady(true)
+	log.Printf("Миграция базы данных завершена, сервис готов принимать запросы")
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+	log.Printf("Остановка сервиса")
+	checker.SetReady(false)
+	ctx, cancel := context.WithTimeout(context.Background(), durationFromEnv("SHUTDOWN_TIMEOUT", 30*time.Second))
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("Ошибка остановки HTTP-сервера: %v", err)
+	}
+	server.GracefulStop()
+	// Новых писем после остановки серверов нет, дожидаемся отправки очереди
+	m.Close()
+}
+
+func stringFromEnv(name, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// listFromEnv читает список через запятую, пустые элементы пропускаются
+func listFromEnv(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Некорректное значение %s: %v", name, err)
+	}
+	return duration
+}
+
+func intFromEnv(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		log.Fatalf("Некорректное значение %s: %s", name, value)
+	}
+	return number
+}
+
+// newMailer выбирает способ доставки писем по MAILER: log (по умолчанию) или smtp
+func newMailer() mailer.Mailer {
+	switch os.Getenv("MAILER") {
+	case "", "log":
+		path := os.Getenv("MAIL_LOG_FILE")
+		if path == "" {
+			return mailer.NewLogMailer(os.Stdout)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Не удалось открыть MAIL_LOG_FILE: %v", err)
+		}
+		return mailer.NewLogMailer(file)
+	case "smtp":
+		addr, from := os.Getenv("SMTP_ADDR"), os.Getenv("SMTP_FROM")
+		if addr == "" || from == "" {
+			log.Fatalf("Для MAILER=smtp нужны SMTP_ADDR и SMTP_FROM")
+		}
+		return mailer.NewSMTPMailer(addr, from, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	default:
+		log.Fatalf("Неизвестный MAILER: %s", os.Getenv("MAILER"))
+		return nil
+	}
+}
+
+// bootstrapAdmin выдает роль admin пользователю из ADMIN_LOGIN, чтобы было кому назначать роли остальным
+func bootstrapAdmin(userRepo repository.UserRepositoryInterface, userService services.UserServiceInterface) {
+	login := os.Getenv("ADMIN_LOGIN")
+	if login == "" {
+		return
+	}
+	ctx := context.Background()
+	user, err := userRepo.GetUserByLogin(ctx, login)
+	if err != nil {
+		log.Fatalf("Ошибка поиска администратора %s: %v", login, err)
+	}
+	if user == nil {
+		log.Printf("Пользователь %s из ADMIN_LOGIN еще не зарегистрирован", login)
+		return
+	}
+	if user.Role == models.RoleAdmin {
+		return
+	}
+	if err := userService.AssignRole(ctx, user.ID, models.RoleAdmin); err != nil {
+		log.Fatalf("Ошибка назначения роли администратора %s: %v", login, err)
+	}
+	log.Printf("Пользователю %s назначена роль admin", login)
 }
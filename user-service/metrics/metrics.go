@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	Registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_registrations_total",
+		Help: "Количество успешных регистраций.",
+	})
+
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_logins_total",
+		Help: "Попытки входа по результату: success, failure, throttled.",
+	}, []string{"result"})
+
+	LoginLockouts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_login_lockouts_total",
+		Help: "Количество временных блокировок входа после подбора пароля.",
+	})
+
+	TokenValidationFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_token_validation_failures_total",
+		Help: "Количество отклоненных токенов.",
+	})
+
+	TokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_token_refreshes_total",
+		Help: "Обновления токенов по результату: success, failure, reuse.",
+	}, []string{"result"})
+
+	EmailsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_emails_sent_total",
+		Help: "Отправленные письма по результату: success, failure.",
+	}, []string{"result"})
+
+	CompanyModerationDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_company_moderation_decisions_total",
+		Help: "Решения модераторов по компаниям: approved, rejected.",
+	}, []string{"status"})
+
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "user_db_query_duration_seconds",
+		Help:    "Время выполнения методов репозиториев.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// ObserveDBQuery используется как defer metrics.ObserveDBQuery("UserRepository.GetUserByID", time.Now())
+func ObserveDBQuery(method string, start time.Time) {
+	DBQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
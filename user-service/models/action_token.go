@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// TokenPurpose отделяет одноразовые токены разных действий друг от друга:
+// токен подтверждения email нельзя предъявить, например, для сброса пароля
+type TokenPurpose string
+
+const (
+	PurposeEmailVerification TokenPurpose = "email_verification"
+	PurposePasswordReset     TokenPurpose = "password_reset"
+	PurposeMFAChallenge      TokenPurpose = "mfa_challenge"
+	PurposeCompanyInvitation TokenPurpose = "company_invitation"
+)
+
+// ConsumedToken - уже использованный одноразовый токен. Запись нужна только до ExpiresAt,
+// после этого токен отклоняется по сроку действия.
+type ConsumedToken struct {
+	JTI       string       `gorm:"primaryKey"`
+	Purpose   TokenPurpose `gorm:"not null"`
+	ExpiresAt time.Time    `gorm:"index;not null"`
+}
+
+// VerificationPolicy определяет, что доступно учетной записи с неподтвержденным email
+type VerificationPolicy string
+
+const (
+	// VerificationPolicyAllow - ограничений нет
+	VerificationPolicyAllow VerificationPolicy = "allow"
+	// VerificationPolicyRestrict - вход разрешен, но маршруты с RequireVerifiedEmail недоступны
+	VerificationPolicyRestrict VerificationPolicy = "restrict"
+	// VerificationPolicyDeny - вход запрещен до подтверждения email
+	VerificationPolicyDeny VerificationPolicy = "deny"
+)
+
+func (p VerificationPolicy) Valid() bool {
+	switch p {
+	case VerificationPolicyAllow, VerificationPolicyRestrict, VerificationPolicyDeny:
+		return true
+	}
+	return false
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
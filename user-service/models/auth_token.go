@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// AuthToken - refresh-токен (сущность AuthToken в doc/gateway/ER_gateway.puml).
+// Сам токен не хранится, только его SHA-256. Токены, выданные друг другу при ротации, образуют семейство;
+// FamilyID совпадает с ID сессии (models.Session).
+type AuthToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"`
+	TokenHash string     `gorm:"uniqueIndex;not null"`
+	FamilyID  string     `gorm:"index;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // заполняется при ротации
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// SubscriptionLevel - тариф компании, от него зависят доступные ей возможности программы лояльности
+type SubscriptionLevel string
+
+const (
+	SubscriptionFree     SubscriptionLevel = "free"
+	SubscriptionStandard SubscriptionLevel = "standard"
+	SubscriptionPremium  SubscriptionLevel = "premium"
+)
+
+func (l SubscriptionLevel) Valid() bool {
+	switch l {
+	case SubscriptionFree, SubscriptionStandard, SubscriptionPremium:
+		return true
+	}
+	return false
+}
+
+// Company - компания, которая проводит промо-кампании. Владелец - пользователь, создавший компанию.
+type Company struct {
+	ID                uint              `json:"id" gorm:"primaryKey"`
+	OwnerID           uint              `json:"owner_id" gorm:"index;not null"`
+	Name              string            `json:"name" gorm:"not null"`
+	Description       string            `json:"description"`
+	SubscriptionLevel SubscriptionLevel `json:"subscription_level" gorm:"not null;default:free"`
+	// ModerationStatus - результат проверки последней версии компании, ModerationReason - причина отказа
+	ModerationStatus ModerationStatus `json:"moderation_status" gorm:"index;not null;default:pending"`
+	ModerationReason string           `json:"moderation_reason,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+}
+
+// ModerationHash - отпечаток данных компании, которые проверяет модератор
+func (c *Company) ModerationHash() string {
+	return moderationHash(c.Name, c.Description)
+}
+
+type CreateCompanyRequest struct {
+	Name        string `json:"name" binding:"required,min=2,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+}
+
+// UpdateCompanyRequest - пустые поля не меняются
+type UpdateCompanyRequest struct {
+	Name        string `json:"name" binding:"omitempty,min=2,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+}
+
+type SetSubscriptionRequest struct {
+	SubscriptionLevel SubscriptionLevel `json:"subscription_level" binding:"required"`
+}
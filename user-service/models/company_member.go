@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// CompanyRole - роль участника в команде компании
+type CompanyRole string
+
+const (
+	// CompanyRoleOwner управляет компанией и ее командой
+	CompanyRoleOwner CompanyRole = "owner"
+	// CompanyRoleManager ведет промо-кампании компании
+	CompanyRoleManager CompanyRole = "manager"
+	// CompanyRoleAnalyst только просматривает данные компании
+	CompanyRoleAnalyst CompanyRole = "analyst"
+)
+
+func (r CompanyRole) Valid() bool {
+	switch r {
+	case CompanyRoleOwner, CompanyRoleManager, CompanyRoleAnalyst:
+		return true
+	}
+	return false
+}
+
+type CompanyMember struct {
+	CompanyID uint        `json:"company_id" gorm:"primaryKey"`
+	UserID    uint        `json:"user_id" gorm:"primaryKey;index"`
+	Role      CompanyRole `json:"role" gorm:"not null"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// CompanyInvitation - приглашение в команду компании. Ссылка с подписанным токеном уходит на Email,
+// принять приглашение может только пользователь с этим адресом.
+type CompanyInvitation struct {
+	ID         uint        `json:"id" gorm:"primaryKey"`
+	CompanyID  uint        `json:"company_id" gorm:"index;not null"`
+	Email      string      `json:"email" gorm:"not null"`
+	Role       CompanyRole `json:"role" gorm:"not null"`
+	InvitedBy  uint        `json:"invited_by" gorm:"not null"`
+	ExpiresAt  time.Time   `json:"expires_at" gorm:"not null"`
+	AcceptedAt *time.Time  `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Pending - приглашение еще можно принять
+func (i *CompanyInvitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
+
+type InviteMemberRequest struct {
+	Email string      `json:"email" binding:"required,email"`
+	Role  CompanyRole `json:"role" binding:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role CompanyRole `json:"role" binding:"required"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
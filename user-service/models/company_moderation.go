@@ -0,0 +1,110 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// ModerationStatus - результат проверки компании модератором
+type ModerationStatus string
+
+const (
+	ModerationPending  ModerationStatus = "pending"
+	ModerationApproved ModerationStatus = "approved"
+	ModerationRejected ModerationStatus = "rejected"
+	// ModerationSuperseded - автор изменил компанию до решения, и заявку заменила новая
+	ModerationSuperseded ModerationStatus = "superseded"
+)
+
+func (s ModerationStatus) Valid() bool {
+	switch s {
+	case ModerationPending, ModerationApproved, ModerationRejected, ModerationSuperseded:
+		return true
+	}
+	return false
+}
+
+// ModerationAction - действие модератора, которое попадает в журнал
+type ModerationAction string
+
+const (
+	ModerationActionClaim   ModerationAction = "claim"
+	ModerationActionRelease ModerationAction = "release"
+	ModerationActionApprove ModerationAction = "approve"
+	ModerationActionReject  ModerationAction = "reject"
+)
+
+// CompanyModerationItem - заявка на проверку одной версии компании. Изменение компании закрывает
+// ожидающую заявку и создает новую, даже если прежнюю уже взял модератор. Промокоды и комментарии
+// проверяются в очереди promocodes-service.
+type CompanyModerationItem struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	CompanyID uint             `json:"company_id" gorm:"index;not null"`
+	AuthorID  uint             `json:"author_id" gorm:"not null"`
+	Status    ModerationStatus `json:"status" gorm:"index;not null"`
+	// ContentHash - отпечаток версии компании, на которую подана заявка; решение по заявке
+	// принимается, только пока компания совпадает с ней
+	ContentHash string `json:"-" gorm:"not null;default:''"`
+	// ClaimedBy - модератор, который взял заявку в работу
+	ClaimedBy   *uint      `json:"claimed_by,omitempty"`
+	ClaimedAt   *time.Time `json:"claimed_at,omitempty"`
+	ModeratorID *uint      `json:"moderator_id,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CompanyModerationAudit - запись журнала действий модераторов; при удалении компании журнал сохраняется
+type CompanyModerationAudit struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	ItemID      uint             `json:"item_id" gorm:"index;not null"`
+	CompanyID   uint             `json:"company_id" gorm:"not null"`
+	ModeratorID uint             `json:"moderator_id" gorm:"index;not null"`
+	Action      ModerationAction `json:"action" gorm:"not null"`
+	Reason      string           `json:"reason,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+type RejectModerationRequest struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
+
+type CompanyModerationFilter struct {
+	Status   ModerationStatus `form:"status"`
+	Page     int              `form:"page" binding:"omitempty,min=1"`
+	PageSize int              `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type CompanyModerationList struct {
+	Items    []CompanyModerationItem `json:"items"`
+	Total    int64                   `json:"total"`
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"page_size"`
+}
+
+type CompanyModerationAuditFilter struct {
+	ItemID      uint `form:"item_id"`
+	CompanyID   uint `form:"company_id"`
+	ModeratorID uint `form:"moderator_id"`
+	Page        int  `form:"page" binding:"omitempty,min=1"`
+	PageSize    int  `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type CompanyModerationAuditList struct {
+	Records  []CompanyModerationAudit `json:"records"`
+	Total    int64                    `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+}
+
+// moderationHash - отпечаток полей, которые проверяет модератор
+func moderationHash(fields ...interface{}) string {
+	hash := sha256.New()
+	for _, field := range fields {
+		fmt.Fprintf(hash, "%v\x00", field)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt - счетчик неудачных попыток входа по ключу: логину или IP-адресу.
+// Логин учитывается независимо от того, существует ли такая учетная запись.
+type LoginAttempt struct {
+	Key           string    `gorm:"primaryKey"`
+	Failures      int       `gorm:"not null"`
+	LastFailureAt time.Time `gorm:"index;not null"`
+	LockedUntil   *time.Time
+}
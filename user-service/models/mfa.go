@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode - одноразовый код восстановления доступа при потере аутентификатора.
+// Хранится только хэш, сами коды показываются пользователю один раз.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	// ProvisioningURI - otpauth-ссылка, которую клиент показывает в виде QR-кода
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFALoginRequest - второй шаг входа: токен из ответа /login и код из аутентификатора
+// или один из кодов восстановления
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// NotificationRequest - письмо пользователю от другого сервиса; адрес подставляет User Service
+type NotificationRequest struct {
+	Subject string `json:"subject" binding:"required,max=200"`
+	Body    string `json:"body" binding:"required,max=10000"`
+}
@@ -0,0 +1,67 @@
+package models
+
+type Role string
+
+const (
+	RoleUser         Role = "user"
+	RoleCompanyOwner Role = "company_owner"
+	RoleModerator    Role = "moderator"
+	RoleAdmin        Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermissionManageOwnProfile Permission = "profile:manage"
+	PermissionManageCompanies  Permission = "companies:manage"
+	PermissionModerateContent  Permission = "content:moderate"
+	PermissionViewUsers        Permission = "users:view"
+	PermissionManageUsers      Permission = "users:manage"
+	PermissionAssignRoles      Permission = "roles:assign"
+
+	// PermissionManageAllCompanies - управление любой компанией, а не только своей
+	PermissionManageAllCompanies Permission = "companies:manage_all"
+)
+
+// RolePermissions - права каждой роли. Роли не наследуются друг от друга, права перечисляются явно.
+var RolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermissionManageOwnProfile,
+	},
+	RoleCompanyOwner: {
+		PermissionManageOwnProfile,
+		PermissionManageCompanies,
+	},
+	RoleModerator: {
+		PermissionManageOwnProfile,
+		PermissionModerateContent,
+		PermissionViewUsers,
+	},
+	RoleAdmin: {
+		PermissionManageOwnProfile,
+		PermissionManageCompanies,
+		PermissionManageAllCompanies,
+		PermissionModerateContent,
+		PermissionViewUsers,
+		PermissionManageUsers,
+		PermissionAssignRoles,
+	},
+}
+
+func (r Role) Valid() bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+func (r Role) HasPermission(permission Permission) bool {
+	for _, p := range RolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type AssignRoleRequest struct {
+	Role Role `json:"role" binding:"required"`
+}
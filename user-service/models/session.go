@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// Session создается при каждом входе. Ее ID попадает в claim sid access-токена
+// и используется как FamilyID refresh-токенов, поэтому отзыв сессии отзывает и те, и другие.
+type Session struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"index;not null"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current" gorm:"-"`
+}
+
+// RevokedToken - отозванный до истечения срока access-токен. Запись нужна только до ExpiresAt.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+// ClientInfo описывает устройство, с которого выполнен вход
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// TokenClaims - проверенное содержимое access-токена
+type TokenClaims struct {
+	UserID        uint
+	Roles         []Role
+	EmailVerified bool
+	SessionID     string
+	JTI           string
+	ExpiresAt     time.Time
+	// Companies - роли пользователя в командах компаний по идентификатору компании
+	Companies map[uint]CompanyRole
+	Segments  []string
+}
+
+func (c *TokenClaims) HasRole(role Role) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *TokenClaims) CompanyRole(companyID uint) (CompanyRole, bool) {
+	role, ok := c.Companies[companyID]
+	return role, ok
+}
+
+func (c *TokenClaims) HasPermission(permission Permission) bool {
+	for _, r := range c.Roles {
+		if r.HasPermission(permission) {
+			return true
+		}
+	}
+	return false
+}
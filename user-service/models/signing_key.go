@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// SigningKey - RSA-ключ подписи access-токенов. ID используется как kid в заголовке JWT.
+// Подписывает только самый новый ключ; после ротации открытый ключ публикуется в JWKS до ExpiresAt,
+// чтобы выданные им токены оставались действительными.
+type SigningKey struct {
+	ID         string     `gorm:"primaryKey"`
+	PrivateKey string     `gorm:"not null"` // PKCS#1 PEM
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	RotatedAt  *time.Time // когда ключ перестал подписывать
+	ExpiresAt  *time.Time `gorm:"index"` // когда открытый ключ убирается из JWKS
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
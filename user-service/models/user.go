@@ -5,18 +5,39 @@ import (
 )
 
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Login     string    `json:"login" gorm:"unique;not null"`
-	Password  string    `json:"-" gorm:"not null"` // Пароль не возвращается в JSON
-	Email     string    `json:"email" gorm:"unique;not null"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	BirthDate time.Time `json:"birth_date"`
-	Phone     string    `json:"phone"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Login         string     `json:"login" gorm:"unique;not null"`
+	Password      string     `json:"-" gorm:"not null"` // Пароль не возвращается в JSON
+	Email         string     `json:"email" gorm:"unique;not null"`
+	EmailVerified bool       `json:"email_verified" gorm:"not null;default:false"`
+	TOTPEnabled   bool       `json:"totp_enabled" gorm:"not null;default:false"`
+	Role          Role       `json:"role" gorm:"not null;default:user"`
+	Status        UserStatus `json:"status" gorm:"not null;default:active;index"`
+	Segments      Segments   `json:"segments" gorm:"type:text;not null;default:''"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	BirthDate     time.Time  `json:"birth_date"`
+	Phone         string     `json:"phone"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Время последней отправки письма подтверждения, по нему ограничивается повторная отправка
+	VerificationSentAt *time.Time `json:"-"`
+	// Время последней отправки ссылки сброса пароля
+	PasswordResetSentAt *time.Time `json:"-"`
+	// Секрет сохраняется при начале подключения 2FA, а включается после подтверждения первым кодом
+	TOTPSecret string `json:"-"`
+	// Шаг последнего принятого кода: код нельзя использовать повторно
+	TOTPLastStep int64 `json:"-"`
 }
 
+type UserStatus string
+
+const (
+	UserStatusActive  UserStatus = "active"
+	UserStatusBlocked UserStatus = "blocked"
+)
+
 type RegisterRequest struct {
 	Login    string `json:"login" binding:"required,min=4,max=20"`
 	Password string `json:"password" binding:"required,min=6"`
@@ -36,6 +57,49 @@ type UpdateProfileRequest struct {
 	Phone     string     `json:"phone"`
 }
 
+// LoginResponse содержит либо пару токенов, либо, если включена 2FA, только MFAToken
+// для второго шага входа
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"` // время жизни access-токена в секундах
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// UserFilter - параметры поиска пользователей в административном API
+type UserFilter struct {
+	LoginPrefix string     `form:"login"`
+	EmailPrefix string     `form:"email"`
+	CreatedFrom time.Time  `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo   time.Time  `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Role        Role       `form:"role"`
+	Status      UserStatus `form:"status"`
+	Page        int        `form:"page" binding:"omitempty,min=1"`
+	PageSize    int        `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type UserList struct {
+	Users    []User `json:"users"`
+	Total    int64  `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+}
+
+type ResetPasswordResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
 }
@@ -0,0 +1,64 @@
+package promocodes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// HeaderInternalToken передает общий секрет внутренних сервисов (INTERNAL_API_TOKEN)
+const HeaderInternalToken = "X-Internal-Token"
+
+// Client управляет данными компаний в promocodes-service
+type Client interface {
+	// DeleteCompany отзывает API-ключи компании и удаляет ее промокоды и настройки.
+	// Повторный вызов для уже удаленной компании успешен.
+	DeleteCompany(ctx context.Context, companyID uint) error
+}
+
+// HTTPClient обращается к внутренним маршрутам promocodes-service, которые шлюз не проксирует
+type HTTPClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewHTTPClient(baseURL, token string, timeout time.Duration) *HTTPClient {
+	return &HTTPClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *HTTPClient) DeleteCompany(ctx context.Context, companyID uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/internal/companies/%d", companyID))
+}
+
+func (c *HTTPClient) do(ctx context.Context, method, path string) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderInternalToken, c.token)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("promocodes-service недоступен: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("promocodes-service ответил %d на %s %s: %s", resp.StatusCode, method, path, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+var _ Client = (*HTTPClient)(nil)
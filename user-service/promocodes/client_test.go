@@ -0,0 +1,38 @@
+package promocodes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeleteCompany(t *testing.T) {
+	var method, path, token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, token = r.Method, r.URL.Path, r.Header.Get(HeaderInternalToken)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL+"/", "secret", time.Second)
+	if err := client.DeleteCompany(context.Background(), 7); err != nil {
+		t.Fatalf("Ожидается успешный запрос, получено: %v", err)
+	}
+	if method != http.MethodDelete || path != "/internal/companies/7" || token != "secret" {
+		t.Errorf("Неверный запрос: %s %s, токен %q", method, path, token)
+	}
+}
+
+func TestDeleteCompanyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"не авторизован"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "wrong", time.Second)
+	if err := client.DeleteCompany(context.Background(), 7); err == nil {
+		t.Error("Ответ с ошибкой должен возвращать ошибку")
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+)
+
+type CompanyInvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewCompanyInvitationRepository(db *gorm.DB) *CompanyInvitationRepository {
+	return &CompanyInvitationRepository{db: db}
+}
+
+func (r *CompanyInvitationRepository) CreateInvitation(ctx context.Context, invitation *models.CompanyInvitation) error {
+	ctx, span := tracing.Start(ctx, "CompanyInvitationRepository.CreateInvitation")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyInvitationRepository.CreateInvitation", time.Now())
+
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+func (r *CompanyInvitationRepository) GetInvitation(ctx context.Context, id uint) (*models.CompanyInvitation, error) {
+	ctx, span := tracing.Start(ctx, "CompanyInvitationRepository.GetInvitation")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyInvitationRepository.GetInvitation", time.Now())
+
+	var invitation models.CompanyInvitation
+	result := r.db.WithContext(ctx).First(&invitation, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &invitation, nil
+}
+
+func (r *CompanyInvitationRepository) ListPendingInvitations(ctx context.Context, companyID uint, now time.Time) ([]models.CompanyInvitation, error) {
+	ctx, span := tracing.Start(ctx, "CompanyInvitationRepository.ListPendingInvitations")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyInvitationRepository.ListPendingInvitations", time.Now())
+
+	var invitations []models.CompanyInvitation
+	err := r.db.WithContext(ctx).
+		Where("company_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", companyID, now).
+		Order("id").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *CompanyInvitationRepository) MarkInvitationAccepted(ctx context.Context, id uint, acceptedAt time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "CompanyInvitationRepository.MarkInvitationAccepted")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyInvitationRepository.MarkInvitationAccepted", time.Now())
+
+	return r.closeInvitation(ctx, id, "accepted_at", acceptedAt)
+}
+
+func (r *CompanyInvitationRepository) RevokeInvitation(ctx context.Context, id uint, revokedAt time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "CompanyInvitationRepository.RevokeInvitation")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyInvitationRepository.RevokeInvitation", time.Now())
+
+	return r.closeInvitation(ctx, id, "revoked_at", revokedAt)
+}
+
+// closeInvitation отмечает приглашение принятым или отозванным, только если оно еще действует
+func (r *CompanyInvitationRepository) closeInvitation(ctx context.Context, id uint, column string, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.CompanyInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", id, at).
+		Update(column, at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+var _ CompanyInvitationRepositoryInterface = (*CompanyInvitationRepository)(nil)
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CompanyMemberRepository struct {
+	db *gorm.DB
+}
+
+func NewCompanyMemberRepository(db *gorm.DB) *CompanyMemberRepository {
+	return &CompanyMemberRepository{db: db}
+}
+
+func (r *CompanyMemberRepository) SaveMember(ctx context.Context, member *models.CompanyMember) error {
+	ctx, span := tracing.Start(ctx, "CompanyMemberRepository.SaveMember")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyMemberRepository.SaveMember", time.Now())
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "company_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(member).Error
+}
+
+func (r *CompanyMemberRepository) GetMember(ctx context.Context, companyID, userID uint) (*models.CompanyMember, error) {
+	ctx, span := tracing.Start(ctx, "CompanyMemberRepository.GetMember")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyMemberRepository.GetMember", time.Now())
+
+	var member models.CompanyMember
+	result := r.db.WithContext(ctx).Where("company_id = ? AND user_id = ?", companyID, userID).First(&member)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &member, nil
+}
+
+func (r *CompanyMemberRepository) ListMembers(ctx context.Context, companyID uint) ([]models.CompanyMember, error) {
+	ctx, span := tracing.Start(ctx, "CompanyMemberRepository.ListMembers")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyMemberRepository.ListMembers", time.Now())
+
+	var members []models.CompanyMember
+	err := r.db.WithContext(ctx).Where("company_id = ?", companyID).Order("created_at").Find(&members).Error
+	return members, err
+}
+
+func (r *CompanyMemberRepository) ListUserMemberships(ctx context.Context, userID uint) ([]models.CompanyMember, error) {
+	ctx, span := tracing.Start(ctx, "CompanyMemberRepository.ListUserMemberships")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyMemberRepository.ListUserMemberships", time.Now())
+
+	var members []models.CompanyMember
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("company_id").Find(&members).Error
+	return members, err
+}
+
+func (r *CompanyMemberRepository) RemoveMember(ctx context.Context, companyID, userID uint) error {
+	ctx, span := tracing.Start(ctx, "CompanyMemberRepository.RemoveMember")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyMemberRepository.RemoveMember", time.Now())
+
+	return r.db.WithContext(ctx).
+		Where("company_id = ? AND user_id = ?", companyID, userID).
+		Delete(&models.CompanyMember{}).Error
+}
+
+var _ CompanyMemberRepositoryInterface = (*CompanyMemberRepository)(nil)
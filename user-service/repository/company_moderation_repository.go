@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CompanyModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewCompanyModerationRepository(db *gorm.DB) *CompanyModerationRepository {
+	return &CompanyModerationRepository{db: db}
+}
+
+func (r *CompanyModerationRepository) SubmitItem(ctx context.Context, item *models.CompanyModerationItem) error {
+	ctx, span := tracing.Start(ctx, "CompanyModerationRepository.SubmitItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyModerationRepository.SubmitItem", time.Now())
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		hash, err := lockCompany(tx, item.CompanyID)
+		if err != nil {
+			return err
+		}
+		// Решение по прежней заявке относилось бы к версии, которой уже нет, поэтому она закрывается,
+		// даже если ее взял модератор
+		err = tx.Model(&models.CompanyModerationItem{}).
+			Where("company_id = ? AND status = ?", item.CompanyID, models.ModerationPending).
+			Update("status", models.ModerationSuperseded).Error
+		if err != nil {
+			return err
+		}
+		item.Status = models.ModerationPending
+		item.ContentHash = hash
+		return tx.Create(item).Error
+	})
+}
+
+func (r *CompanyModerationRepository) GetItem(ctx context.Context, id uint) (*models.CompanyModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationRepository.GetItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyModerationRepository.GetItem", time.Now())
+
+	var item models.CompanyModerationItem
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&item)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &item, nil
+}
+
+func (r *CompanyModerationRepository) ListItems(ctx context.Context, filter models.CompanyModerationFilter) ([]models.CompanyModerationItem, int64, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationRepository.ListItems")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyModerationRepository.ListItems", time.Now())
+
+	query := r.db.WithContext(ctx).Model(&models.CompanyModerationItem{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []models.CompanyModerationItem
+	err := query.Order("id").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&items).Error
+	return items, total, err
+}
+
+func (r *CompanyModerationRepository) ClaimItem(ctx context.Context, audit *models.CompanyModerationAudit, staleBefore time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationRepository.ClaimItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyModerationRepository.ClaimItem", time.Now())
+
+	claimedAt := audit.CreatedAt
+	return r.withAudit(ctx, audit, func(tx *gorm.DB) *gorm.DB {
+		return availableItem(tx, audit, staleBefore).
+			Updates(map[string]interface{}{"claimed_by": audit.ModeratorID, "claimed_at": &claimedAt})
+	})
+}
+
+func (r *CompanyModerationRepository) ReleaseItem(ctx context.Context, audit *models.CompanyModerationAudit) (bool, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationRepository.ReleaseItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyModerationRepository.ReleaseItem", time.Now())
+
+	return r.withAudit(ctx, audit, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&models.CompanyModerationItem{}).
+			Where("id = ? AND status = ? AND claimed_by = ?", audit.ItemID, models.ModerationPending, audit.ModeratorID).
+			Updates(map[string]interface{}{"claimed_by": nil, "claimed_at": nil})
+	})
+}
+
+func (r *CompanyModerationRepository) DecideItem(ctx context.Context, audit *models.CompanyModerationAudit, status models.ModerationStatus, staleBefore time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationRepository.DecideItem")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyModerationRepository.DecideItem", time.Now())
+
+	decidedAt := audit.CreatedAt
+	return r.withAudit(ctx, audit, func(tx *gorm.DB) *gorm.DB {
+		hash, err := lockCompany(tx, audit.CompanyID)
+		if err != nil {
+			tx.AddError(err)
+			return tx
+		}
+		result := availableItem(tx, audit, staleBefore).Where("content_hash = ?", hash).Updates(map[string]interface{}{
+			"status":       status,
+			"moderator_id": audit.ModeratorID,
+			"reason":       audit.Reason,
+			"decided_at":   &decidedAt,
+		})
+		if result.Error != nil {
+			return result
+		}
+		if result.RowsAffected == 0 {
+			// Компанию изменили, а новая заявка еще не подана: решение по старой версии не принимается
+			if err := tx.Model(&models.CompanyModerationItem{}).
+				Where("id = ? AND status = ? AND content_hash <> ?", audit.ItemID, models.ModerationPending, hash).
+				Update("status", models.ModerationSuperseded).Error; err != nil {
+				result.Error = err
+			}
+			return result
+		}
+		err = tx.Model(&models.Company{}).Where("id = ?", audit.CompanyID).
+			Updates(map[string]interface{}{"moderation_status": status, "moderation_reason": audit.Reason}).Error
+		if err != nil {
+			result.Error = err
+		}
+		return result
+	})
+}
+
+func (r *CompanyModerationRepository) ListAudit(ctx context.Context, filter models.CompanyModerationAuditFilter) ([]models.CompanyModerationAudit, int64, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationRepository.ListAudit")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyModerationRepository.ListAudit", time.Now())
+
+	query := r.db.WithContext(ctx).Model(&models.CompanyModerationAudit{})
+	if filter.ItemID != 0 {
+		query = query.Where("item_id = ?", filter.ItemID)
+	}
+	if filter.CompanyID != 0 {
+		query = query.Where("company_id = ?", filter.CompanyID)
+	}
+	if filter.ModeratorID != 0 {
+		query = query.Where("moderator_id = ?", filter.ModeratorID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []models.CompanyModerationAudit
+	err := query.Order("id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&records).Error
+	return records, total, err
+}
+
+// withAudit выполняет update и при успехе пишет audit в той же транзакции
+func (r *CompanyModerationRepository) withAudit(ctx context.Context, audit *models.CompanyModerationAudit, update func(tx *gorm.DB) *gorm.DB) (bool, error) {
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := update(tx)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		changed = true
+		return tx.Create(audit).Error
+	})
+	return changed && err == nil, err
+}
+
+// availableItem выбирает заявку audit.ItemID, если она ждет решения и не занята другим модератором
+func availableItem(tx *gorm.DB, audit *models.CompanyModerationAudit, staleBefore time.Time) *gorm.DB {
+	return tx.Model(&models.CompanyModerationItem{}).
+		Where("id = ? AND status = ?", audit.ItemID, models.ModerationPending).
+		Where("claimed_by IS NULL OR claimed_by = ? OR claimed_at < ?", audit.ModeratorID, staleBefore)
+}
+
+// lockCompany блокирует компанию до конца транзакции и возвращает отпечаток ее текущей версии.
+// Удаленная компания дает пустой отпечаток.
+func lockCompany(tx *gorm.DB, companyID uint) (string, error) {
+	var company models.Company
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", companyID).First(&company).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return company.ModerationHash(), nil
+}
+
+var _ CompanyModerationRepositoryInterface = (*CompanyModerationRepository)(nil)
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+)
+
+type CompanyRepository struct {
+	db *gorm.DB
+}
+
+func NewCompanyRepository(db *gorm.DB) *CompanyRepository {
+	return &CompanyRepository{db: db}
+}
+
+func (r *CompanyRepository) CreateCompany(ctx context.Context, company *models.Company) error {
+	ctx, span := tracing.Start(ctx, "CompanyRepository.CreateCompany")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyRepository.CreateCompany", time.Now())
+
+	// Компания без владельца в команде или без заявки на модерацию осталась бы недоступной
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(company).Error; err != nil {
+			return err
+		}
+		err := tx.Create(&models.CompanyMember{CompanyID: company.ID, UserID: company.OwnerID, Role: models.CompanyRoleOwner}).Error
+		if err != nil {
+			return err
+		}
+		// Остальные роли шире company_owner и не понижаются
+		err = tx.Model(&models.User{}).Where("id = ? AND role = ?", company.OwnerID, models.RoleUser).
+			Update("role", models.RoleCompanyOwner).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.CompanyModerationItem{
+			CompanyID:   company.ID,
+			AuthorID:    company.OwnerID,
+			Status:      models.ModerationPending,
+			ContentHash: company.ModerationHash(),
+		}).Error
+	})
+}
+
+func (r *CompanyRepository) GetCompanyByID(ctx context.Context, id uint) (*models.Company, error) {
+	ctx, span := tracing.Start(ctx, "CompanyRepository.GetCompanyByID")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyRepository.GetCompanyByID", time.Now())
+
+	var company models.Company
+	result := r.db.WithContext(ctx).First(&company, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &company, nil
+}
+
+func (r *CompanyRepository) ListCompaniesByMember(ctx context.Context, userID uint) ([]models.Company, error) {
+	ctx, span := tracing.Start(ctx, "CompanyRepository.ListCompaniesByMember")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyRepository.ListCompaniesByMember", time.Now())
+
+	var companies []models.Company
+	err := r.db.WithContext(ctx).
+		Joins("JOIN company_members ON company_members.company_id = companies.id").
+		Where("company_members.user_id = ?", userID).
+		Order("companies.id").
+		Find(&companies).Error
+	return companies, err
+}
+
+func (r *CompanyRepository) UpdateCompany(ctx context.Context, company *models.Company) error {
+	ctx, span := tracing.Start(ctx, "CompanyRepository.UpdateCompany")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyRepository.UpdateCompany", time.Now())
+
+	return r.db.WithContext(ctx).Save(company).Error
+}
+
+func (r *CompanyRepository) DeleteCompany(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "CompanyRepository.DeleteCompany")
+	defer span.End()
+	defer metrics.ObserveDBQuery("CompanyRepository.DeleteCompany", time.Now())
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("company_id = ?", id).Delete(&models.CompanyMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("company_id = ?", id).Delete(&models.CompanyInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("company_id = ?", id).Delete(&models.CompanyModerationItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Company{}, id).Error
+	})
+}
+
+var _ CompanyRepositoryInterface = (*CompanyRepository)(nil)
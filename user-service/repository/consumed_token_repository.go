@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ConsumedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewConsumedTokenRepository(db *gorm.DB) *ConsumedTokenRepository {
+	return &ConsumedTokenRepository{db: db}
+}
+
+func (r *ConsumedTokenRepository) ConsumeToken(ctx context.Context, token *models.ConsumedToken) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ConsumedTokenRepository.ConsumeToken")
+	defer span.End()
+	defer metrics.ObserveDBQuery("ConsumedTokenRepository.ConsumeToken", time.Now())
+
+	db := r.db.WithContext(ctx)
+	// Заодно удаляем записи о токенах, срок которых уже истек
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&models.ConsumedToken{}).Error; err != nil {
+		return false, err
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(token)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+var _ ConsumedTokenRepositoryInterface = (*ConsumedTokenRepository)(nil)
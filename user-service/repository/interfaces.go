@@ -2,12 +2,119 @@
 package repository
 
 import (
+	"context"
+	"time"
 	"user-service/models"
 )
 
 type UserRepositoryInterface interface {
-    CreateUser(user *models.User) error
-    GetUserByLogin(login string) (*models.User, error)
-    GetUserByID(id uint) (*models.User, error)
-    UpdateUser(user *models.User) error
+    CreateUser(ctx context.Context, user *models.User) error
+    GetUserByLogin(ctx context.Context, login string) (*models.User, error)
+    GetUserByID(ctx context.Context, id uint) (*models.User, error)
+    GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+    UpdateUser(ctx context.Context, user *models.User) error
+    // AdvanceTOTPStep запоминает шаг принятого кода 2FA, только если он новее сохраненного.
+    // Возвращает false, если код этого или более позднего шага уже был принят.
+    AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+    // ListUsers возвращает страницу пользователей по фильтру и общее число подходящих
+    ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error)
+    DeleteUser(ctx context.Context, id uint) error
+}
+
+type TokenRepositoryInterface interface {
+    CreateToken(ctx context.Context, token *models.AuthToken) error
+    GetTokenByHash(ctx context.Context, tokenHash string) (*models.AuthToken, error)
+    // MarkTokenUsed помечает токен использованным, только если он еще не был использован.
+    // Возвращает false, если токен уже ротирован (в том числе параллельным запросом).
+    MarkTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+    RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+}
+
+type SessionRepositoryInterface interface {
+    CreateSession(ctx context.Context, session *models.Session) error
+    GetSession(ctx context.Context, id string) (*models.Session, error)
+    ListActiveSessions(ctx context.Context, userID uint) ([]models.Session, error)
+    TouchSession(ctx context.Context, id string, usedAt time.Time) error
+    RevokeSession(ctx context.Context, id string, revokedAt time.Time) error
+    RevokeUserSessions(ctx context.Context, userID uint, revokedAt time.Time) error
+    RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+    IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type KeyRepositoryInterface interface {
+    CreateKey(ctx context.Context, key *models.SigningKey) error
+    // ListValidKeys возвращает ключи, открытая часть которых еще публикуется, от новых к старым
+    ListValidKeys(ctx context.Context, now time.Time) ([]models.SigningKey, error)
+    RetireKey(ctx context.Context, id string, rotatedAt, expiresAt time.Time) error
+}
+
+type RecoveryCodeRepositoryInterface interface {
+    // ReplaceRecoveryCodes удаляет прежние коды пользователя и сохраняет новые
+    ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error
+    // UseRecoveryCode помечает неиспользованный код использованным; false - такого кода нет или он уже использован
+    UseRecoveryCode(ctx context.Context, userID uint, codeHash string, usedAt time.Time) (bool, error)
+    DeleteRecoveryCodes(ctx context.Context, userID uint) error
+}
+
+type ConsumedTokenRepositoryInterface interface {
+    // ConsumeToken отмечает одноразовый токен использованным.
+    // Возвращает false, если токен уже был использован (в том числе параллельным запросом).
+    ConsumeToken(ctx context.Context, token *models.ConsumedToken) (bool, error)
+}
+
+type LoginAttemptRepositoryInterface interface {
+    // GetLoginAttempts возвращает счетчики по ключам; для ключей без неудачных попыток записей нет
+    GetLoginAttempts(ctx context.Context, keys []string) ([]models.LoginAttempt, error)
+    // RecordLoginFailure атомарно увеличивает счетчик ключа и возвращает его новое значение.
+    // Если прошлая неудача была раньше windowStart, счетчик начинается заново.
+    RecordLoginFailure(ctx context.Context, key string, at, windowStart time.Time) (*models.LoginAttempt, error)
+    LockLogin(ctx context.Context, key string, until time.Time) error
+    ResetLoginAttempts(ctx context.Context, key string) error
+}
+
+type CompanyRepositoryInterface interface {
+    // CreateCompany в одной транзакции создает компанию, добавляет OwnerID владельцем в ее команду,
+    // выдает ему роль company_owner вместо user и ставит компанию в очередь модерации
+    CreateCompany(ctx context.Context, company *models.Company) error
+    GetCompanyByID(ctx context.Context, id uint) (*models.Company, error)
+    // ListCompaniesByMember возвращает компании, в командах которых состоит пользователь
+    ListCompaniesByMember(ctx context.Context, userID uint) ([]models.Company, error)
+    UpdateCompany(ctx context.Context, company *models.Company) error
+    // DeleteCompany удаляет компанию вместе с ее командой, приглашениями и заявками на модерацию
+    DeleteCompany(ctx context.Context, id uint) error
+}
+
+type CompanyMemberRepositoryInterface interface {
+    // SaveMember добавляет участника или меняет роль уже состоящего в команде
+    SaveMember(ctx context.Context, member *models.CompanyMember) error
+    GetMember(ctx context.Context, companyID, userID uint) (*models.CompanyMember, error)
+    ListMembers(ctx context.Context, companyID uint) ([]models.CompanyMember, error)
+    ListUserMemberships(ctx context.Context, userID uint) ([]models.CompanyMember, error)
+    RemoveMember(ctx context.Context, companyID, userID uint) error
+}
+
+type CompanyInvitationRepositoryInterface interface {
+    CreateInvitation(ctx context.Context, invitation *models.CompanyInvitation) error
+    GetInvitation(ctx context.Context, id uint) (*models.CompanyInvitation, error)
+    ListPendingInvitations(ctx context.Context, companyID uint, now time.Time) ([]models.CompanyInvitation, error)
+    // MarkInvitationAccepted и RevokeInvitation закрывают только действующее приглашение.
+    // Возвращают false, если оно уже принято, отозвано или истекло (в том числе параллельным запросом).
+    MarkInvitationAccepted(ctx context.Context, id uint, acceptedAt time.Time) (bool, error)
+    RevokeInvitation(ctx context.Context, id uint, revokedAt time.Time) (bool, error)
+}
+
+type CompanyModerationRepositoryInterface interface {
+    // SubmitItem ставит текущую версию компании в очередь и закрывает ожидающую заявку на прежнюю версию
+    SubmitItem(ctx context.Context, item *models.CompanyModerationItem) error
+    GetItem(ctx context.Context, id uint) (*models.CompanyModerationItem, error)
+    ListItems(ctx context.Context, filter models.CompanyModerationFilter) ([]models.CompanyModerationItem, int64, error)
+    // ClaimItem, ReleaseItem и DecideItem меняют заявку, только если она еще ждет решения и не занята
+    // другим модератором (захват старше staleBefore не учитывается), и в той же транзакции пишут
+    // audit в журнал. Время действия берется из audit.CreatedAt. false - условие не выполнено.
+    ClaimItem(ctx context.Context, audit *models.CompanyModerationAudit, staleBefore time.Time) (bool, error)
+    ReleaseItem(ctx context.Context, audit *models.CompanyModerationAudit) (bool, error)
+    // DecideItem также переносит решение и причину отказа на компанию. Если компания изменилась
+    // после подачи заявки, заявка закрывается со статусом superseded и решение не принимается.
+    DecideItem(ctx context.Context, audit *models.CompanyModerationAudit, status models.ModerationStatus, staleBefore time.Time) (bool, error)
+    ListAudit(ctx context.Context, filter models.CompanyModerationAuditFilter) ([]models.CompanyModerationAudit, int64, error)
 }
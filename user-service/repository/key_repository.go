@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+)
+
+type KeyRepository struct {
+	db *gorm.DB
+}
+
+func NewKeyRepository(db *gorm.DB) *KeyRepository {
+	return &KeyRepository{db: db}
+}
+
+func (r *KeyRepository) CreateKey(ctx context.Context, key *models.SigningKey) error {
+	ctx, span := tracing.Start(ctx, "KeyRepository.CreateKey")
+	defer span.End()
+	defer metrics.ObserveDBQuery("KeyRepository.CreateKey", time.Now())
+
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *KeyRepository) ListValidKeys(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	ctx, span := tracing.Start(ctx, "KeyRepository.ListValidKeys")
+	defer span.End()
+	defer metrics.ObserveDBQuery("KeyRepository.ListValidKeys", time.Now())
+
+	var keys []models.SigningKey
+	err := r.db.WithContext(ctx).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+func (r *KeyRepository) RetireKey(ctx context.Context, id string, rotatedAt, expiresAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "KeyRepository.RetireKey")
+	defer span.End()
+	defer metrics.ObserveDBQuery("KeyRepository.RetireKey", time.Now())
+
+	return r.db.WithContext(ctx).Model(&models.SigningKey{}).
+		Where("id = ? AND rotated_at IS NULL", id).
+		Updates(map[string]interface{}{"rotated_at": rotatedAt, "expires_at": expiresAt}).Error
+}
+
+var _ KeyRepositoryInterface = (*KeyRepository)(nil)
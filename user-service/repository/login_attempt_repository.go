@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) GetLoginAttempts(ctx context.Context, keys []string) ([]models.LoginAttempt, error) {
+	ctx, span := tracing.Start(ctx, "LoginAttemptRepository.GetLoginAttempts")
+	defer span.End()
+	defer metrics.ObserveDBQuery("LoginAttemptRepository.GetLoginAttempts", time.Now())
+
+	var attempts []models.LoginAttempt
+	if err := r.db.WithContext(ctx).Where("key IN ?", keys).Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+func (r *LoginAttemptRepository) RecordLoginFailure(ctx context.Context, key string, at, windowStart time.Time) (*models.LoginAttempt, error) {
+	ctx, span := tracing.Start(ctx, "LoginAttemptRepository.RecordLoginFailure")
+	defer span.End()
+	defer metrics.ObserveDBQuery("LoginAttemptRepository.RecordLoginFailure", time.Now())
+
+	db := r.db.WithContext(ctx)
+	// Заодно удаляем забытые счетчики без действующей блокировки
+	if err := db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", windowStart, at).
+		Delete(&models.LoginAttempt{}).Error; err != nil {
+		return nil, err
+	}
+
+	// Счетчик увеличивается одним запросом, чтобы параллельные попытки не терялись
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", windowStart),
+			"last_failure_at": at,
+		}),
+	}).Create(&models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: at}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var attempt models.LoginAttempt
+	if err := db.Where("key = ?", key).First(&attempt).Error; err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *LoginAttemptRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	ctx, span := tracing.Start(ctx, "LoginAttemptRepository.LockLogin")
+	defer span.End()
+	defer metrics.ObserveDBQuery("LoginAttemptRepository.LockLogin", time.Now())
+
+	return r.db.WithContext(ctx).Model(&models.LoginAttempt{}).
+		Where("key = ?", key).
+		Update("locked_until", until).Error
+}
+
+func (r *LoginAttemptRepository) ResetLoginAttempts(ctx context.Context, key string) error {
+	ctx, span := tracing.Start(ctx, "LoginAttemptRepository.ResetLoginAttempts")
+	defer span.End()
+	defer metrics.ObserveDBQuery("LoginAttemptRepository.ResetLoginAttempts", time.Now())
+
+	return r.db.WithContext(ctx).Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+var _ LoginAttemptRepositoryInterface = (*LoginAttemptRepository)(nil)
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+func (r *RecoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	ctx, span := tracing.Start(ctx, "RecoveryCodeRepository.ReplaceRecoveryCodes")
+	defer span.End()
+	defer metrics.ObserveDBQuery("RecoveryCodeRepository.ReplaceRecoveryCodes", time.Now())
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *RecoveryCodeRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, usedAt time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "RecoveryCodeRepository.UseRecoveryCode")
+	defer span.End()
+	defer metrics.ObserveDBQuery("RecoveryCodeRepository.UseRecoveryCode", time.Now())
+
+	result := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *RecoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "RecoveryCodeRepository.DeleteRecoveryCodes")
+	defer span.End()
+	defer metrics.ObserveDBQuery("RecoveryCodeRepository.DeleteRecoveryCodes", time.Now())
+
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+var _ RecoveryCodeRepositoryInterface = (*RecoveryCodeRepository)(nil)
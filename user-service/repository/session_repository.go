@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	ctx, span := tracing.Start(ctx, "SessionRepository.CreateSession")
+	defer span.End()
+	defer metrics.ObserveDBQuery("SessionRepository.CreateSession", time.Now())
+
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *SessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionRepository.GetSession")
+	defer span.End()
+	defer metrics.ObserveDBQuery("SessionRepository.GetSession", time.Now())
+
+	var session models.Session
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&session)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) ListActiveSessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionRepository.ListActiveSessions")
+	defer span.End()
+	defer metrics.ObserveDBQuery("SessionRepository.ListActiveSessions", time.Now())
+
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *SessionRepository) TouchSession(ctx context.Context, id string, usedAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "SessionRepository.TouchSession")
+	defer span.End()
+	defer metrics.ObserveDBQuery("SessionRepository.TouchSession", time.Now())
+
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error
+}
+
+func (r *SessionRepository) RevokeSession(ctx context.Context, id string, revokedAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "SessionRepository.RevokeSession")
+	defer span.End()
+	defer metrics.ObserveDBQuery("SessionRepository.RevokeSession", time.Now())
+
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userID uint, revokedAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "SessionRepository.RevokeUserSessions")
+	defer span.End()
+	defer metrics.ObserveDBQuery("SessionRepository.RevokeUserSessions", time.Now())
+
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *SessionRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "SessionRepository.RevokeToken")
+	defer span.End()
+	defer metrics.ObserveDBQuery("SessionRepository.RevokeToken", time.Now())
+
+	db := r.db.WithContext(ctx)
+	// Заодно удаляем записи о токенах, срок которых уже истек
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+func (r *SessionRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "SessionRepository.IsTokenRevoked")
+	defer span.End()
+	defer metrics.ObserveDBQuery("SessionRepository.IsTokenRevoked", time.Now())
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+var _ SessionRepositoryInterface = (*SessionRepository)(nil)
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/tracing"
+
+	"gorm.io/gorm"
+)
+
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) CreateToken(ctx context.Context, token *models.AuthToken) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.CreateToken")
+	defer span.End()
+	defer metrics.ObserveDBQuery("TokenRepository.CreateToken", time.Now())
+
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *TokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.AuthToken, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.GetTokenByHash")
+	defer span.End()
+	defer metrics.ObserveDBQuery("TokenRepository.GetTokenByHash", time.Now())
+
+	var token models.AuthToken
+	result := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r *TokenRepository) MarkTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TokenRepository.MarkTokenUsed")
+	defer span.End()
+	defer metrics.ObserveDBQuery("TokenRepository.MarkTokenUsed", time.Now())
+
+	result := r.db.WithContext(ctx).Model(&models.AuthToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *TokenRepository) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	ctx, span := tracing.Start(ctx, "TokenRepository.RevokeFamily")
+	defer span.End()
+	defer metrics.ObserveDBQuery("TokenRepository.RevokeFamily", time.Now())
+
+	return r.db.WithContext(ctx).Model(&models.AuthToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", revokedAt).Error
+}
+
+var _ TokenRepositoryInterface = (*TokenRepository)(nil)
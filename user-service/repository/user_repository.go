@@ -1,8 +1,13 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"strings"
+	"time"
+	"user-service/metrics"
 	"user-service/models"
+	"user-service/tracing"
 
 	"gorm.io/gorm"
 )
@@ -15,13 +20,21 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) CreateUser(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.CreateUser")
+	defer span.End()
+	defer metrics.ObserveDBQuery("UserRepository.CreateUser", time.Now())
+
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *UserRepository) GetUserByLogin(login string) (*models.User, error) {
+func (r *UserRepository) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetUserByLogin")
+	defer span.End()
+	defer metrics.ObserveDBQuery("UserRepository.GetUserByLogin", time.Now())
+
 	var user models.User
-	result := r.db.Where("login = ?", login).First(&user)
+	result := r.db.WithContext(ctx).Where("login = ?", login).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -31,9 +44,13 @@ func (r *UserRepository) GetUserByLogin(login string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetUserByID(id uint) (*models.User, error) {
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetUserByEmail")
+	defer span.End()
+	defer metrics.ObserveDBQuery("UserRepository.GetUserByEmail", time.Now())
+
 	var user models.User
-	result := r.db.First(&user, id)
+	result := r.db.WithContext(ctx).Where("email = ?", email).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -43,8 +60,112 @@ func (r *UserRepository) GetUserByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) UpdateUser(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *UserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetUserByID")
+	defer span.End()
+	defer metrics.ObserveDBQuery("UserRepository.GetUserByID", time.Now())
+
+	var user models.User
+	result := r.db.WithContext(ctx).First(&user, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.UpdateUser")
+	defer span.End()
+	defer metrics.ObserveDBQuery("UserRepository.UpdateUser", time.Now())
+
+	return r.db.WithContext(ctx).Save(user).Error
+}
+
+func (r *UserRepository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.AdvanceTOTPStep")
+	defer span.End()
+	defer metrics.ObserveDBQuery("UserRepository.AdvanceTOTPStep", time.Now())
+
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.ListUsers")
+	defer span.End()
+	defer metrics.ObserveDBQuery("UserRepository.ListUsers", time.Now())
+
+	query := r.db.WithContext(ctx).Model(&models.User{})
+	if filter.LoginPrefix != "" {
+		query = query.Where("login LIKE ?", escapeLike(filter.LoginPrefix)+"%")
+	}
+	if filter.EmailPrefix != "" {
+		query = query.Where("email LIKE ?", escapeLike(filter.EmailPrefix)+"%")
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.Order("id").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&users).Error
+	return users, total, err
+}
+
+func (r *UserRepository) DeleteUser(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.DeleteUser")
+	defer span.End()
+	defer metrics.ObserveDBQuery("UserRepository.DeleteUser", time.Now())
+
+	// Созданные пользователем компании удаляются вместе с ним, из чужих команд он исключается
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		owned := tx.Model(&models.Company{}).Select("id").Where("owner_id = ?", id)
+		if err := tx.Where("company_id IN (?)", owned).Delete(&models.CompanyMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("company_id IN (?)", owned).Delete(&models.CompanyInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("company_id IN (?)", owned).Delete(&models.CompanyModerationItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ?", id).Delete(&models.Company{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.CompanyMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, id).Error
+	})
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы префикс искался буквально
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
 }
 
 var _ UserRepositoryInterface = (*UserRepository)(nil)
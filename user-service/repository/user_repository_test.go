@@ -2,6 +2,8 @@
 package repository
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"user-service/models"
 )
@@ -22,7 +24,7 @@ func NewMockUserRepository() *MockUserRepository {
     }
 }
 
-func (r *MockUserRepository) CreateUser(user *models.User) error {
+func (r *MockUserRepository) CreateUser(ctx context.Context, user *models.User) error {
     user.ID = r.idCounter
     r.idCounter++
     r.users[user.Login] = user
@@ -30,7 +32,7 @@ func (r *MockUserRepository) CreateUser(user *models.User) error {
     return nil
 }
 
-func (r *MockUserRepository) GetUserByLogin(login string) (*models.User, error) {
+func (r *MockUserRepository) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
     user, exists := r.users[login]
     if !exists {
         return nil, nil
@@ -38,7 +40,7 @@ func (r *MockUserRepository) GetUserByLogin(login string) (*models.User, error)
     return user, nil
 }
 
-func (r *MockUserRepository) GetUserByID(id uint) (*models.User, error) {
+func (r *MockUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
     user, exists := r.usersById[id]
     if !exists {
         return nil, nil
@@ -46,12 +48,62 @@ func (r *MockUserRepository) GetUserByID(id uint) (*models.User, error) {
     return user, nil
 }
 
-func (r *MockUserRepository) UpdateUser(user *models.User) error {
+func (r *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+    for _, user := range r.usersById {
+        if user.Email == email {
+            return user, nil
+        }
+    }
+    return nil, nil
+}
+
+func (r *MockUserRepository) UpdateUser(ctx context.Context, user *models.User) error {
     r.users[user.Login] = user
     r.usersById[user.ID] = user
     return nil
 }
 
+func (r *MockUserRepository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+    user, exists := r.usersById[userID]
+    if !exists || user.TOTPLastStep >= step {
+        return false, nil
+    }
+    user.TOTPLastStep = step
+    return true, nil
+}
+
+func (r *MockUserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error) {
+    var users []models.User
+    for id := uint(1); id < r.idCounter; id++ {
+        user, exists := r.usersById[id]
+        if !exists || !strings.HasPrefix(user.Login, filter.LoginPrefix) {
+            continue
+        }
+        if (filter.Role != "" && user.Role != filter.Role) || (filter.Status != "" && user.Status != filter.Status) {
+            continue
+        }
+        users = append(users, *user)
+    }
+    total := int64(len(users))
+    from := (filter.Page - 1) * filter.PageSize
+    if from > len(users) {
+        from = len(users)
+    }
+    to := from + filter.PageSize
+    if to > len(users) {
+        to = len(users)
+    }
+    return users[from:to], total, nil
+}
+
+func (r *MockUserRepository) DeleteUser(ctx context.Context, id uint) error {
+    if user, exists := r.usersById[id]; exists {
+        delete(r.users, user.Login)
+        delete(r.usersById, id)
+    }
+    return nil
+}
+
 func TestCreateUser(t *testing.T) {
     repo := NewMockUserRepository()
     
@@ -61,7 +113,7 @@ func TestCreateUser(t *testing.T) {
         Email: "test@example.com",
     }
     
-    err := repo.CreateUser(user)
+    err := repo.CreateUser(context.Background(), user)
     if err != nil {
         t.Errorf("Ожидается успешное создание пользователя, получено: %v", err)
     }
@@ -69,7 +121,7 @@ func TestCreateUser(t *testing.T) {
         t.Errorf("Ожидается ID=1, получено: %d", user.ID)
     }
     
-    savedUser, err := repo.GetUserByLogin("testuser")
+    savedUser, err := repo.GetUserByLogin(context.Background(), "testuser")
     if err != nil {
         t.Errorf("Ошибка при получении пользователя: %v", err)
     }
@@ -86,9 +138,9 @@ func TestGetUserByLogin(t *testing.T) {
         Password: "hashedpassword",
         Email: "test@example.com",
     }
-    repo.CreateUser(testUser)
+    repo.CreateUser(context.Background(), testUser)
     
-    user, err := repo.GetUserByLogin("testuser")
+    user, err := repo.GetUserByLogin(context.Background(), "testuser")
     if err != nil {
         t.Errorf("Ожидается успешный поиск, получено: %v", err)
     }
@@ -96,7 +148,7 @@ func TestGetUserByLogin(t *testing.T) {
         t.Error("Пользователь должен быть найден")
     }
     
-    user, err = repo.GetUserByLogin("nonexistent")
+    user, err = repo.GetUserByLogin(context.Background(), "nonexistent")
     if err != nil {
         t.Errorf("Ожидается nil без ошибки, получено: %v", err)
     }
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"time"
+	"user-service/models"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var ErrInvalidActionToken = errors.New("ссылка недействительна или устарела")
+
+// ActionTokenSigner выпускает подписанные токены для ссылок из писем. Токен проверяется
+// без обращения к БД, а одноразовость обеспечивается записью его jti в ConsumedToken.
+// Используется отдельный HMAC-ключ, а не ключи access-токенов: такие токены не публикуются
+// через JWKS и не могут быть приняты шлюзом как access-токен.
+type ActionTokenSigner struct {
+	secret []byte
+}
+
+type actionClaims struct {
+	Purpose models.TokenPurpose
+	UserID  uint
+	// Subject привязывает токен к значению, для которого он выпущен, например к email
+	Subject   string
+	JTI       string
+	ExpiresAt time.Time
+}
+
+func NewActionTokenSigner(secret string) *ActionTokenSigner {
+	return &ActionTokenSigner{secret: []byte(secret)}
+}
+
+func (s *ActionTokenSigner) Sign(purpose models.TokenPurpose, userID uint, subject string, ttl time.Duration) (string, *actionClaims, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	claims := &actionClaims{
+		Purpose:   purpose,
+		UserID:    userID,
+		Subject:   subject,
+		JTI:       jti,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": string(purpose),
+		"uid":     userID,
+		"sub":     subject,
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     claims.ExpiresAt.Unix(),
+	})
+	signed, err := token.SignedString(s.secret)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// Parse проверяет подпись, срок действия и назначение токена
+func (s *ActionTokenSigner) Parse(purpose models.TokenPurpose, tokenString string) (*actionClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, ErrInvalidActionToken
+		}
+		return s.secret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidActionToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != string(purpose) {
+		return nil, ErrInvalidActionToken
+	}
+	userID, _ := claims["uid"].(float64)
+	subject, _ := claims["sub"].(string)
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	if jti == "" || exp == 0 {
+		return nil, ErrInvalidActionToken
+	}
+	return &actionClaims{
+		Purpose:   purpose,
+		UserID:    uint(userID),
+		Subject:   subject,
+		JTI:       jti,
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}, nil
+}
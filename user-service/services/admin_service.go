@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"user-service/models"
+	"user-service/promocodes"
+	"user-service/repository"
+	"user-service/tracing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultPageSize = 20
+	// Длина временного пароля в байтах до кодирования base64
+	temporaryPasswordSize = 12
+)
+
+var (
+	ErrCannotModifySelf = errors.New("нельзя заблокировать или удалить собственную учетную запись")
+	ErrInvalidSegment   = errors.New("сегмент может содержать только строчные латинские буквы, цифры, \"_\" и \"-\" (до 32 символов)")
+)
+
+type AdminService struct {
+	userRepo     repository.UserRepositoryInterface
+	companyRepo  repository.CompanyRepositoryInterface
+	memberRepo   repository.CompanyMemberRepositoryInterface
+	tokenService TokenServiceInterface
+	promocodes   promocodes.Client
+}
+
+func NewAdminService(userRepo repository.UserRepositoryInterface, companyRepo repository.CompanyRepositoryInterface, memberRepo repository.CompanyMemberRepositoryInterface, tokenService TokenServiceInterface, promocodesClient promocodes.Client) *AdminService {
+	return &AdminService{
+		userRepo:     userRepo,
+		companyRepo:  companyRepo,
+		memberRepo:   memberRepo,
+		tokenService: tokenService,
+		promocodes:   promocodesClient,
+	}
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, filter models.UserFilter) (*models.UserList, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.ListUsers")
+	defer span.End()
+
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+
+	users, total, err := s.userRepo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+	return &models.UserList{
+		Users:    users,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+func (s *AdminService) GetUser(ctx context.Context, userID uint) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.GetUser")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// BlockUser блокирует учетную запись и завершает все ее сессии, поэтому выданные токены
+// перестают проходить ValidateToken, а Login и обновление токена отклоняются
+func (s *AdminService) BlockUser(ctx context.Context, adminID, userID uint) error {
+	ctx, span := tracing.Start(ctx, "AdminService.BlockUser")
+	defer span.End()
+
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+	if err := s.setStatus(ctx, userID, models.UserStatusBlocked); err != nil {
+		return err
+	}
+	return s.tokenService.LogoutAll(ctx, userID)
+}
+
+func (s *AdminService) UnblockUser(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "AdminService.UnblockUser")
+	defer span.End()
+
+	return s.setStatus(ctx, userID, models.UserStatusActive)
+}
+
+// DeleteUser удаляет пользователя вместе с созданными им компаниями так же, как DeleteCompany,
+// и исключает его из остальных команд
+func (s *AdminService) DeleteUser(ctx context.Context, adminID, userID uint) error {
+	ctx, span := tracing.Start(ctx, "AdminService.DeleteUser")
+	defer span.End()
+
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+	// Сначала сессии: пользователь не должен успеть создать компанию, которую удалит только БД
+	if err := s.tokenService.LogoutAll(ctx, userID); err != nil {
+		return err
+	}
+
+	companies, err := s.companyRepo.ListCompaniesByMember(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, company := range companies {
+		if company.OwnerID != userID {
+			continue
+		}
+		if err := deleteCompany(ctx, s.companyRepo, s.memberRepo, s.userRepo, s.tokenService, s.promocodes, company.ID); err != nil {
+			return err
+		}
+	}
+	return s.userRepo.DeleteUser(ctx, userID)
+}
+
+// ForceResetPassword заменяет пароль случайным временным и завершает все сессии пользователя.
+// Временный пароль возвращается администратору один раз и нигде не сохраняется в открытом виде.
+func (s *AdminService) ForceResetPassword(ctx context.Context, userID uint) (string, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.ForceResetPassword")
+	defer span.End()
+
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	password, err := randomString(temporaryPasswordSize)
+	if err != nil {
+		return "", err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	user.Password = string(hashedPassword)
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return "", err
+	}
+	if err := s.tokenService.LogoutAll(ctx, userID); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// SetSegments заменяет сегменты пользователя. Сервисы видят их в access-токене, поэтому
+// изменения вступают в силу при следующем входе или обновлении токена.
+func (s *AdminService) SetSegments(ctx context.Context, userID uint, segments []string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.SetSegments")
+	defer span.End()
+
+	for _, segment := range segments {
+		if !models.ValidSegment(segment) {
+			return nil, ErrInvalidSegment
+		}
+	}
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Segments = models.NormalizeSegments(segments)
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *AdminService) setStatus(ctx context.Context, userID uint, status models.UserStatus) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Status == status {
+		return nil
+	}
+	user.Status = status
+	return s.userRepo.UpdateUser(ctx, user)
+}
+
+var _ AdminServiceInterface = (*AdminService)(nil)
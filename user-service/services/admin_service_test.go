@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"user-service/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAdminService(t *testing.T) (*AdminService, *UserService, *MockUserRepository) {
+	mockRepo := NewMockUserRepository()
+	members := NewMockCompanyMemberRepository()
+	companies := NewMockCompanyRepository(members, mockRepo)
+	NewMockCompanyModerationRepository(companies)
+	tokenService := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), mockRepo, members, newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+	verification, _ := newTestVerificationService(mockRepo)
+	userService := NewUserService(mockRepo, tokenService, verification, newTestMFAService(mockRepo), newTestLoginThrottler(), models.VerificationPolicyRestrict)
+	for _, login := range []string{"admin", "testuser", "another"} {
+		userService.Register(context.Background(), models.RegisterRequest{
+			Login:    login,
+			Password: "password123",
+			Email:    login + "@example.com",
+		})
+	}
+	return NewAdminService(mockRepo, companies, members, tokenService, &MockPromocodesClient{}), userService, mockRepo
+}
+
+func TestAdminListUsers(t *testing.T) {
+	service, _, _ := newTestAdminService(t)
+
+	list, err := service.ListUsers(context.Background(), models.UserFilter{})
+	if err != nil {
+		t.Fatalf("Ожидается успешное получение списка, получено: %v", err)
+	}
+	if list.Total != 3 || len(list.Users) != 3 || list.Page != 1 || list.PageSize != defaultPageSize {
+		t.Errorf("Неверный список по умолчанию: %+v", list)
+	}
+
+	list, _ = service.ListUsers(context.Background(), models.UserFilter{Page: 2, PageSize: 2})
+	if list.Total != 3 || len(list.Users) != 1 || list.Users[0].Login != "another" {
+		t.Errorf("Неверная вторая страница: %+v", list)
+	}
+
+	list, _ = service.ListUsers(context.Background(), models.UserFilter{Status: models.UserStatusBlocked})
+	if list.Total != 0 || list.Users == nil {
+		t.Errorf("Ожидается пустой список, получено: %+v", list)
+	}
+}
+
+func TestAdminBlockUser(t *testing.T) {
+	service, userService, mockRepo := newTestAdminService(t)
+
+	resp, _ := userService.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+
+	if err := service.BlockUser(context.Background(), 1, 1); err != ErrCannotModifySelf {
+		t.Errorf("Ожидается запрет блокировки самого себя, получено: %v", err)
+	}
+	if err := service.BlockUser(context.Background(), 1, 42); err != ErrUserNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего пользователя, получено: %v", err)
+	}
+	if err := service.BlockUser(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Ожидается успешная блокировка, получено: %v", err)
+	}
+	if mockRepo.usersById[2].Status != models.UserStatusBlocked {
+		t.Errorf("Ожидается статус blocked, получен: %s", mockRepo.usersById[2].Status)
+	}
+	if _, err := userService.ValidateToken(context.Background(), resp.Token); err == nil {
+		t.Error("Токены заблокированного пользователя должны быть отозваны")
+	}
+	if _, err := userService.RefreshToken(context.Background(), resp.RefreshToken); err == nil {
+		t.Error("Заблокированный пользователь не может обновить токен")
+	}
+	if _, err := userService.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{}); err != ErrUserBlocked {
+		t.Errorf("Ожидается ошибка блокировки при входе, получено: %v", err)
+	}
+
+	if err := service.UnblockUser(context.Background(), 2); err != nil {
+		t.Fatalf("Ожидается успешная разблокировка, получено: %v", err)
+	}
+	if _, err := userService.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{}); err != nil {
+		t.Errorf("Ожидается успешный вход после разблокировки, получено: %v", err)
+	}
+}
+
+func TestAdminDeleteUser(t *testing.T) {
+	service, _, mockRepo := newTestAdminService(t)
+
+	if err := service.DeleteUser(context.Background(), 1, 1); err != ErrCannotModifySelf {
+		t.Errorf("Ожидается запрет удаления самого себя, получено: %v", err)
+	}
+	if err := service.DeleteUser(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Ожидается успешное удаление, получено: %v", err)
+	}
+	if _, exists := mockRepo.usersById[2]; exists {
+		t.Error("Пользователь должен быть удален")
+	}
+	if err := service.DeleteUser(context.Background(), 1, 2); err != ErrUserNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего пользователя, получено: %v", err)
+	}
+}
+
+func TestAdminDeleteCompanyOwner(t *testing.T) {
+	service, _, _ := newTestAdminService(t)
+	ctx := context.Background()
+	owned := &models.Company{OwnerID: 2, Name: "Кофейня"}
+	foreign := &models.Company{OwnerID: 3, Name: "Пекарня"}
+	service.companyRepo.CreateCompany(ctx, owned)
+	service.companyRepo.CreateCompany(ctx, foreign)
+	service.memberRepo.SaveMember(ctx, &models.CompanyMember{CompanyID: foreign.ID, UserID: 2, Role: models.CompanyRoleManager})
+
+	if err := service.DeleteUser(ctx, 1, 2); err != nil {
+		t.Fatalf("Ожидается успешное удаление, получено: %v", err)
+	}
+	client := service.promocodes.(*MockPromocodesClient)
+	if len(client.deleted) != 1 || client.deleted[0] != owned.ID {
+		t.Errorf("Должны удаляться промокоды только созданных пользователем компаний: %v", client.deleted)
+	}
+	if company, _ := service.companyRepo.GetCompanyByID(ctx, owned.ID); company != nil {
+		t.Error("Компания пользователя должна быть удалена")
+	}
+	if company, _ := service.companyRepo.GetCompanyByID(ctx, foreign.ID); company == nil {
+		t.Error("Чужая компания должна остаться")
+	}
+}
+
+func TestAdminForceResetPassword(t *testing.T) {
+	service, userService, mockRepo := newTestAdminService(t)
+
+	resp, _ := userService.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+
+	password, err := service.ForceResetPassword(context.Background(), 2)
+	if err != nil || password == "" {
+		t.Fatalf("Ожидается временный пароль, получено: %q, %v", password, err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(mockRepo.usersById[2].Password), []byte(password)) != nil {
+		t.Error("В хранилище должен быть хэш временного пароля")
+	}
+	if _, err := userService.ValidateToken(context.Background(), resp.Token); err == nil {
+		t.Error("Сессии пользователя должны быть завершены после сброса пароля")
+	}
+	if _, err := userService.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{}); err == nil {
+		t.Error("Старый пароль не должен подходить")
+	}
+}
+
+func TestAdminSetSegments(t *testing.T) {
+	service, userService, _ := newTestAdminService(t)
+	ctx := context.Background()
+
+	if _, err := service.SetSegments(ctx, 2, []string{"vip", "Новые"}); err != ErrInvalidSegment {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrInvalidSegment, err)
+	}
+	if _, err := service.SetSegments(ctx, 42, []string{"vip"}); err != ErrUserNotFound {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrUserNotFound, err)
+	}
+
+	user, err := service.SetSegments(ctx, 2, []string{"vip", "employees", "vip"})
+	if err != nil || strings.Join(user.Segments, ",") != "employees,vip" {
+		t.Fatalf("Ожидаются сегменты employees и vip без повторов, получено: %+v, %v", user, err)
+	}
+
+	// Сегменты попадают в новый access-токен
+	resp, _ := userService.Login(ctx, models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+	claims, err := userService.ValidateToken(ctx, resp.Token)
+	if err != nil || strings.Join(claims.Segments, ",") != "employees,vip" {
+		t.Errorf("Ожидаются сегменты в токене, получено: %+v, %v", claims, err)
+	}
+}
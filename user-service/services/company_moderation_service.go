@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"user-service/mailer"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/tracing"
+)
+
+var (
+	ErrModerationItemNotFound   = errors.New("заявка на модерацию не найдена")
+	ErrModerationItemClosed     = errors.New("по заявке уже принято решение")
+	ErrModerationItemSuperseded = errors.New("автор изменил компанию, заявку заменила новая")
+	ErrModerationItemClaimed    = errors.New("заявку взял в работу другой модератор")
+	ErrModerationItemNotClaimed = errors.New("вы не брали эту заявку в работу")
+	ErrUnknownModerationStatus  = errors.New("неизвестный статус модерации")
+)
+
+// CompanyModerationService ведет очередь проверки компаний. Право content:moderate проверяется
+// на маршрутах; moderatorID - идентификатор модератора из токена.
+type CompanyModerationService struct {
+	companyRepo    repository.CompanyRepositoryInterface
+	moderationRepo repository.CompanyModerationRepositoryInterface
+	userRepo       repository.UserRepositoryInterface
+	mailer         mailer.Mailer
+	claimTTL       time.Duration
+}
+
+// NewCompanyModerationService: claimTTL - через сколько захват заявки перестает мешать другим модераторам
+func NewCompanyModerationService(companyRepo repository.CompanyRepositoryInterface, moderationRepo repository.CompanyModerationRepositoryInterface, userRepo repository.UserRepositoryInterface, m mailer.Mailer, claimTTL time.Duration) *CompanyModerationService {
+	return &CompanyModerationService{
+		companyRepo:    companyRepo,
+		moderationRepo: moderationRepo,
+		userRepo:       userRepo,
+		mailer:         m,
+		claimTTL:       claimTTL,
+	}
+}
+
+// ListQueue по умолчанию показывает заявки, которые ждут решения, в порядке поступления
+func (s *CompanyModerationService) ListQueue(ctx context.Context, filter models.CompanyModerationFilter) (*models.CompanyModerationList, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationService.ListQueue")
+	defer span.End()
+
+	if filter.Status == "" {
+		filter.Status = models.ModerationPending
+	}
+	if !filter.Status.Valid() {
+		return nil, ErrUnknownModerationStatus
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+
+	items, total, err := s.moderationRepo.ListItems(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []models.CompanyModerationItem{}
+	}
+	return &models.CompanyModerationList{
+		Items:    items,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+func (s *CompanyModerationService) ClaimItem(ctx context.Context, moderatorID, id uint) (*models.CompanyModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationService.ClaimItem")
+	defer span.End()
+
+	audit, err := s.newAudit(ctx, moderatorID, id, models.ModerationActionClaim, "")
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := s.moderationRepo.ClaimItem(ctx, audit, audit.CreatedAt.Add(-s.claimTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, s.conflict(ctx, id, ErrModerationItemClaimed)
+	}
+	return s.getItem(ctx, id)
+}
+
+func (s *CompanyModerationService) ReleaseItem(ctx context.Context, moderatorID, id uint) (*models.CompanyModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationService.ReleaseItem")
+	defer span.End()
+
+	audit, err := s.newAudit(ctx, moderatorID, id, models.ModerationActionRelease, "")
+	if err != nil {
+		return nil, err
+	}
+	released, err := s.moderationRepo.ReleaseItem(ctx, audit)
+	if err != nil {
+		return nil, err
+	}
+	if !released {
+		return nil, s.conflict(ctx, id, ErrModerationItemNotClaimed)
+	}
+	return s.getItem(ctx, id)
+}
+
+func (s *CompanyModerationService) ApproveItem(ctx context.Context, moderatorID, id uint) (*models.CompanyModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationService.ApproveItem")
+	defer span.End()
+
+	return s.decide(ctx, moderatorID, id, models.ModerationApproved, models.ModerationActionApprove, "")
+}
+
+func (s *CompanyModerationService) RejectItem(ctx context.Context, moderatorID, id uint, reason string) (*models.CompanyModerationItem, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationService.RejectItem")
+	defer span.End()
+
+	return s.decide(ctx, moderatorID, id, models.ModerationRejected, models.ModerationActionReject, reason)
+}
+
+func (s *CompanyModerationService) ListAudit(ctx context.Context, filter models.CompanyModerationAuditFilter) (*models.CompanyModerationAuditList, error) {
+	ctx, span := tracing.Start(ctx, "CompanyModerationService.ListAudit")
+	defer span.End()
+
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultPageSize
+	}
+
+	records, total, err := s.moderationRepo.ListAudit(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		records = []models.CompanyModerationAudit{}
+	}
+	return &models.CompanyModerationAuditList{
+		Records:  records,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+func (s *CompanyModerationService) decide(ctx context.Context, moderatorID, id uint, status models.ModerationStatus, action models.ModerationAction, reason string) (*models.CompanyModerationItem, error) {
+	audit, err := s.newAudit(ctx, moderatorID, id, action, reason)
+	if err != nil {
+		return nil, err
+	}
+	decided, err := s.moderationRepo.DecideItem(ctx, audit, status, audit.CreatedAt.Add(-s.claimTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !decided {
+		return nil, s.conflict(ctx, id, ErrModerationItemClaimed)
+	}
+	metrics.CompanyModerationDecisions.WithLabelValues(string(status)).Inc()
+
+	item, err := s.getItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Решение уже сохранено, поэтому ошибка отправки письма его не отменяет
+	if err := s.notifyAuthor(ctx, item); err != nil {
+		log.Printf("Не удалось уведомить пользователя %d о решении по компании %d: %v", item.AuthorID, item.CompanyID, err)
+	}
+	return item, nil
+}
+
+// notifyAuthor сообщает автору заявки о решении модератора
+func (s *CompanyModerationService) notifyAuthor(ctx context.Context, item *models.CompanyModerationItem) error {
+	author, err := s.userRepo.GetUserByID(ctx, item.AuthorID)
+	if err != nil || author == nil {
+		return err
+	}
+	company, err := s.companyRepo.GetCompanyByID(ctx, item.CompanyID)
+	if err != nil || company == nil {
+		return err
+	}
+
+	message := mailer.Message{To: author.Email}
+	if item.Status == models.ModerationApproved {
+		message.Subject = "Компания " + company.Name + " прошла модерацию"
+		message.Body = fmt.Sprintf("Здравствуйте!\n\nМодератор проверил компанию %s, изменения опубликованы.", company.Name)
+	} else {
+		message.Subject = "Компания " + company.Name + " не прошла модерацию"
+		message.Body = fmt.Sprintf("Здравствуйте!\n\nМодератор отклонил изменения компании %s. Причина: %s\n\nИсправьте данные компании, и она снова попадет на проверку.",
+			company.Name, item.Reason)
+	}
+	return s.mailer.Send(ctx, message)
+}
+
+// newAudit готовит запись журнала о действии модератора над заявкой id
+func (s *CompanyModerationService) newAudit(ctx context.Context, moderatorID, id uint, action models.ModerationAction, reason string) (*models.CompanyModerationAudit, error) {
+	item, err := s.getItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != models.ModerationPending {
+		return nil, closedError(item)
+	}
+	return &models.CompanyModerationAudit{
+		ItemID:      item.ID,
+		CompanyID:   item.CompanyID,
+		ModeratorID: moderatorID,
+		Action:      action,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// conflict объясняет, почему условное изменение заявки не прошло: по ней уже приняли решение,
+// компания изменилась или заявку держит другой модератор (busy)
+func (s *CompanyModerationService) conflict(ctx context.Context, id uint, busy error) error {
+	item, err := s.getItem(ctx, id)
+	if err != nil {
+		return err
+	}
+	if item.Status != models.ModerationPending {
+		return closedError(item)
+	}
+	return busy
+}
+
+// closedError - ошибка для заявки, которая больше не ждет решения
+func closedError(item *models.CompanyModerationItem) error {
+	if item.Status == models.ModerationSuperseded {
+		return ErrModerationItemSuperseded
+	}
+	return ErrModerationItemClosed
+}
+
+func (s *CompanyModerationService) getItem(ctx context.Context, id uint) (*models.CompanyModerationItem, error) {
+	item, err := s.moderationRepo.GetItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrModerationItemNotFound
+	}
+	return item, nil
+}
+
+var _ CompanyModerationServiceInterface = (*CompanyModerationService)(nil)
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"user-service/models"
+	"user-service/repository"
+)
+
+// MockCompanyModerationRepository переносит решения на компании из companies, как и настоящий репозиторий
+type MockCompanyModerationRepository struct {
+	items     map[uint]*models.CompanyModerationItem
+	audit     []models.CompanyModerationAudit
+	companies *MockCompanyRepository
+	idCounter uint
+}
+
+var _ repository.CompanyModerationRepositoryInterface = (*MockCompanyModerationRepository)(nil)
+
+func NewMockCompanyModerationRepository(companies *MockCompanyRepository) *MockCompanyModerationRepository {
+	r := &MockCompanyModerationRepository{items: make(map[uint]*models.CompanyModerationItem), companies: companies}
+	companies.moderation = r
+	return r
+}
+
+func (r *MockCompanyModerationRepository) SubmitItem(ctx context.Context, item *models.CompanyModerationItem) error {
+	for _, pending := range r.items {
+		if pending.CompanyID == item.CompanyID && pending.Status == models.ModerationPending {
+			pending.Status = models.ModerationSuperseded
+		}
+	}
+	r.idCounter++
+	item.ID = r.idCounter
+	item.Status = models.ModerationPending
+	item.ContentHash = r.contentHash(item.CompanyID)
+	stored := *item
+	r.items[item.ID] = &stored
+	return nil
+}
+
+func (r *MockCompanyModerationRepository) GetItem(ctx context.Context, id uint) (*models.CompanyModerationItem, error) {
+	item, exists := r.items[id]
+	if !exists {
+		return nil, nil
+	}
+	stored := *item
+	return &stored, nil
+}
+
+func (r *MockCompanyModerationRepository) ListItems(ctx context.Context, filter models.CompanyModerationFilter) ([]models.CompanyModerationItem, int64, error) {
+	var items []models.CompanyModerationItem
+	for id := uint(1); id <= r.idCounter; id++ {
+		if item, exists := r.items[id]; exists && (filter.Status == "" || item.Status == filter.Status) {
+			items = append(items, *item)
+		}
+	}
+	return items, int64(len(items)), nil
+}
+
+func (r *MockCompanyModerationRepository) ClaimItem(ctx context.Context, audit *models.CompanyModerationAudit, staleBefore time.Time) (bool, error) {
+	item := r.available(audit, staleBefore)
+	if item == nil {
+		return false, nil
+	}
+	claimedAt := audit.CreatedAt
+	item.ClaimedBy = &audit.ModeratorID
+	item.ClaimedAt = &claimedAt
+	r.audit = append(r.audit, *audit)
+	return true, nil
+}
+
+func (r *MockCompanyModerationRepository) ReleaseItem(ctx context.Context, audit *models.CompanyModerationAudit) (bool, error) {
+	item := r.items[audit.ItemID]
+	if item == nil || item.Status != models.ModerationPending || item.ClaimedBy == nil || *item.ClaimedBy != audit.ModeratorID {
+		return false, nil
+	}
+	item.ClaimedBy = nil
+	item.ClaimedAt = nil
+	r.audit = append(r.audit, *audit)
+	return true, nil
+}
+
+func (r *MockCompanyModerationRepository) DecideItem(ctx context.Context, audit *models.CompanyModerationAudit, status models.ModerationStatus, staleBefore time.Time) (bool, error) {
+	item := r.available(audit, staleBefore)
+	if item == nil {
+		return false, nil
+	}
+	if item.ContentHash != r.contentHash(audit.CompanyID) {
+		item.Status = models.ModerationSuperseded
+		return false, nil
+	}
+	decidedAt := audit.CreatedAt
+	moderatorID := audit.ModeratorID
+	item.Status = status
+	item.ModeratorID = &moderatorID
+	item.Reason = audit.Reason
+	item.DecidedAt = &decidedAt
+	if company, exists := r.companies.companies[audit.CompanyID]; exists {
+		company.ModerationStatus = status
+		company.ModerationReason = audit.Reason
+	}
+	r.audit = append(r.audit, *audit)
+	return true, nil
+}
+
+func (r *MockCompanyModerationRepository) ListAudit(ctx context.Context, filter models.CompanyModerationAuditFilter) ([]models.CompanyModerationAudit, int64, error) {
+	var records []models.CompanyModerationAudit
+	for i := len(r.audit) - 1; i >= 0; i-- {
+		record := r.audit[i]
+		if (filter.ItemID == 0 || record.ItemID == filter.ItemID) &&
+			(filter.CompanyID == 0 || record.CompanyID == filter.CompanyID) &&
+			(filter.ModeratorID == 0 || record.ModeratorID == filter.ModeratorID) {
+			records = append(records, record)
+		}
+	}
+	return records, int64(len(records)), nil
+}
+
+func (r *MockCompanyModerationRepository) available(audit *models.CompanyModerationAudit, staleBefore time.Time) *models.CompanyModerationItem {
+	item := r.items[audit.ItemID]
+	if item == nil || item.Status != models.ModerationPending {
+		return nil
+	}
+	if item.ClaimedBy != nil && *item.ClaimedBy != audit.ModeratorID && !item.ClaimedAt.Before(staleBefore) {
+		return nil
+	}
+	return item
+}
+
+// contentHash - отпечаток текущей версии компании из companies
+func (r *MockCompanyModerationRepository) contentHash(companyID uint) string {
+	if company, exists := r.companies.companies[companyID]; exists {
+		return company.ModerationHash()
+	}
+	return ""
+}
+
+type testCompanyModeration struct {
+	service        *CompanyModerationService
+	companyService *CompanyService
+	moderationRepo *MockCompanyModerationRepository
+	mailer         *MockMailer
+}
+
+// Модераторы в тестах - пользователи 2 и 3 из newTestCompanyService; право проверяется на маршрутах
+func newTestCompanyModerationService(t *testing.T) *testCompanyModeration {
+	companyService, users, _ := newTestCompanyService(t)
+	moderationRepo := companyService.moderationRepo.(*MockCompanyModerationRepository)
+	env := &testCompanyModeration{
+		companyService: companyService,
+		moderationRepo: moderationRepo,
+		mailer:         &MockMailer{},
+	}
+	env.service = NewCompanyModerationService(companyService.companyRepo, moderationRepo, users, env.mailer, 30*time.Minute)
+	return env
+}
+
+func TestCompanyModerationQueue(t *testing.T) {
+	env := newTestCompanyModerationService(t)
+	ctx := context.Background()
+
+	company, err := env.companyService.CreateCompany(ctx, 1, models.CreateCompanyRequest{Name: "Кофейня"})
+	if err != nil {
+		t.Fatalf("Ожидается успешное создание компании, получено: %v", err)
+	}
+	if company.ModerationStatus != models.ModerationPending {
+		t.Errorf("Новая компания должна ждать модерации, получено: %s", company.ModerationStatus)
+	}
+
+	queue, err := env.service.ListQueue(ctx, models.CompanyModerationFilter{})
+	if err != nil || queue.Total != 1 || queue.Items[0].CompanyID != company.ID || queue.Items[0].AuthorID != 1 {
+		t.Fatalf("Ожидается одна заявка на компанию, получено: %+v, %v", queue, err)
+	}
+	itemID := queue.Items[0].ID
+
+	if _, err := env.service.ClaimItem(ctx, 2, itemID); err != nil {
+		t.Fatalf("Ожидается успешный захват заявки, получено: %v", err)
+	}
+	if _, err := env.service.ClaimItem(ctx, 3, itemID); err != ErrModerationItemClaimed {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrModerationItemClaimed, err)
+	}
+	if _, err := env.service.ApproveItem(ctx, 3, itemID); err != ErrModerationItemClaimed {
+		t.Errorf("Чужую заявку нельзя одобрить, ожидается %v, получено: %v", ErrModerationItemClaimed, err)
+	}
+	if _, err := env.service.ReleaseItem(ctx, 3, itemID); err != ErrModerationItemNotClaimed {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrModerationItemNotClaimed, err)
+	}
+
+	item, err := env.service.RejectItem(ctx, 2, itemID, "Нет описания")
+	if err != nil {
+		t.Fatalf("Ожидается успешный отказ, получено: %v", err)
+	}
+	if item.Status != models.ModerationRejected || item.ModeratorID == nil || *item.ModeratorID != 2 {
+		t.Errorf("Неверная заявка после отказа: %+v", item)
+	}
+	stored, _ := env.companyService.LookupCompany(ctx, company.ID)
+	if stored.ModerationStatus != models.ModerationRejected || stored.ModerationReason != "Нет описания" {
+		t.Errorf("Решение должно перейти на компанию, получено: %+v", stored)
+	}
+	if len(env.mailer.messages) != 1 || env.mailer.messages[0].To != "owner@example.com" || !strings.Contains(env.mailer.messages[0].Body, "Нет описания") {
+		t.Errorf("Автор должен получить письмо с причиной отказа, получено: %+v", env.mailer.messages)
+	}
+	if _, err := env.service.ApproveItem(ctx, 2, itemID); err != ErrModerationItemClosed {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrModerationItemClosed, err)
+	}
+
+	audit, _ := env.service.ListAudit(ctx, models.CompanyModerationAuditFilter{ItemID: itemID})
+	if audit.Total != 2 || audit.Records[0].Action != models.ModerationActionReject || audit.Records[1].Action != models.ModerationActionClaim {
+		t.Errorf("Журнал должен содержать захват и отказ, получено: %+v", audit.Records)
+	}
+}
+
+func TestCompanyModerationResubmission(t *testing.T) {
+	env := newTestCompanyModerationService(t)
+	ctx := context.Background()
+
+	company, _ := env.companyService.CreateCompany(ctx, 1, models.CreateCompanyRequest{Name: "Кофейня"})
+	owner := &models.TokenClaims{UserID: 1, Roles: []models.Role{models.RoleCompanyOwner}}
+	if _, err := env.companyService.UpdateCompany(ctx, owner, company.ID, models.UpdateCompanyRequest{Description: "Скидки на кофе"}); err != nil {
+		t.Fatalf("Ожидается успешное изменение компании, получено: %v", err)
+	}
+	queue, _ := env.service.ListQueue(ctx, models.CompanyModerationFilter{})
+	if queue.Total != 1 || queue.Items[0].ID != 2 {
+		t.Fatalf("Изменение ожидающей компании заменяет заявку новой, получено: %+v", queue.Items)
+	}
+	if _, err := env.service.ClaimItem(ctx, 2, 1); err != ErrModerationItemSuperseded {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrModerationItemSuperseded, err)
+	}
+
+	// Захват старше claimTTL не мешает другому модератору
+	env.service.ClaimItem(ctx, 2, queue.Items[0].ID)
+	staleAt := time.Now().Add(-time.Hour)
+	env.moderationRepo.items[queue.Items[0].ID].ClaimedAt = &staleAt
+	if _, err := env.service.ApproveItem(ctx, 3, queue.Items[0].ID); err != nil {
+		t.Fatalf("Ожидается одобрение заявки с просроченным захватом, получено: %v", err)
+	}
+
+	env.mailer.err = errors.New("SMTP недоступен")
+	updated, err := env.companyService.UpdateCompany(ctx, owner, company.ID, models.UpdateCompanyRequest{Description: "Скидки на чай"})
+	if err != nil || updated.ModerationStatus != models.ModerationPending {
+		t.Fatalf("Измененная компания снова должна ждать модерации, получено: %+v, %v", updated, err)
+	}
+	queue, _ = env.service.ListQueue(ctx, models.CompanyModerationFilter{})
+	if queue.Total != 1 || queue.Items[0].ID != 3 {
+		t.Fatalf("Ожидается новая заявка после одобрения, получено: %+v", queue.Items)
+	}
+	if _, err := env.service.RejectItem(ctx, 2, queue.Items[0].ID, "Спам"); err != nil {
+		t.Errorf("Ошибка отправки письма не должна отменять решение, получено: %v", err)
+	}
+}
+
+func TestCompanyModerationContentSwap(t *testing.T) {
+	env := newTestCompanyModerationService(t)
+	ctx := context.Background()
+	owner := &models.TokenClaims{UserID: 1, Roles: []models.Role{models.RoleCompanyOwner}}
+	company, _ := env.companyService.CreateCompany(ctx, 1, models.CreateCompanyRequest{Name: "Кофейня"})
+
+	// Модератор взял проверенную версию, а автор подменил данные
+	env.service.ClaimItem(ctx, 2, 1)
+	env.companyService.UpdateCompany(ctx, owner, company.ID, models.UpdateCompanyRequest{Name: "Казино"})
+	if _, err := env.service.ApproveItem(ctx, 2, 1); err != ErrModerationItemSuperseded {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrModerationItemSuperseded, err)
+	}
+	queue, _ := env.service.ListQueue(ctx, models.CompanyModerationFilter{})
+	if queue.Total != 1 || queue.Items[0].ID != 2 || queue.Items[0].ClaimedBy != nil {
+		t.Fatalf("Ожидается новая свободная заявка, получено: %+v", queue.Items)
+	}
+
+	// Компанию изменили, а новая заявка еще не подана
+	env.moderationRepo.companies.companies[company.ID].Description = "Ставки"
+	if _, err := env.service.ApproveItem(ctx, 2, 2); err != ErrModerationItemSuperseded {
+		t.Errorf("Ожидается ошибка %v, получено: %v", ErrModerationItemSuperseded, err)
+	}
+	if stored := env.moderationRepo.companies.companies[company.ID]; stored.ModerationStatus != models.ModerationPending {
+		t.Errorf("Непроверенная версия не должна одобряться, получено: %s", stored.ModerationStatus)
+	}
+}
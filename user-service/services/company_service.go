@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"user-service/models"
+	"user-service/promocodes"
+	"user-service/repository"
+	"user-service/tracing"
+)
+
+var (
+	ErrCompanyNotFound          = errors.New("компания не найдена")
+	ErrNotCompanyOwner          = errors.New("действие доступно только владельцу компании")
+	ErrNotCompanyMember         = errors.New("вы не состоите в команде компании")
+	ErrUnknownSubscriptionLevel = errors.New("неизвестный уровень подписки")
+)
+
+type CompanyService struct {
+	companyRepo    repository.CompanyRepositoryInterface
+	memberRepo     repository.CompanyMemberRepositoryInterface
+	userRepo       repository.UserRepositoryInterface
+	moderationRepo repository.CompanyModerationRepositoryInterface
+	tokenService   TokenServiceInterface
+	promocodes     promocodes.Client
+}
+
+func NewCompanyService(companyRepo repository.CompanyRepositoryInterface, memberRepo repository.CompanyMemberRepositoryInterface, userRepo repository.UserRepositoryInterface, moderationRepo repository.CompanyModerationRepositoryInterface, tokenService TokenServiceInterface, promocodesClient promocodes.Client) *CompanyService {
+	return &CompanyService{
+		companyRepo:    companyRepo,
+		memberRepo:     memberRepo,
+		userRepo:       userRepo,
+		moderationRepo: moderationRepo,
+		tokenService:   tokenService,
+		promocodes:     promocodesClient,
+	}
+}
+
+// CreateCompany создает компанию на бесплатном тарифе, создатель становится владельцем в ее команде.
+// Пользователь с ролью user получает роль company_owner; компания, членство, роль и заявка
+// на модерацию создаются одной транзакцией и попадают в токены при следующем обновлении.
+func (s *CompanyService) CreateCompany(ctx context.Context, ownerID uint, req models.CreateCompanyRequest) (*models.Company, error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.CreateCompany")
+	defer span.End()
+
+	owner, err := s.userRepo.GetUserByID(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil {
+		return nil, ErrUserNotFound
+	}
+
+	company := &models.Company{
+		OwnerID:           ownerID,
+		Name:              req.Name,
+		Description:       req.Description,
+		SubscriptionLevel: models.SubscriptionFree,
+		ModerationStatus:  models.ModerationPending,
+	}
+	if err := s.companyRepo.CreateCompany(ctx, company); err != nil {
+		return nil, err
+	}
+	return company, nil
+}
+
+func (s *CompanyService) GetCompany(ctx context.Context, actor *models.TokenClaims, companyID uint) (*models.Company, error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.GetCompany")
+	defer span.End()
+
+	company, err := s.LookupCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company.ModerationStatus == models.ModerationApproved ||
+		actor.HasPermission(models.PermissionModerateContent) || actor.HasPermission(models.PermissionManageAllCompanies) {
+		return company, nil
+	}
+	// Остальным непроверенная компания не видна, как и отсутствующая
+	member, err := s.memberRepo.GetMember(ctx, companyID, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+func (s *CompanyService) LookupCompany(ctx context.Context, companyID uint) (*models.Company, error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.LookupCompany")
+	defer span.End()
+
+	company, err := s.companyRepo.GetCompanyByID(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company == nil {
+		return nil, ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+// ListUserCompanies возвращает компании, в командах которых состоит пользователь
+func (s *CompanyService) ListUserCompanies(ctx context.Context, userID uint) ([]models.Company, error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.ListUserCompanies")
+	defer span.End()
+
+	companies, err := s.companyRepo.ListCompaniesByMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if companies == nil {
+		companies = []models.Company{}
+	}
+	return companies, nil
+}
+
+func (s *CompanyService) UpdateCompany(ctx context.Context, actor *models.TokenClaims, companyID uint, req models.UpdateCompanyRequest) (*models.Company, error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.UpdateCompany")
+	defer span.End()
+
+	company, err := companyAccess(ctx, s.companyRepo, s.memberRepo, actor, companyID, models.CompanyRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		company.Name = req.Name
+	}
+	if req.Description != "" {
+		company.Description = req.Description
+	}
+	// Измененную версию модератор еще не видел
+	company.ModerationStatus = models.ModerationPending
+	company.ModerationReason = ""
+	if err := s.companyRepo.UpdateCompany(ctx, company); err != nil {
+		return nil, err
+	}
+	if err := s.submitForModeration(ctx, company.ID, actor.UserID); err != nil {
+		return nil, err
+	}
+	return company, nil
+}
+
+// DeleteCompany удаляет компанию вместе с командой и ее данными в promocodes-service
+func (s *CompanyService) DeleteCompany(ctx context.Context, actor *models.TokenClaims, companyID uint) error {
+	ctx, span := tracing.Start(ctx, "CompanyService.DeleteCompany")
+	defer span.End()
+
+	company, err := companyAccess(ctx, s.companyRepo, s.memberRepo, actor, companyID, models.CompanyRoleOwner)
+	if err != nil {
+		return err
+	}
+	return deleteCompany(ctx, s.companyRepo, s.memberRepo, s.userRepo, s.tokenService, s.promocodes, company.ID)
+}
+
+func (s *CompanyService) SetSubscription(ctx context.Context, companyID uint, level models.SubscriptionLevel) (*models.Company, error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.SetSubscription")
+	defer span.End()
+
+	if !level.Valid() {
+		return nil, ErrUnknownSubscriptionLevel
+	}
+	company, err := s.LookupCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	company.SubscriptionLevel = level
+	if err := s.companyRepo.UpdateCompany(ctx, company); err != nil {
+		return nil, err
+	}
+	return company, nil
+}
+
+// submitForModeration ставит новую или измененную версию компании в очередь модерации
+func (s *CompanyService) submitForModeration(ctx context.Context, companyID, authorID uint) error {
+	return s.moderationRepo.SubmitItem(ctx, &models.CompanyModerationItem{
+		CompanyID: companyID,
+		AuthorID:  authorID,
+	})
+}
+
+// companyAccess возвращает компанию, если у actor в ее команде одна из ролей roles (любая, если
+// roles не заданы) или есть право companies:manage_all. Роль берется из БД, а не из токена:
+// в токене она обновляется только при выдаче нового.
+func companyAccess(ctx context.Context, companyRepo repository.CompanyRepositoryInterface, memberRepo repository.CompanyMemberRepositoryInterface, actor *models.TokenClaims, companyID uint, roles ...models.CompanyRole) (*models.Company, error) {
+	company, err := companyRepo.GetCompanyByID(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company == nil {
+		return nil, ErrCompanyNotFound
+	}
+	if actor.HasPermission(models.PermissionManageAllCompanies) {
+		return company, nil
+	}
+
+	member, err := memberRepo.GetMember(ctx, companyID, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotCompanyMember
+	}
+	if len(roles) == 0 {
+		return company, nil
+	}
+	for _, role := range roles {
+		if member.Role == role {
+			return company, nil
+		}
+	}
+	return nil, ErrNotCompanyOwner
+}
+
+// deleteCompany завершает сессии участников компании, чтобы токены с членством в ней перестали
+// действовать, отзывает ее API-ключи и удаляет промокоды в promocodes-service, затем удаляет саму
+// компанию. При сбое компания остается и удаление можно повторить. Владельцы, у которых не осталось
+// компаний, снова получают роль user.
+func deleteCompany(ctx context.Context, companyRepo repository.CompanyRepositoryInterface, memberRepo repository.CompanyMemberRepositoryInterface, userRepo repository.UserRepositoryInterface, tokenService TokenServiceInterface, promocodesClient promocodes.Client, companyID uint) error {
+	members, err := memberRepo.ListMembers(ctx, companyID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if err := tokenService.LogoutAll(ctx, member.UserID); err != nil {
+			return err
+		}
+	}
+	if err := promocodesClient.DeleteCompany(ctx, companyID); err != nil {
+		return err
+	}
+	if err := companyRepo.DeleteCompany(ctx, companyID); err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member.Role != models.CompanyRoleOwner {
+			continue
+		}
+		if err := syncCompanyOwnerRole(ctx, userRepo, memberRepo, member.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncCompanyOwnerRole выдает роль company_owner владельцу хотя бы одной компании и снимает ее,
+// когда таких компаний не осталось. Роли user и company_owner не затрагивают остальные роли.
+func syncCompanyOwnerRole(ctx context.Context, userRepo repository.UserRepositoryInterface, memberRepo repository.CompanyMemberRepositoryInterface, userID uint) error {
+	memberships, err := memberRepo.ListUserMemberships(ctx, userID)
+	if err != nil {
+		return err
+	}
+	ownsCompany := false
+	for _, membership := range memberships {
+		if membership.Role == models.CompanyRoleOwner {
+			ownsCompany = true
+			break
+		}
+	}
+
+	user, err := userRepo.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return err
+	}
+	switch {
+	case ownsCompany && user.Role == models.RoleUser:
+		user.Role = models.RoleCompanyOwner
+	case !ownsCompany && user.Role == models.RoleCompanyOwner:
+		user.Role = models.RoleUser
+	default:
+		return nil
+	}
+	return userRepo.UpdateUser(ctx, user)
+}
+
+var _ CompanyServiceInterface = (*CompanyService)(nil)
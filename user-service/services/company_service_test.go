@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-service/models"
+	"user-service/promocodes"
+	"user-service/repository"
+)
+
+type MockCompanyRepository struct {
+	companies  map[uint]*models.Company
+	members    *MockCompanyMemberRepository
+	users      *MockUserRepository
+	moderation *MockCompanyModerationRepository
+	idCounter  uint
+}
+
+var _ repository.CompanyRepositoryInterface = (*MockCompanyRepository)(nil)
+
+func NewMockCompanyRepository(members *MockCompanyMemberRepository, users *MockUserRepository) *MockCompanyRepository {
+	return &MockCompanyRepository{companies: make(map[uint]*models.Company), members: members, users: users}
+}
+
+// CreateCompany повторяет транзакцию репозитория: компания, владелец в команде, роль и заявка
+func (r *MockCompanyRepository) CreateCompany(ctx context.Context, company *models.Company) error {
+	r.idCounter++
+	company.ID = r.idCounter
+	stored := *company
+	r.companies[company.ID] = &stored
+	r.members.SaveMember(ctx, &models.CompanyMember{CompanyID: company.ID, UserID: company.OwnerID, Role: models.CompanyRoleOwner})
+	if owner := r.users.usersById[company.OwnerID]; owner != nil && owner.Role == models.RoleUser {
+		owner.Role = models.RoleCompanyOwner
+	}
+	return r.moderation.SubmitItem(ctx, &models.CompanyModerationItem{CompanyID: company.ID, AuthorID: company.OwnerID})
+}
+
+func (r *MockCompanyRepository) GetCompanyByID(ctx context.Context, id uint) (*models.Company, error) {
+	company, exists := r.companies[id]
+	if !exists {
+		return nil, nil
+	}
+	stored := *company
+	return &stored, nil
+}
+
+func (r *MockCompanyRepository) ListCompaniesByMember(ctx context.Context, userID uint) ([]models.Company, error) {
+	var companies []models.Company
+	for id := uint(1); id <= r.idCounter; id++ {
+		if company, exists := r.companies[id]; exists && r.members.members[[2]uint{id, userID}] != nil {
+			companies = append(companies, *company)
+		}
+	}
+	return companies, nil
+}
+
+func (r *MockCompanyRepository) UpdateCompany(ctx context.Context, company *models.Company) error {
+	stored := *company
+	r.companies[company.ID] = &stored
+	return nil
+}
+
+func (r *MockCompanyRepository) DeleteCompany(ctx context.Context, id uint) error {
+	delete(r.companies, id)
+	for key := range r.members.members {
+		if key[0] == id {
+			delete(r.members.members, key)
+		}
+	}
+	return nil
+}
+
+// MockPromocodesClient запоминает компании, данные которых удалены в promocodes-service
+type MockPromocodesClient struct {
+	deleted []uint
+	err     error
+}
+
+var _ promocodes.Client = (*MockPromocodesClient)(nil)
+
+func (c *MockPromocodesClient) DeleteCompany(ctx context.Context, companyID uint) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.deleted = append(c.deleted, companyID)
+	return nil
+}
+
+// MockCompanyMemberRepository хранит участников по паре (компания, пользователь)
+type MockCompanyMemberRepository struct {
+	members map[[2]uint]*models.CompanyMember
+}
+
+var _ repository.CompanyMemberRepositoryInterface = (*MockCompanyMemberRepository)(nil)
+
+func NewMockCompanyMemberRepository() *MockCompanyMemberRepository {
+	return &MockCompanyMemberRepository{members: make(map[[2]uint]*models.CompanyMember)}
+}
+
+func (r *MockCompanyMemberRepository) SaveMember(ctx context.Context, member *models.CompanyMember) error {
+	stored := *member
+	r.members[[2]uint{member.CompanyID, member.UserID}] = &stored
+	return nil
+}
+
+func (r *MockCompanyMemberRepository) GetMember(ctx context.Context, companyID, userID uint) (*models.CompanyMember, error) {
+	member, exists := r.members[[2]uint{companyID, userID}]
+	if !exists {
+		return nil, nil
+	}
+	stored := *member
+	return &stored, nil
+}
+
+func (r *MockCompanyMemberRepository) ListMembers(ctx context.Context, companyID uint) ([]models.CompanyMember, error) {
+	var members []models.CompanyMember
+	for key, member := range r.members {
+		if key[0] == companyID {
+			members = append(members, *member)
+		}
+	}
+	return members, nil
+}
+
+func (r *MockCompanyMemberRepository) ListUserMemberships(ctx context.Context, userID uint) ([]models.CompanyMember, error) {
+	var members []models.CompanyMember
+	for key, member := range r.members {
+		if key[1] == userID {
+			members = append(members, *member)
+		}
+	}
+	return members, nil
+}
+
+func (r *MockCompanyMemberRepository) RemoveMember(ctx context.Context, companyID, userID uint) error {
+	delete(r.members, [2]uint{companyID, userID})
+	return nil
+}
+
+func newTestCompanyService(t *testing.T) (*CompanyService, *MockUserRepository, *MockCompanyMemberRepository) {
+	mockRepo := NewMockUserRepository()
+	for _, user := range []*models.User{
+		{Login: "owner", Email: "owner@example.com", Role: models.RoleUser},
+		{Login: "stranger", Email: "stranger@example.com", Role: models.RoleUser},
+		{Login: "admin", Email: "admin@example.com", Role: models.RoleAdmin},
+	} {
+		mockRepo.CreateUser(context.Background(), user)
+	}
+	members := NewMockCompanyMemberRepository()
+	companies := NewMockCompanyRepository(members, mockRepo)
+	tokenService := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), mockRepo, members, newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+	return NewCompanyService(companies, members, mockRepo, NewMockCompanyModerationRepository(companies), tokenService, &MockPromocodesClient{}), mockRepo, members
+}
+
+func TestCreateCompany(t *testing.T) {
+	service, mockRepo, members := newTestCompanyService(t)
+
+	company, err := service.CreateCompany(context.Background(), 1, models.CreateCompanyRequest{Name: "Кофейня", Description: "Скидки на кофе"})
+	if err != nil {
+		t.Fatalf("Ожидается успешное создание компании, получено: %v", err)
+	}
+	if company.ID == 0 || company.OwnerID != 1 || company.SubscriptionLevel != models.SubscriptionFree {
+		t.Errorf("Неверная компания: %+v", company)
+	}
+	if mockRepo.usersById[1].Role != models.RoleCompanyOwner {
+		t.Errorf("Создатель компании должен получить роль company_owner, получено: %s", mockRepo.usersById[1].Role)
+	}
+	if member, _ := members.GetMember(context.Background(), company.ID, 1); member == nil || member.Role != models.CompanyRoleOwner {
+		t.Errorf("Создатель должен состоять в команде владельцем, получено: %+v", member)
+	}
+
+	// Роль администратора не понижается до company_owner
+	service.CreateCompany(context.Background(), 3, models.CreateCompanyRequest{Name: "Админская"})
+	if mockRepo.usersById[3].Role != models.RoleAdmin {
+		t.Errorf("Роль администратора не должна меняться, получено: %s", mockRepo.usersById[3].Role)
+	}
+
+	if _, err := service.CreateCompany(context.Background(), 42, models.CreateCompanyRequest{Name: "Никто"}); err != ErrUserNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего пользователя, получено: %v", err)
+	}
+
+	companies, _ := service.ListUserCompanies(context.Background(), 1)
+	if len(companies) != 1 || companies[0].Name != "Кофейня" {
+		t.Errorf("Ожидается одна компания владельца, получено: %+v", companies)
+	}
+	if companies, _ := service.ListUserCompanies(context.Background(), 2); companies == nil || len(companies) != 0 {
+		t.Errorf("Ожидается пустой список, получено: %+v", companies)
+	}
+}
+
+func TestCompanyOwnership(t *testing.T) {
+	service, mockRepo, members := newTestCompanyService(t)
+	company, _ := service.CreateCompany(context.Background(), 1, models.CreateCompanyRequest{Name: "Кофейня", Description: "Скидки на кофе"})
+
+	// Роль и команды в токене владельца еще старые: права определяются членством в БД, а не токеном
+	owner := &models.TokenClaims{UserID: 1, Roles: []models.Role{models.RoleUser}}
+	stranger := &models.TokenClaims{UserID: 2, Roles: []models.Role{models.RoleCompanyOwner}}
+	admin := &models.TokenClaims{UserID: 3, Roles: []models.Role{models.RoleAdmin}}
+
+	if _, err := service.UpdateCompany(context.Background(), stranger, company.ID, models.UpdateCompanyRequest{Name: "Чужая"}); err != ErrNotCompanyMember {
+		t.Errorf("Ожидается запрет изменения чужой компании, получено: %v", err)
+	}
+	members.SaveMember(context.Background(), &models.CompanyMember{CompanyID: company.ID, UserID: 2, Role: models.CompanyRoleManager})
+	if _, err := service.UpdateCompany(context.Background(), stranger, company.ID, models.UpdateCompanyRequest{Name: "Чужая"}); err != ErrNotCompanyOwner {
+		t.Errorf("Менеджер не может изменять компанию, получено: %v", err)
+	}
+	updated, err := service.UpdateCompany(context.Background(), owner, company.ID, models.UpdateCompanyRequest{Name: "Кофейня на углу"})
+	if err != nil || updated.Name != "Кофейня на углу" || updated.Description != "Скидки на кофе" {
+		t.Errorf("Ожидается изменение только названия, получено: %+v, %v", updated, err)
+	}
+	if _, err := service.UpdateCompany(context.Background(), admin, company.ID, models.UpdateCompanyRequest{Description: "Проверено"}); err != nil {
+		t.Errorf("Администратор может изменять любую компанию, получено: %v", err)
+	}
+	if _, err := service.UpdateCompany(context.Background(), owner, 42, models.UpdateCompanyRequest{Name: "Нет"}); err != ErrCompanyNotFound {
+		t.Errorf("Ожидается ошибка отсутствующей компании, получено: %v", err)
+	}
+
+	if err := service.DeleteCompany(context.Background(), stranger, company.ID); err != ErrNotCompanyOwner {
+		t.Errorf("Ожидается запрет удаления компании менеджером, получено: %v", err)
+	}
+	if err := service.DeleteCompany(context.Background(), owner, company.ID); err != nil {
+		t.Fatalf("Ожидается успешное удаление, получено: %v", err)
+	}
+	if _, err := service.LookupCompany(context.Background(), company.ID); err != ErrCompanyNotFound {
+		t.Errorf("Компания должна быть удалена, получено: %v", err)
+	}
+	if mockRepo.usersById[1].Role != models.RoleUser {
+		t.Errorf("Владелец без компаний должен снова получить роль user, получено: %s", mockRepo.usersById[1].Role)
+	}
+	if len(members.members) != 0 {
+		t.Errorf("Команда должна быть удалена вместе с компанией: %v", members.members)
+	}
+}
+
+func TestCompanyVisibility(t *testing.T) {
+	service, _, members := newTestCompanyService(t)
+	ctx := context.Background()
+	company, _ := service.CreateCompany(ctx, 1, models.CreateCompanyRequest{Name: "Кофейня"})
+	members.SaveMember(ctx, &models.CompanyMember{CompanyID: company.ID, UserID: 2, Role: models.CompanyRoleAnalyst})
+
+	analyst := &models.TokenClaims{UserID: 2, Roles: []models.Role{models.RoleUser}}
+	moderator := &models.TokenClaims{UserID: 5, Roles: []models.Role{models.RoleModerator}}
+	stranger := &models.TokenClaims{UserID: 4, Roles: []models.Role{models.RoleUser}}
+
+	for _, actor := range []*models.TokenClaims{analyst, moderator} {
+		if _, err := service.GetCompany(ctx, actor, company.ID); err != nil {
+			t.Errorf("Пользователь %d должен видеть компанию до модерации, получено: %v", actor.UserID, err)
+		}
+	}
+	if _, err := service.GetCompany(ctx, stranger, company.ID); err != ErrCompanyNotFound {
+		t.Errorf("Посторонний не должен видеть компанию до модерации, получено: %v", err)
+	}
+
+	stored, _ := service.LookupCompany(ctx, company.ID)
+	stored.ModerationStatus = models.ModerationApproved
+	service.companyRepo.UpdateCompany(ctx, stored)
+	if _, err := service.GetCompany(ctx, stranger, company.ID); err != nil {
+		t.Errorf("Одобренная компания видна всем, получено: %v", err)
+	}
+}
+
+func TestDeleteCompanyCleanup(t *testing.T) {
+	service, mockRepo, members := newTestCompanyService(t)
+	ctx := context.Background()
+	company, _ := service.CreateCompany(ctx, 1, models.CreateCompanyRequest{Name: "Кофейня"})
+	members.SaveMember(ctx, &models.CompanyMember{CompanyID: company.ID, UserID: 2, Role: models.CompanyRoleManager})
+	owner := &models.TokenClaims{UserID: 1, Roles: []models.Role{models.RoleCompanyOwner}}
+
+	var tokens []string
+	for _, userID := range []uint{1, 2} {
+		resp, err := service.tokenService.IssueTokens(ctx, mockRepo.usersById[userID], models.ClientInfo{})
+		if err != nil {
+			t.Fatalf("Не удалось выдать токены: %v", err)
+		}
+		tokens = append(tokens, resp.Token)
+	}
+
+	client := service.promocodes.(*MockPromocodesClient)
+	client.err = errors.New("promocodes-service недоступен")
+	if err := service.DeleteCompany(ctx, owner, company.ID); err == nil {
+		t.Fatal("Компания не должна удаляться, пока не удалены ее промокоды")
+	}
+	if _, err := service.LookupCompany(ctx, company.ID); err != nil {
+		t.Errorf("Компания должна остаться для повторного удаления, получено: %v", err)
+	}
+
+	client.err = nil
+	if err := service.DeleteCompany(ctx, owner, company.ID); err != nil {
+		t.Fatalf("Ожидается успешное удаление, получено: %v", err)
+	}
+	if len(client.deleted) != 1 || client.deleted[0] != company.ID {
+		t.Errorf("Промокоды и API-ключи компании должны быть удалены: %v", client.deleted)
+	}
+	for i, token := range tokens {
+		if _, err := service.tokenService.ValidateAccessToken(ctx, token); err == nil {
+			t.Errorf("Токен участника %d с членством в удаленной компании должен быть отозван", i+1)
+		}
+	}
+}
+
+func TestSetSubscription(t *testing.T) {
+	service, _, _ := newTestCompanyService(t)
+	company, _ := service.CreateCompany(context.Background(), 1, models.CreateCompanyRequest{Name: "Кофейня"})
+
+	if _, err := service.SetSubscription(context.Background(), company.ID, "platinum"); err != ErrUnknownSubscriptionLevel {
+		t.Errorf("Ожидается ошибка неизвестного уровня подписки, получено: %v", err)
+	}
+	updated, err := service.SetSubscription(context.Background(), company.ID, models.SubscriptionPremium)
+	if err != nil || updated.SubscriptionLevel != models.SubscriptionPremium {
+		t.Errorf("Ожидается уровень premium, получено: %+v, %v", updated, err)
+	}
+}
@@ -2,13 +2,137 @@
 package services
 
 import (
+	"context"
+	"crypto/rsa"
+	"time"
 	"user-service/models"
 )
 
 type UserServiceInterface interface {
-    Register(req models.RegisterRequest) error
-    Login(req models.LoginRequest) (string, error)
-    GetUserProfile(userID uint) (*models.User, error)
-    UpdateUserProfile(userID uint, req models.UpdateProfileRequest) error
-    ValidateToken(tokenString string) (uint, error)
-}
\ No newline at end of file
+    Register(ctx context.Context, req models.RegisterRequest) error
+    // Login при включенной 2FA возвращает не токены, а MFAToken для LoginMFA
+    Login(ctx context.Context, req models.LoginRequest, client models.ClientInfo) (*models.LoginResponse, error)
+    LoginMFA(ctx context.Context, req models.MFALoginRequest, client models.ClientInfo) (*models.LoginResponse, error)
+    RefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error)
+    GetUserProfile(ctx context.Context, userID uint) (*models.User, error)
+    UpdateUserProfile(ctx context.Context, userID uint, req models.UpdateProfileRequest) error
+    ValidateToken(ctx context.Context, tokenString string) (*models.TokenClaims, error)
+    // AssignRole меняет роль пользователя и завершает его сессии, чтобы новая роль сразу попала в токены
+    AssignRole(ctx context.Context, userID uint, role models.Role) error
+    // ChangePassword проверяет текущий пароль, сохраняет новый и завершает все сессии
+    ChangePassword(ctx context.Context, userID uint, req models.ChangePasswordRequest, client models.ClientInfo) error
+    // ForgotPassword отправляет ссылку сброса, если адрес зарегистрирован. Результат не зависит
+    // от существования учетной записи, ошибка возвращается только при сбое хранилища.
+    ForgotPassword(ctx context.Context, email string) error
+    ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error
+}
+
+type TokenServiceInterface interface {
+    // IssueTokens открывает новую сессию и выдает для нее пару access/refresh-токенов
+    IssueTokens(ctx context.Context, user *models.User, client models.ClientInfo) (*models.LoginResponse, error)
+    Refresh(ctx context.Context, refreshToken string) (*models.LoginResponse, error)
+    ValidateAccessToken(ctx context.Context, tokenString string) (*models.TokenClaims, error)
+    // Logout отзывает предъявленный access-токен и завершает его сессию
+    Logout(ctx context.Context, claims *models.TokenClaims) error
+    // LogoutAll завершает все сессии пользователя, например после смены пароля
+    LogoutAll(ctx context.Context, userID uint) error
+    ListSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.Session, error)
+    RevokeSession(ctx context.Context, userID uint, sessionID string) error
+}
+
+type KeyServiceInterface interface {
+    // SigningKey возвращает kid и закрытый ключ, которым подписываются новые токены
+    SigningKey() (string, *rsa.PrivateKey, error)
+    PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, bool)
+    JWKS() models.JWKS
+}
+
+type AdminServiceInterface interface {
+    ListUsers(ctx context.Context, filter models.UserFilter) (*models.UserList, error)
+    GetUser(ctx context.Context, userID uint) (*models.User, error)
+    BlockUser(ctx context.Context, adminID, userID uint) error
+    UnblockUser(ctx context.Context, userID uint) error
+    DeleteUser(ctx context.Context, adminID, userID uint) error
+    // ForceResetPassword возвращает сгенерированный временный пароль
+    ForceResetPassword(ctx context.Context, userID uint) (string, error)
+    SetSegments(ctx context.Context, userID uint, segments []string) (*models.User, error)
+}
+
+type VerificationServiceInterface interface {
+    // SendVerification отправляет письмо со ссылкой подтверждения email
+    SendVerification(ctx context.Context, user *models.User) error
+    VerifyEmail(ctx context.Context, token string) error
+    ResendVerification(ctx context.Context, userID uint) error
+    ResendInterval() time.Duration
+    // SendPasswordReset отправляет ссылку сброса пароля не чаще одного раза в ResendInterval
+    SendPasswordReset(ctx context.Context, user *models.User) error
+    // ConsumePasswordReset проверяет и гасит токен сброса, возвращая его владельца
+    ConsumePasswordReset(ctx context.Context, token string) (*models.User, error)
+}
+
+type MFAServiceInterface interface {
+    Enroll(ctx context.Context, userID uint) (*models.TOTPEnrollment, error)
+    // Confirm включает 2FA и возвращает коды восстановления, которые больше нигде не показываются
+    Confirm(ctx context.Context, userID uint, code string) ([]string, error)
+    Disable(ctx context.Context, userID uint, code string) error
+    RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error)
+    // IssueChallenge выдает токен для второго шага входа
+    IssueChallenge(ctx context.Context, user *models.User) (string, error)
+    // ChallengeUser возвращает владельца токена второго шага, не погашая токен
+    ChallengeUser(ctx context.Context, token string) (*models.User, error)
+    // CompleteChallenge проверяет токен и код второго шага и возвращает пользователя
+    CompleteChallenge(ctx context.Context, token, code string) (*models.User, error)
+}
+
+type LoginThrottlerInterface interface {
+    // Check возвращает *ThrottledError, если вход по логину или с адреса временно запрещен
+    Check(ctx context.Context, login, ip string) error
+    // RecordFailure учитывает неудачную попытку и при превышении порога блокирует логин
+    RecordFailure(ctx context.Context, login, ip string) error
+    RecordSuccess(ctx context.Context, login string) error
+}
+
+type CompanyServiceInterface interface {
+    // CreateCompany создает компанию и добавляет пользователя в ее команду владельцем.
+    // Новые и измененные компании попадают в очередь модерации.
+    CreateCompany(ctx context.Context, ownerID uint, req models.CreateCompanyRequest) (*models.Company, error)
+    // GetCompany показывает компанию, которую модератор еще не одобрил, только ее команде,
+    // модераторам и тем, у кого есть право companies:manage_all
+    GetCompany(ctx context.Context, actor *models.TokenClaims, companyID uint) (*models.Company, error)
+    // LookupCompany возвращает компанию без проверки доступа, для администраторов и других сервисов
+    LookupCompany(ctx context.Context, companyID uint) (*models.Company, error)
+    ListUserCompanies(ctx context.Context, userID uint) ([]models.Company, error)
+    // UpdateCompany и DeleteCompany доступны владельцам из команды компании и тем, у кого есть право companies:manage_all
+    UpdateCompany(ctx context.Context, actor *models.TokenClaims, companyID uint, req models.UpdateCompanyRequest) (*models.Company, error)
+    DeleteCompany(ctx context.Context, actor *models.TokenClaims, companyID uint) error
+    SetSubscription(ctx context.Context, companyID uint, level models.SubscriptionLevel) (*models.Company, error)
+}
+
+type NotificationServiceInterface interface {
+    // NotifyUser отправляет письмо на адрес пользователя userID
+    NotifyUser(ctx context.Context, userID uint, req models.NotificationRequest) error
+}
+
+type MembershipServiceInterface interface {
+    // ListMembers доступен любому участнику команды
+    ListMembers(ctx context.Context, actor *models.TokenClaims, companyID uint) ([]models.CompanyMember, error)
+    UpdateMemberRole(ctx context.Context, actor *models.TokenClaims, companyID, userID uint, role models.CompanyRole) (*models.CompanyMember, error)
+    // RemoveMember доступен владельцу, а также самому участнику для выхода из команды
+    RemoveMember(ctx context.Context, actor *models.TokenClaims, companyID, userID uint) error
+    // Invite создает приглашение и отправляет ссылку на указанный email
+    Invite(ctx context.Context, actor *models.TokenClaims, companyID uint, req models.InviteMemberRequest) (*models.CompanyInvitation, error)
+    ListInvitations(ctx context.Context, actor *models.TokenClaims, companyID uint) ([]models.CompanyInvitation, error)
+    RevokeInvitation(ctx context.Context, actor *models.TokenClaims, companyID, invitationID uint) error
+    AcceptInvitation(ctx context.Context, userID uint, token string) (*models.CompanyMember, error)
+}
+
+type CompanyModerationServiceInterface interface {
+    // ListQueue по умолчанию возвращает заявки, которые ждут решения
+    ListQueue(ctx context.Context, filter models.CompanyModerationFilter) (*models.CompanyModerationList, error)
+    ClaimItem(ctx context.Context, moderatorID, id uint) (*models.CompanyModerationItem, error)
+    ReleaseItem(ctx context.Context, moderatorID, id uint) (*models.CompanyModerationItem, error)
+    // ApproveItem и RejectItem переносят решение на компанию и сообщают о нем автору заявки
+    ApproveItem(ctx context.Context, moderatorID, id uint) (*models.CompanyModerationItem, error)
+    RejectItem(ctx context.Context, moderatorID, id uint, reason string) (*models.CompanyModerationItem, error)
+    ListAudit(ctx context.Context, filter models.CompanyModerationAuditFilter) (*models.CompanyModerationAuditList, error)
+}
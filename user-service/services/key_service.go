@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/tracing"
+)
+
+const (
+	keyBits = 2048
+	// Не чаще этого интервала ключи перечитываются из базы при запросе неизвестного kid
+	keyReloadInterval = 10 * time.Second
+)
+
+var ErrNoSigningKey = errors.New("нет действующего ключа подписи")
+
+type signingKey struct {
+	id        string
+	private   *rsa.PrivateKey
+	createdAt time.Time
+	rotated   bool
+}
+
+// KeyService хранит ключи подписи в базе, чтобы их разделяли все экземпляры сервиса,
+// и держит в памяти копию для подписи и проверки токенов.
+type KeyService struct {
+	keyRepo        repository.KeyRepositoryInterface
+	rotationPeriod time.Duration
+	gracePeriod    time.Duration
+
+	mu       sync.RWMutex
+	keys     []signingKey // от новых к старым
+	loadedAt time.Time
+}
+
+func NewKeyService(keyRepo repository.KeyRepositoryInterface, rotationPeriod, gracePeriod time.Duration) *KeyService {
+	return &KeyService{
+		keyRepo:        keyRepo,
+		rotationPeriod: rotationPeriod,
+		gracePeriod:    gracePeriod,
+	}
+}
+
+func (s *KeyService) SigningKey() (string, *rsa.PrivateKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if !key.rotated {
+			return key.id, key.private, nil
+		}
+	}
+	return "", nil, ErrNoSigningKey
+}
+
+func (s *KeyService) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, bool) {
+	if key, ok := s.findKey(kid); ok {
+		return key, true
+	}
+
+	// Ключ мог выпустить другой экземпляр сервиса
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > keyReloadInterval
+	s.mu.RUnlock()
+	if !stale {
+		return nil, false
+	}
+	if err := s.Load(ctx); err != nil {
+		log.Printf("Ошибка загрузки ключей подписи: %v", err)
+		return nil, false
+	}
+	return s.findKey(kid)
+}
+
+func (s *KeyService) JWKS() models.JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jwks := models.JWKS{Keys: make([]models.JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwks.Keys = append(jwks.Keys, models.JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: key.id,
+			N:   base64.RawURLEncoding.EncodeToString(key.private.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.private.PublicKey.E)).Bytes()),
+		})
+	}
+	return jwks
+}
+
+// Load перечитывает из базы ключи, открытая часть которых еще действительна
+func (s *KeyService) Load(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "KeyService.Load")
+	defer span.End()
+
+	stored, err := s.keyRepo.ListValidKeys(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	keys := make([]signingKey, 0, len(stored))
+	for _, key := range stored {
+		block, _ := pem.Decode([]byte(key.PrivateKey))
+		if block == nil {
+			return fmt.Errorf("ключ %s: некорректный PEM", key.ID)
+		}
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("ключ %s: %w", key.ID, err)
+		}
+		keys = append(keys, signingKey{
+			id:        key.ID,
+			private:   private,
+			createdAt: key.CreatedAt,
+			rotated:   key.RotatedAt != nil,
+		})
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// RotateIfNeeded выпускает новый ключ, если действующего нет или он старше периода ротации
+func (s *KeyService) RotateIfNeeded(ctx context.Context) error {
+	if err := s.Load(ctx); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	var active *signingKey
+	for i := range s.keys {
+		if !s.keys[i].rotated {
+			active = &s.keys[i]
+			break
+		}
+	}
+	due := active == nil || time.Since(active.createdAt) >= s.rotationPeriod
+	s.mu.RUnlock()
+
+	if !due {
+		return nil
+	}
+	return s.Rotate(ctx)
+}
+
+// Rotate выпускает новый ключ подписи. Прежние ключи перестают подписывать,
+// но остаются в JWKS еще gracePeriod.
+func (s *KeyService) Rotate(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "KeyService.Rotate")
+	defer span.End()
+
+	private, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return err
+	}
+	kid, err := randomString(8)
+	if err != nil {
+		return err
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})
+	if err := s.keyRepo.CreateKey(ctx, &models.SigningKey{ID: kid, PrivateKey: string(pemKey)}); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.mu.RLock()
+	var previous []string
+	for _, key := range s.keys {
+		if !key.rotated {
+			previous = append(previous, key.id)
+		}
+	}
+	s.mu.RUnlock()
+	for _, id := range previous {
+		if err := s.keyRepo.RetireKey(ctx, id, now, now.Add(s.gracePeriod)); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Выпущен новый ключ подписи токенов %s", kid)
+	return s.Load(ctx)
+}
+
+// Run проверяет необходимость ротации с заданным интервалом, пока не отменен ctx
+func (s *KeyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.RotateIfNeeded(ctx); err != nil {
+				log.Printf("Ошибка ротации ключей подписи: %v", err)
+			}
+		}
+	}
+}
+
+func (s *KeyService) findKey(kid string) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.id == kid {
+			return &key.private.PublicKey, true
+		}
+	}
+	return nil, false
+}
+
+var _ KeyServiceInterface = (*KeyService)(nil)
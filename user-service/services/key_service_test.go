@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/models"
+	"user-service/repository"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type MockKeyRepository struct {
+	keys []*models.SigningKey
+}
+
+var _ repository.KeyRepositoryInterface = (*MockKeyRepository)(nil)
+
+func (r *MockKeyRepository) CreateKey(ctx context.Context, key *models.SigningKey) error {
+	key.CreatedAt = time.Now()
+	r.keys = append([]*models.SigningKey{key}, r.keys...)
+	return nil
+}
+
+func (r *MockKeyRepository) ListValidKeys(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	for _, key := range r.keys {
+		if key.ExpiresAt == nil || key.ExpiresAt.After(now) {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *MockKeyRepository) RetireKey(ctx context.Context, id string, rotatedAt, expiresAt time.Time) error {
+	for _, key := range r.keys {
+		if key.ID == id && key.RotatedAt == nil {
+			key.RotatedAt = &rotatedAt
+			key.ExpiresAt = &expiresAt
+		}
+	}
+	return nil
+}
+
+func newTestKeyService(t *testing.T) *KeyService {
+	keys := NewKeyService(&MockKeyRepository{}, time.Hour, time.Hour)
+	if err := keys.RotateIfNeeded(context.Background()); err != nil {
+		t.Fatalf("Ошибка создания ключа подписи: %v", err)
+	}
+	return keys
+}
+
+func TestKeyRotation(t *testing.T) {
+	keyRepo := &MockKeyRepository{}
+	keys := NewKeyService(keyRepo, time.Hour, time.Hour)
+
+	if _, _, err := keys.SigningKey(); err != ErrNoSigningKey {
+		t.Errorf("До первой ротации ключа подписи нет, получено: %v", err)
+	}
+	if err := keys.RotateIfNeeded(context.Background()); err != nil {
+		t.Fatalf("Ошибка ротации: %v", err)
+	}
+	firstKid, _, _ := keys.SigningKey()
+
+	// Ключ еще не устарел, повторная проверка ничего не меняет
+	keys.RotateIfNeeded(context.Background())
+	if len(keyRepo.keys) != 1 {
+		t.Fatalf("Ожидается 1 ключ, получено: %d", len(keyRepo.keys))
+	}
+
+	if err := keys.Rotate(context.Background()); err != nil {
+		t.Fatalf("Ошибка ротации: %v", err)
+	}
+	secondKid, _, _ := keys.SigningKey()
+	if secondKid == firstKid {
+		t.Error("После ротации должен подписывать новый ключ")
+	}
+	if _, ok := keys.PublicKey(context.Background(), firstKid); !ok {
+		t.Error("Старый открытый ключ должен оставаться действительным в течение grace-периода")
+	}
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != secondKid || jwks.Keys[0].Alg != "RS256" || jwks.Keys[0].E != "AQAB" {
+		t.Errorf("Неверный JWKS: %+v", jwks)
+	}
+	for _, key := range jwks.Keys {
+		if key.N == "" {
+			t.Error("JWK должен содержать модуль открытого ключа")
+		}
+	}
+
+	// По окончании grace-периода старый ключ убирается из JWKS
+	expired := time.Now().Add(-time.Second)
+	keyRepo.keys[1].ExpiresAt = &expired
+	keys.Load(context.Background())
+	if _, ok := keys.PublicKey(context.Background(), firstKid); ok {
+		t.Error("Ключ с истекшим grace-периодом не должен приниматься")
+	}
+	if len(keys.JWKS().Keys) != 1 {
+		t.Errorf("Ожидается 1 ключ в JWKS, получено: %d", len(keys.JWKS().Keys))
+	}
+}
+
+func TestTokenSignedByOtherKeyRejected(t *testing.T) {
+	keys := newTestKeyService(t)
+	service := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), newTestUsers(), NewMockCompanyMemberRepository(), keys, 15*time.Minute, 24*time.Hour)
+
+	resp, err := service.IssueTokens(context.Background(), &models.User{ID: 1, Role: models.RoleUser}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Ошибка выдачи токенов: %v", err)
+	}
+	token, _ := jwt.Parse(resp.Token, nil)
+	if token.Header["alg"] != "RS256" || token.Header["kid"] == "" {
+		t.Errorf("Ожидается RS256 и kid в заголовке, получено: %v", token.Header)
+	}
+
+	// Токен с тем же kid, подписанный посторонним ключом
+	forged := newTestKeyService(t)
+	_, forgedKey, _ := forged.SigningKey()
+	kid, _, _ := keys.SigningKey()
+	forgedToken := jwt.NewWithClaims(jwt.SigningMethodRS256, token.Claims)
+	forgedToken.Header["kid"] = kid
+	forgedString, _ := forgedToken.SignedString(forgedKey)
+	if _, err := service.ValidateAccessToken(context.Background(), forgedString); err == nil {
+		t.Error("Токен, подписанный посторонним ключом, должен отклоняться")
+	}
+
+	// Подмена алгоритма на HS256 с открытым ключом в качестве секрета
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, token.Claims)
+	hmacToken.Header["kid"] = kid
+	hmacString, _ := hmacToken.SignedString([]byte("test_secret"))
+	if _, err := service.ValidateAccessToken(context.Background(), hmacString); err == nil {
+		t.Error("Токен с алгоритмом HS256 должен отклоняться")
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/tracing"
+)
+
+const (
+	loginKeyPrefix = "login:"
+	ipKeyPrefix    = "ip:"
+)
+
+// ErrTooManyAttempts - вход временно запрещен. Сообщение одинаково для существующих
+// и несуществующих логинов: счетчики ведутся по любому введенному логину.
+var ErrTooManyAttempts = errors.New("слишком много попыток входа, повторите позже")
+
+// ThrottledError сообщает, через сколько можно повторить вход
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginThrottleConfig задает ограничения на неудачные попытки входа
+type LoginThrottleConfig struct {
+	// FreeAttempts неудач по логину не вызывают задержки, дальше она удваивается с каждой неудачей
+	FreeAttempts int
+	// MaxFailures неудач по логину подряд блокируют вход на LockoutDuration
+	MaxFailures     int
+	LockoutDuration time.Duration
+	// IPFreeAttempts - то же, что FreeAttempts, для адреса клиента. Адрес не блокируется,
+	// чтобы не закрывать вход всем пользователям за общим NAT.
+	IPFreeAttempts int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	// Window - через сколько после последней неудачи счетчик сбрасывается
+	Window time.Duration
+}
+
+// LoginThrottler ограничивает подбор паролей: экспоненциальная задержка по логину и по адресу
+// и временная блокировка логина. Счетчики хранятся в БД и общие для всех экземпляров сервиса.
+type LoginThrottler struct {
+	repo   repository.LoginAttemptRepositoryInterface
+	config LoginThrottleConfig
+}
+
+func NewLoginThrottler(repo repository.LoginAttemptRepositoryInterface, config LoginThrottleConfig) *LoginThrottler {
+	return &LoginThrottler{repo: repo, config: config}
+}
+
+func (t *LoginThrottler) Check(ctx context.Context, login, ip string) error {
+	ctx, span := tracing.Start(ctx, "LoginThrottler.Check")
+	defer span.End()
+
+	keys := attemptKeys(login, ip)
+	if len(keys) == 0 {
+		return nil
+	}
+	attempts, err := t.repo.GetLoginAttempts(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, attempt := range attempts {
+		if d := t.retryAfter(attempt, now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (t *LoginThrottler) RecordFailure(ctx context.Context, login, ip string) error {
+	ctx, span := tracing.Start(ctx, "LoginThrottler.RecordFailure")
+	defer span.End()
+
+	now := time.Now()
+	for _, key := range attemptKeys(login, ip) {
+		attempt, err := t.repo.RecordLoginFailure(ctx, key, now, now.Add(-t.config.Window))
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(key, loginKeyPrefix) || attempt.Failures < t.config.MaxFailures {
+			continue
+		}
+
+		until := now.Add(t.config.LockoutDuration)
+		if err := t.repo.LockLogin(ctx, key, until); err != nil {
+			return err
+		}
+		metrics.LoginLockouts.Inc()
+		log.Printf("[SECURITY] Вход по логину %q заблокирован до %s после %d неудачных попыток, последняя с адреса %s",
+			login, until.Format(time.RFC3339), attempt.Failures, ip)
+	}
+	return nil
+}
+
+func (t *LoginThrottler) RecordSuccess(ctx context.Context, login string) error {
+	ctx, span := tracing.Start(ctx, "LoginThrottler.RecordSuccess")
+	defer span.End()
+
+	// Счетчик адреса не сбрасывается: иначе атакующий мог бы обнулять его входом в свою учетную запись
+	return t.repo.ResetLoginAttempts(ctx, loginKeyPrefix+login)
+}
+
+// retryAfter возвращает, сколько еще действует задержка или блокировка по счетчику
+func (t *LoginThrottler) retryAfter(attempt models.LoginAttempt, now time.Time) time.Duration {
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return attempt.LockedUntil.Sub(now)
+	}
+	if attempt.LastFailureAt.Before(now.Add(-t.config.Window)) {
+		return 0
+	}
+
+	free := t.config.FreeAttempts
+	if strings.HasPrefix(attempt.Key, ipKeyPrefix) {
+		free = t.config.IPFreeAttempts
+	}
+	if attempt.Failures <= free {
+		return 0
+	}
+	delay := t.config.BaseDelay
+	for i := free + 1; i < attempt.Failures && delay < t.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.config.MaxDelay {
+		delay = t.config.MaxDelay
+	}
+	return attempt.LastFailureAt.Add(delay).Sub(now)
+}
+
+func attemptKeys(login, ip string) []string {
+	var keys []string
+	if login != "" {
+		keys = append(keys, loginKeyPrefix+login)
+	}
+	if ip != "" {
+		keys = append(keys, ipKeyPrefix+ip)
+	}
+	return keys
+}
+
+var _ LoginThrottlerInterface = (*LoginThrottler)(nil)
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/totp"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockLoginAttemptRepository struct {
+	attempts map[string]*models.LoginAttempt
+}
+
+var _ repository.LoginAttemptRepositoryInterface = (*MockLoginAttemptRepository)(nil)
+
+func NewMockLoginAttemptRepository() *MockLoginAttemptRepository {
+	return &MockLoginAttemptRepository{attempts: make(map[string]*models.LoginAttempt)}
+}
+
+func (r *MockLoginAttemptRepository) GetLoginAttempts(ctx context.Context, keys []string) ([]models.LoginAttempt, error) {
+	var attempts []models.LoginAttempt
+	for _, key := range keys {
+		if attempt, exists := r.attempts[key]; exists {
+			attempts = append(attempts, *attempt)
+		}
+	}
+	return attempts, nil
+}
+
+func (r *MockLoginAttemptRepository) RecordLoginFailure(ctx context.Context, key string, at, windowStart time.Time) (*models.LoginAttempt, error) {
+	attempt, exists := r.attempts[key]
+	if !exists {
+		attempt = &models.LoginAttempt{Key: key}
+		r.attempts[key] = attempt
+	}
+	if attempt.LastFailureAt.Before(windowStart) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	stored := *attempt
+	return &stored, nil
+}
+
+func (r *MockLoginAttemptRepository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	if attempt, exists := r.attempts[key]; exists {
+		attempt.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *MockLoginAttemptRepository) ResetLoginAttempts(ctx context.Context, key string) error {
+	delete(r.attempts, key)
+	return nil
+}
+
+// rewind сдвигает все отметки времени в прошлое, чтобы не ждать окончания задержки в тестах
+func (r *MockLoginAttemptRepository) rewind(d time.Duration) {
+	for _, attempt := range r.attempts {
+		attempt.LastFailureAt = attempt.LastFailureAt.Add(-d)
+		if attempt.LockedUntil != nil {
+			lockedUntil := attempt.LockedUntil.Add(-d)
+			attempt.LockedUntil = &lockedUntil
+		}
+	}
+}
+
+var testLoginThrottleConfig = LoginThrottleConfig{
+	FreeAttempts:    2,
+	MaxFailures:     5,
+	LockoutDuration: 15 * time.Minute,
+	IPFreeAttempts:  3,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	Window:          time.Hour,
+}
+
+func newTestLoginThrottler() *LoginThrottler {
+	return NewLoginThrottler(NewMockLoginAttemptRepository(), testLoginThrottleConfig)
+}
+
+// captureLog перенаправляет стандартный логгер в буфер до конца теста
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func retryAfter(err error) time.Duration {
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		return throttled.RetryAfter
+	}
+	return 0
+}
+
+func TestLoginThrottlerBackoff(t *testing.T) {
+	repo := NewMockLoginAttemptRepository()
+	throttler := NewLoginThrottler(repo, testLoginThrottleConfig)
+	ctx := context.Background()
+
+	// Первые неудачи не замедляют вход: пользователь мог просто опечататься
+	for i := 0; i < 2; i++ {
+		throttler.RecordFailure(ctx, "testuser", "10.0.0.1")
+		if err := throttler.Check(ctx, "testuser", "10.0.0.1"); err != nil {
+			t.Fatalf("Задержка не ожидается после %d неудач, получено: %v", i+1, err)
+		}
+	}
+
+	// Дальше задержка удваивается с каждой неудачей
+	for i, want := range []time.Duration{time.Second, 2 * time.Second} {
+		throttler.RecordFailure(ctx, "testuser", "10.0.0.1")
+		err := throttler.Check(ctx, "testuser", "10.0.0.1")
+		if !errors.Is(err, ErrTooManyAttempts) || retryAfter(err) > want || retryAfter(err) < want-time.Second/2 {
+			t.Errorf("Ожидается задержка %v после %d неудач, получено: %v (%v)", want, i+3, err, retryAfter(err))
+		}
+		repo.rewind(want)
+		if err := throttler.Check(ctx, "testuser", "10.0.0.1"); err != nil {
+			t.Errorf("После задержки вход должен быть разрешен, получено: %v", err)
+		}
+	}
+
+	// Счетчик адреса действует и для других логинов
+	if err := throttler.Check(ctx, "otheruser", "10.0.0.1"); err != nil {
+		t.Errorf("Задержка по адресу начинается только после %d неудач, получено: %v", testLoginThrottleConfig.IPFreeAttempts, err)
+	}
+	throttler.RecordFailure(ctx, "otheruser", "10.0.0.1")
+	if err := throttler.Check(ctx, "thirduser", "10.0.0.1"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Ожидается задержка по адресу для другого логина, получено: %v", err)
+	}
+	if err := throttler.Check(ctx, "thirduser", "10.0.0.2"); err != nil {
+		t.Errorf("Другой адрес не должен замедляться, получено: %v", err)
+	}
+
+	// Успешный вход сбрасывает счетчик логина, но не адреса
+	throttler.RecordSuccess(ctx, "testuser")
+	if err := throttler.Check(ctx, "testuser", ""); err != nil {
+		t.Errorf("После успешного входа задержка по логину не ожидается, получено: %v", err)
+	}
+	if err := throttler.Check(ctx, "", "10.0.0.1"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Успешный вход не должен сбрасывать счетчик адреса, получено: %v", err)
+	}
+
+	// Неудачи за пределами окна забываются
+	repo.rewind(testLoginThrottleConfig.Window)
+	if err := throttler.Check(ctx, "otheruser", "10.0.0.1"); err != nil {
+		t.Errorf("Старые неудачи не должны учитываться, получено: %v", err)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	logs := captureLog(t)
+	mockRepo := NewMockUserRepository()
+	service := newTestUserService(t, mockRepo)
+	attempts := NewMockLoginAttemptRepository()
+	service.throttler = NewLoginThrottler(attempts, testLoginThrottleConfig)
+	service.Register(context.Background(), models.RegisterRequest{
+		Login:    "testuser",
+		Password: "password123",
+		Email:    "test@example.com",
+	})
+
+	// Существующий и несуществующий логины проходят одинаковый путь с одинаковыми ошибками
+	for _, login := range []string{"testuser", "nobody"} {
+		for i := 0; i < testLoginThrottleConfig.MaxFailures; i++ {
+			_, err := service.Login(context.Background(), models.LoginRequest{Login: login, Password: "wrongpassword"}, models.ClientInfo{})
+			if err != ErrInvalidCredentials {
+				t.Fatalf("Ожидается ErrInvalidCredentials для %s на попытке %d, получено: %v", login, i+1, err)
+			}
+			attempts.rewind(time.Minute)
+		}
+
+		_, err := service.Login(context.Background(), models.LoginRequest{Login: login, Password: "password123"}, models.ClientInfo{})
+		if !errors.Is(err, ErrTooManyAttempts) || retryAfter(err) <= 10*time.Minute {
+			t.Errorf("Ожидается блокировка %s даже с верным паролем, получено: %v (%v)", login, err, retryAfter(err))
+		}
+		if !strings.Contains(logs.String(), "[SECURITY]") || !strings.Contains(logs.String(), login) {
+			t.Errorf("Ожидается событие безопасности о блокировке %s, получено: %s", login, logs.String())
+		}
+	}
+
+	attempts.rewind(testLoginThrottleConfig.LockoutDuration)
+	if _, err := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{}); err != nil {
+		t.Errorf("После окончания блокировки вход должен быть разрешен, получено: %v", err)
+	}
+	if len(attempts.attempts) != 1 || attempts.attempts["login:nobody"] == nil {
+		t.Errorf("Успешный вход должен сбросить счетчик только своего логина: %v", attempts.attempts)
+	}
+}
+
+func TestLoginMFAThrottled(t *testing.T) {
+	captureLog(t)
+	mockRepo := NewMockUserRepository()
+	service := newTestUserService(t, mockRepo)
+	attempts := NewMockLoginAttemptRepository()
+	service.throttler = NewLoginThrottler(attempts, testLoginThrottleConfig)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockRepo.CreateUser(context.Background(), &models.User{Login: "testuser", Password: string(hashedPassword), Status: models.UserStatusActive})
+	secret, _ := enableTestMFA(t, service.mfa.(*MFAService))
+
+	resp, err := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+	if err != nil || !resp.MFARequired {
+		t.Fatalf("Ожидается первый шаг входа, получено: %+v, %v", resp, err)
+	}
+
+	// Подбор кода 2FA ограничивается так же, как подбор пароля
+	var lastErr error
+	for i := 0; i < testLoginThrottleConfig.MaxFailures+1; i++ {
+		_, lastErr = service.LoginMFA(context.Background(), models.MFALoginRequest{MFAToken: resp.MFAToken, Code: "000000"}, models.ClientInfo{})
+		attempts.rewind(time.Minute)
+	}
+	if !errors.Is(lastErr, ErrTooManyAttempts) {
+		t.Errorf("Ожидается блокировка после подбора кода, получено: %v", lastErr)
+	}
+
+	code, _ := totp.Code(secret, time.Now())
+	if _, err := service.LoginMFA(context.Background(), models.MFALoginRequest{MFAToken: resp.MFAToken, Code: code}, models.ClientInfo{}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Верный код не должен приниматься во время блокировки, получено: %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"user-service/mailer"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/tracing"
+)
+
+var (
+	ErrUnknownCompanyRole      = errors.New("неизвестная роль в команде")
+	ErrCompanyMemberNotFound   = errors.New("участник команды не найден")
+	ErrAlreadyMember           = errors.New("пользователь уже состоит в команде компании")
+	ErrLastOwner               = errors.New("в компании должен остаться хотя бы один владелец")
+	ErrInvitationNotFound      = errors.New("приглашение не найдено")
+	ErrInvitationClosed        = errors.New("приглашение уже принято, отозвано или истекло")
+	ErrInvitationEmailMismatch = errors.New("приглашение отправлено на другой email")
+)
+
+// InvitationConfig - адрес страницы принятия приглашения (токен добавляется параметром token)
+// и срок действия приглашения
+type InvitationConfig struct {
+	AcceptURL string
+	TTL       time.Duration
+}
+
+// MembershipService управляет командами компаний: участниками, их ролями и приглашениями
+type MembershipService struct {
+	companyRepo    repository.CompanyRepositoryInterface
+	memberRepo     repository.CompanyMemberRepositoryInterface
+	invitationRepo repository.CompanyInvitationRepositoryInterface
+	userRepo       repository.UserRepositoryInterface
+	tokenService   TokenServiceInterface
+	mailer         mailer.Mailer
+	signer         *ActionTokenSigner
+	config         InvitationConfig
+}
+
+func NewMembershipService(companyRepo repository.CompanyRepositoryInterface, memberRepo repository.CompanyMemberRepositoryInterface, invitationRepo repository.CompanyInvitationRepositoryInterface, userRepo repository.UserRepositoryInterface, tokenService TokenServiceInterface, m mailer.Mailer, signer *ActionTokenSigner, config InvitationConfig) *MembershipService {
+	return &MembershipService{
+		companyRepo:    companyRepo,
+		memberRepo:     memberRepo,
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		tokenService:   tokenService,
+		mailer:         m,
+		signer:         signer,
+		config:         config,
+	}
+}
+
+func (s *MembershipService) ListMembers(ctx context.Context, actor *models.TokenClaims, companyID uint) ([]models.CompanyMember, error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.ListMembers")
+	defer span.End()
+
+	if _, err := companyAccess(ctx, s.companyRepo, s.memberRepo, actor, companyID); err != nil {
+		return nil, err
+	}
+	members, err := s.memberRepo.ListMembers(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if members == nil {
+		members = []models.CompanyMember{}
+	}
+	return members, nil
+}
+
+// UpdateMemberRole меняет роль участника. Сессии участника завершаются, чтобы новая роль
+// сразу попала в его токены.
+func (s *MembershipService) UpdateMemberRole(ctx context.Context, actor *models.TokenClaims, companyID, userID uint, role models.CompanyRole) (*models.CompanyMember, error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.UpdateMemberRole")
+	defer span.End()
+
+	if !role.Valid() {
+		return nil, ErrUnknownCompanyRole
+	}
+	if _, err := companyAccess(ctx, s.companyRepo, s.memberRepo, actor, companyID, models.CompanyRoleOwner); err != nil {
+		return nil, err
+	}
+	member, err := s.memberRepo.GetMember(ctx, companyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrCompanyMemberNotFound
+	}
+	if member.Role == role {
+		return member, nil
+	}
+	if member.Role == models.CompanyRoleOwner {
+		if err := s.ensureAnotherOwner(ctx, companyID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	member.Role = role
+	if err := s.memberRepo.SaveMember(ctx, member); err != nil {
+		return nil, err
+	}
+	if err := s.applyMembershipChange(ctx, userID); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember исключает участника из команды. Владелец может исключить любого участника,
+// остальные - только выйти из команды сами.
+func (s *MembershipService) RemoveMember(ctx context.Context, actor *models.TokenClaims, companyID, userID uint) error {
+	ctx, span := tracing.Start(ctx, "MembershipService.RemoveMember")
+	defer span.End()
+
+	roles := []models.CompanyRole{models.CompanyRoleOwner}
+	if actor.UserID == userID {
+		roles = nil
+	}
+	if _, err := companyAccess(ctx, s.companyRepo, s.memberRepo, actor, companyID, roles...); err != nil {
+		return err
+	}
+	member, err := s.memberRepo.GetMember(ctx, companyID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrCompanyMemberNotFound
+	}
+	if member.Role == models.CompanyRoleOwner {
+		if err := s.ensureAnotherOwner(ctx, companyID, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.memberRepo.RemoveMember(ctx, companyID, userID); err != nil {
+		return err
+	}
+	return s.applyMembershipChange(ctx, userID)
+}
+
+// Invite отправляет на email приглашение в команду с ролью role. Приглашать может только владелец.
+func (s *MembershipService) Invite(ctx context.Context, actor *models.TokenClaims, companyID uint, req models.InviteMemberRequest) (*models.CompanyInvitation, error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.Invite")
+	defer span.End()
+
+	if !req.Role.Valid() {
+		return nil, ErrUnknownCompanyRole
+	}
+	company, err := companyAccess(ctx, s.companyRepo, s.memberRepo, actor, companyID, models.CompanyRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	invitee, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if invitee != nil {
+		member, err := s.memberRepo.GetMember(ctx, companyID, invitee.ID)
+		if err != nil {
+			return nil, err
+		}
+		if member != nil {
+			return nil, ErrAlreadyMember
+		}
+	}
+
+	invitation := &models.CompanyInvitation{
+		CompanyID: companyID,
+		Email:     email,
+		Role:      req.Role,
+		InvitedBy: actor.UserID,
+		ExpiresAt: time.Now().Add(s.config.TTL),
+	}
+	if err := s.invitationRepo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	// Токен ссылается на запись приглашения: ее отзыв или принятие делают ссылку недействительной
+	token, _, err := s.signer.Sign(models.PurposeCompanyInvitation, actor.UserID, strconv.FormatUint(uint64(invitation.ID), 10), s.config.TTL)
+	if err != nil {
+		return nil, err
+	}
+	link, err := linkWithToken(s.config.AcceptURL, token)
+	if err != nil {
+		return nil, err
+	}
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Приглашение в команду компании " + company.Name,
+		Body: fmt.Sprintf("Здравствуйте!\n\nВас пригласили в команду компании %s с ролью %s. Чтобы принять приглашение, войдите в аккаунт с этим email и перейдите по ссылке:\n%s\n\nСсылка действительна %s.",
+			company.Name, invitation.Role, link, s.config.TTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (s *MembershipService) ListInvitations(ctx context.Context, actor *models.TokenClaims, companyID uint) ([]models.CompanyInvitation, error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.ListInvitations")
+	defer span.End()
+
+	if _, err := companyAccess(ctx, s.companyRepo, s.memberRepo, actor, companyID, models.CompanyRoleOwner); err != nil {
+		return nil, err
+	}
+	invitations, err := s.invitationRepo.ListPendingInvitations(ctx, companyID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if invitations == nil {
+		invitations = []models.CompanyInvitation{}
+	}
+	return invitations, nil
+}
+
+func (s *MembershipService) RevokeInvitation(ctx context.Context, actor *models.TokenClaims, companyID, invitationID uint) error {
+	ctx, span := tracing.Start(ctx, "MembershipService.RevokeInvitation")
+	defer span.End()
+
+	if _, err := companyAccess(ctx, s.companyRepo, s.memberRepo, actor, companyID, models.CompanyRoleOwner); err != nil {
+		return err
+	}
+	invitation, err := s.invitationRepo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if invitation == nil || invitation.CompanyID != companyID {
+		return ErrInvitationNotFound
+	}
+	revoked, err := s.invitationRepo.RevokeInvitation(ctx, invitationID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvitationClosed
+	}
+	return nil
+}
+
+// AcceptInvitation добавляет пользователя в команду по токену из письма. Принять приглашение
+// может только пользователь, чей email совпадает с адресом приглашения.
+func (s *MembershipService) AcceptInvitation(ctx context.Context, userID uint, token string) (*models.CompanyMember, error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.AcceptInvitation")
+	defer span.End()
+
+	claims, err := s.signer.Parse(models.PurposeCompanyInvitation, token)
+	if err != nil {
+		return nil, err
+	}
+	invitationID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	invitation, err := s.invitationRepo.GetInvitation(ctx, uint(invitationID))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil {
+		return nil, ErrInvitationNotFound
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	now := time.Now()
+	if !invitation.Pending(now) {
+		return nil, ErrInvitationClosed
+	}
+	existing, err := s.memberRepo.GetMember(ctx, invitation.CompanyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	accepted, err := s.invitationRepo.MarkInvitationAccepted(ctx, invitation.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvitationClosed
+	}
+	member := &models.CompanyMember{
+		CompanyID: invitation.CompanyID,
+		UserID:    userID,
+		Role:      invitation.Role,
+	}
+	if err := s.memberRepo.SaveMember(ctx, member); err != nil {
+		return nil, err
+	}
+	if err := syncCompanyOwnerRole(ctx, s.userRepo, s.memberRepo, userID); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// ensureAnotherOwner не дает лишить компанию последнего владельца
+func (s *MembershipService) ensureAnotherOwner(ctx context.Context, companyID, userID uint) error {
+	members, err := s.memberRepo.ListMembers(ctx, companyID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.Role == models.CompanyRoleOwner && member.UserID != userID {
+			return nil
+		}
+	}
+	return ErrLastOwner
+}
+
+// applyMembershipChange обновляет глобальную роль участника и завершает его сессии:
+// токены с прежней ролью в команде не должны оставаться действительными
+func (s *MembershipService) applyMembershipChange(ctx context.Context, userID uint) error {
+	if err := syncCompanyOwnerRole(ctx, s.userRepo, s.memberRepo, userID); err != nil {
+		return err
+	}
+	return s.tokenService.LogoutAll(ctx, userID)
+}
+
+var _ MembershipServiceInterface = (*MembershipService)(nil)
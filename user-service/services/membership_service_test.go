@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"user-service/models"
+	"user-service/repository"
+)
+
+type MockCompanyInvitationRepository struct {
+	invitations map[uint]*models.CompanyInvitation
+	idCounter   uint
+}
+
+var _ repository.CompanyInvitationRepositoryInterface = (*MockCompanyInvitationRepository)(nil)
+
+func (r *MockCompanyInvitationRepository) CreateInvitation(ctx context.Context, invitation *models.CompanyInvitation) error {
+	r.idCounter++
+	invitation.ID = r.idCounter
+	stored := *invitation
+	r.invitations[invitation.ID] = &stored
+	return nil
+}
+
+func (r *MockCompanyInvitationRepository) GetInvitation(ctx context.Context, id uint) (*models.CompanyInvitation, error) {
+	invitation, exists := r.invitations[id]
+	if !exists {
+		return nil, nil
+	}
+	stored := *invitation
+	return &stored, nil
+}
+
+func (r *MockCompanyInvitationRepository) ListPendingInvitations(ctx context.Context, companyID uint, now time.Time) ([]models.CompanyInvitation, error) {
+	var invitations []models.CompanyInvitation
+	for id := uint(1); id <= r.idCounter; id++ {
+		if invitation, exists := r.invitations[id]; exists && invitation.CompanyID == companyID && invitation.Pending(now) {
+			invitations = append(invitations, *invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (r *MockCompanyInvitationRepository) MarkInvitationAccepted(ctx context.Context, id uint, acceptedAt time.Time) (bool, error) {
+	invitation, exists := r.invitations[id]
+	if !exists || !invitation.Pending(acceptedAt) {
+		return false, nil
+	}
+	invitation.AcceptedAt = &acceptedAt
+	return true, nil
+}
+
+func (r *MockCompanyInvitationRepository) RevokeInvitation(ctx context.Context, id uint, revokedAt time.Time) (bool, error) {
+	invitation, exists := r.invitations[id]
+	if !exists || !invitation.Pending(revokedAt) {
+		return false, nil
+	}
+	invitation.RevokedAt = &revokedAt
+	return true, nil
+}
+
+type testMembership struct {
+	service      *MembershipService
+	users        *MockUserRepository
+	members      *MockCompanyMemberRepository
+	invitations  *MockCompanyInvitationRepository
+	tokenService *TokenService
+	mailer       *MockMailer
+	company      *models.Company
+}
+
+// newTestMembershipService создает компанию пользователя 1; пользователи 2 и 3 в команде не состоят
+func newTestMembershipService(t *testing.T) *testMembership {
+	companyService, users, members := newTestCompanyService(t)
+	company, _ := companyService.CreateCompany(context.Background(), 1, models.CreateCompanyRequest{Name: "Кофейня"})
+
+	env := &testMembership{
+		users:        users,
+		members:      members,
+		invitations:  &MockCompanyInvitationRepository{invitations: make(map[uint]*models.CompanyInvitation)},
+		tokenService: NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), users, members, newTestKeyService(t), 15*time.Minute, 24*time.Hour),
+		mailer:       &MockMailer{},
+		company:      company,
+	}
+	env.service = NewMembershipService(companyService.companyRepo, members, env.invitations, users, env.tokenService,
+		env.mailer, NewActionTokenSigner("test_secret"), InvitationConfig{
+			AcceptURL: "http://localhost/invitations/accept",
+			TTL:       72 * time.Hour,
+		})
+	return env
+}
+
+var testOwner = &models.TokenClaims{UserID: 1, Roles: []models.Role{models.RoleCompanyOwner}}
+
+func TestInviteAndAccept(t *testing.T) {
+	env := newTestMembershipService(t)
+	ctx := context.Background()
+
+	invitation, err := env.service.Invite(ctx, testOwner, env.company.ID, models.InviteMemberRequest{Email: "Stranger@Example.com", Role: models.CompanyRoleManager})
+	if err != nil {
+		t.Fatalf("Ожидается успешное приглашение, получено: %v", err)
+	}
+	if invitation.Email != "stranger@example.com" || len(env.mailer.messages) != 1 || env.mailer.messages[0].To != "stranger@example.com" {
+		t.Fatalf("Ожидается письмо на адрес приглашения, получено: %+v, %+v", invitation, env.mailer.messages)
+	}
+	if !strings.Contains(env.mailer.messages[0].Body, "Кофейня") {
+		t.Errorf("Письмо должно содержать название компании: %s", env.mailer.messages[0].Body)
+	}
+	token := tokenFromMessage(t, env.mailer.messages[0])
+
+	if _, err := env.service.AcceptInvitation(ctx, 3, token); err != ErrInvitationEmailMismatch {
+		t.Errorf("Приглашение на чужой email принять нельзя, получено: %v", err)
+	}
+	if _, err := env.service.AcceptInvitation(ctx, 2, "forged"); err != ErrInvalidActionToken {
+		t.Errorf("Ожидается ошибка недействительного токена, получено: %v", err)
+	}
+	member, err := env.service.AcceptInvitation(ctx, 2, token)
+	if err != nil || member.Role != models.CompanyRoleManager || member.CompanyID != env.company.ID {
+		t.Fatalf("Ожидается вступление в команду менеджером, получено: %+v, %v", member, err)
+	}
+	if _, err := env.service.AcceptInvitation(ctx, 2, token); err != ErrInvitationClosed {
+		t.Errorf("Приглашение одноразовое, получено: %v", err)
+	}
+
+	// Роль в команде попадает в новые токены
+	resp, _ := env.tokenService.IssueTokens(ctx, env.users.usersById[2], models.ClientInfo{})
+	claims, _ := env.tokenService.ValidateAccessToken(ctx, resp.Token)
+	if role, ok := claims.CompanyRole(env.company.ID); !ok || role != models.CompanyRoleManager {
+		t.Errorf("Ожидается роль manager в токене, получено: %v", claims.Companies)
+	}
+
+	manager := &models.TokenClaims{UserID: 2}
+	if _, err := env.service.Invite(ctx, manager, env.company.ID, models.InviteMemberRequest{Email: "admin@example.com", Role: models.CompanyRoleAnalyst}); err != ErrNotCompanyOwner {
+		t.Errorf("Приглашать может только владелец, получено: %v", err)
+	}
+	if _, err := env.service.Invite(ctx, testOwner, env.company.ID, models.InviteMemberRequest{Email: "stranger@example.com", Role: models.CompanyRoleAnalyst}); err != ErrAlreadyMember {
+		t.Errorf("Ожидается ошибка повторного приглашения участника, получено: %v", err)
+	}
+	if _, err := env.service.Invite(ctx, testOwner, env.company.ID, models.InviteMemberRequest{Email: "admin@example.com", Role: "boss"}); err != ErrUnknownCompanyRole {
+		t.Errorf("Ожидается ошибка неизвестной роли, получено: %v", err)
+	}
+}
+
+func TestRevokeAndExpireInvitation(t *testing.T) {
+	env := newTestMembershipService(t)
+	ctx := context.Background()
+
+	revoked, _ := env.service.Invite(ctx, testOwner, env.company.ID, models.InviteMemberRequest{Email: "stranger@example.com", Role: models.CompanyRoleAnalyst})
+	expired, _ := env.service.Invite(ctx, testOwner, env.company.ID, models.InviteMemberRequest{Email: "stranger@example.com", Role: models.CompanyRoleAnalyst})
+	env.invitations.invitations[expired.ID].ExpiresAt = time.Now().Add(-time.Minute)
+
+	invitations, _ := env.service.ListInvitations(ctx, testOwner, env.company.ID)
+	if len(invitations) != 1 || invitations[0].ID != revoked.ID {
+		t.Errorf("Ожидается одно действующее приглашение, получено: %+v", invitations)
+	}
+
+	if err := env.service.RevokeInvitation(ctx, testOwner, env.company.ID, 42); err != ErrInvitationNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего приглашения, получено: %v", err)
+	}
+	if err := env.service.RevokeInvitation(ctx, testOwner, env.company.ID, revoked.ID); err != nil {
+		t.Fatalf("Ожидается успешный отзыв, получено: %v", err)
+	}
+	if err := env.service.RevokeInvitation(ctx, testOwner, env.company.ID, revoked.ID); err != ErrInvitationClosed {
+		t.Errorf("Повторный отзыв невозможен, получено: %v", err)
+	}
+
+	for i, message := range env.mailer.messages {
+		if _, err := env.service.AcceptInvitation(ctx, 2, tokenFromMessage(t, message)); err != ErrInvitationClosed {
+			t.Errorf("Отозванное или истекшее приглашение %d принять нельзя, получено: %v", i+1, err)
+		}
+	}
+	if member, _ := env.members.GetMember(ctx, env.company.ID, 2); member != nil {
+		t.Errorf("Пользователь не должен попасть в команду: %+v", member)
+	}
+}
+
+func TestManageMembers(t *testing.T) {
+	env := newTestMembershipService(t)
+	ctx := context.Background()
+	env.members.SaveMember(ctx, &models.CompanyMember{CompanyID: env.company.ID, UserID: 2, Role: models.CompanyRoleAnalyst})
+	analyst := &models.TokenClaims{UserID: 2}
+
+	if members, err := env.service.ListMembers(ctx, analyst, env.company.ID); err != nil || len(members) != 2 {
+		t.Errorf("Участник видит команду, получено: %+v, %v", members, err)
+	}
+	if _, err := env.service.ListMembers(ctx, &models.TokenClaims{UserID: 3}, env.company.ID); err != ErrNotCompanyMember {
+		t.Errorf("Посторонний не видит команду, получено: %v", err)
+	}
+	if _, err := env.service.UpdateMemberRole(ctx, analyst, env.company.ID, 2, models.CompanyRoleOwner); err != ErrNotCompanyOwner {
+		t.Errorf("Аналитик не может менять роли, получено: %v", err)
+	}
+	if _, err := env.service.UpdateMemberRole(ctx, testOwner, env.company.ID, 1, models.CompanyRoleManager); err != ErrLastOwner {
+		t.Errorf("Ожидается защита последнего владельца, получено: %v", err)
+	}
+
+	// Смена роли завершает сессии участника, чтобы токены с прежней ролью перестали действовать
+	resp, _ := env.tokenService.IssueTokens(ctx, env.users.usersById[2], models.ClientInfo{})
+	if _, err := env.service.UpdateMemberRole(ctx, testOwner, env.company.ID, 2, models.CompanyRoleOwner); err != nil {
+		t.Fatalf("Ожидается успешная смена роли, получено: %v", err)
+	}
+	if _, err := env.tokenService.ValidateAccessToken(ctx, resp.Token); err != ErrTokenRevoked {
+		t.Errorf("Токен с прежней ролью должен отклоняться, получено: %v", err)
+	}
+	if env.users.usersById[2].Role != models.RoleCompanyOwner {
+		t.Errorf("Новый владелец должен получить роль company_owner, получено: %s", env.users.usersById[2].Role)
+	}
+
+	// Теперь владельцев двое, и первый может выйти из команды
+	if err := env.service.RemoveMember(ctx, &models.TokenClaims{UserID: 1}, env.company.ID, 1); err != nil {
+		t.Fatalf("Ожидается выход из команды, получено: %v", err)
+	}
+	if env.users.usersById[1].Role != models.RoleUser {
+		t.Errorf("Бывший владелец должен снова получить роль user, получено: %s", env.users.usersById[1].Role)
+	}
+	if err := env.service.RemoveMember(ctx, analyst, env.company.ID, 2); err != ErrLastOwner {
+		t.Errorf("Последний владелец не может выйти из команды, получено: %v", err)
+	}
+	if err := env.service.RemoveMember(ctx, analyst, env.company.ID, 42); err != ErrCompanyMemberNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего участника, получено: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/totp"
+	"user-service/tracing"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("двухфакторная аутентификация уже включена")
+	ErrMFANotEnabled     = errors.New("двухфакторная аутентификация не включена")
+	ErrMFANotEnrolled    = errors.New("сначала начните подключение двухфакторной аутентификации")
+	ErrInvalidMFACode    = errors.New("неверный код подтверждения")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAService управляет TOTP-аутентификацией (RFC 6238) и вторым шагом входа
+type MFAService struct {
+	userRepo     repository.UserRepositoryInterface
+	recoveryRepo repository.RecoveryCodeRepositoryInterface
+	consumedRepo repository.ConsumedTokenRepositoryInterface
+	signer       *ActionTokenSigner
+	issuer       string
+	challengeTTL time.Duration
+}
+
+// NewMFAService создает сервис 2FA. issuer отображается в приложении-аутентификаторе,
+// challengeTTL - сколько действует токен между первым и вторым шагом входа.
+func NewMFAService(userRepo repository.UserRepositoryInterface, recoveryRepo repository.RecoveryCodeRepositoryInterface, consumedRepo repository.ConsumedTokenRepositoryInterface, signer *ActionTokenSigner, issuer string, challengeTTL time.Duration) *MFAService {
+	return &MFAService{
+		userRepo:     userRepo,
+		recoveryRepo: recoveryRepo,
+		consumedRepo: consumedRepo,
+		signer:       signer,
+		issuer:       issuer,
+		challengeTTL: challengeTTL,
+	}
+}
+
+// Enroll создает новый секрет. 2FA включается только после Confirm, до этого секрет можно
+// перевыпустить повторным вызовом.
+func (s *MFAService) Enroll(ctx context.Context, userID uint) (*models.TOTPEnrollment, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Enroll")
+	defer span.End()
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return &models.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.issuer, user.Login, secret),
+	}, nil
+}
+
+// Confirm включает 2FA по первому коду из аутентификатора и возвращает коды восстановления
+func (s *MFAService) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Confirm")
+	defer span.End()
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	step, ok := totp.Validate(user.TOTPSecret, normalizeCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	// Коды сохраняются до включения 2FA, чтобы пользователь не остался без них
+	codes, err := s.replaceRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable выключает 2FA; нужен действующий код из аутентификатора или код восстановления
+func (s *MFAService) Disable(ctx context.Context, userID uint, code string) error {
+	ctx, span := tracing.Start(ctx, "MFAService.Disable")
+	defer span.End()
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	return s.recoveryRepo.DeleteRecoveryCodes(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes заменяет все коды восстановления новыми
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.RegenerateRecoveryCodes")
+	defer span.End()
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, user.ID)
+}
+
+// IssueChallenge выдает токен второго шага входа. Токен привязан к текущему паролю,
+// поэтому смена пароля делает недействительными незавершенные входы.
+func (s *MFAService) IssueChallenge(ctx context.Context, user *models.User) (string, error) {
+	token, _, err := s.signer.Sign(models.PurposeMFAChallenge, user.ID, passwordFingerprint(user), s.challengeTTL)
+	return token, err
+}
+
+// CompleteChallenge проверяет токен первого шага и код. При неверном коде токен остается
+// действительным до истечения срока, при успехе гасится.
+func (s *MFAService) CompleteChallenge(ctx context.Context, token, code string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.CompleteChallenge")
+	defer span.End()
+
+	user, claims, err := s.challenge(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	consumed, err := s.consumedRepo.ConsumeToken(ctx, &models.ConsumedToken{
+		JTI:       claims.JTI,
+		Purpose:   claims.Purpose,
+		ExpiresAt: claims.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidActionToken
+	}
+	return user, nil
+}
+
+func (s *MFAService) ChallengeUser(ctx context.Context, token string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.ChallengeUser")
+	defer span.End()
+
+	user, _, err := s.challenge(ctx, token)
+	return user, err
+}
+
+// challenge проверяет подпись токена второго шага и что он выдан для текущего пароля
+func (s *MFAService) challenge(ctx context.Context, token string) (*models.User, *actionClaims, error) {
+	claims, err := s.signer.Parse(models.PurposeMFAChallenge, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil || !user.TOTPEnabled || passwordFingerprint(user) != claims.Subject {
+		return nil, nil, ErrInvalidActionToken
+	}
+	return user, claims, nil
+}
+
+// verifyCode принимает код из аутентификатора (каждый не более одного раза) или код восстановления
+func (s *MFAService) verifyCode(ctx context.Context, user *models.User, code string) error {
+	code = normalizeCode(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		// Шаг сдвигается условным обновлением: из параллельных запросов с одним кодом проходит один
+		advanced, err := s.userRepo.AdvanceTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrInvalidMFACode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	used, err := s.recoveryRepo.UseRecoveryCode(ctx, user.ID, hashToken(code), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	log.Printf("[SECURITY] Пользователь %d использовал код восстановления 2FA", user.ID)
+	return nil
+}
+
+func (s *MFAService) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))
+		codes[i] = code[:4] + "-" + code[4:]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashToken(code)}
+	}
+	if err := s.recoveryRepo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *MFAService) getUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// normalizeCode убирает пробелы и дефисы, которые пользователи вводят вместе с кодом
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+var _ MFAServiceInterface = (*MFAService)(nil)
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/totp"
+)
+
+type MockRecoveryCodeRepository struct {
+	codes map[uint][]models.RecoveryCode
+}
+
+var _ repository.RecoveryCodeRepositoryInterface = (*MockRecoveryCodeRepository)(nil)
+
+func (r *MockRecoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	r.codes[userID] = codes
+	return nil
+}
+
+func (r *MockRecoveryCodeRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string, usedAt time.Time) (bool, error) {
+	for i := range r.codes[userID] {
+		code := &r.codes[userID][i]
+		if code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockRecoveryCodeRepository) DeleteRecoveryCodes(ctx context.Context, userID uint) error {
+	delete(r.codes, userID)
+	return nil
+}
+
+func newTestMFAService(mockRepo *MockUserRepository) *MFAService {
+	return NewMFAService(mockRepo, &MockRecoveryCodeRepository{codes: make(map[uint][]models.RecoveryCode)},
+		&MockConsumedTokenRepository{consumed: make(map[string]bool)}, NewActionTokenSigner("test_secret"), "SOAProject", 5*time.Minute)
+}
+
+// enableTestMFA подключает 2FA пользователю 1 и возвращает секрет и коды восстановления
+func enableTestMFA(t *testing.T, service *MFAService) (string, []string) {
+	enrollment, err := service.Enroll(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Ожидается успешное подключение 2FA, получено: %v", err)
+	}
+	// Код прошлого шага, чтобы следующий код в тесте был принят как новый
+	code, _ := totp.Code(enrollment.Secret, time.Now().Add(-totp.Period))
+	codes, err := service.Confirm(context.Background(), 1, code)
+	if err != nil {
+		t.Fatalf("Ожидается успешное подтверждение 2FA, получено: %v", err)
+	}
+	return enrollment.Secret, codes
+}
+
+func TestMFAEnrollment(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	service := newTestMFAService(mockRepo)
+	mockRepo.CreateUser(context.Background(), &models.User{Login: "testuser", Password: "hash"})
+
+	enrollment, err := service.Enroll(context.Background(), 1)
+	if err != nil || enrollment.Secret == "" || enrollment.ProvisioningURI == "" {
+		t.Fatalf("Ожидается секрет и ссылка для QR-кода, получено: %+v, %v", enrollment, err)
+	}
+	if mockRepo.usersById[1].TOTPEnabled {
+		t.Error("2FA не должна включаться до подтверждения кодом")
+	}
+
+	if _, err := service.Confirm(context.Background(), 1, "000000"); err != ErrInvalidMFACode {
+		t.Errorf("Ожидается ошибка неверного кода, получено: %v", err)
+	}
+
+	code, _ := totp.Code(enrollment.Secret, time.Now())
+	codes, err := service.Confirm(context.Background(), 1, code)
+	if err != nil {
+		t.Fatalf("Ожидается успешное подтверждение, получено: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("Ожидается %d кодов восстановления, получено: %d", recoveryCodeCount, len(codes))
+	}
+	if !mockRepo.usersById[1].TOTPEnabled {
+		t.Error("2FA должна быть включена")
+	}
+	for _, stored := range service.recoveryRepo.(*MockRecoveryCodeRepository).codes[1] {
+		for _, plain := range codes {
+			if stored.CodeHash == plain || stored.CodeHash == normalizeCode(plain) {
+				t.Fatal("Коды восстановления должны храниться только в виде хэша")
+			}
+		}
+	}
+
+	if _, err := service.Enroll(context.Background(), 1); err != ErrMFAAlreadyEnabled {
+		t.Errorf("Ожидается ошибка повторного подключения, получено: %v", err)
+	}
+}
+
+func TestMFALogin(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	service := newTestUserService(t, mockRepo)
+	service.Register(context.Background(), models.RegisterRequest{Login: "testuser", Password: "password123", Email: "test@example.com"})
+	mfa := service.mfa.(*MFAService)
+	secret, recoveryCodes := enableTestMFA(t, mfa)
+
+	resp, err := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Ожидается первый шаг входа, получено: %v", err)
+	}
+	if !resp.MFARequired || resp.MFAToken == "" || resp.Token != "" || resp.RefreshToken != "" {
+		t.Fatalf("При включенной 2FA токены не выдаются до второго шага, получено: %+v", resp)
+	}
+	if _, err := service.ValidateToken(context.Background(), resp.MFAToken); err == nil {
+		t.Error("Токен второго шага не должен приниматься как access-токен")
+	}
+
+	if _, err := service.LoginMFA(context.Background(), models.MFALoginRequest{MFAToken: resp.MFAToken, Code: "000000"}, models.ClientInfo{}); err != ErrInvalidMFACode {
+		t.Errorf("Ожидается ошибка неверного кода, получено: %v", err)
+	}
+
+	code, _ := totp.Code(secret, time.Now())
+	tokens, err := service.LoginMFA(context.Background(), models.MFALoginRequest{MFAToken: resp.MFAToken, Code: code}, models.ClientInfo{})
+	if err != nil || tokens.Token == "" {
+		t.Fatalf("Ожидается выдача токенов после второго шага, получено: %+v, %v", tokens, err)
+	}
+	if _, err := service.ValidateToken(context.Background(), tokens.Token); err != nil {
+		t.Errorf("Выданный токен должен быть действительным, получено: %v", err)
+	}
+
+	// Код и токен второго шага одноразовые
+	second, _ := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+	if _, err := service.LoginMFA(context.Background(), models.MFALoginRequest{MFAToken: second.MFAToken, Code: code}, models.ClientInfo{}); err != ErrInvalidMFACode {
+		t.Errorf("Повторное использование кода должно отклоняться, получено: %v", err)
+	}
+	if _, err := service.LoginMFA(context.Background(), models.MFALoginRequest{MFAToken: resp.MFAToken, Code: recoveryCodes[0]}, models.ClientInfo{}); err != ErrInvalidActionToken {
+		t.Errorf("Повторное использование токена второго шага должно отклоняться, получено: %v", err)
+	}
+
+	if _, err := service.LoginMFA(context.Background(), models.MFALoginRequest{MFAToken: second.MFAToken, Code: recoveryCodes[1]}, models.ClientInfo{}); err != nil {
+		t.Errorf("Ожидается вход по коду восстановления, получено: %v", err)
+	}
+	third, _ := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+	if _, err := service.LoginMFA(context.Background(), models.MFALoginRequest{MFAToken: third.MFAToken, Code: recoveryCodes[1]}, models.ClientInfo{}); err != ErrInvalidMFACode {
+		t.Errorf("Код восстановления одноразовый, получено: %v", err)
+	}
+}
+
+func TestMFACodeReplayRace(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	service := newTestMFAService(mockRepo)
+	mockRepo.CreateUser(context.Background(), &models.User{Login: "testuser", Password: "hash"})
+	secret, _ := enableTestMFA(t, service)
+
+	// Оба запроса прочитали пользователя до того, как первый принял код
+	stale := *mockRepo.usersById[1]
+	code, _ := totp.Code(secret, time.Now())
+	if err := service.verifyCode(context.Background(), mockRepo.usersById[1], code); err != nil {
+		t.Fatalf("Ожидается принятие кода, получено: %v", err)
+	}
+	if err := service.verifyCode(context.Background(), &stale, code); err != ErrInvalidMFACode {
+		t.Errorf("Параллельный запрос с тем же кодом должен отклоняться, получено: %v", err)
+	}
+}
+
+func TestMFADisable(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	service := newTestMFAService(mockRepo)
+	mockRepo.CreateUser(context.Background(), &models.User{Login: "testuser", Password: "hash"})
+	_, recoveryCodes := enableTestMFA(t, service)
+
+	if err := service.Disable(context.Background(), 1, "000000"); err != ErrInvalidMFACode {
+		t.Errorf("Ожидается ошибка неверного кода, получено: %v", err)
+	}
+	if err := service.Disable(context.Background(), 1, recoveryCodes[0]); err != nil {
+		t.Fatalf("Ожидается выключение 2FA кодом восстановления, получено: %v", err)
+	}
+	user := mockRepo.usersById[1]
+	if user.TOTPEnabled || user.TOTPSecret != "" {
+		t.Error("Секрет должен быть удален после выключения 2FA")
+	}
+	if err := service.Disable(context.Background(), 1, recoveryCodes[1]); err != ErrMFANotEnabled {
+		t.Errorf("Ожидается ошибка выключенной 2FA, получено: %v", err)
+	}
+}
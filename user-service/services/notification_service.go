@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"user-service/mailer"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/tracing"
+)
+
+// NotificationService отправляет письма пользователям по запросам других сервисов,
+// у которых нет адресов пользователей
+type NotificationService struct {
+	userRepo repository.UserRepositoryInterface
+	mailer   mailer.Mailer
+}
+
+func NewNotificationService(userRepo repository.UserRepositoryInterface, m mailer.Mailer) *NotificationService {
+	return &NotificationService{userRepo: userRepo, mailer: m}
+}
+
+func (s *NotificationService) NotifyUser(ctx context.Context, userID uint, req models.NotificationRequest) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.NotifyUser")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	return s.mailer.Send(ctx, mailer.Message{To: user.Email, Subject: req.Subject, Body: req.Body})
+}
+
+var _ NotificationServiceInterface = (*NotificationService)(nil)
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"user-service/models"
+)
+
+func TestNotifyUser(t *testing.T) {
+	users := NewMockUserRepository()
+	users.CreateUser(context.Background(), &models.User{Login: "author", Email: "author@example.com"})
+	mailer := &MockMailer{}
+	service := NewNotificationService(users, mailer)
+	req := models.NotificationRequest{Subject: "Промокод COFFEE10 прошел модерацию", Body: "Здравствуйте!"}
+
+	if err := service.NotifyUser(context.Background(), 1, req); err != nil {
+		t.Fatalf("Ожидается успешная отправка, получено: %v", err)
+	}
+	if len(mailer.messages) != 1 || mailer.messages[0].To != "author@example.com" || mailer.messages[0].Subject != req.Subject {
+		t.Errorf("Письмо должно уйти на адрес пользователя: %+v", mailer.messages)
+	}
+	if err := service.NotifyUser(context.Background(), 42, req); err != ErrUserNotFound {
+		t.Errorf("Ожидается ошибка отсутствующего пользователя, получено: %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/tracing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("недействительный refresh-токен")
+	ErrTokenRevoked        = errors.New("токен отозван")
+	ErrSessionNotFound     = errors.New("сессия не найдена")
+)
+
+type TokenService struct {
+	tokenRepo   repository.TokenRepositoryInterface
+	sessionRepo repository.SessionRepositoryInterface
+	userRepo    repository.UserRepositoryInterface
+	memberRepo  repository.CompanyMemberRepositoryInterface
+	keys        KeyServiceInterface
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+}
+
+func NewTokenService(tokenRepo repository.TokenRepositoryInterface, sessionRepo repository.SessionRepositoryInterface, userRepo repository.UserRepositoryInterface, memberRepo repository.CompanyMemberRepositoryInterface, keys KeyServiceInterface, accessTTL, refreshTTL time.Duration) *TokenService {
+	return &TokenService{
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		memberRepo:  memberRepo,
+		keys:        keys,
+		accessTTL:   accessTTL,
+		refreshTTL:  refreshTTL,
+	}
+}
+
+func (s *TokenService) IssueTokens(ctx context.Context, user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.IssueTokens")
+	defer span.End()
+
+	sessionID, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	err = s.sessionRepo.CreateSession(ctx, &models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		LastUsedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, sessionID)
+}
+
+// Refresh обменивает refresh-токен на новую пару. Каждый refresh-токен одноразовый:
+// повторное предъявление уже ротированного токена означает его утечку, поэтому отзывается вся сессия.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Refresh")
+	defer span.End()
+
+	token, err := s.tokenRepo.GetTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		metrics.TokenRefreshes.WithLabelValues("failure").Inc()
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.RevokedAt != nil {
+		metrics.TokenRefreshes.WithLabelValues("failure").Inc()
+		return nil, ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	marked := false
+	if token.UsedAt == nil {
+		marked, err = s.tokenRepo.MarkTokenUsed(ctx, token.ID, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !marked {
+		log.Printf("[SECURITY] Повторное использование refresh-токена пользователя %d, сессия %s завершена", token.UserID, token.FamilyID)
+		metrics.TokenRefreshes.WithLabelValues("reuse").Inc()
+		if err := s.revokeSession(ctx, token.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Роли берутся заново, чтобы их изменение вступало в силу при обновлении токена
+	user, err := s.userRepo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Status == models.UserStatusBlocked {
+		metrics.TokenRefreshes.WithLabelValues("failure").Inc()
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.sessionRepo.TouchSession(ctx, session.ID, now); err != nil {
+		return nil, err
+	}
+	resp, err := s.issue(ctx, user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	metrics.TokenRefreshes.WithLabelValues("success").Inc()
+	return resp, nil
+}
+
+func (s *TokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*models.TokenClaims, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ValidateAccessToken")
+	defer span.End()
+
+	claims, err := s.parse(ctx, tokenString)
+	if err != nil {
+		metrics.TokenValidationFailures.Inc()
+		return nil, err
+	}
+
+	revoked, err := s.sessionRepo.IsTokenRevoked(ctx, claims.JTI)
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.sessionRepo.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked || session == nil || session.RevokedAt != nil || session.UserID != claims.UserID {
+		metrics.TokenValidationFailures.Inc()
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+func (s *TokenService) Logout(ctx context.Context, claims *models.TokenClaims) error {
+	ctx, span := tracing.Start(ctx, "TokenService.Logout")
+	defer span.End()
+
+	if err := s.sessionRepo.RevokeToken(ctx, claims.JTI, claims.ExpiresAt); err != nil {
+		return err
+	}
+	return s.revokeSession(ctx, claims.SessionID, time.Now())
+}
+
+func (s *TokenService) LogoutAll(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "TokenService.LogoutAll")
+	defer span.End()
+
+	return s.sessionRepo.RevokeUserSessions(ctx, userID, time.Now())
+}
+
+func (s *TokenService) ListSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.Session, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ListSessions")
+	defer span.End()
+
+	sessions, err := s.sessionRepo.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+func (s *TokenService) RevokeSession(ctx context.Context, userID uint, sessionID string) error {
+	ctx, span := tracing.Start(ctx, "TokenService.RevokeSession")
+	defer span.End()
+
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	// Чужая сессия неотличима от несуществующей
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+	return s.revokeSession(ctx, sessionID, time.Now())
+}
+
+func (s *TokenService) revokeSession(ctx context.Context, sessionID string, now time.Time) error {
+	if err := s.sessionRepo.RevokeSession(ctx, sessionID, now); err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeFamily(ctx, sessionID, now)
+}
+
+func (s *TokenService) parse(ctx context.Context, tokenString string) (*models.TokenClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, errors.New("неожиданный алгоритм подписи")
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys.PublicKey(ctx, kid)
+		if !ok {
+			return nil, errors.New("неизвестный ключ подписи")
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("недействительный токен")
+	}
+	userID, _ := claims["user_id"].(float64)
+	sessionID, _ := claims["sid"].(string)
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	emailVerified, _ := claims["email_verified"].(bool)
+	if userID == 0 || sessionID == "" || jti == "" {
+		return nil, errors.New("недействительный токен")
+	}
+	var roles []models.Role
+	if values, ok := claims["roles"].([]interface{}); ok {
+		for _, value := range values {
+			if role, ok := value.(string); ok {
+				roles = append(roles, models.Role(role))
+			}
+		}
+	}
+	companies := make(map[uint]models.CompanyRole)
+	if values, ok := claims["companies"].(map[string]interface{}); ok {
+		for key, value := range values {
+			companyID, err := strconv.ParseUint(key, 10, 64)
+			role, ok := value.(string)
+			if err == nil && ok {
+				companies[uint(companyID)] = models.CompanyRole(role)
+			}
+		}
+	}
+	var segments []string
+	if values, ok := claims["segments"].([]interface{}); ok {
+		for _, value := range values {
+			if segment, ok := value.(string); ok {
+				segments = append(segments, segment)
+			}
+		}
+	}
+	return &models.TokenClaims{
+		UserID:        uint(userID),
+		Roles:         roles,
+		EmailVerified: emailVerified,
+		SessionID:     sessionID,
+		JTI:           jti,
+		ExpiresAt:     time.Unix(int64(exp), 0),
+		Companies:     companies,
+		Segments:      segments,
+	}, nil
+}
+
+func (s *TokenService) issue(ctx context.Context, user *models.User, sessionID string) (*models.LoginResponse, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	kid, privateKey, err := s.keys.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+	// Роли в командах компаний передаются в токене, чтобы шлюз и другие сервисы
+	// проверяли доступ к данным компании без обращения к user-service
+	memberships, err := s.memberRepo.ListUserMemberships(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	companies := make(map[string]models.CompanyRole, len(memberships))
+	for _, membership := range memberships {
+		companies[strconv.FormatUint(uint64(membership.CompanyID), 10)] = membership.Role
+	}
+
+	now := time.Now()
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Header["kid"] = kid
+	claims := token.Claims.(jwt.MapClaims)
+	claims["user_id"] = user.ID
+	claims["roles"] = []models.Role{user.Role}
+	claims["email_verified"] = user.EmailVerified
+	claims["companies"] = companies
+	claims["segments"] = models.NormalizeSegments(user.Segments)
+	claims["sid"] = sessionID
+	claims["jti"] = jti
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(s.accessTTL).Unix()
+
+	accessToken, err := token.SignedString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	err = s.tokenRepo.CreateToken(ctx, &models.AuthToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  sessionID,
+		ExpiresAt: now.Add(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+	}, nil
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var _ TokenServiceInterface = (*TokenService)(nil)
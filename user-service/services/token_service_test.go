@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/metrics"
+	"user-service/models"
+	"user-service/repository"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type MockTokenRepository struct {
+	tokens    map[string]*models.AuthToken
+	idCounter uint
+}
+
+var _ repository.TokenRepositoryInterface = (*MockTokenRepository)(nil)
+
+func NewMockTokenRepository() *MockTokenRepository {
+	return &MockTokenRepository{
+		tokens:    make(map[string]*models.AuthToken),
+		idCounter: 1,
+	}
+}
+
+func (r *MockTokenRepository) CreateToken(ctx context.Context, token *models.AuthToken) error {
+	token.ID = r.idCounter
+	r.idCounter++
+	r.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (r *MockTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.AuthToken, error) {
+	token, exists := r.tokens[tokenHash]
+	if !exists {
+		return nil, nil
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (r *MockTokenRepository) MarkTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil && token.RevokedAt == nil {
+			token.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockTokenRepository) RevokeFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+type MockSessionRepository struct {
+	sessions map[string]*models.Session
+	revoked  map[string]time.Time
+}
+
+var _ repository.SessionRepositoryInterface = (*MockSessionRepository)(nil)
+
+func NewMockSessionRepository() *MockSessionRepository {
+	return &MockSessionRepository{
+		sessions: make(map[string]*models.Session),
+		revoked:  make(map[string]time.Time),
+	}
+}
+
+func (r *MockSessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	r.sessions[session.ID] = session
+	return nil
+}
+
+func (r *MockSessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	session, exists := r.sessions[id]
+	if !exists {
+		return nil, nil
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *MockSessionRepository) ListActiveSessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *MockSessionRepository) TouchSession(ctx context.Context, id string, usedAt time.Time) error {
+	if session, exists := r.sessions[id]; exists {
+		session.LastUsedAt = usedAt
+	}
+	return nil
+}
+
+func (r *MockSessionRepository) RevokeSession(ctx context.Context, id string, revokedAt time.Time) error {
+	if session, exists := r.sessions[id]; exists && session.RevokedAt == nil {
+		session.RevokedAt = &revokedAt
+	}
+	return nil
+}
+
+func (r *MockSessionRepository) RevokeUserSessions(ctx context.Context, userID uint, revokedAt time.Time) error {
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *MockSessionRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.revoked[jti] = expiresAt
+	return nil
+}
+
+func (r *MockSessionRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	_, exists := r.revoked[jti]
+	return exists, nil
+}
+
+// newTestUsers возвращает репозиторий с пользователем 1, которому выдаются токены в тестах
+func newTestUsers() *MockUserRepository {
+	users := NewMockUserRepository()
+	users.CreateUser(context.Background(), &models.User{Login: "testuser", Role: models.RoleModerator})
+	return users
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	tokenRepo := NewMockTokenRepository()
+	service := NewTokenService(tokenRepo, NewMockSessionRepository(), newTestUsers(), NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+
+	first, err := service.IssueTokens(context.Background(), &models.User{ID: 1, Role: models.RoleUser}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Ожидается выдача токенов, получена ошибка: %v", err)
+	}
+	for hash := range tokenRepo.tokens {
+		if hash == first.RefreshToken {
+			t.Error("Refresh-токен не должен храниться в открытом виде")
+		}
+	}
+
+	second, err := service.Refresh(context.Background(), first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Ожидается успешное обновление, получена ошибка: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("При обновлении должен выдаваться новый refresh-токен")
+	}
+	if claims, err := service.ValidateAccessToken(context.Background(), second.Token); err != nil || claims.UserID != 1 {
+		t.Errorf("Ожидается действительный access-токен пользователя 1, получено: %v, %v", claims, err)
+	} else if !claims.HasRole(models.RoleModerator) || claims.HasRole(models.RoleUser) {
+		t.Errorf("При обновлении роли должны браться из профиля пользователя, получено: %v", claims.Roles)
+	}
+
+	reuseBefore := testutil.ToFloat64(metrics.TokenRefreshes.WithLabelValues("reuse"))
+
+	// Повторное использование ротированного токена завершает сессию, включая выданные после него токены
+	if _, err := service.Refresh(context.Background(), first.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("Ожидается ошибка повторного использования, получено: %v", err)
+	}
+	if _, err := service.Refresh(context.Background(), second.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("После обнаружения повторного использования семейство должно быть отозвано, получено: %v", err)
+	}
+	if _, err := service.ValidateAccessToken(context.Background(), second.Token); err != ErrTokenRevoked {
+		t.Errorf("Access-токен завершенной сессии должен отклоняться, получено: %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.TokenRefreshes.WithLabelValues("reuse")) - reuseBefore; got != 1 {
+		t.Errorf("Ожидается 1 повторное использование в метриках, получено: %v", got)
+	}
+
+	if _, err := service.Refresh(context.Background(), "unknown"); err != ErrInvalidRefreshToken {
+		t.Errorf("Ожидается ошибка для неизвестного токена, получено: %v", err)
+	}
+}
+
+func TestRefreshTokenExpired(t *testing.T) {
+	tokenRepo := NewMockTokenRepository()
+	service := NewTokenService(tokenRepo, NewMockSessionRepository(), newTestUsers(), NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, -time.Minute)
+
+	resp, _ := service.IssueTokens(context.Background(), &models.User{ID: 1, Role: models.RoleUser}, models.ClientInfo{})
+	if _, err := service.Refresh(context.Background(), resp.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("Ожидается ошибка для просроченного токена, получено: %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	sessionRepo := NewMockSessionRepository()
+	service := NewTokenService(NewMockTokenRepository(), sessionRepo, newTestUsers(), NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+
+	phone, _ := service.IssueTokens(context.Background(), &models.User{ID: 1, Role: models.RoleUser}, models.ClientInfo{IP: "10.0.0.1", UserAgent: "phone"})
+	laptop, _ := service.IssueTokens(context.Background(), &models.User{ID: 1, Role: models.RoleUser}, models.ClientInfo{IP: "10.0.0.2", UserAgent: "laptop"})
+
+	claims, err := service.ValidateAccessToken(context.Background(), phone.Token)
+	if err != nil {
+		t.Fatalf("Ожидается действительный токен, получено: %v", err)
+	}
+	sessions, _ := service.ListSessions(context.Background(), 1, claims.SessionID)
+	if len(sessions) != 2 {
+		t.Fatalf("Ожидается 2 активные сессии, получено: %d", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.Current != (session.UserAgent == "phone") {
+			t.Errorf("Текущей должна быть только сессия телефона: %+v", session)
+		}
+	}
+
+	if err := service.Logout(context.Background(), claims); err != nil {
+		t.Fatalf("Ожидается успешный выход, получено: %v", err)
+	}
+	if _, exists := sessionRepo.revoked[claims.JTI]; !exists {
+		t.Error("jti должен попасть в список отозванных токенов")
+	}
+	if _, err := service.ValidateAccessToken(context.Background(), phone.Token); err != ErrTokenRevoked {
+		t.Errorf("Ожидается отклонение токена после выхода, получено: %v", err)
+	}
+	if _, err := service.Refresh(context.Background(), phone.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Errorf("Refresh-токен завершенной сессии должен отклоняться, получено: %v", err)
+	}
+	if _, err := service.ValidateAccessToken(context.Background(), laptop.Token); err != nil {
+		t.Errorf("Другие сессии не должны затрагиваться, получено: %v", err)
+	}
+
+	if err := service.LogoutAll(context.Background(), 1); err != nil {
+		t.Fatalf("Ожидается успешное завершение всех сессий, получено: %v", err)
+	}
+	if _, err := service.ValidateAccessToken(context.Background(), laptop.Token); err != ErrTokenRevoked {
+		t.Errorf("После выхода со всех устройств токен должен отклоняться, получено: %v", err)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	service := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), newTestUsers(), NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+
+	resp, _ := service.IssueTokens(context.Background(), &models.User{ID: 1, Role: models.RoleUser}, models.ClientInfo{})
+	claims, _ := service.ValidateAccessToken(context.Background(), resp.Token)
+
+	if err := service.RevokeSession(context.Background(), 2, claims.SessionID); err != ErrSessionNotFound {
+		t.Errorf("Чужую сессию завершить нельзя, получено: %v", err)
+	}
+	if err := service.RevokeSession(context.Background(), 1, claims.SessionID); err != nil {
+		t.Errorf("Ожидается успешное завершение сессии, получено: %v", err)
+	}
+	if _, err := service.ValidateAccessToken(context.Background(), resp.Token); err != ErrTokenRevoked {
+		t.Errorf("Ожидается отклонение токена завершенной сессии, получено: %v", err)
+	}
+}
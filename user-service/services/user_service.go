@@ -1,31 +1,53 @@
 package services
 
 import (
+	"context"
 	"errors"
-	"time"
+	"log"
+	"user-service/metrics"
 	"user-service/models"
 	"user-service/repository"
-
-	"github.com/dgrijalva/jwt-go"
+	"user-service/tracing"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrUserNotFound       = errors.New("пользователь не найден")
+	ErrUnknownRole        = errors.New("неизвестная роль")
+	ErrUserBlocked        = errors.New("учетная запись заблокирована")
+	ErrWrongPassword      = errors.New("неверный текущий пароль")
+	ErrInvalidCredentials = errors.New("неверный логин или пароль")
+)
+
+// dummyPasswordHash сверяется с паролем при входе под несуществующим логином,
+// чтобы время ответа не выдавало, есть ли такая учетная запись
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
 type UserService struct {
-    userRepo    repository.UserRepositoryInterface
-    jwtSecret   []byte
-    tokenExpiry time.Duration
+    userRepo     repository.UserRepositoryInterface
+    tokenService TokenServiceInterface
+    verification VerificationServiceInterface
+    mfa          MFAServiceInterface
+    throttler    LoginThrottlerInterface
+    policy       models.VerificationPolicy
 }
 
-func NewUserService(userRepo repository.UserRepositoryInterface, jwtSecret string, tokenExpiry time.Duration) *UserService {
+func NewUserService(userRepo repository.UserRepositoryInterface, tokenService TokenServiceInterface, verification VerificationServiceInterface, mfa MFAServiceInterface, throttler LoginThrottlerInterface, policy models.VerificationPolicy) *UserService {
     return &UserService{
-        userRepo:    userRepo,
-        jwtSecret:   []byte(jwtSecret),
-        tokenExpiry: tokenExpiry,
+        userRepo:     userRepo,
+        tokenService: tokenService,
+        verification: verification,
+        mfa:          mfa,
+        throttler:    throttler,
+        policy:       policy,
     }
 }
 
-func (s *UserService) Register(req models.RegisterRequest) error {
-	existingUser, err := s.userRepo.GetUserByLogin(req.Login)
+func (s *UserService) Register(ctx context.Context, req models.RegisterRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.Register")
+	defer span.End()
+
+	existingUser, err := s.userRepo.GetUserByLogin(ctx, req.Login)
 	if err != nil {
 		return err
 	}
@@ -42,51 +64,149 @@ func (s *UserService) Register(req models.RegisterRequest) error {
 		Login:    req.Login,
 		Password: string(hashedPassword),
 		Email:    req.Email,
+		Role:     models.RoleUser,
+		Status:   models.UserStatusActive,
 	}
 
-	return s.userRepo.CreateUser(user)
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		return err
+	}
+	metrics.Registrations.Inc()
+
+	// Регистрация не отменяется из-за почты: письмо можно запросить повторно
+	if err := s.verification.SendVerification(ctx, user); err != nil {
+		log.Printf("Не удалось отправить письмо подтверждения пользователю %d: %v", user.ID, err)
+	}
+	return nil
 }
 
-func (s *UserService) Login(req models.LoginRequest) (string, error) {
-	user, err := s.userRepo.GetUserByLogin(req.Login)
+func (s *UserService) Login(ctx context.Context, req models.LoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer span.End()
+
+	if err := s.throttler.Check(ctx, req.Login, client.IP); err != nil {
+		metrics.Logins.WithLabelValues("throttled").Inc()
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByLogin(ctx, req.Login)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if user == nil {
-		return "", errors.New("неверный логин или пароль")
+
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = []byte(user.Password)
+	}
+	err = bcrypt.CompareHashAndPassword(passwordHash, []byte(req.Password))
+	if err != nil || user == nil {
+		return nil, s.loginFailed(ctx, req.Login, client.IP, ErrInvalidCredentials)
+	}
+	// Статус сообщается только тому, кто знает пароль
+	if user.Status == models.UserStatusBlocked {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return nil, ErrUserBlocked
+	}
+	if !user.EmailVerified && s.policy == models.VerificationPolicyDeny {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return nil, ErrEmailNotVerified
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	if user.TOTPEnabled {
+		mfaToken, err := s.mfa.IssueChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResponse{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	if err := s.throttler.RecordSuccess(ctx, user.Login); err != nil {
+		return nil, err
+	}
+	resp, err := s.tokenService.IssueTokens(ctx, user, client)
 	if err != nil {
-		return "", errors.New("неверный логин или пароль")
+		return nil, err
 	}
 
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["user_id"] = user.ID
-	claims["exp"] = time.Now().Add(s.tokenExpiry).Unix()
+	metrics.Logins.WithLabelValues("success").Inc()
+	return resp, nil
+}
+
+func (s *UserService) LoginMFA(ctx context.Context, req models.MFALoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.LoginMFA")
+	defer span.End()
 
-	tokenString, err := token.SignedString(s.jwtSecret)
+	user, err := s.mfa.ChallengeUser(ctx, req.MFAToken)
 	if err != nil {
-		return "", err
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return nil, err
+	}
+	// Подбор кода ограничивается так же, как подбор пароля: по логину и по адресу
+	if err := s.throttler.Check(ctx, user.Login, client.IP); err != nil {
+		metrics.Logins.WithLabelValues("throttled").Inc()
+		return nil, err
+	}
+
+	login := user.Login
+	user, err = s.mfa.CompleteChallenge(ctx, req.MFAToken, req.Code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		return nil, s.loginFailed(ctx, login, client.IP, err)
+	}
+	if err != nil {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return nil, err
+	}
+	// Учетную запись могли заблокировать между шагами входа
+	if user.Status == models.UserStatusBlocked {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return nil, ErrUserBlocked
 	}
 
-	return tokenString, nil
+	if err := s.throttler.RecordSuccess(ctx, user.Login); err != nil {
+		return nil, err
+	}
+	resp, err := s.tokenService.IssueTokens(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.Logins.WithLabelValues("success").Inc()
+	return resp, nil
 }
 
-func (s *UserService) GetUserProfile(userID uint) (*models.User, error) {
-	return s.userRepo.GetUserByID(userID)
+// loginFailed учитывает неудачную попытку входа и возвращает ошибку для клиента
+func (s *UserService) loginFailed(ctx context.Context, login, ip string, err error) error {
+	metrics.Logins.WithLabelValues("failure").Inc()
+	if recordErr := s.throttler.RecordFailure(ctx, login, ip); recordErr != nil {
+		return recordErr
+	}
+	return err
 }
 
-func (s *UserService) UpdateUserProfile(userID uint, req models.UpdateProfileRequest) error {
-	user, err := s.userRepo.GetUserByID(userID)
+func (s *UserService) RefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
+	return s.tokenService.Refresh(ctx, refreshToken)
+}
+
+func (s *UserService) GetUserProfile(ctx context.Context, userID uint) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserProfile")
+	defer span.End()
+
+	return s.userRepo.GetUserByID(ctx, userID)
+}
+
+func (s *UserService) UpdateUserProfile(ctx context.Context, userID uint, req models.UpdateProfileRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUserProfile")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return err
 	}
 	if user == nil {
-		return errors.New("пользователь не найден")
+		return ErrUserNotFound
 	}
 
+	emailChanged := user.Email != req.Email
 	user.FirstName = req.FirstName
 	user.LastName = req.LastName
 	if req.BirthDate != nil {
@@ -94,25 +214,128 @@ func (s *UserService) UpdateUserProfile(userID uint, req models.UpdateProfileReq
 	}
 	user.Email = req.Email
 	user.Phone = req.Phone
+	if emailChanged {
+		// Новый адрес нужно подтвердить заново
+		user.EmailVerified = false
+		user.VerificationSentAt = nil
+	}
+
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	if emailChanged {
+		if err := s.verification.SendVerification(ctx, user); err != nil {
+			log.Printf("Не удалось отправить письмо подтверждения пользователю %d: %v", user.ID, err)
+		}
+	}
+	return nil
+}
 
-	return s.userRepo.UpdateUser(user)
+func (s *UserService) ValidateToken(ctx context.Context, tokenString string) (*models.TokenClaims, error) {
+	return s.tokenService.ValidateAccessToken(ctx, tokenString)
 }
 
-func (s *UserService) ValidateToken(tokenString string) (uint, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return s.jwtSecret, nil
-	})
+func (s *UserService) AssignRole(ctx context.Context, userID uint, role models.Role) error {
+	ctx, span := tracing.Start(ctx, "UserService.AssignRole")
+	defer span.End()
 
+	if !role.Valid() {
+		return ErrUnknownRole
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
 	}
+	if user.Role == role {
+		return nil
+	}
+
+	user.Role = role
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	return s.tokenService.LogoutAll(ctx, userID)
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID := uint(claims["user_id"].(float64))
-		return userID, nil
+// ChangePassword ограничивает подбор текущего пароля тем же счетчиком, что и вход:
+// иначе украденный access-токен позволил бы перебирать пароль без ограничений
+func (s *UserService) ChangePassword(ctx context.Context, userID uint, req models.ChangePasswordRequest, client models.ClientInfo) error {
+	ctx, span := tracing.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
 	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if err := s.throttler.Check(ctx, user.Login, client.IP); err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)) != nil {
+		if err := s.throttler.RecordFailure(ctx, user.Login, client.IP); err != nil {
+			return err
+		}
+		return ErrWrongPassword
+	}
+	if err := s.throttler.RecordSuccess(ctx, user.Login); err != nil {
+		return err
+	}
+	return s.setPassword(ctx, user, req.NewPassword)
+}
 
-	return 0, errors.New("недействительный токен")
+func (s *UserService) ForgotPassword(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ForgotPassword")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Status == models.UserStatusBlocked {
+		return nil
+	}
+	// Ошибка не должна выдавать, что адрес зарегистрирован. Письмо уходит через очередь
+	// AsyncMailer, поэтому время ответа не зависит от почтового сервера.
+	if err := s.verification.SendPasswordReset(ctx, user); err != nil {
+		log.Printf("Не удалось отправить ссылку сброса пароля пользователю %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (s *UserService) ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPassword")
+	defer span.End()
+
+	user, err := s.verification.ConsumePasswordReset(ctx, req.Token)
+	if err != nil {
+		return err
+	}
+	// Ссылка пришла на email пользователя, значит адрес ему принадлежит
+	user.EmailVerified = true
+	if err := s.setPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+	// Владелец подтвердил доступ к почте - снимаем блокировку входа после подбора пароля
+	return s.throttler.RecordSuccess(ctx, user.Login)
+}
+
+// setPassword сохраняет хэш нового пароля и завершает все сессии пользователя,
+// отзывая выданные refresh- и access-токены
+func (s *UserService) setPassword(ctx context.Context, user *models.User, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.Password = string(hashedPassword)
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	return s.tokenService.LogoutAll(ctx, user.ID)
 }
 
 var _ UserServiceInterface = (*UserService)(nil)
\ No newline at end of file
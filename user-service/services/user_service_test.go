@@ -1,11 +1,16 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
+	"user-service/metrics"
 	"user-service/models"
 	"user-service/repository" // Импортируем пакет repository
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,7 +30,7 @@ func NewMockUserRepository() *MockUserRepository {
     }
 }
 
-func (r *MockUserRepository) CreateUser(user *models.User) error {
+func (r *MockUserRepository) CreateUser(ctx context.Context, user *models.User) error {
     user.ID = r.idCounter
     r.idCounter++
     r.users[user.Login] = user
@@ -33,7 +38,7 @@ func (r *MockUserRepository) CreateUser(user *models.User) error {
     return nil
 }
 
-func (r *MockUserRepository) GetUserByLogin(login string) (*models.User, error) {
+func (r *MockUserRepository) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
     user, exists := r.users[login]
     if !exists {
         return nil, nil
@@ -41,7 +46,7 @@ func (r *MockUserRepository) GetUserByLogin(login string) (*models.User, error)
     return user, nil
 }
 
-func (r *MockUserRepository) GetUserByID(id uint) (*models.User, error) {
+func (r *MockUserRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
     user, exists := r.usersById[id]
     if !exists {
         return nil, nil
@@ -49,17 +54,73 @@ func (r *MockUserRepository) GetUserByID(id uint) (*models.User, error) {
     return user, nil
 }
 
-func (r *MockUserRepository) UpdateUser(user *models.User) error {
+func (r *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+    for _, user := range r.usersById {
+        if user.Email == email {
+            return user, nil
+        }
+    }
+    return nil, nil
+}
+
+func (r *MockUserRepository) UpdateUser(ctx context.Context, user *models.User) error {
     r.users[user.Login] = user
     r.usersById[user.ID] = user
     return nil
 }
 
+func (r *MockUserRepository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+    user, exists := r.usersById[userID]
+    if !exists || user.TOTPLastStep >= step {
+        return false, nil
+    }
+    user.TOTPLastStep = step
+    return true, nil
+}
+
+func (r *MockUserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error) {
+    var users []models.User
+    for id := uint(1); id < r.idCounter; id++ {
+        user, exists := r.usersById[id]
+        if !exists || !strings.HasPrefix(user.Login, filter.LoginPrefix) {
+            continue
+        }
+        if (filter.Role != "" && user.Role != filter.Role) || (filter.Status != "" && user.Status != filter.Status) {
+            continue
+        }
+        users = append(users, *user)
+    }
+    total := int64(len(users))
+    from := (filter.Page - 1) * filter.PageSize
+    if from > len(users) {
+        from = len(users)
+    }
+    to := from + filter.PageSize
+    if to > len(users) {
+        to = len(users)
+    }
+    return users[from:to], total, nil
+}
+
+func (r *MockUserRepository) DeleteUser(ctx context.Context, id uint) error {
+    if user, exists := r.usersById[id]; exists {
+        delete(r.users, user.Login)
+        delete(r.usersById, id)
+    }
+    return nil
+}
+
+func newTestUserService(t *testing.T, mockRepo *MockUserRepository) *UserService {
+    tokenService := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), mockRepo, NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+    verification, _ := newTestVerificationService(mockRepo)
+    return NewUserService(mockRepo, tokenService, verification, newTestMFAService(mockRepo), newTestLoginThrottler(), models.VerificationPolicyRestrict)
+}
+
 func TestRegister(t *testing.T) {
     mockRepo := NewMockUserRepository()
-    service := NewUserService(mockRepo, "test_secret", 24*time.Hour)
+    service := newTestUserService(t, mockRepo)
 
-    err := service.Register(models.RegisterRequest{
+    err := service.Register(context.Background(), models.RegisterRequest{
         Login:    "testuser",
         Password: "password123",
         Email:    "test@example.com",
@@ -69,7 +130,7 @@ func TestRegister(t *testing.T) {
         t.Errorf("Ожидается успешная регистрация, получена ошибка: %v", err)
     }
 
-    user, _ := mockRepo.GetUserByLogin("testuser")
+    user, _ := mockRepo.GetUserByLogin(context.Background(), "testuser")
     if user == nil {
         t.Error("Пользователь должен быть создан")
     }
@@ -77,7 +138,7 @@ func TestRegister(t *testing.T) {
         t.Errorf("Ожидается email test@example.com, получен: %s", user.Email)
     }
 
-    err = service.Register(models.RegisterRequest{
+    err = service.Register(context.Background(), models.RegisterRequest{
         Login:    "testuser",
         Password: "anotherpassword",
         Email:    "another@example.com",
@@ -90,7 +151,7 @@ func TestRegister(t *testing.T) {
 
 func TestLogin(t *testing.T) {
     mockRepo := NewMockUserRepository()
-    service := NewUserService(mockRepo, "test_secret", 24*time.Hour)
+    service := newTestUserService(t, mockRepo)
 
     hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
     mockRepo.users["testuser"] = &models.User{
@@ -101,24 +162,227 @@ func TestLogin(t *testing.T) {
     }
     mockRepo.usersById[1] = mockRepo.users["testuser"]
 
-    token, err := service.Login(models.LoginRequest{
+    resp, err := service.Login(context.Background(), models.LoginRequest{
         Login:    "testuser",
         Password: "password123",
-    })
+    }, models.ClientInfo{IP: "127.0.0.1", UserAgent: "test"})
 
     if err != nil {
-        t.Errorf("Ожидается успешный вход, получена ошибка: %v", err)
+        t.Fatalf("Ожидается успешный вход, получена ошибка: %v", err)
     }
-    if token == "" {
-        t.Error("Токен не должен быть пустым")
+    if resp.Token == "" || resp.RefreshToken == "" {
+        t.Error("Токены не должны быть пустыми")
+    }
+    if resp.ExpiresIn != int64((15 * time.Minute).Seconds()) {
+        t.Errorf("Ожидается время жизни access-токена 900 секунд, получено: %d", resp.ExpiresIn)
+    }
+
+    claims, err := service.ValidateToken(context.Background(), resp.Token)
+    if err != nil || claims.UserID != 1 {
+        t.Errorf("Ожидается действительный токен пользователя 1, получено: %v, %v", claims, err)
     }
 
-    _, err = service.Login(models.LoginRequest{
+    _, err = service.Login(context.Background(), models.LoginRequest{
         Login:    "testuser",
         Password: "wrongpassword",
-    })
+    }, models.ClientInfo{})
 
     if err == nil {
         t.Error("Ожидается ошибка неверного пароля")
     }
 }
+
+func TestLoginMetrics(t *testing.T) {
+    mockRepo := NewMockUserRepository()
+    service := newTestUserService(t, mockRepo)
+    service.Register(context.Background(), models.RegisterRequest{
+        Login:    "metricsuser",
+        Password: "password123",
+        Email:    "metrics@example.com",
+    })
+
+    successBefore := testutil.ToFloat64(metrics.Logins.WithLabelValues("success"))
+    failureBefore := testutil.ToFloat64(metrics.Logins.WithLabelValues("failure"))
+
+    service.Login(context.Background(), models.LoginRequest{Login: "metricsuser", Password: "password123"}, models.ClientInfo{})
+    service.Login(context.Background(), models.LoginRequest{Login: "metricsuser", Password: "wrongpassword"}, models.ClientInfo{})
+    service.Login(context.Background(), models.LoginRequest{Login: "nobody", Password: "password123"}, models.ClientInfo{})
+
+    if got := testutil.ToFloat64(metrics.Logins.WithLabelValues("success")) - successBefore; got != 1 {
+        t.Errorf("Ожидается 1 успешный вход в метриках, получено: %v", got)
+    }
+    if got := testutil.ToFloat64(metrics.Logins.WithLabelValues("failure")) - failureBefore; got != 2 {
+        t.Errorf("Ожидается 2 неудачных входа в метриках, получено: %v", got)
+    }
+}
+
+func TestAssignRole(t *testing.T) {
+    mockRepo := NewMockUserRepository()
+    service := newTestUserService(t, mockRepo)
+    service.Register(context.Background(), models.RegisterRequest{
+        Login:    "testuser",
+        Password: "password123",
+        Email:    "test@example.com",
+    })
+
+    resp, _ := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+    claims, err := service.ValidateToken(context.Background(), resp.Token)
+    if err != nil || !claims.HasRole(models.RoleUser) {
+        t.Fatalf("Новый пользователь должен получить роль user, получено: %v, %v", claims, err)
+    }
+
+    if err := service.AssignRole(context.Background(), 1, models.Role("superuser")); err != ErrUnknownRole {
+        t.Errorf("Ожидается ошибка неизвестной роли, получено: %v", err)
+    }
+    if err := service.AssignRole(context.Background(), 42, models.RoleModerator); err != ErrUserNotFound {
+        t.Errorf("Ожидается ошибка отсутствующего пользователя, получено: %v", err)
+    }
+    if err := service.AssignRole(context.Background(), 1, models.RoleModerator); err != nil {
+        t.Fatalf("Ожидается успешное назначение роли, получено: %v", err)
+    }
+    if mockRepo.usersById[1].Role != models.RoleModerator {
+        t.Errorf("Ожидается роль moderator, получена: %s", mockRepo.usersById[1].Role)
+    }
+    if _, err := service.ValidateToken(context.Background(), resp.Token); err == nil {
+        t.Error("Токены со старой ролью должны быть отозваны")
+    }
+}
+
+func TestChangePassword(t *testing.T) {
+    mockRepo := NewMockUserRepository()
+    service := newTestUserService(t, mockRepo)
+    service.Register(context.Background(), models.RegisterRequest{
+        Login:    "testuser",
+        Password: "password123",
+        Email:    "test@example.com",
+    })
+    resp, _ := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+
+    err := service.ChangePassword(context.Background(), 1, models.ChangePasswordRequest{CurrentPassword: "wrongpassword", NewPassword: "newpassword"}, models.ClientInfo{})
+    if err != ErrWrongPassword {
+        t.Errorf("Ожидается ошибка неверного текущего пароля, получено: %v", err)
+    }
+    if _, err := service.ValidateToken(context.Background(), resp.Token); err != nil {
+        t.Errorf("Неудачная попытка смены пароля не должна завершать сессии, получено: %v", err)
+    }
+
+    err = service.ChangePassword(context.Background(), 1, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword"}, models.ClientInfo{})
+    if err != nil {
+        t.Fatalf("Ожидается успешная смена пароля, получено: %v", err)
+    }
+    if _, err := service.ValidateToken(context.Background(), resp.Token); err == nil {
+        t.Error("После смены пароля выданные токены должны быть отозваны")
+    }
+    if _, err := service.RefreshToken(context.Background(), resp.RefreshToken); err == nil {
+        t.Error("После смены пароля refresh-токен должен быть отозван")
+    }
+    if _, err := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "newpassword"}, models.ClientInfo{}); err != nil {
+        t.Errorf("Ожидается вход с новым паролем, получено: %v", err)
+    }
+}
+
+func TestChangePasswordThrottled(t *testing.T) {
+    captureLog(t)
+    mockRepo := NewMockUserRepository()
+    service := newTestUserService(t, mockRepo)
+    attempts := NewMockLoginAttemptRepository()
+    service.throttler = NewLoginThrottler(attempts, testLoginThrottleConfig)
+    service.Register(context.Background(), models.RegisterRequest{
+        Login:    "testuser",
+        Password: "password123",
+        Email:    "test@example.com",
+    })
+    client := models.ClientInfo{IP: "10.0.0.1"}
+
+    // Подбор текущего пароля по украденному токену ограничивается так же, как подбор при входе
+    var lastErr error
+    for i := 0; i < testLoginThrottleConfig.MaxFailures+1; i++ {
+        lastErr = service.ChangePassword(context.Background(), 1, models.ChangePasswordRequest{CurrentPassword: "guess", NewPassword: "newpassword"}, client)
+        attempts.rewind(time.Minute)
+    }
+    if !errors.Is(lastErr, ErrTooManyAttempts) {
+        t.Errorf("Ожидается блокировка после подбора пароля, получено: %v", lastErr)
+    }
+    err := service.ChangePassword(context.Background(), 1, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword"}, client)
+    if !errors.Is(err, ErrTooManyAttempts) {
+        t.Errorf("Верный пароль не должен приниматься во время блокировки, получено: %v", err)
+    }
+    if _, err := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{}); !errors.Is(err, ErrTooManyAttempts) {
+        t.Errorf("Блокировка после подбора действует и для входа, получено: %v", err)
+    }
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+    mockRepo := NewMockUserRepository()
+    verification, m := newTestVerificationService(mockRepo)
+    tokenService := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), mockRepo, NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+    service := NewUserService(mockRepo, tokenService, verification, newTestMFAService(mockRepo), newTestLoginThrottler(), models.VerificationPolicyRestrict)
+    service.Register(context.Background(), models.RegisterRequest{
+        Login:    "testuser",
+        Password: "password123",
+        Email:    "test@example.com",
+    })
+    resp, _ := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+    m.messages = nil
+
+    if err := service.ForgotPassword(context.Background(), "nobody@example.com"); err != nil {
+        t.Errorf("Ответ для незарегистрированного адреса не должен отличаться, получено: %v", err)
+    }
+    if len(m.messages) != 0 {
+        t.Error("На незарегистрированный адрес письмо не отправляется")
+    }
+
+    if err := service.ForgotPassword(context.Background(), "test@example.com"); err != nil {
+        t.Fatalf("Ожидается успешный запрос сброса, получено: %v", err)
+    }
+    service.ForgotPassword(context.Background(), "test@example.com")
+    if len(m.messages) != 1 || m.messages[0].To != "test@example.com" {
+        t.Fatalf("Ожидается одно письмо со ссылкой сброса, получено: %+v", m.messages)
+    }
+    token := tokenFromMessage(t, m.messages[0])
+
+    if err := verification.VerifyEmail(context.Background(), token); err != ErrInvalidActionToken {
+        t.Errorf("Токен сброса пароля не подходит для подтверждения email, получено: %v", err)
+    }
+
+    err := service.ResetPassword(context.Background(), models.ResetPasswordRequest{Token: token, NewPassword: "newpassword"})
+    if err != nil {
+        t.Fatalf("Ожидается успешный сброс пароля, получено: %v", err)
+    }
+    if _, err := service.ValidateToken(context.Background(), resp.Token); err == nil {
+        t.Error("После сброса пароля выданные токены должны быть отозваны")
+    }
+    if _, err := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "newpassword"}, models.ClientInfo{}); err != nil {
+        t.Errorf("Ожидается вход с новым паролем, получено: %v", err)
+    }
+    if !mockRepo.usersById[1].EmailVerified {
+        t.Error("Переход по ссылке из письма подтверждает email")
+    }
+
+    err = service.ResetPassword(context.Background(), models.ResetPasswordRequest{Token: token, NewPassword: "another"})
+    if err != ErrInvalidActionToken {
+        t.Errorf("Токен сброса одноразовый, получено: %v", err)
+    }
+}
+
+func TestResetLinkInvalidatedByPasswordChange(t *testing.T) {
+    mockRepo := NewMockUserRepository()
+    verification, m := newTestVerificationService(mockRepo)
+    tokenService := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), mockRepo, NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+    service := NewUserService(mockRepo, tokenService, verification, newTestMFAService(mockRepo), newTestLoginThrottler(), models.VerificationPolicyRestrict)
+    service.Register(context.Background(), models.RegisterRequest{
+        Login:    "testuser",
+        Password: "password123",
+        Email:    "test@example.com",
+    })
+    m.messages = nil
+
+    service.ForgotPassword(context.Background(), "test@example.com")
+    token := tokenFromMessage(t, m.messages[0])
+    service.ChangePassword(context.Background(), 1, models.ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword"}, models.ClientInfo{})
+
+    err := service.ResetPassword(context.Background(), models.ResetPasswordRequest{Token: token, NewPassword: "another"})
+    if err != ErrInvalidActionToken {
+        t.Errorf("После смены пароля старая ссылка сброса не действует, получено: %v", err)
+    }
+}
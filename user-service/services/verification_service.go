@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	"user-service/mailer"
+	"user-service/models"
+	"user-service/repository"
+	"user-service/tracing"
+)
+
+var (
+	ErrEmailNotVerified     = errors.New("email не подтвержден")
+	ErrEmailAlreadyVerified = errors.New("email уже подтвержден")
+	ErrResendTooSoon        = errors.New("письмо уже отправлено, повторите попытку позже")
+)
+
+// VerificationConfig - адреса страниц, на которые ведут ссылки из писем (токен добавляется
+// параметром token), и сроки действия ссылок
+type VerificationConfig struct {
+	VerifyURL       string
+	ResetURL        string
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+	// ResendInterval ограничивает частоту писем одному пользователю
+	ResendInterval time.Duration
+}
+
+// VerificationService отправляет письма со ссылками подтверждения email и сброса пароля
+// и проверяет токены из этих ссылок
+type VerificationService struct {
+	userRepo     repository.UserRepositoryInterface
+	consumedRepo repository.ConsumedTokenRepositoryInterface
+	mailer       mailer.Mailer
+	signer       *ActionTokenSigner
+	config       VerificationConfig
+}
+
+func NewVerificationService(userRepo repository.UserRepositoryInterface, consumedRepo repository.ConsumedTokenRepositoryInterface, m mailer.Mailer, signer *ActionTokenSigner, config VerificationConfig) *VerificationService {
+	return &VerificationService{
+		userRepo:     userRepo,
+		consumedRepo: consumedRepo,
+		mailer:       m,
+		signer:       signer,
+		config:       config,
+	}
+}
+
+// SendVerification отправляет письмо со ссылкой подтверждения. Токен привязан к текущему
+// email пользователя, поэтому после смены адреса старые ссылки перестают работать.
+func (s *VerificationService) SendVerification(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "VerificationService.SendVerification")
+	defer span.End()
+
+	token, _, err := s.signer.Sign(models.PurposeEmailVerification, user.ID, user.Email, s.config.VerificationTTL)
+	if err != nil {
+		return err
+	}
+	err = s.sendLink(ctx, user, "Подтверждение email",
+		"Здравствуйте, %s!\n\nЧтобы подтвердить email, перейдите по ссылке:\n%s\n\nСсылка действительна %s.",
+		s.config.VerifyURL, token, s.config.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.VerificationSentAt = &now
+	return s.userRepo.UpdateUser(ctx, user)
+}
+
+func (s *VerificationService) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "VerificationService.VerifyEmail")
+	defer span.End()
+
+	claims, err := s.signer.Parse(models.PurposeEmailVerification, token)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Email != claims.Subject {
+		return ErrInvalidActionToken
+	}
+
+	if err := s.consume(ctx, claims); err != nil {
+		return err
+	}
+
+	if user.EmailVerified {
+		return nil
+	}
+	user.EmailVerified = true
+	return s.userRepo.UpdateUser(ctx, user)
+}
+
+// ResendVerification повторно отправляет письмо не чаще одного раза в resendInterval
+func (s *VerificationService) ResendVerification(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "VerificationService.ResendVerification")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	if user.VerificationSentAt != nil && time.Since(*user.VerificationSentAt) < s.config.ResendInterval {
+		return ErrResendTooSoon
+	}
+	return s.SendVerification(ctx, user)
+}
+
+// ResendInterval нужен обработчику для заголовка Retry-After
+func (s *VerificationService) ResendInterval() time.Duration {
+	return s.config.ResendInterval
+}
+
+// SendPasswordReset отправляет ссылку сброса пароля. Токен привязан к текущему хэшу пароля,
+// поэтому после любой смены пароля все ранее отправленные ссылки перестают работать.
+// Слишком частые запросы молча игнорируются, чтобы ответ не выдавал существование учетной записи.
+func (s *VerificationService) SendPasswordReset(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "VerificationService.SendPasswordReset")
+	defer span.End()
+
+	if user.PasswordResetSentAt != nil && time.Since(*user.PasswordResetSentAt) < s.config.ResendInterval {
+		return nil
+	}
+
+	token, _, err := s.signer.Sign(models.PurposePasswordReset, user.ID, passwordFingerprint(user), s.config.ResetTTL)
+	if err != nil {
+		return err
+	}
+	err = s.sendLink(ctx, user, "Сброс пароля",
+		"Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\nСсылка действительна %s. "+
+			"Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.",
+		s.config.ResetURL, token, s.config.ResetTTL)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.PasswordResetSentAt = &now
+	return s.userRepo.UpdateUser(ctx, user)
+}
+
+func (s *VerificationService) ConsumePasswordReset(ctx context.Context, token string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "VerificationService.ConsumePasswordReset")
+	defer span.End()
+
+	claims, err := s.signer.Parse(models.PurposePasswordReset, token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || passwordFingerprint(user) != claims.Subject {
+		return nil, ErrInvalidActionToken
+	}
+	if err := s.consume(ctx, claims); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// sendLink отправляет письмо со ссылкой baseURL?token=...; format получает логин, ссылку и срок ее действия
+func (s *VerificationService) sendLink(ctx context.Context, user *models.User, subject, format, baseURL, token string, ttl time.Duration) error {
+	link, err := linkWithToken(baseURL, token)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf(format, user.Login, link, ttl),
+	})
+}
+
+// linkWithToken добавляет токен к ссылке параметром token
+func linkWithToken(baseURL, token string) (string, error) {
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// consume гасит одноразовый токен; повторное предъявление отклоняется
+func (s *VerificationService) consume(ctx context.Context, claims *actionClaims) error {
+	consumed, err := s.consumedRepo.ConsumeToken(ctx, &models.ConsumedToken{
+		JTI:       claims.JTI,
+		Purpose:   claims.Purpose,
+		ExpiresAt: claims.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidActionToken
+	}
+	return nil
+}
+
+// passwordFingerprint - короткий отпечаток хэша пароля; сам хэш в токен не попадает
+func passwordFingerprint(user *models.User) string {
+	return hashToken(user.Password)[:16]
+}
+
+var _ VerificationServiceInterface = (*VerificationService)(nil)
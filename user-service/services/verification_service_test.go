@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+	"user-service/mailer"
+	"user-service/models"
+	"user-service/repository"
+)
+
+type MockMailer struct {
+	messages []mailer.Message
+	err      error
+}
+
+var _ mailer.Mailer = (*MockMailer)(nil)
+
+func (m *MockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+type MockConsumedTokenRepository struct {
+	consumed map[string]bool
+}
+
+var _ repository.ConsumedTokenRepositoryInterface = (*MockConsumedTokenRepository)(nil)
+
+func (r *MockConsumedTokenRepository) ConsumeToken(ctx context.Context, token *models.ConsumedToken) (bool, error) {
+	if r.consumed[token.JTI] {
+		return false, nil
+	}
+	r.consumed[token.JTI] = true
+	return true, nil
+}
+
+func newTestVerificationService(mockRepo *MockUserRepository) (*VerificationService, *MockMailer) {
+	m := &MockMailer{}
+	service := NewVerificationService(mockRepo, &MockConsumedTokenRepository{consumed: make(map[string]bool)}, m,
+		NewActionTokenSigner("test_secret"), VerificationConfig{
+			VerifyURL:       "http://localhost/email/verify",
+			ResetURL:        "http://localhost/password/reset",
+			VerificationTTL: time.Hour,
+			ResetTTL:        time.Hour,
+			ResendInterval:  time.Minute,
+		})
+	return service, m
+}
+
+var tokenPattern = regexp.MustCompile(`http://localhost/[a-z/]+\?token=\S+`)
+
+// tokenFromMessage извлекает токен из ссылки в письме
+func tokenFromMessage(t *testing.T, msg mailer.Message) string {
+	link, err := url.Parse(tokenPattern.FindString(msg.Body))
+	if err != nil || link.Query().Get("token") == "" {
+		t.Fatalf("Письмо должно содержать ссылку с токеном, получено: %s", msg.Body)
+	}
+	return link.Query().Get("token")
+}
+
+func TestRegisterSendsVerification(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	verification, m := newTestVerificationService(mockRepo)
+	tokenService := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), mockRepo, NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+	service := NewUserService(mockRepo, tokenService, verification, newTestMFAService(mockRepo), newTestLoginThrottler(), models.VerificationPolicyRestrict)
+
+	service.Register(context.Background(), models.RegisterRequest{Login: "testuser", Password: "password123", Email: "test@example.com"})
+
+	if len(m.messages) != 1 || m.messages[0].To != "test@example.com" {
+		t.Fatalf("Ожидается одно письмо на test@example.com, получено: %+v", m.messages)
+	}
+	user := mockRepo.usersById[1]
+	if user.EmailVerified {
+		t.Error("Email не должен быть подтвержден до перехода по ссылке")
+	}
+
+	resp, _ := service.Login(context.Background(), models.LoginRequest{Login: "testuser", Password: "password123"}, models.ClientInfo{})
+	claims, err := service.ValidateToken(context.Background(), resp.Token)
+	if err != nil || claims.EmailVerified {
+		t.Errorf("Ожидается токен с неподтвержденным email, получено: %+v, %v", claims, err)
+	}
+
+	token := tokenFromMessage(t, m.messages[0])
+	if err := verification.VerifyEmail(context.Background(), token); err != nil {
+		t.Fatalf("Ожидается успешное подтверждение, получено: %v", err)
+	}
+	if !user.EmailVerified {
+		t.Error("Email должен быть подтвержден")
+	}
+	if err := verification.VerifyEmail(context.Background(), token); err != ErrInvalidActionToken {
+		t.Errorf("Токен подтверждения одноразовый, получено: %v", err)
+	}
+
+	resp, _ = service.RefreshToken(context.Background(), resp.RefreshToken)
+	claims, _ = service.ValidateToken(context.Background(), resp.Token)
+	if !claims.EmailVerified {
+		t.Error("После обновления токен должен содержать подтвержденный email")
+	}
+}
+
+func TestVerifyEmailRejectsInvalidTokens(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	verification, m := newTestVerificationService(mockRepo)
+	mockRepo.CreateUser(context.Background(), &models.User{Login: "testuser", Email: "old@example.com"})
+	user := mockRepo.usersById[1]
+
+	verification.SendVerification(context.Background(), user)
+	oldToken := tokenFromMessage(t, m.messages[0])
+
+	// После смены адреса ссылка на старый адрес не действует
+	user.Email = "new@example.com"
+	if err := verification.VerifyEmail(context.Background(), oldToken); err != ErrInvalidActionToken {
+		t.Errorf("Ожидается отказ для ссылки на старый email, получено: %v", err)
+	}
+
+	other := NewActionTokenSigner("other_secret")
+	forged, _, _ := other.Sign(models.PurposeEmailVerification, 1, "new@example.com", time.Hour)
+	if err := verification.VerifyEmail(context.Background(), forged); err != ErrInvalidActionToken {
+		t.Errorf("Ожидается отказ для токена с чужой подписью, получено: %v", err)
+	}
+
+	expired, _, _ := verification.signer.Sign(models.PurposeEmailVerification, 1, "new@example.com", -time.Minute)
+	if err := verification.VerifyEmail(context.Background(), expired); err != ErrInvalidActionToken {
+		t.Errorf("Ожидается отказ для просроченного токена, получено: %v", err)
+	}
+
+	wrongPurpose, _, _ := verification.signer.Sign(models.TokenPurpose("other"), 1, "new@example.com", time.Hour)
+	if err := verification.VerifyEmail(context.Background(), wrongPurpose); err != ErrInvalidActionToken {
+		t.Errorf("Ожидается отказ для токена другого назначения, получено: %v", err)
+	}
+	if user.EmailVerified {
+		t.Error("Email не должен быть подтвержден")
+	}
+}
+
+func TestResendVerificationThrottling(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	verification, m := newTestVerificationService(mockRepo)
+	mockRepo.CreateUser(context.Background(), &models.User{Login: "testuser", Email: "test@example.com"})
+
+	if err := verification.ResendVerification(context.Background(), 1); err != nil {
+		t.Fatalf("Ожидается успешная отправка, получено: %v", err)
+	}
+	if err := verification.ResendVerification(context.Background(), 1); err != ErrResendTooSoon {
+		t.Errorf("Ожидается ограничение повторной отправки, получено: %v", err)
+	}
+	if len(m.messages) != 1 {
+		t.Errorf("Ожидается одно письмо, отправлено: %d", len(m.messages))
+	}
+
+	sentAt := time.Now().Add(-2 * time.Minute)
+	mockRepo.usersById[1].VerificationSentAt = &sentAt
+	if err := verification.ResendVerification(context.Background(), 1); err != nil {
+		t.Errorf("После интервала отправка должна быть разрешена, получено: %v", err)
+	}
+
+	mockRepo.usersById[1].EmailVerified = true
+	if err := verification.ResendVerification(context.Background(), 1); err != ErrEmailAlreadyVerified {
+		t.Errorf("Ожидается ошибка уже подтвержденного email, получено: %v", err)
+	}
+}
+
+func TestVerificationPolicyDeny(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	verification, m := newTestVerificationService(mockRepo)
+	tokenService := NewTokenService(NewMockTokenRepository(), NewMockSessionRepository(), mockRepo, NewMockCompanyMemberRepository(), newTestKeyService(t), 15*time.Minute, 24*time.Hour)
+	service := NewUserService(mockRepo, tokenService, verification, newTestMFAService(mockRepo), newTestLoginThrottler(), models.VerificationPolicyDeny)
+
+	service.Register(context.Background(), models.RegisterRequest{Login: "testuser", Password: "password123", Email: "test@example.com"})
+
+	login := models.LoginRequest{Login: "testuser", Password: "password123"}
+	if _, err := service.Login(context.Background(), login, models.ClientInfo{}); err != ErrEmailNotVerified {
+		t.Errorf("Ожидается запрет входа до подтверждения email, получено: %v", err)
+	}
+
+	verification.VerifyEmail(context.Background(), tokenFromMessage(t, m.messages[0]))
+	if _, err := service.Login(context.Background(), login, models.ClientInfo{}); err != nil {
+		t.Errorf("Ожидается успешный вход после подтверждения, получено: %v", err)
+	}
+}
+
+func TestEmailChangeRequiresVerification(t *testing.T) {
+	mockRepo := NewMockUserRepository()
+	verification, m := newTestVerificationService(mockRepo)
+	service := NewUserService(mockRepo, nil, verification, newTestMFAService(mockRepo), newTestLoginThrottler(), models.VerificationPolicyRestrict)
+	mockRepo.CreateUser(context.Background(), &models.User{Login: "testuser", Email: "old@example.com", EmailVerified: true})
+
+	service.UpdateUserProfile(context.Background(), 1, models.UpdateProfileRequest{Email: "old@example.com", FirstName: "Иван"})
+	if !mockRepo.usersById[1].EmailVerified || len(m.messages) != 0 {
+		t.Error("Без смены адреса подтверждение не сбрасывается")
+	}
+
+	service.UpdateUserProfile(context.Background(), 1, models.UpdateProfileRequest{Email: "new@example.com"})
+	if mockRepo.usersById[1].EmailVerified {
+		t.Error("После смены адреса email должен требовать подтверждения")
+	}
+	if len(m.messages) != 1 || m.messages[0].To != "new@example.com" {
+		t.Errorf("Ожидается письмо на новый адрес, получено: %+v", m.messages)
+	}
+}
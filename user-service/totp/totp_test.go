@@ -0,0 +1,79 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Тестовые значения из приложения B RFC 6238 для SHA1
+func TestRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, tc := range []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		step := Step(time.Unix(tc.unix, 0))
+		if code := hotp(key, uint64(step), 8); code != tc.expected {
+			t.Errorf("Время %d: ожидается %s, получено: %s", tc.unix, tc.expected, code)
+		}
+	}
+}
+
+// Тестовые значения из приложения D RFC 4226
+func TestHOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range expected {
+		if got := hotp(key, uint64(counter), 6); got != code {
+			t.Errorf("Счетчик %d: ожидается %s, получено: %s", counter, code, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("Ошибка генерации секрета: %v", err)
+	}
+	now := time.Now()
+	code, _ := Code(secret, now)
+
+	step, ok := Validate(secret, code, now)
+	if !ok || step != Step(now) {
+		t.Errorf("Текущий код должен приниматься, получено: %v %d", ok, step)
+	}
+	if _, ok := Validate(secret, code, now.Add(Period)); !ok {
+		t.Error("Код предыдущего шага должен приниматься из-за расхождения часов")
+	}
+	if _, ok := Validate(secret, code, now.Add(3*Period)); ok {
+		t.Error("Устаревший код не должен приниматься")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Error("Код неверной длины не должен приниматься")
+	}
+	if _, ok := Validate("not base32!", code, now); ok {
+		t.Error("Некорректный секрет не должен приниматься")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(ProvisioningURI("SOAProject", "testuser", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("Некорректная ссылка: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || !strings.HasSuffix(uri.Path, "SOAProject:testuser") {
+		t.Errorf("Неверная ссылка: %s", uri)
+	}
+	if uri.Query().Get("secret") != "JBSWY3DPEHPK3PXP" || uri.Query().Get("issuer") != "SOAProject" {
+		t.Errorf("Неверные параметры ссылки: %s", uri.RawQuery)
+	}
+}
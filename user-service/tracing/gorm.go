@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// GormPlugin создает спан на каждый SQL-запрос GORM. Контекст запроса передается через db.WithContext.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("tracing:before_create", beforeQuery("gorm.Create")); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("tracing:after_create", afterQuery); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("tracing:before_query", beforeQuery("gorm.Query")); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("tracing:after_query", afterQuery); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tracing:before_update", beforeQuery("gorm.Update")); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("tracing:after_update", afterQuery); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("tracing:before_delete", beforeQuery("gorm.Delete")); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("tracing:after_delete", afterQuery); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("tracing:before_row", beforeQuery("gorm.Row")); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("tracing:after_row", afterQuery); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().Before("gorm:raw").Register("tracing:before_raw", beforeQuery("gorm.Raw")); err != nil {
+		return err
+	}
+	return db.Callback().Raw().After("gorm:raw").Register("tracing:after_raw", afterQuery)
+}
+
+func beforeQuery(name string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := Start(db.Statement.Context, name)
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func afterQuery(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
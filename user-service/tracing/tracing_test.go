@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	var seen string
+	r.GET("/profile", func(c *gin.Context) {
+		seen = c.GetHeader(RequestIDHeader)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/profile", nil)
+	req.Header.Set(RequestIDHeader, "gateway-request-1")
+	r.ServeHTTP(w, req)
+
+	if seen != "gateway-request-1" || w.Header().Get(RequestIDHeader) != "gateway-request-1" {
+		t.Errorf("Ожидается переданный шлюзом X-Request-ID, получено: %q / %q", seen, w.Header().Get(RequestIDHeader))
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/profile", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n"+strings.Repeat("x", 200))
+	r.ServeHTTP(w, req)
+
+	generated := w.Header().Get(RequestIDHeader)
+	if len(generated) != 32 || generated != seen {
+		t.Errorf("Ожидается сгенерированный X-Request-ID, получено: %q / %q", generated, seen)
+	}
+}